@@ -0,0 +1,360 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"programming-lang/debugger"
+	"programming-lang/framing"
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"sync"
+)
+
+type server struct {
+	mu  sync.Mutex // guards writing messages and state of the stopped program
+	out io.Writer
+	seq int
+
+	debugger    *debugger.Debugger
+	program     *parser.Program
+	path        string
+	stopOnEntry bool
+	started     bool
+
+	// valid while the program is stopped
+	stop      *debugger.Stop
+	variables map[int]*object.Environment
+
+	// starts or resumes the program after the response is sent, so the client sees it before the next stop
+	afterResponse func() error
+}
+
+type handler func(s *server, args json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":        (*server).initialize,
+	"launch":            (*server).launch,
+	"setBreakpoints":    (*server).setBreakpoints,
+	"configurationDone": (*server).configurationDone,
+	"threads":           (*server).threads,
+	"stackTrace":        (*server).stackTrace,
+	"scopes":            (*server).scopes,
+	"variables":         (*server).variablesRequest,
+	"evaluate":          (*server).evaluate,
+	"continue":          resume((*debugger.Debugger).Continue),
+	"next":              resume((*debugger.Debugger).StepOver),
+	"stepIn":            resume((*debugger.Debugger).StepIn),
+	"stepOut":           resume((*debugger.Debugger).StepOut),
+	"pause":             (*server).pause,
+}
+
+// Serve runs the debug adapter over the given streams, until the client disconnects.
+// The launched program is read from the path given in the launch request
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{out: out, debugger: debugger.New()}
+	reader := bufio.NewReader(in)
+	for {
+		data, err := framing.Read(reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("dap error - invalid message: %v", err)
+		}
+
+		if req.Command == "disconnect" || req.Command == "terminate" {
+			return s.respond(req, nil, nil)
+		}
+
+		var body any
+		h, ok := handlers[req.Command]
+		if ok {
+			body, err = h(s, req.Arguments)
+		} else {
+			err = fmt.Errorf("unsupported command %s", req.Command)
+		}
+		if err := s.respond(req, body, err); err != nil {
+			return err
+		}
+		if s.afterResponse != nil {
+			run := s.afterResponse
+			s.afterResponse = nil
+			if err := run(); err != nil {
+				return err
+			}
+		}
+
+		if req.Command == "initialize" {
+			// breakpoints are accepted only after this event
+			if err := s.send(&event{Type: "event", Event: "initialized"}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *server) respond(req request, body any, err error) error {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	return s.send(resp)
+}
+
+// send numbers the message and writes it, messages are sent from both the request loop and program events
+func (s *server) send(msg any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	return framing.Write(s.out, msg)
+}
+
+func decode(args json.RawMessage, v any) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+func (s *server) initialize(json.RawMessage) (any, error) {
+	return capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsConditionalBreakpoints:   true,
+		SupportsEvaluateForHovers:        true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+func (s *server) launch(args json.RawMessage) (any, error) {
+	var a launchArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(a.Program)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading program: %v", err)
+	}
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(string(content)))
+	if len(program.Errors) > 0 {
+		return nil, fmt.Errorf("syntax error in %s: %v", a.Program, program.Errors[0])
+	}
+
+	s.program = program
+	s.path = a.Program
+	s.stopOnEntry = a.StopOnEntry
+	return nil, nil
+}
+
+// setBreakpoints replaces all breakpoints, the program consists of a single source
+func (s *server) setBreakpoints(args json.RawMessage) (any, error) {
+	var a setBreakpointsArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	s.debugger.ClearBreakpoints()
+	out := []breakpoint{}
+	for _, bp := range a.Breakpoints {
+		result := breakpoint{Verified: true, Line: bp.Line}
+		if err := s.debugger.SetBreakpoint(bp.Line, bp.Condition); err != nil {
+			result.Verified = false
+			result.Message = err.Error()
+		}
+		out = append(out, result)
+	}
+	return map[string]any{"breakpoints": out}, nil
+}
+
+func (s *server) configurationDone(json.RawMessage) (any, error) {
+	if s.program == nil {
+		return nil, fmt.Errorf("program not launched")
+	}
+	if !s.started {
+		s.started = true
+		s.afterResponse = func() error {
+			s.debugger.Start(s.program, object.NewEnvironment(), s.stopOnEntry)
+			go s.forwardEvents()
+			return nil
+		}
+	}
+	return nil, nil
+}
+
+// forwardEvents reports stops and the end of the program to the client
+func (s *server) forwardEvents() {
+	for {
+		ev := s.debugger.Wait()
+		if ev.Stop != nil {
+			s.mu.Lock()
+			s.stop = ev.Stop
+			s.variables = map[int]*object.Environment{}
+			s.mu.Unlock()
+			s.send(&event{Type: "event", Event: "stopped", Body: stoppedEvent{Reason: ev.Stop.Reason, ThreadID: threadID, AllThreadsStopped: true}})
+			continue
+		}
+
+		exitCode := 0
+		if ev.Result != nil {
+			if ev.Result.Type() == object.ERROR {
+				exitCode = 1
+			}
+			s.send(&event{Type: "event", Event: "output", Body: outputEvent{Category: "console", Output: ev.Result.Inspect() + "\n"}})
+		}
+		s.send(&event{Type: "event", Event: "exited", Body: exitedEvent{ExitCode: exitCode}})
+		s.send(&event{Type: "event", Event: "terminated"})
+		return
+	}
+}
+
+func (s *server) threads(json.RawMessage) (any, error) {
+	return map[string]any{"threads": []thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+func (s *server) stopped() (*debugger.Stop, error) {
+	if s.stop == nil {
+		return nil, fmt.Errorf("program is not stopped")
+	}
+	return s.stop, nil
+}
+
+// frame finds index of the frame in the stack, frames are numbered from 1 for the innermost one
+func (s *server) frame(id int) (*debugger.Stop, int, error) {
+	stop, err := s.stopped()
+	if err != nil {
+		return nil, 0, err
+	}
+	idx := len(stop.Stack) - id
+	if id < 1 || idx < 0 {
+		return nil, 0, fmt.Errorf("unknown frame %d", id)
+	}
+	return stop, idx, nil
+}
+
+func (s *server) stackTrace(json.RawMessage) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop, err := s.stopped()
+	if err != nil {
+		return nil, err
+	}
+
+	frames := []stackFrame{}
+	for i := len(stop.Stack) - 1; i >= 0; i-- {
+		f := stop.Stack[i]
+		frames = append(frames, stackFrame{
+			ID:     len(stop.Stack) - i,
+			Name:   f.Name,
+			Source: source{Name: filepath.Base(s.path), Path: s.path},
+			Line:   f.Pos.Line,
+			Column: f.Pos.Column,
+		})
+	}
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+func (s *server) scopes(args json.RawMessage) (any, error) {
+	var a frameArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop, idx, err := s.frame(a.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []scope{}
+	for _, sc := range debugger.Scopes(stop.Stack[idx].Env) {
+		ref := len(s.variables) + 1
+		s.variables[ref] = sc.Env
+		out = append(out, scope{Name: sc.Name, VariablesReference: ref})
+	}
+	return map[string]any{"scopes": out}, nil
+}
+
+func (s *server) variablesRequest(args json.RawMessage) (any, error) {
+	var a variablesArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env, ok := s.variables[a.VariablesReference]
+	if !ok {
+		return nil, fmt.Errorf("unknown variables reference %d", a.VariablesReference)
+	}
+
+	out := []variable{}
+	for _, name := range env.Names() {
+		value, _ := env.Get(name)
+		out = append(out, variable{Name: name, Value: value.Inspect(), Type: string(value.Type())})
+	}
+	return map[string]any{"variables": out}, nil
+}
+
+func (s *server) evaluate(args json.RawMessage) (any, error) {
+	var a evaluateArguments
+	if err := decode(args, &a); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a.FrameID == 0 {
+		a.FrameID = 1
+	}
+	stop, idx, err := s.frame(a.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := debugger.Evaluate(a.Expression, stop.Stack[idx])
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return map[string]any{"result": "", "variablesReference": 0}, nil
+	}
+	return map[string]any{"result": result.Inspect(), "type": string(result.Type()), "variablesReference": 0}, nil
+}
+
+// resume creates handler of a command running the program, state of the stop is dropped
+func resume(run func(*debugger.Debugger) error) handler {
+	return func(s *server, _ json.RawMessage) (any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, err := s.stopped(); err != nil {
+			return nil, err
+		}
+		s.stop = nil
+		s.variables = nil
+		s.afterResponse = func() error {
+			return run(s.debugger)
+		}
+		return map[string]any{"allThreadsContinued": true}, nil
+	}
+}
+
+func (s *server) pause(json.RawMessage) (any, error) {
+	s.debugger.Pause()
+	return nil, nil
+}
@@ -0,0 +1,210 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"programming-lang/framing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const program = `var add = fn(a, b) {
+	var sum = a + b;
+	return sum;
+};
+var x = add(1, 2);
+var y = add(x, 10);
+y * 2;`
+
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client talks to the server running in background, events are queued while waiting for responses
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	seq    int
+	events []message
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: clientOut, out: bufio.NewReader(clientIn), done: make(chan error, 1)}
+	go func() {
+		err := Serve(serverIn, serverOut)
+		serverOut.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) read() message {
+	data, err := framing.Read(c.out)
+	require.NoError(c.t, err)
+	var msg message
+	require.NoError(c.t, json.Unmarshal(data, &msg))
+	return msg
+}
+
+func (c *client) request(command string, args any) message {
+	c.seq++
+	require.NoError(c.t, framing.Write(c.in, map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args}))
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		require.Equal(c.t, c.seq, msg.RequestSeq)
+		require.Equal(c.t, command, msg.Command)
+		return msg
+	}
+}
+
+// success sends the request expecting it to succeed, returns the body
+func (c *client) success(command string, args any) string {
+	resp := c.request(command, args)
+	require.True(c.t, resp.Success, "%s failed: %s", command, resp.Message)
+	return string(resp.Body)
+}
+
+func (c *client) event(name string) string {
+	for len(c.events) == 0 {
+		c.events = append(c.events, c.read())
+	}
+	ev := c.events[0]
+	c.events = c.events[1:]
+	require.Equal(c.t, name, ev.Event)
+	return string(ev.Body)
+}
+
+func (c *client) stopped(reason string) {
+	assert.JSONEq(c.t, `{"reason":"`+reason+`","threadId":1,"allThreadsStopped":true}`, c.event("stopped"))
+}
+
+func (c *client) disconnect() {
+	c.success("disconnect", nil)
+	require.NoError(c.t, <-c.done)
+}
+
+func writeProgram(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "main.mk")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func launch(t *testing.T, path string, stopOnEntry bool) *client {
+	c := newClient(t)
+	assert.JSONEq(t, `{"supportsConfigurationDoneRequest":true,"supportsConditionalBreakpoints":true,
+		"supportsEvaluateForHovers":true,"supportsTerminateRequest":true}`, c.success("initialize", map[string]any{"adapterID": "monkey"}))
+	c.event("initialized")
+	c.success("launch", map[string]any{"program": path, "stopOnEntry": stopOnEntry})
+	return c
+}
+
+func TestDebugSession(t *testing.T) {
+	path := writeProgram(t, program)
+	c := launch(t, path, false)
+
+	breakpoints := c.success("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": path},
+		"breakpoints": []any{map[string]any{"line": 2}, map[string]any{"line": 3, "condition": "a >"}},
+	})
+	var bps struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(breakpoints), &bps))
+	require.Len(t, bps.Breakpoints, 2)
+	assert.True(t, bps.Breakpoints[0].Verified)
+	assert.False(t, bps.Breakpoints[1].Verified)
+	assert.Contains(t, bps.Breakpoints[1].Message, "invalid condition")
+
+	c.success("configurationDone", nil)
+	c.stopped("breakpoint")
+
+	assert.JSONEq(t, `{"threads":[{"id":1,"name":"main"}]}`, c.success("threads", nil))
+	assert.JSONEq(t, `{"totalFrames":2,"stackFrames":[
+		{"id":1,"name":"add","source":{"name":"main.mk","path":"`+path+`"},"line":2,"column":2},
+		{"id":2,"name":"main","source":{"name":"main.mk","path":"`+path+`"},"line":5,"column":12}]}`,
+		c.success("stackTrace", map[string]any{"threadId": 1}))
+
+	assert.JSONEq(t, `{"scopes":[{"name":"locals","variablesReference":1,"expensive":false},
+		{"name":"globals","variablesReference":2,"expensive":false}]}`, c.success("scopes", map[string]any{"frameId": 1}))
+	assert.JSONEq(t, `{"variables":[{"name":"a","value":"1","type":"INTEGER","variablesReference":0},
+		{"name":"b","value":"2","type":"INTEGER","variablesReference":0}]}`, c.success("variables", map[string]any{"variablesReference": 1}))
+	assert.JSONEq(t, `{"variables":[{"name":"add","value":"fn(a, b)","type":"FUNCTION","variablesReference":0}]}`,
+		c.success("variables", map[string]any{"variablesReference": 2}))
+	assert.JSONEq(t, `{"result":"30","type":"INTEGER","variablesReference":0}`,
+		c.success("evaluate", map[string]any{"expression": "(a + b) * 10", "frameId": 1}))
+
+	c.success("next", map[string]any{"threadId": 1})
+	c.stopped("step")
+	c.success("stepOut", map[string]any{"threadId": 1})
+	c.stopped("step")
+	assert.Contains(t, c.success("stackTrace", map[string]any{"threadId": 1}), `"line":6`)
+
+	c.success("continue", map[string]any{"threadId": 1})
+	c.stopped("breakpoint")
+	c.success("continue", map[string]any{"threadId": 1})
+
+	assert.JSONEq(t, `{"category":"console","output":"26\n"}`, c.event("output"))
+	assert.JSONEq(t, `{"exitCode":0}`, c.event("exited"))
+	c.event("terminated")
+	c.disconnect()
+}
+
+func TestStopOnEntryAndStepIn(t *testing.T) {
+	c := launch(t, writeProgram(t, program), true)
+	c.success("configurationDone", nil)
+	c.stopped("entry")
+
+	c.success("next", map[string]any{"threadId": 1})
+	c.stopped("step")
+	c.success("stepIn", map[string]any{"threadId": 1})
+	c.stopped("step")
+	assert.Contains(t, c.success("stackTrace", map[string]any{"threadId": 1}), `"name":"add"`)
+	c.disconnect()
+}
+
+func TestErrorResponses(t *testing.T) {
+	c := newClient(t)
+	c.success("initialize", nil)
+	c.event("initialized")
+
+	assert.False(t, c.request("launch", map[string]any{"program": writeProgram(t, "var x = ;")}).Success)
+	assert.False(t, c.request("launch", map[string]any{"program": "missing.mk"}).Success)
+	assert.False(t, c.request("configurationDone", nil).Success)
+	assert.False(t, c.request("stackTrace", map[string]any{"threadId": 1}).Success)
+	assert.False(t, c.request("continue", map[string]any{"threadId": 1}).Success)
+
+	resp := c.request("restartFrame", nil)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "unsupported command restartFrame", resp.Message)
+	c.disconnect()
+}
+
+func TestProgramError(t *testing.T) {
+	c := launch(t, writeProgram(t, "1 + true;"), false)
+	c.success("configurationDone", nil)
+
+	assert.JSONEq(t, `{"category":"console","output":"error: type mismatch: INTEGER + BOOLEAN\n"}`, c.event("output"))
+	assert.JSONEq(t, `{"exitCode":1}`, c.event("exited"))
+	c.event("terminated")
+	c.disconnect()
+}
@@ -0,0 +1,187 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"strconv"
+	"strings"
+)
+
+const consoleHelp = `commands:
+  break <line> [if <condition>]  set breakpoint, b for short
+  clear <line>                   remove breakpoint
+  breakpoints                    list breakpoints
+  continue, c                    run until the next breakpoint
+  step, s                        step in
+  next, n                        step over
+  out, o                         step out
+  stack, bt                      print call stack
+  env [frame]                    print variables of the frame, innermost by default
+  print <expression>, p          evaluate expression in the innermost frame
+  list, l                        print source around the current line
+  help, h                        print this help
+  quit, q                        stop debugging`
+
+// Console runs the program under the debugger, controlled by commands read from in.
+// The program stops before the first statement
+func Console(program *parser.Program, source string, in io.Reader, out io.Writer) error {
+	c := &console{debugger: New(), lines: strings.Split(source, "\n"), in: bufio.NewScanner(in), out: out}
+	c.debugger.Start(program, object.NewEnvironment(), true)
+
+	for {
+		event := c.debugger.Wait()
+		if event.Stop == nil {
+			fmt.Fprintln(out, "program finished:", inspect(event.Result))
+			return nil
+		}
+
+		c.stop = event.Stop
+		fmt.Fprintf(out, "stopped at %v (%s)\n", c.stop.Pos, c.stop.Reason)
+		c.printLine(c.stop.Pos.Line, true)
+
+		resumed, err := c.commands()
+		if err != nil || !resumed {
+			return err
+		}
+	}
+}
+
+type console struct {
+	debugger *Debugger
+	lines    []string
+	in       *bufio.Scanner
+	out      io.Writer
+	stop     *Stop
+}
+
+// commands reads commands until the program is resumed, returns false when debugging should end
+func (c *console) commands() (bool, error) {
+	for {
+		fmt.Fprint(c.out, "(debug) ")
+		if !c.in.Scan() {
+			return false, c.in.Err()
+		}
+
+		command, args, _ := strings.Cut(strings.TrimSpace(c.in.Text()), " ")
+		args = strings.TrimSpace(args)
+		switch command {
+		case "":
+		case "continue", "c":
+			return true, c.debugger.Continue()
+		case "step", "s":
+			return true, c.debugger.StepIn()
+		case "next", "n":
+			return true, c.debugger.StepOver()
+		case "out", "o":
+			return true, c.debugger.StepOut()
+		case "break", "b":
+			c.setBreakpoint(args)
+		case "clear":
+			line, err := strconv.Atoi(args)
+			if err != nil {
+				fmt.Fprintln(c.out, "invalid line:", args)
+				continue
+			}
+			c.debugger.ClearBreakpoint(line)
+		case "breakpoints":
+			for _, bp := range c.debugger.Breakpoints() {
+				if bp.Condition == "" {
+					fmt.Fprintf(c.out, "line %d\n", bp.Line)
+				} else {
+					fmt.Fprintf(c.out, "line %d if %s\n", bp.Line, bp.Condition)
+				}
+			}
+		case "stack", "bt":
+			c.printStack()
+		case "env":
+			c.printEnv(args)
+		case "print", "p":
+			c.print(args)
+		case "list", "l":
+			for line := c.stop.Pos.Line - 2; line <= c.stop.Pos.Line+2; line++ {
+				c.printLine(line, line == c.stop.Pos.Line)
+			}
+		case "help", "h":
+			fmt.Fprintln(c.out, consoleHelp)
+		case "quit", "q":
+			return false, nil
+		default:
+			fmt.Fprintf(c.out, "unknown command %q, try help\n", command)
+		}
+	}
+}
+
+func (c *console) setBreakpoint(args string) {
+	lineArg, condition, _ := strings.Cut(args, " if ")
+	line, err := strconv.Atoi(strings.TrimSpace(lineArg))
+	if err != nil {
+		fmt.Fprintln(c.out, "invalid line:", lineArg)
+		return
+	}
+	if err := c.debugger.SetBreakpoint(line, strings.TrimSpace(condition)); err != nil {
+		fmt.Fprintln(c.out, err)
+		return
+	}
+	fmt.Fprintf(c.out, "breakpoint set at line %d\n", line)
+}
+
+func (c *console) printLine(line int, current bool) {
+	if line < 1 || line > len(c.lines) {
+		return
+	}
+	marker := " "
+	if current {
+		marker = ">"
+	}
+	fmt.Fprintf(c.out, "%s %3d | %s\n", marker, line, c.lines[line-1])
+}
+
+// printStack prints frames from the innermost one, numbered like in env command
+func (c *console) printStack() {
+	for i := range c.stop.Stack {
+		frame := c.stop.Stack[len(c.stop.Stack)-1-i]
+		fmt.Fprintf(c.out, "#%d %s at %v\n", i, frame.Name, frame.Pos)
+	}
+}
+
+func (c *console) printEnv(args string) {
+	idx := 0
+	if args != "" {
+		var err error
+		idx, err = strconv.Atoi(args)
+		if err != nil || idx < 0 || idx >= len(c.stop.Stack) {
+			fmt.Fprintln(c.out, "invalid frame:", args)
+			return
+		}
+	}
+
+	frame := c.stop.Stack[len(c.stop.Stack)-1-idx]
+	for _, scope := range Scopes(frame.Env) {
+		vars := []string{}
+		for _, name := range scope.Env.Names() {
+			value, _ := scope.Env.Get(name)
+			vars = append(vars, name+" = "+inspect(value))
+		}
+		fmt.Fprintf(c.out, "%s: %s\n", scope.Name, strings.Join(vars, ", "))
+	}
+}
+
+func (c *console) print(source string) {
+	result, err := Evaluate(source, c.stop.Stack[len(c.stop.Stack)-1])
+	if err != nil {
+		fmt.Fprintln(c.out, err)
+		return
+	}
+	fmt.Fprintln(c.out, inspect(result))
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return evaluator.NULL_VAL.Inspect()
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,64 @@
+package debugger
+
+import (
+	"bytes"
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runConsole(t *testing.T, commands ...string) string {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(source))
+	require.Len(t, program.Errors, 0)
+
+	var out bytes.Buffer
+	require.NoError(t, Console(program, source, strings.NewReader(strings.Join(commands, "\n")), &out))
+	return out.String()
+}
+
+func TestConsoleSession(t *testing.T) {
+	out := runConsole(t,
+		"break 3 if a > 1",
+		"breakpoints",
+		"c",
+		"bt",
+		"env",
+		"env 1",
+		"p sum * 2",
+		"n",
+		"c",
+	)
+
+	expected := `stopped at 1:1 (entry)
+>   1 | var add = fn(a, b) {
+(debug) breakpoint set at line 3
+(debug) line 3 if a > 1
+(debug) stopped at 3:2 (breakpoint)
+>   3 | 	return sum;
+(debug) #0 add at 3:2
+#1 main at 6:12
+(debug) locals: a = 3, b = 10, sum = 13
+globals: add = fn(a, b), x = 3
+(debug) globals: add = fn(a, b), x = 3
+(debug) 26
+(debug) stopped at 7:1 (step)
+>   7 | y * 2;
+(debug) program finished: 26
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestConsoleErrors(t *testing.T) {
+	out := runConsole(t, "break x", "break 2 if var", "env 5", "p 1 +", "foo", "q")
+
+	assert.Contains(t, out, "invalid line: x")
+	assert.Contains(t, out, "breakpoint error - invalid condition")
+	assert.Contains(t, out, "invalid frame: 5")
+	assert.Contains(t, out, "no prefix parsing function")
+	assert.Contains(t, out, `unknown command "foo", try help`)
+	assert.NotContains(t, out, "program finished")
+}
@@ -0,0 +1,269 @@
+package debugger
+
+import (
+	"fmt"
+	"programming-lang/evaluator"
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"sort"
+	"sync"
+)
+
+// Reasons of stopping the program
+const (
+	ReasonEntry      = "entry"
+	ReasonBreakpoint = "breakpoint"
+	ReasonStep       = "step"
+	ReasonPause      = "pause"
+)
+
+type Breakpoint struct {
+	Line      int
+	Condition string // expression, the breakpoint is hit only when it's truthy. Empty when unconditional
+	condition parser.ExpressionNode
+}
+
+// Stop describes the paused program
+type Stop struct {
+	Reason string
+	Pos    lexer.Position
+	Stack  []evaluator.Frame // outermost first
+}
+
+// Event reports that the program stopped, or finished with Result when Stop is nil
+type Event struct {
+	Stop   *Stop
+	Result object.Object
+}
+
+type mode int
+
+const (
+	modeContinue mode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+// Debugger controls evaluation of a program running in background. It stops at statements,
+// when a breakpoint is hit or a step is finished. A line is reported once per call, even if it holds many statements
+type Debugger struct {
+	mu             sync.Mutex
+	breakpoints    map[int]Breakpoint
+	pauseRequested bool
+	stopped        bool
+
+	// owned by the evaluating goroutine
+	mode      mode
+	entry     bool
+	stopDepth int
+	lastLine  int
+	lastFrame *object.Environment // environment of the call the last line belongs to, new for each call
+
+	events chan Event
+	resume chan mode
+}
+
+func New() *Debugger {
+	return &Debugger{
+		breakpoints: map[int]Breakpoint{},
+		events:      make(chan Event),
+		resume:      make(chan mode),
+	}
+}
+
+// SetBreakpoint sets a breakpoint on the line, replacing the previous one
+func (d *Debugger) SetBreakpoint(line int, condition string) error {
+	bp := Breakpoint{Line: line, Condition: condition}
+	if condition != "" {
+		program := parser.ParseWithPositions(lexer.TokenizeWithPositions(condition))
+		if len(program.Errors) > 0 {
+			return fmt.Errorf("breakpoint error - invalid condition %q: %v", condition, program.Errors[0])
+		}
+		var exp *parser.ExpressionStatementNode
+		if len(program.Statements) == 1 {
+			exp, _ = program.Statements[0].(*parser.ExpressionStatementNode)
+		}
+		if exp == nil {
+			return fmt.Errorf("breakpoint error - condition %q is not an expression", condition)
+		}
+		bp.condition = exp.Value
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[line] = bp
+	return nil
+}
+
+func (d *Debugger) ClearBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints, line)
+}
+
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = map[int]Breakpoint{}
+}
+
+// Breakpoints returns breakpoints sorted by line
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := []Breakpoint{}
+	for _, bp := range d.breakpoints {
+		out = append(out, bp)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Line < out[j].Line
+	})
+	return out
+}
+
+// Start evaluates the program in background. With stopOnEntry it stops before the first statement
+func (d *Debugger) Start(program *parser.Program, env *object.Environment, stopOnEntry bool) {
+	d.entry = stopOnEntry
+	go func() {
+		e := evaluator.New()
+		e.Hook = d
+		result := e.Eval(program, env)
+		d.events <- Event{Result: result}
+		close(d.events)
+	}()
+}
+
+// Wait blocks until the program stops or finishes. After the program finished it returns empty events
+func (d *Debugger) Wait() Event {
+	return <-d.events
+}
+
+// Continue runs until a breakpoint is hit
+func (d *Debugger) Continue() error {
+	return d.run(modeContinue)
+}
+
+// StepIn stops at the next statement, including statements of called functions
+func (d *Debugger) StepIn() error {
+	return d.run(modeStepIn)
+}
+
+// StepOver stops at the next statement of the current function
+func (d *Debugger) StepOver() error {
+	return d.run(modeStepOver)
+}
+
+// StepOut stops at the next statement after return from the current function
+func (d *Debugger) StepOut() error {
+	return d.run(modeStepOut)
+}
+
+func (d *Debugger) run(m mode) error {
+	d.mu.Lock()
+	if !d.stopped {
+		d.mu.Unlock()
+		return fmt.Errorf("debugger error - program is not stopped")
+	}
+	d.stopped = false
+	d.mu.Unlock()
+
+	d.resume <- m
+	return nil
+}
+
+// Pause stops the running program at the next statement
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pauseRequested = true
+}
+
+// Before implements evaluator.Hook, it blocks the evaluation while the program is stopped
+func (d *Debugger) Before(node parser.Node, stack []evaluator.Frame) {
+	if _, ok := node.(parser.StatementNode); !ok {
+		return
+	}
+	pos := node.Position()
+	depth := len(stack)
+	if pos.Line == d.lastLine && stack[depth-1].Env == d.lastFrame {
+		return
+	}
+	d.lastLine, d.lastFrame = pos.Line, stack[depth-1].Env
+
+	reason := d.reason(pos.Line, depth, stack[depth-1])
+	if reason == "" {
+		return
+	}
+
+	d.mu.Lock()
+	d.stopped = true
+	d.pauseRequested = false
+	d.mu.Unlock()
+
+	d.events <- Event{Stop: &Stop{Reason: reason, Pos: pos, Stack: append([]evaluator.Frame{}, stack...)}}
+	d.mode = <-d.resume
+	d.stopDepth = depth
+}
+
+func (d *Debugger) reason(line int, depth int, frame evaluator.Frame) string {
+	d.mu.Lock()
+	pause := d.pauseRequested
+	bp, hasBreakpoint := d.breakpoints[line]
+	d.mu.Unlock()
+
+	switch {
+	case pause:
+		return ReasonPause
+	case d.entry:
+		d.entry = false
+		return ReasonEntry
+	case hasBreakpoint && bp.hit(frame.Env):
+		return ReasonBreakpoint
+	case d.mode == modeStepIn,
+		d.mode == modeStepOver && depth <= d.stopDepth,
+		d.mode == modeStepOut && depth < d.stopDepth:
+		return ReasonStep
+	}
+	return ""
+}
+
+// hit evaluates the condition, errors count as not hit
+func (bp Breakpoint) hit(env *object.Environment) bool {
+	if bp.condition == nil {
+		return true
+	}
+	result := evaluator.New().Eval(bp.condition, env)
+	return result != nil && result.Type() != object.ERROR && evaluator.IsTruthy(result)
+}
+
+// Scope is a named level of the environment chain
+type Scope struct {
+	Name string // locals, closure or globals
+	Env  *object.Environment
+}
+
+// Scopes lists the environment chain from the innermost environment
+func Scopes(env *object.Environment) []Scope {
+	out := []Scope{}
+	for e := env; e != nil; e = e.Outer() {
+		name := "closure"
+		if e.Outer() == nil {
+			name = "globals"
+		} else if e == env {
+			name = "locals"
+		}
+		out = append(out, Scope{Name: name, Env: e})
+	}
+	return out
+}
+
+// Evaluate evaluates the source in the environment of the frame, it's only safe while the program is stopped
+func Evaluate(source string, frame evaluator.Frame) (object.Object, error) {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(source))
+	if len(program.Errors) > 0 {
+		return nil, program.Errors[0]
+	}
+	return evaluator.New().Eval(program, frame.Env), nil
+}
@@ -0,0 +1,188 @@
+package debugger
+
+import (
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const source = `var add = fn(a, b) {
+	var sum = a + b;
+	return sum;
+};
+var x = add(1, 2);
+var y = add(x, 10);
+y * 2;`
+
+func start(t *testing.T, input string, stopOnEntry bool, breakpoints ...int) *Debugger {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(input))
+	require.Len(t, program.Errors, 0)
+
+	d := New()
+	for _, line := range breakpoints {
+		require.NoError(t, d.SetBreakpoint(line, ""))
+	}
+	d.Start(program, object.NewEnvironment(), stopOnEntry)
+	return d
+}
+
+// expectStop waits for the next stop, checking its reason, line and stack depth
+func expectStop(t *testing.T, d *Debugger, reason string, line int, depth int) *Stop {
+	event := d.Wait()
+	require.NotNil(t, event.Stop, "program finished with %v, expected stop at line %d", event.Result, line)
+	assert.Equal(t, reason, event.Stop.Reason)
+	assert.Equal(t, line, event.Stop.Pos.Line)
+	assert.Len(t, event.Stop.Stack, depth)
+	return event.Stop
+}
+
+func expectFinished(t *testing.T, d *Debugger, expected int) {
+	event := d.Wait()
+	require.Nil(t, event.Stop, "expected end of program")
+	integer, ok := event.Result.(*object.Integer)
+	require.True(t, ok, "expected integer result, got %v", event.Result)
+	assert.Equal(t, expected, integer.Value)
+}
+
+func TestRunWithoutBreakpoints(t *testing.T) {
+	d := start(t, source, false)
+	expectFinished(t, d, 26)
+}
+
+func TestBreakpoints(t *testing.T) {
+	d := start(t, source, false, 2, 6)
+
+	stop := expectStop(t, d, ReasonBreakpoint, 2, 2)
+	assert.Equal(t, "add", stop.Stack[1].Name)
+	assert.Equal(t, "main", stop.Stack[0].Name)
+	assert.Equal(t, 5, stop.Stack[0].Pos.Line)
+	require.NoError(t, d.Continue())
+
+	expectStop(t, d, ReasonBreakpoint, 6, 1)
+	require.NoError(t, d.Continue())
+	expectStop(t, d, ReasonBreakpoint, 2, 2)
+	require.NoError(t, d.Continue())
+	expectFinished(t, d, 26)
+}
+
+func TestConditionalBreakpoint(t *testing.T) {
+	d := start(t, source, false)
+	require.NoError(t, d.SetBreakpoint(3, "sum > 5"))
+
+	stop := expectStop(t, d, ReasonBreakpoint, 3, 2)
+	sum, _ := stop.Stack[1].Env.Get("sum")
+	assert.Equal(t, "13", sum.Inspect())
+	require.NoError(t, d.Continue())
+	expectFinished(t, d, 26)
+}
+
+func TestInvalidBreakpointCondition(t *testing.T) {
+	d := New()
+	assert.Error(t, d.SetBreakpoint(1, "1 +"))
+	assert.Error(t, d.SetBreakpoint(1, "var x = 1;"))
+	assert.Empty(t, d.Breakpoints())
+}
+
+func TestStepping(t *testing.T) {
+	d := start(t, source, true)
+
+	expectStop(t, d, ReasonEntry, 1, 1)
+	require.NoError(t, d.StepOver())
+	expectStop(t, d, ReasonStep, 5, 1)
+	require.NoError(t, d.StepIn())
+	expectStop(t, d, ReasonStep, 2, 2)
+	require.NoError(t, d.StepOver())
+	expectStop(t, d, ReasonStep, 3, 2)
+	require.NoError(t, d.StepOut())
+	expectStop(t, d, ReasonStep, 6, 1)
+	require.NoError(t, d.StepOver())
+	expectStop(t, d, ReasonStep, 7, 1)
+	require.NoError(t, d.StepOver())
+	expectFinished(t, d, 26)
+}
+
+func TestStepInRecursion(t *testing.T) {
+	d := start(t, `var f = fn(n) { if (n < 1) { return 0; } return f(n - 1); };
+f(1);`, true)
+
+	expectStop(t, d, ReasonEntry, 1, 1)
+	require.NoError(t, d.StepIn())
+	expectStop(t, d, ReasonStep, 2, 1)
+	require.NoError(t, d.StepIn())
+	expectStop(t, d, ReasonStep, 1, 2)
+	require.NoError(t, d.StepIn())
+	expectStop(t, d, ReasonStep, 1, 3)
+	require.NoError(t, d.Continue())
+	expectFinished(t, d, 0)
+}
+
+func TestStepInTailCall(t *testing.T) {
+	d := start(t, `var f = fn(n) {
+	if (n < 1) { return 0; }
+	return f(n - 1);
+};
+f(1);`, true)
+
+	expectStop(t, d, ReasonEntry, 1, 1)
+	require.NoError(t, d.StepIn())
+	expectStop(t, d, ReasonStep, 5, 1)
+	require.NoError(t, d.StepIn())
+	expectStop(t, d, ReasonStep, 2, 2)
+	require.NoError(t, d.StepIn())
+	expectStop(t, d, ReasonStep, 3, 2)
+	require.NoError(t, d.StepIn())
+	// the caller of the tail call stays in the stack
+	stop := expectStop(t, d, ReasonStep, 2, 3)
+	assert.Equal(t, 3, stop.Stack[1].Pos.Line)
+	require.NoError(t, d.Continue())
+	expectFinished(t, d, 0)
+}
+
+func TestPause(t *testing.T) {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(source))
+	d := New()
+	d.Pause()
+	d.Start(program, object.NewEnvironment(), false)
+
+	expectStop(t, d, ReasonPause, 1, 1)
+	require.NoError(t, d.Continue())
+	expectFinished(t, d, 26)
+}
+
+func TestResumeWhenRunning(t *testing.T) {
+	assert.Error(t, New().Continue())
+}
+
+func TestScopesAndEvaluate(t *testing.T) {
+	d := start(t, `var base = 10;
+var adder = fn(x) { fn(y) {
+	x + y + base
+} };
+adder(1)(2);`, false, 3)
+
+	stop := expectStop(t, d, ReasonBreakpoint, 3, 2)
+	frame := stop.Stack[1]
+
+	names := [][]string{}
+	kinds := []string{}
+	for _, scope := range Scopes(frame.Env) {
+		kinds = append(kinds, scope.Name)
+		names = append(names, scope.Env.Names())
+	}
+	assert.Equal(t, []string{"locals", "closure", "globals"}, kinds)
+	assert.Equal(t, [][]string{{"y"}, {"x"}, {"adder", "base"}}, names)
+
+	result, err := Evaluate("x * 100 + y", frame)
+	require.NoError(t, err)
+	assert.Equal(t, "102", result.Inspect())
+
+	_, err = Evaluate("x +", frame)
+	assert.Error(t, err)
+
+	require.NoError(t, d.Continue())
+	expectFinished(t, d, 13)
+}
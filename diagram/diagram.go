@@ -0,0 +1,313 @@
+package diagram
+
+import (
+	"fmt"
+	"programming-lang/parser"
+	"strings"
+)
+
+// Dot renders the tree as a Graphviz digraph
+func Dot(node parser.Node) string {
+	g := build(node)
+
+	var out strings.Builder
+	out.WriteString("digraph AST {\n")
+	out.WriteString("\tnode [shape=box];\n")
+	for _, n := range g.nodes {
+		fmt.Fprintf(&out, "\tn%d [label=%q];\n", n.id, n.label)
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&out, "\tn%d -> n%d [label=%q];\n", e.from, e.to, e.label)
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// Mermaid renders the tree as a top-down flowchart
+func Mermaid(node parser.Node) string {
+	g := build(node)
+
+	var out strings.Builder
+	out.WriteString("flowchart TD\n")
+	for _, n := range g.nodes {
+		fmt.Fprintf(&out, "\tn%d[\"%s\"]\n", n.id, mermaidEscape(n.label))
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&out, "\tn%d -->|%s| n%d\n", e.from, mermaidEscape(e.label), e.to)
+	}
+	return out.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "|", "#124;").Replace(s)
+}
+
+type graphNode struct {
+	id    int
+	label string
+}
+
+type graphEdge struct {
+	from  int
+	to    int
+	label string
+}
+
+type graph struct {
+	nodes []graphNode
+	edges []graphEdge
+}
+
+func build(node parser.Node) *graph {
+	g := &graph{}
+	g.add(node)
+	return g
+}
+
+func (g *graph) newNode(label string) int {
+	id := len(g.nodes)
+	g.nodes = append(g.nodes, graphNode{id: id, label: label})
+	return id
+}
+
+// edge adds the child subtree, edges are kept in the order of walking the tree.
+// Children missing in trees of incomplete code are skipped
+func (g *graph) edge(from int, child parser.Node, label string) {
+	if child == nil {
+		return
+	}
+	idx := len(g.edges)
+	g.edges = append(g.edges, graphEdge{from: from, label: label})
+	g.edges[idx].to = g.add(child)
+}
+
+// add walks the node, expression statements are skipped as they only wrap the expression
+func (g *graph) add(node parser.Node) int {
+	switch n := node.(type) {
+	case *parser.Program:
+		id := g.newNode("program")
+		for i, st := range n.Statements {
+			g.edge(id, st, fmt.Sprint(i+1))
+		}
+		return id
+	case *parser.VarStatementNode:
+		id := g.newNode(strings.TrimSpace("var " + n.Name))
+		if n.Pattern != nil {
+			g.edge(id, n.Pattern, "Pattern")
+		}
+		if n.Type != nil {
+			g.edge(id, n.Type, "Type")
+		}
+		if n.Value != nil {
+			g.edge(id, n.Value, "Value")
+		}
+		return id
+	case *parser.ReturnStatementNode:
+		id := g.newNode("return")
+		if n.Value != nil {
+			g.edge(id, n.Value, "Value")
+		}
+		return id
+	case *parser.ExpressionStatementNode:
+		if n.Value == nil {
+			return g.newNode("invalid expression")
+		}
+		return g.add(n.Value)
+	case *parser.BlockStatement:
+		id := g.newNode("block")
+		for i, st := range n.Statements {
+			g.edge(id, st, fmt.Sprint(i+1))
+		}
+		return id
+	case *parser.ImportStatement:
+		return g.newNode(n.String())
+	case *parser.ExportStatement:
+		id := g.newNode("export")
+		g.edge(id, n.Statement, "Statement")
+		return id
+	case *parser.ThrowStatement:
+		id := g.newNode("throw")
+		g.edge(id, n.Value, "Value")
+		return id
+	case *parser.TryStatement:
+		id := g.newNode("try")
+		g.edge(id, n.Body, "Body")
+		if n.Catch != nil {
+			g.edge(id, n.Catch, "Catch "+n.Name)
+		}
+		if n.Finally != nil {
+			g.edge(id, n.Finally, "Finally")
+		}
+		return id
+	case *parser.StructStatement:
+		return g.newNode("struct " + n.Name + " { " + strings.Join(n.Fields, ", ") + " }")
+	case *parser.MethodStatement:
+		id := g.newNode("method " + n.Name)
+		g.edge(id, n.Receiver, "Receiver")
+		g.edge(id, n.Function, "Function")
+		return id
+	case *parser.AssignStatement:
+		id := g.newNode("=")
+		g.edge(id, n.Target, "Target")
+		g.edge(id, n.Value, "Value")
+		return id
+	case *parser.IntegerLiteralExpression:
+		return g.newNode(fmt.Sprint(n.Value))
+	case *parser.FloatLiteralExpression, *parser.StringLiteralExpression:
+		return g.newNode(n.String())
+	case *parser.InterpolatedString:
+		// values are drawn as children, the label keeps only their placeholders
+		parts := []string{}
+		for _, part := range n.Parts {
+			parts = append(parts, parser.EscapeTemplate(part))
+		}
+		id := g.newNode("`" + strings.Join(parts, "${}") + "`")
+		for i, v := range n.Values {
+			g.edge(id, v, fmt.Sprintf("Value %d", i+1))
+		}
+		return id
+	case *parser.BooleanExpression:
+		return g.newNode(fmt.Sprint(n.Value))
+	case *parser.IdentifierExpression:
+		return g.newNode(n.Name)
+	case *parser.PrefixExpression:
+		id := g.newNode(n.Operator)
+		g.edge(id, n.Right, "Right")
+		return id
+	case *parser.InfixExpression:
+		id := g.newNode(n.Operator)
+		g.edge(id, n.Left, "Left")
+		g.edge(id, n.Right, "Right")
+		return id
+	case *parser.IfExpression:
+		id := g.newNode("if")
+		g.edge(id, n.Condition, "Condition")
+		g.edge(id, n.Consequence, "Consequence")
+		if n.Alternative != nil {
+			g.edge(id, n.Alternative, "Alternative")
+		}
+		return id
+	case *parser.ConditionalExpression:
+		id := g.newNode("?:")
+		g.edge(id, n.Condition, "Condition")
+		g.edge(id, n.Consequence, "Consequence")
+		g.edge(id, n.Alternative, "Alternative")
+		return id
+	case *parser.MacroLiteral:
+		id := g.newNode("macro")
+		for i, p := range n.Parameters {
+			g.edge(id, p, fmt.Sprintf("Parameter %d", i+1))
+		}
+		g.edge(id, n.Body, "Body")
+		return id
+	case *parser.FunctionLiteralExpression:
+		id := g.newNode("fn")
+		for i, p := range n.Parameters {
+			g.edge(id, p, fmt.Sprintf("Parameter %d", i+1))
+		}
+		if n.ReturnType != nil {
+			g.edge(id, n.ReturnType, "Return")
+		}
+		g.edge(id, n.Body, "Body")
+		return id
+	case *parser.FunctionParameter:
+		id := g.newNode(n.Name)
+		if n.Type != nil {
+			g.edge(id, n.Type, "Type")
+		}
+		return id
+	case *parser.CallExpression:
+		id := g.newNode("call")
+		g.edge(id, n.Function, "Function")
+		for i, a := range n.Arguments {
+			g.edge(id, a, fmt.Sprintf("Argument %d", i+1))
+		}
+		return id
+	case *parser.ArrayLiteralExpression:
+		id := g.newNode("array")
+		for i, e := range n.Elements {
+			g.edge(id, e, fmt.Sprintf("Element %d", i+1))
+		}
+		return id
+	case *parser.HashLiteralExpression:
+		id := g.newNode("hash")
+		for i := range n.Keys {
+			g.edge(id, n.Keys[i], fmt.Sprintf("Key %d", i+1))
+			g.edge(id, n.Values[i], fmt.Sprintf("Value %d", i+1))
+		}
+		return id
+	case *parser.IndexExpression:
+		label := "index"
+		if n.Optional {
+			label = "?.index"
+		}
+		id := g.newNode(label)
+		g.edge(id, n.Left, "Left")
+		g.edge(id, n.Index, "Index")
+		return id
+	case *parser.MemberExpression:
+		label := "." + n.Member
+		if n.Optional {
+			label = "?." + n.Member
+		}
+		id := g.newNode(label)
+		g.edge(id, n.Object, "Object")
+		return id
+	case *parser.SpawnExpression:
+		id := g.newNode("spawn")
+		g.edge(id, n.Call, "Call")
+		return id
+	case *parser.SelectExpression:
+		id := g.newNode("select")
+		for i, c := range n.Cases {
+			g.edge(id, c, fmt.Sprintf("Case %d", i+1))
+		}
+		if n.Default != nil {
+			g.edge(id, n.Default, "Default")
+		}
+		return id
+	case *parser.SelectCase:
+		label := "case"
+		if n.Name != "" {
+			label += " as " + n.Name
+		}
+		id := g.newNode(label)
+		g.edge(id, n.Comm, "Comm")
+		g.edge(id, n.Body, "Body")
+		return id
+	case *parser.MatchExpression:
+		id := g.newNode("match")
+		g.edge(id, n.Subject, "Subject")
+		for i, a := range n.Arms {
+			g.edge(id, a, fmt.Sprintf("Arm %d", i+1))
+		}
+		return id
+	case *parser.MatchArm:
+		id := g.newNode("=>")
+		g.edge(id, n.Pattern, "Pattern")
+		if n.Guard != nil {
+			g.edge(id, n.Guard, "Guard")
+		}
+		g.edge(id, n.Body, "Body")
+		return id
+	case *parser.ArrayPattern:
+		id := g.newNode("array pattern")
+		for i, e := range n.Elements {
+			g.edge(id, e, fmt.Sprintf("Element %d", i+1))
+		}
+		if n.Rest != nil {
+			g.edge(id, n.Rest, "Rest")
+		}
+		return id
+	case *parser.HashPattern:
+		id := g.newNode("hash pattern")
+		for i := range n.Keys {
+			g.edge(id, n.Keys[i], fmt.Sprintf("Key %d", i+1))
+			g.edge(id, n.Values[i], fmt.Sprintf("Value %d", i+1))
+		}
+		return id
+	case *parser.TypeAnnotation:
+		return g.newNode(": " + n.String())
+	}
+	return g.newNode(node.String())
+}
@@ -0,0 +1,188 @@
+package diagram
+
+import (
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, input string) *parser.Program {
+	program := parser.Parse(lexer.Tokenize(input))
+	require.Len(t, program.Errors, 0)
+	return program
+}
+
+func TestDot(t *testing.T) {
+	expected := `digraph AST {
+	node [shape=box];
+	n0 [label="program"];
+	n1 [label="var x"];
+	n2 [label="+"];
+	n3 [label="a"];
+	n4 [label="*"];
+	n5 [label="2"];
+	n6 [label="-"];
+	n7 [label="b"];
+	n0 -> n1 [label="1"];
+	n1 -> n2 [label="Value"];
+	n2 -> n3 [label="Left"];
+	n2 -> n4 [label="Right"];
+	n4 -> n5 [label="Left"];
+	n4 -> n6 [label="Right"];
+	n6 -> n7 [label="Right"];
+}
+`
+	assert.Equal(t, expected, Dot(parse(t, `var x = a + 2 * -b;`)))
+}
+
+func TestMermaid(t *testing.T) {
+	expected := `flowchart TD
+	n0["program"]
+	n1["if"]
+	n2["#lt;"]
+	n3["x"]
+	n4["1"]
+	n5["block"]
+	n6["call"]
+	n7["f"]
+	n8["true"]
+	n9["block"]
+	n0 -->|1| n1
+	n1 -->|Condition| n2
+	n2 -->|Left| n3
+	n2 -->|Right| n4
+	n1 -->|Consequence| n5
+	n5 -->|1| n6
+	n6 -->|Function| n7
+	n6 -->|Argument 1| n8
+	n1 -->|Alternative| n9
+`
+	assert.Equal(t, expected, Mermaid(parse(t, `if (x < 1) { f(true) } else {}`)))
+}
+
+func TestFunctionLiteral(t *testing.T) {
+	got := Dot(parse(t, `fn(a: int): bool { return a; }`))
+	assert.Contains(t, got, `n0 -> n1 [label="1"];`)
+	assert.Contains(t, got, `[label="Parameter 1"]`)
+	assert.Contains(t, got, `[label=": int"]`)
+	assert.Contains(t, got, `[label="Return"]`)
+	assert.Contains(t, got, `[label="Body"]`)
+	assert.Contains(t, got, `[label="return"]`)
+}
+
+func TestModules(t *testing.T) {
+	got := Dot(parse(t, `import "lib.mk" as lib; export var x = lib.f("a");`))
+	assert.Contains(t, got, `[label="import \"lib.mk\" as lib"]`)
+	assert.Contains(t, got, `[label="export"]`)
+	assert.Contains(t, got, `[label=".f"]`)
+	assert.Contains(t, got, `[label="Object"]`)
+	assert.Contains(t, got, `[label="\"a\""]`)
+}
+
+func TestArrays(t *testing.T) {
+	got := Dot(parse(t, `[1, x][0]`))
+	assert.Contains(t, got, `n0 [label="program"];`)
+	assert.Contains(t, got, `n1 [label="index"];`)
+	assert.Contains(t, got, `n2 [label="array"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Left"];`)
+	assert.Contains(t, got, `n2 -> n4 [label="Element 2"];`)
+	assert.Contains(t, got, `n1 -> n5 [label="Index"];`)
+}
+
+func TestHashes(t *testing.T) {
+	got := Dot(parse(t, `{"a": 1}`))
+	assert.Contains(t, got, `n1 [label="hash"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Key 1"];`)
+	assert.Contains(t, got, `n1 -> n3 [label="Value 1"];`)
+}
+
+func TestConcurrency(t *testing.T) {
+	got := Dot(parse(t, `select { case <-c as v { spawn f(v) } default {} }`))
+	assert.Contains(t, got, `n1 [label="select"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Case 1"];`)
+	assert.Contains(t, got, `n2 [label="case as v"];`)
+	assert.Contains(t, got, `n2 -> n3 [label="Comm"];`)
+	assert.Contains(t, got, `n3 [label="<-"];`)
+	assert.Contains(t, got, `n6 [label="spawn"];`)
+	assert.Contains(t, got, `n1 -> n10 [label="Default"];`)
+}
+
+func TestExceptions(t *testing.T) {
+	got := Dot(parse(t, `try { throw "x"; } catch (e) {} finally {}`))
+	assert.Contains(t, got, `n1 [label="try"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Body"];`)
+	assert.Contains(t, got, `n3 [label="throw"];`)
+	assert.Contains(t, got, `n1 -> n5 [label="Catch e"];`)
+	assert.Contains(t, got, `n1 -> n6 [label="Finally"];`)
+}
+
+func TestPatterns(t *testing.T) {
+	got := Dot(parse(t, `match (x) { [a, ...r] if a => r, {"k": 1} => 2 }`))
+	assert.Contains(t, got, `n1 [label="match"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Subject"];`)
+	assert.Contains(t, got, `n1 -> n3 [label="Arm 1"];`)
+	assert.Contains(t, got, `n3 [label="=>"];`)
+	assert.Contains(t, got, `n4 [label="array pattern"];`)
+	assert.Contains(t, got, `n4 -> n6 [label="Rest"];`)
+	assert.Contains(t, got, `n3 -> n7 [label="Guard"];`)
+	assert.Contains(t, got, `n10 [label="hash pattern"];`)
+
+	got = Dot(parse(t, `var [a] = b;`))
+	assert.Contains(t, got, `n1 [label="var"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Pattern"];`)
+}
+
+func TestStructs(t *testing.T) {
+	got := Dot(parse(t, `struct P { x, y } fn (p: P) m() { p.x = 1; }`))
+	assert.Contains(t, got, `n1 [label="struct P { x, y }"];`)
+	assert.Contains(t, got, `n2 [label="method m"];`)
+	assert.Contains(t, got, `n2 -> n3 [label="Receiver"];`)
+	assert.Contains(t, got, `n2 -> n5 [label="Function"];`)
+	assert.Contains(t, got, `n7 [label="="];`)
+	assert.Contains(t, got, `n7 -> n8 [label="Target"];`)
+	assert.Contains(t, got, `n7 -> n10 [label="Value"];`)
+}
+
+func TestTemplates(t *testing.T) {
+	got := Dot(parse(t, "`a ${x} b ${1}`;"))
+	assert.Contains(t, got, "n1 [label=\"`a ${} b ${}`\"];")
+	assert.Contains(t, got, `n1 -> n2 [label="Value 1"];`)
+	assert.Contains(t, got, `n1 -> n3 [label="Value 2"];`)
+}
+
+func TestMacros(t *testing.T) {
+	got := Dot(parse(t, `var m = macro(a) { quote(a) };`))
+	assert.Contains(t, got, `n2 [label="macro"];`)
+	assert.Contains(t, got, `n2 -> n3 [label="Parameter 1"];`)
+	assert.Contains(t, got, `n2 -> n4 [label="Body"];`)
+}
+
+func TestConditionals(t *testing.T) {
+	got := Dot(parse(t, `a ? b?.c : d?.[0] ?? 1;`))
+	assert.Contains(t, got, `n1 [label="?:"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Condition"];`)
+	assert.Contains(t, got, `n3 [label="?.c"];`)
+	assert.Contains(t, got, `n1 -> n3 [label="Consequence"];`)
+	assert.Contains(t, got, `n5 [label="??"];`)
+	assert.Contains(t, got, `n1 -> n5 [label="Alternative"];`)
+	assert.Contains(t, got, `n6 [label="?.index"];`)
+}
+
+func TestIncompleteCode(t *testing.T) {
+	for _, input := range []string{"1 +", "-", "1 ??", "return -", "throw -", "x.y = 1 +", "if (x) { 1 + }"} {
+		t.Run(input, func(t *testing.T) {
+			program := parser.Parse(lexer.Tokenize(input))
+			require.NotEmpty(t, program.Errors)
+			assert.NotPanics(t, func() { Dot(program) })
+			assert.NotPanics(t, func() { Mermaid(program) })
+		})
+	}
+
+	got := Dot(parser.Parse(lexer.Tokenize("1 +")))
+	assert.Contains(t, got, `n1 [label="+"];`)
+	assert.Contains(t, got, `n1 -> n2 [label="Left"];`)
+	assert.NotContains(t, got, `[label="Right"]`)
+}
@@ -0,0 +1,156 @@
+package evaluator
+
+import (
+	"errors"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"reflect"
+)
+
+// evalSpawn calls the function on a new goroutine, the result is received from the returned channel,
+// which is closed afterwards. Spawned calls don't report to the hook, the debugger follows only the main goroutine
+func (e *Evaluator) evalSpawn(node *parser.SpawnExpression, env *object.Environment) object.Object {
+	function := e.eval(node.Call.Function, env)
+	if isError(function) {
+		return function
+	}
+	args, err := e.evalExpressions(node.Call.Arguments, env)
+	if err != nil {
+		return err
+	}
+	switch function.(type) {
+	case *object.Function, *object.Builtin, *object.BoundMethod, *object.StructType:
+	default:
+		return newError("not a function: %s", function.Type())
+	}
+
+	name := functionName(node.Call)
+	child := &Evaluator{
+		Importer: e.Importer,
+		Context:  e.Context,
+		Clock:    e.Clock,
+		stack:    []Frame{{Name: "spawn " + name, Pos: node.Pos, Env: env}},
+	}
+	result := object.NewChannel(1)
+	go func() {
+		// the channel has room for the result, sending doesn't wait
+		result.Send(child.context(), child.apply(name, function, args))
+		result.Close()
+	}()
+	return result
+}
+
+// send waits until the channel takes the value
+func (e *Evaluator) send(target, value object.Object) object.Object {
+	channel, ok := target.(*object.Channel)
+	if !ok {
+		return newError("send to non-channel: %s", target.Type())
+	}
+	if err := channel.Send(e.context(), value); err != nil {
+		return e.channelError(err)
+	}
+	return NULL_VAL
+}
+
+// receive waits for a value of the channel, closed channels give null
+func (e *Evaluator) receive(source object.Object) object.Object {
+	channel, ok := source.(*object.Channel)
+	if !ok {
+		return newError("receive from non-channel: %s", source.Type())
+	}
+	value, ok, err := channel.Receive(e.context())
+	if err != nil {
+		return e.channelError(err)
+	}
+	if !ok {
+		return NULL_VAL
+	}
+	return value
+}
+
+func (e *Evaluator) channelError(err error) *object.Error {
+	if errors.Is(err, object.ErrClosedChannel) {
+		return newError("%v", err)
+	}
+	return newError("evaluation cancelled: %v", err)
+}
+
+// selected is a channel operation of a select case, each case waits on values and on closing of the channel
+type selected struct {
+	c       *parser.SelectCase
+	channel *object.Channel
+	closed  bool // the case was chosen because the channel got closed
+}
+
+// evalSelect evaluates operands of all cases in order, then runs the body of the case
+// whose operation proceeds first. With default it doesn't wait
+func (e *Evaluator) evalSelect(node *parser.SelectExpression, env *object.Environment) object.Object {
+	cases := []reflect.SelectCase{}
+	operations := []selected{}
+	for _, c := range node.Cases {
+		var target, value object.Object
+		switch comm := c.Comm.(type) {
+		case *parser.PrefixExpression:
+			if target = e.eval(comm.Right, env); isError(target) {
+				return target
+			}
+		case *parser.InfixExpression:
+			if target = e.eval(comm.Left, env); isError(target) {
+				return target
+			}
+			if value = e.eval(comm.Right, env); isError(value) {
+				return value
+			}
+		}
+
+		channel, ok := target.(*object.Channel)
+		if !ok && value == nil {
+			return newError("receive from non-channel: %s", target.Type())
+		} else if !ok {
+			return newError("send to non-channel: %s", target.Type())
+		}
+
+		if value == nil {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.Values())})
+		} else if channel.Closed() {
+			// the buffer may still have room, sending would be chosen at random
+			return newError("%v", object.ErrClosedChannel)
+		} else {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(channel.Values()), Send: reflect.ValueOf(value)})
+		}
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.Done())})
+		operations = append(operations, selected{c: c, channel: channel}, selected{c: c, channel: channel, closed: true})
+	}
+
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(e.context().Done())})
+	if node.Default != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	chosen, received, _ := reflect.Select(cases)
+	switch {
+	case chosen == len(operations):
+		return newError("evaluation cancelled: %v", e.context().Err())
+	case chosen > len(operations):
+		return e.eval(node.Default, env)
+	}
+
+	op := operations[chosen]
+	var value object.Object = NULL_VAL
+	if _, isSend := op.c.Comm.(*parser.InfixExpression); isSend && op.closed {
+		return newError("%v", object.ErrClosedChannel)
+	} else if op.closed {
+		if v, ok := op.channel.Drain(); ok {
+			value = v
+		}
+	} else if !isSend {
+		value = received.Interface().(object.Object)
+	}
+
+	if op.c.Name == "" {
+		return e.eval(op.c.Body, env)
+	}
+	caseEnv := object.NewEnclosedEnvironment(env)
+	caseEnv.Set(op.c.Name, value)
+	return e.eval(op.c.Body, caseEnv)
+}
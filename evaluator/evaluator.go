@@ -1,8 +1,12 @@
 package evaluator
 
 import (
+	"context"
+	"fmt"
+	"programming-lang/lexer"
 	"programming-lang/object"
 	"programming-lang/parser"
+	"strings"
 )
 
 var (
@@ -11,42 +15,304 @@ var (
 	NULL_VAL = &object.Null{}
 )
 
+// Frame is an entry of the call stack
+type Frame struct {
+	Name string         // called function, main for the program itself
+	Pos  lexer.Position // statement or call being evaluated in this frame
+	Env  *object.Environment
 
+	Elided int // frames of tail calls left out of the stack right before this one
+}
+
+// Hook is notified before each statement and expression is evaluated.
+// Blocks and the program itself are not reported. Stack is ordered from the outermost frame
+// and is only valid during the call
+type Hook interface {
+	Before(node parser.Node, stack []Frame)
+}
+
+// Importer loads modules named by import statements
+type Importer interface {
+	Import(path string) (*object.Module, error)
+}
+
+type Evaluator struct {
+	Hook     Hook            // optional
+	Importer Importer        // optional, imports fail without it
+	Context  context.Context // optional, evaluation stops with an error when it's done
+	Clock    object.Clock    // optional, the system clock by default
+	stack    []Frame
+	analysed map[*parser.BlockStatement]bool // function bodies with known tail calls
+	tail     map[*parser.CallExpression]bool // calls in tail position of analysed bodies
+}
+
+func New() *Evaluator {
+	return &Evaluator{}
+}
+
+// Eval evaluates the node in a new environment
 func Eval(node parser.Node) object.Object {
+	return New().Eval(node, object.NewEnvironment())
+}
+
+// Eval evaluates the node in the environment, definitions of the program are stored there.
+// Macros of a program are expanded before it's evaluated, they stay in the environment
+func (e *Evaluator) Eval(node parser.Node, env *object.Environment) object.Object {
+	if node != nil && len(e.stack) == 0 {
+		e.stack = append(e.stack, Frame{Name: "main", Pos: node.Position(), Env: env})
+		defer func() { e.stack = nil }()
+	}
+	if program, ok := node.(*parser.Program); ok {
+		if err := e.expandMacros(program, env); err != nil {
+			return err
+		}
+	}
+	return e.eval(node, env)
+}
+
+func (e *Evaluator) eval(node parser.Node, env *object.Environment) object.Object {
+	if node == nil {
+		return nil
+	}
+	e.before(node)
+
 	switch n := node.(type) {
 	case *parser.Program:
-		return evalStatemnets(n)
+		return e.evalProgram(n, env)
+	case *parser.BlockStatement:
+		return e.evalBlock(n, env)
+	case *parser.VarStatementNode:
+		return e.evalVar(n, env)
+	case *parser.ReturnStatementNode:
+		if n.Value == nil {
+			return &object.ReturnValue{Value: NULL_VAL}
+		}
+		value := e.eval(n.Value, env)
+		if isError(value) {
+			return value
+		}
+		return &object.ReturnValue{Value: value}
+	case *parser.ExpressionStatementNode:
+		return e.eval(n.Value, env)
+	case *parser.ImportStatement:
+		return e.evalImport(n, env)
+	case *parser.ExportStatement:
+		if result := e.eval(n.Statement, env); isError(result) {
+			return result
+		}
+		if n.Statement.Pattern != nil {
+			for _, b := range parser.Bindings(n.Statement.Pattern) {
+				env.Export(b.Name)
+			}
+			return nil
+		}
+		env.Export(n.Statement.Name)
+		return nil
+	case *parser.ThrowStatement:
+		return e.evalThrow(n, env)
+	case *parser.TryStatement:
+		return e.evalTry(n, env)
+	case *parser.StructStatement:
+		return e.evalStruct(n, env)
+	case *parser.MethodStatement:
+		return e.evalMethod(n, env)
+	case *parser.AssignStatement:
+		return e.evalAssign(n, env)
 	case *parser.IntegerLiteralExpression:
 		return &object.Integer{Value: n.Value}
+	case *parser.FloatLiteralExpression:
+		return &object.Float{Value: n.Value}
+	case *parser.StringLiteralExpression:
+		return &object.String{Value: n.Value}
+	case *parser.InterpolatedString:
+		return e.evalInterpolatedString(n, env)
 	case *parser.BooleanExpression:
 		return evalBoolean(n)
-	case *parser.ExpressionStatementNode:
-		return Eval(n.Value)
+	case *parser.IdentifierExpression:
+		return evalIdentifier(n, env)
 	case *parser.PrefixExpression:
-		return evalPrefix(n)
+		return e.evalPrefix(n, env)
+	case *parser.InfixExpression:
+		return e.evalInfix(n, env)
+	case *parser.IfExpression:
+		return e.evalIf(n, env)
+	case *parser.ConditionalExpression:
+		return e.evalConditional(n, env)
+	case *parser.FunctionLiteralExpression:
+		return &object.Function{Parameters: n.Parameters, Body: n.Body, Env: env}
+	case *parser.MacroLiteral:
+		return &object.Macro{Parameters: n.Parameters, Body: n.Body, Env: env}
+	case *parser.CallExpression:
+		return e.evalCall(n, env)
+	case *parser.MemberExpression:
+		return e.evalMember(n, env)
+	case *parser.ArrayLiteralExpression:
+		elements, err := e.evalExpressions(n.Elements, env)
+		if err != nil {
+			return err
+		}
+		return &object.Array{Elements: elements}
+	case *parser.IndexExpression:
+		return e.evalIndex(n, env)
+	case *parser.HashLiteralExpression:
+		return e.evalHash(n, env)
+	case *parser.SpawnExpression:
+		return e.evalSpawn(n, env)
+	case *parser.SelectExpression:
+		return e.evalSelect(n, env)
+	case *parser.MatchExpression:
+		return e.evalMatch(n, env)
 	}
 	return nil
 }
 
-func evalStatemnets(node *parser.Program) object.Object {
+// before reports the node to the hook, statements also move position of the current frame
+func (e *Evaluator) before(node parser.Node) {
+	switch node.(type) {
+	case *parser.Program, *parser.BlockStatement:
+		return
+	case parser.StatementNode, *parser.CallExpression:
+		e.stack[len(e.stack)-1].Pos = node.Position()
+	}
+	if e.Hook != nil {
+		e.Hook.Before(node, e.stack)
+	}
+}
+
+func (e *Evaluator) evalProgram(node *parser.Program, env *object.Environment) object.Object {
 	var out object.Object
 	for _, v := range node.Statements {
-		out = Eval(v)
+		if err := e.cancelled(); err != nil {
+			return err
+		}
+		out = e.eval(v, env)
+		switch result := out.(type) {
+		case *object.ReturnValue:
+			return result.Value
+		case *object.Error:
+			e.trace(result)
+			return result
+		}
 	}
 	return out
 }
 
-func evalBoolean(node *parser.BooleanExpression) object.Object {
-	switch node.Value {
-	case true: return TRUE_VAL
-	case false: return FALSE_VAL
+// evalBlock keeps return values wrapped, so they can stop evaluation of enclosing blocks
+func (e *Evaluator) evalBlock(node *parser.BlockStatement, env *object.Environment) object.Object {
+	var out object.Object = NULL_VAL
+	for _, v := range node.Statements {
+		if err := e.cancelled(); err != nil {
+			return err
+		}
+		out = e.eval(v, env)
+		if err, ok := out.(*object.Error); ok {
+			e.trace(err)
+		}
+		if out != nil && (out.Type() == object.RETURN_VALUE || out.Type() == object.ERROR) {
+			return out
+		}
+	}
+	return out
+}
+
+// context is passed to blocking builtins, along with the clock
+func (e *Evaluator) context() context.Context {
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if e.Clock != nil {
+		ctx = object.WithClock(ctx, e.Clock)
 	}
+	return ctx
+}
+
+// cancelled returns an error when the context is done, checked before each statement
+func (e *Evaluator) cancelled() *object.Error {
+	if e.Context == nil || e.Context.Err() == nil {
+		return nil
+	}
+	return newError("evaluation cancelled: %v", e.Context.Err())
+}
+
+func (e *Evaluator) evalVar(node *parser.VarStatementNode, env *object.Environment) object.Object {
+	if node.Pattern != nil {
+		return e.evalDestructuring(node, env)
+	}
+	var value object.Object = NULL_VAL
+	if node.Value != nil {
+		value = e.eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+	}
+	env.Set(node.Name, value)
 	return nil
 }
 
-func evalPrefix(node *parser.PrefixExpression) object.Object {
-	right := Eval(node.Right)
-	if node.Operator == "!" {
+func (e *Evaluator) evalImport(node *parser.ImportStatement, env *object.Environment) object.Object {
+	if e.Importer == nil {
+		return newError("cannot import %s: modules are not available", node.Path)
+	}
+	module, err := e.Importer.Import(node.Path)
+	if err != nil {
+		return newError("cannot import %s: %v", node.Path, err)
+	}
+	env.Set(node.Alias, module)
+	return nil
+}
+
+func (e *Evaluator) evalMember(node *parser.MemberExpression, env *object.Environment) object.Object {
+	obj := e.eval(node.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	if node.Optional && obj == NULL_VAL {
+		return NULL_VAL
+	}
+	if caught, ok := obj.(*object.ErrorValue); ok {
+		return errorMember(caught, node.Member)
+	}
+	if instance, ok := obj.(*object.Struct); ok {
+		return structMember(instance, node.Member)
+	}
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return newError("not a module: %s", obj.Type())
+	}
+	if member, ok := module.Member(node.Member); ok {
+		return member
+	}
+	return newError("module %s has no export %s", module.Name, node.Member)
+}
+
+func evalBoolean(node *parser.BooleanExpression) object.Object {
+	return toBoolean(node.Value)
+}
+
+func toBoolean(v bool) object.Object {
+	if v {
+		return TRUE_VAL
+	}
+	return FALSE_VAL
+}
+
+func evalIdentifier(node *parser.IdentifierExpression, env *object.Environment) object.Object {
+	if v, ok := env.Get(node.Name); ok {
+		return v
+	}
+	return newError("identifier not found: %s", node.Name)
+}
+
+func (e *Evaluator) evalPrefix(node *parser.PrefixExpression, env *object.Environment) object.Object {
+	right := e.eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	if node.Operator == "<-" {
+		return e.receive(right)
+	} else if node.Operator == "!" {
 		switch right {
 		case TRUE_VAL: return FALSE_VAL
 		case FALSE_VAL: return TRUE_VAL
@@ -56,6 +322,321 @@ func evalPrefix(node *parser.PrefixExpression) object.Object {
 	} else if node.Operator == "-" && right.Type() == object.INTEGER {
 		v := right.(*object.Integer).Value
 		return &object.Integer{Value: -v}
+	} else if node.Operator == "-" && right.Type() == object.FLOAT {
+		v := right.(*object.Float).Value
+		return &object.Float{Value: -v}
+	}
+	return newError("unknown operator: %s%s", node.Operator, right.Type())
+}
+
+func (e *Evaluator) evalInfix(node *parser.InfixExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	// right operand of ?? is evaluated only when the left one is null
+	if node.Operator == "??" && left != NULL_VAL {
+		return left
+	}
+	right := e.eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	switch {
+	case node.Operator == "??":
+		return right
+	case node.Operator == "<-":
+		return e.send(left, right)
+	case left.Type() == object.INTEGER && right.Type() == object.INTEGER:
+		return evalIntegerInfix(node.Operator, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfix(node.Operator, ToFloat(left), ToFloat(right))
+	case left.Type() == object.STRING && right.Type() == object.STRING:
+		return evalStringInfix(node.Operator, left.(*object.String).Value, right.(*object.String).Value)
+	case isStruct(left) && isStruct(right) && (node.Operator == "==" || node.Operator == "!="):
+		return toBoolean(equal(left, right) == (node.Operator == "=="))
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), node.Operator, right.Type())
+	case node.Operator == "==":
+		return toBoolean(left == right)
+	case node.Operator == "!=":
+		return toBoolean(left != right)
+	}
+	return newError("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
+}
+
+func evalIntegerInfix(operator string, left, right int) object.Object {
+	switch operator {
+	case "+": return &object.Integer{Value: left + right}
+	case "-": return &object.Integer{Value: left - right}
+	case "*": return &object.Integer{Value: left * right}
+	case "/":
+		if right == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: left / right}
+	case "<": return toBoolean(left < right)
+	case ">": return toBoolean(left > right)
+	case "<=": return toBoolean(left <= right)
+	case ">=": return toBoolean(left >= right)
+	case "==": return toBoolean(left == right)
+	case "!=": return toBoolean(left != right)
+	}
+	return newError("unknown operator: %s %s %s", object.INTEGER, operator, object.INTEGER)
+}
+
+// evalFloatInfix evaluates arithmetic on floats, or floats mixed with integers
+func evalFloatInfix(operator string, left, right float64) object.Object {
+	switch operator {
+	case "+": return &object.Float{Value: left + right}
+	case "-": return &object.Float{Value: left - right}
+	case "*": return &object.Float{Value: left * right}
+	case "/":
+		if right == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: left / right}
+	case "<": return toBoolean(left < right)
+	case ">": return toBoolean(left > right)
+	case "<=": return toBoolean(left <= right)
+	case ">=": return toBoolean(left >= right)
+	case "==": return toBoolean(left == right)
+	case "!=": return toBoolean(left != right)
+	}
+	return newError("unknown operator: %s %s %s", object.FLOAT, operator, object.FLOAT)
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER || obj.Type() == object.FLOAT
+}
+
+// ToFloat converts a number to float, objects other than integers and floats become 0
+func ToFloat(obj object.Object) float64 {
+	switch n := obj.(type) {
+	case *object.Integer:
+		return float64(n.Value)
+	case *object.Float:
+		return n.Value
+	}
+	return 0
+}
+
+func evalStringInfix(operator string, left, right string) object.Object {
+	switch operator {
+	case "+": return &object.String{Value: left + right}
+	case "==": return toBoolean(left == right)
+	case "!=": return toBoolean(left != right)
+	}
+	return newError("unknown operator: %s %s %s", object.STRING, operator, object.STRING)
+}
+
+func (e *Evaluator) evalIf(node *parser.IfExpression, env *object.Environment) object.Object {
+	condition := e.eval(node.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if IsTruthy(condition) {
+		return e.eval(node.Consequence, env)
+	} else if node.Alternative != nil {
+		return e.eval(node.Alternative, env)
 	}
 	return NULL_VAL
-}
\ No newline at end of file
+}
+
+func (e *Evaluator) evalConditional(node *parser.ConditionalExpression, env *object.Environment) object.Object {
+	condition := e.eval(node.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+	if IsTruthy(condition) {
+		return e.eval(node.Consequence, env)
+	}
+	return e.eval(node.Alternative, env)
+}
+
+// IsTruthy treats only false and null as false
+func IsTruthy(obj object.Object) bool {
+	return obj != FALSE_VAL && obj != NULL_VAL
+}
+
+func (e *Evaluator) evalCall(node *parser.CallExpression, env *object.Environment) object.Object {
+	if isCallOf(node, "quote") {
+		return e.quote(node, env)
+	}
+	function := e.eval(node.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args, err := e.evalExpressions(node.Arguments, env)
+	if err != nil {
+		return err
+	}
+	if method, ok := function.(*object.BoundMethod); ok {
+		function, args = method.Method, append([]object.Object{method.Receiver}, args...)
+	}
+	if fn, ok := function.(*object.Function); ok && e.tail[node] {
+		return &tailCall{name: functionName(node), fn: fn, args: args}
+	}
+	return e.apply(functionName(node), function, args)
+}
+
+// apply calls the function or builtin, name is shown in the call stack
+func (e *Evaluator) apply(name string, function object.Object, args []object.Object) object.Object {
+	if builtin, ok := function.(*object.Builtin); ok {
+		if builtin.Blocking != nil {
+			return builtin.Blocking(e.context(), args...)
+		}
+		return builtin.Fn(args...)
+	}
+	if def, ok := function.(*object.StructType); ok {
+		return construct(def, args)
+	}
+	if method, ok := function.(*object.BoundMethod); ok {
+		function, args = method.Method, append([]object.Object{method.Receiver}, args...)
+	}
+	fn, ok := function.(*object.Function)
+	if !ok {
+		return newError("not a function: %s", function.Type())
+	}
+
+	// tail calls come back as results and are called in a loop, so they don't grow the host stack.
+	// Their callers stay in the call stack as records, up to maxTailFrames, the rest is counted as elided
+	base, elided := len(e.stack), 0
+	for {
+		if len(args) != len(fn.Parameters) {
+			err := newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+			e.trace(err)
+			e.stack = e.stack[:base]
+			return err
+		}
+		e.analyse(fn.Body)
+
+		callEnv := object.NewEnclosedEnvironment(fn.Env)
+		for i, p := range fn.Parameters {
+			callEnv.Set(p.Name, args[i])
+		}
+
+		e.stack = append(e.stack, Frame{Name: name, Pos: fn.Body.Position(), Env: callEnv, Elided: elided})
+		result := e.eval(fn.Body, callEnv)
+
+		if ret, ok := result.(*object.ReturnValue); ok {
+			result = ret.Value
+		}
+		call, ok := result.(*tailCall)
+		if !ok {
+			e.stack = e.stack[:base]
+			return result
+		}
+		if len(e.stack)-base >= maxTailFrames {
+			e.stack = e.stack[:len(e.stack)-1]
+			elided++
+		}
+		name, fn, args = call.name, call.fn, call.args
+	}
+}
+
+// evalExpressions evaluates the list in order, stopping at the first error
+func (e *Evaluator) evalExpressions(list []parser.ExpressionNode, env *object.Environment) ([]object.Object, *object.Error) {
+	out := []object.Object{}
+	for _, exp := range list {
+		value := e.eval(exp, env)
+		if isError(value) {
+			return nil, value.(*object.Error)
+		}
+		out = append(out, value)
+	}
+	return out, nil
+}
+
+// evalInterpolatedString joins text of the template with embedded values, strings are inserted without quotes
+func (e *Evaluator) evalInterpolatedString(node *parser.InterpolatedString, env *object.Environment) object.Object {
+	values, err := e.evalExpressions(node.Values, env)
+	if err != nil {
+		return err
+	}
+	var out strings.Builder
+	out.WriteString(node.Parts[0])
+	for i, v := range values {
+		out.WriteString(v.Inspect())
+		out.WriteString(node.Parts[i+1])
+	}
+	return &object.String{Value: out.String()}
+}
+
+func (e *Evaluator) evalIndex(node *parser.IndexExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	if node.Optional && left == NULL_VAL {
+		return NULL_VAL
+	}
+	index := e.eval(node.Index, env)
+	if isError(index) {
+		return index
+	}
+
+	if hash, ok := left.(*object.Hash); ok {
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		if value, ok := hash.Get(key); ok {
+			return value
+		}
+		return NULL_VAL
+	}
+	array, ok := left.(*object.Array)
+	if !ok {
+		return newError("index operator not supported: %s", left.Type())
+	}
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return newError("array index must be INTEGER, got %s", index.Type())
+	}
+	if i.Value < 0 || i.Value >= len(array.Elements) {
+		return newError("index out of range: %d with length %d", i.Value, len(array.Elements))
+	}
+	return array.Elements[i.Value]
+}
+
+func (e *Evaluator) evalHash(node *parser.HashLiteralExpression, env *object.Environment) object.Object {
+	out := object.NewHash()
+	for i := range node.Keys {
+		key := e.eval(node.Keys[i], env)
+		if isError(key) {
+			return key
+		}
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+		value := e.eval(node.Values[i], env)
+		if isError(value) {
+			return value
+		}
+		out.Set(hashable, value)
+	}
+	return out
+}
+
+func functionName(node *parser.CallExpression) string {
+	switch f := node.Function.(type) {
+	case *parser.IdentifierExpression:
+		return f.Name
+	case *parser.MemberExpression:
+		return f.String()
+	}
+	return "anonymous"
+}
+
+func newError(format string, args ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...)}
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR
+}
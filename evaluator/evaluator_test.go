@@ -1,10 +1,15 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"programming-lang/lexer"
 	"programming-lang/object"
 	"programming-lang/parser"
+	"runtime/debug"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,12 +62,730 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestEvalInfixExpression(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{"5 + 5 * 2", 15},
+		{"(5 + 5) * 2", 20},
+		{"20 / 3 - 1", 5},
+		{"-50 + 100 + -50", 0},
+		{"1 < 2", true},
+		{"1 >= 2", false},
+		{"2 <= 2", true},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"true == true", true},
+		{"true != false", true},
+		{"(1 < 2) == false", false},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+}
+
+func TestEvalIfExpression(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+}
+
+func TestEvalConditionalOperators(t *testing.T) {
+	const null = "var n; var h = {\"a\": {\"b\": 1}, \"xs\": [2]};\n"
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"1 < 2 ? 10 : 20", "10"},
+		{"1 > 2 ? 10 : 20", "20"},
+		{"0 ? 1 : 2", "1"},
+		{"false ? 1 : true ? 2 : 3", "2"},
+		{"var fact = fn(n) { n < 2 ? 1 : n * fact(n - 1) }; fact(5)", "120"},
+		{"true ? 1 : undefined", "1"},
+		{null + "n ?? 5", "5"},
+		{null + "false ?? 5", "false"},
+		{null + "n ?? n ?? 3", "3"},
+		{null + "1 ?? undefined", "1"},
+		{null + `h["missing"] ?? "default"`, "default"},
+		{null + `h?.["a"]?.["b"]`, "1"},
+		{null + `n?.["a"]`, "null"},
+		{null + `n?.[undefined]`, "null"},
+		{null + `h["c"]?.["d"] ?? 0`, "0"},
+		{null + `n?.x`, "null"},
+		{"struct P { x } var p = P(1); p?.x", "1"},
+		{"struct P { x } var p = P(P(2)); p?.x?.x", "2"},
+		{"struct P { x } var p = P(0); p.x ?? 1", "0"},
+		{null + `n.x`, "error: not a module: NULL"},
+		{null + `n[0]`, "error: index operator not supported: NULL"},
+		{"undefined ? 1 : 2", "error: identifier not found: undefined"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, perform(tc.input).Inspect())
+		})
+	}
+}
+
+func TestEvalStatements(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{"var a = 5; a;", 5},
+		{"var a = 5; var b = a * 2; b + a;", 15},
+		{"var a: int; a;", nil},
+		{"return 10; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{"if (10 > 1) { if (10 > 1) { return 10; } return 1; }", 10},
+		{"var f = fn(x) { return x; 5; }; f(3);", 3},
+		{"var a = 5\nvar b = a * 2\nb + a", 15},
+		{"var f = fn(x) {\n\tif (x) { return }\n\t1\n}\nf(true)", nil},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+}
+
+func TestEvalFunctions(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{"var identity = fn(x) { x; }; identity(5);", 5},
+		{"var double = fn(x: int): int { x * 2 }; double(5);", 10},
+		{"var add = fn(a, b) { a + b }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"var adder = fn(x) { fn(y) { x + y } }; var addTwo = adder(2); addTwo(3);", 5},
+		{"var fact = fn(n) { if (n < 2) { return 1; } return n * fact(n - 1); }; fact(5);", 120},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// without tail calls the recursion needs far more host stack
+	defer debug.SetMaxStack(debug.SetMaxStack(32 << 20))
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"var sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(1000000, 0)", "500000500000"},
+		{`var even = fn(n) { if (n == 0) { return true; } return odd(n - 1); };
+var odd = fn(n) { if (n == 0) { return false; } return even(n - 1); };
+[even(1000000), odd(7)]`, "[true, true]"},
+		{"var count = fn(n) { if (n > 0) { return count(n - 1); } n }; count(100000)", "0"},
+		{"var count = fn(n) { n > 0 ? count(n - 1) : n }; count(100000)", "0"},
+		{"var f = fn(n) { var x = if (n > 0) { return f(n - 1); } else { n }; x }; f(100000)", "0"},
+		{"var id = fn(x) { x }; var twice = fn(f, x) { f(f(x)) }; twice(id, 3)", "3"},
+		{"var f = fn(n) { if (n == 0) { throw \"done\"; } f(n - 1) }; try { f(100000) } catch (e) { e.message }", "done"},
+		{"var f = fn(n) { f(n - 1, 1) }; f(1)", "error: wrong number of arguments: want=1, got=2"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, perform(tc.input).Inspect())
+		})
+	}
+}
+
+func TestTailCallsInTryAreNotReplaced(t *testing.T) {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(`var inner = fn() { throw "x"; };
+var outer = fn() { try { inner() } catch (e) { e.stack } };
+var tail = fn() { inner() };
+var first = outer();
+try { tail() } catch (e) { [first, e.stack] }`))
+	require.Len(t, program.Errors, 0)
+	expected := "[[{name: main, line: 4, column: 18}, {name: outer, line: 2, column: 31}, {name: inner, line: 1, column: 20}], " +
+		"[{name: main, line: 5, column: 11}, {name: tail, line: 3, column: 24}, {name: inner, line: 1, column: 20}]]"
+	assert.Equal(t, expected, New().Eval(program, object.NewEnvironment()).Inspect(), "tail call keeps the frame of tail")
+}
+
+func TestTailCallTraceback(t *testing.T) {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(`var f = fn() { 1 / 0 };
+var g = fn() { f() };
+try { g() } catch (e) { throw e; }`))
+	require.Len(t, program.Errors, 0)
+	err, ok := New().Eval(program, object.NewEnvironment()).(*object.Error)
+	require.True(t, ok)
+	expected := `Traceback (most recent call last):
+  line 3, column 8, in main
+  line 2, column 17, in g
+  line 1, column 16, in f
+Error: division by zero`
+	assert.Equal(t, expected, err.Traceback(), "frame of g stays after its tail call")
+
+	program = parser.ParseWithPositions(lexer.TokenizeWithPositions(`var f = fn() { 1 / 0 };
+var count = fn(n) { n > 0 ? count(n - 1) : f() };
+count(100);`))
+	require.Len(t, program.Errors, 0)
+	err, ok = New().Eval(program, object.NewEnvironment()).(*object.Error)
+	require.True(t, ok)
+	assert.Len(t, err.Trace, maxTailFrames+1)
+	assert.Equal(t, "count", err.Trace[maxTailFrames-1].Name)
+	assert.Equal(t, "f", err.Trace[maxTailFrames].Name)
+	assert.Equal(t, 101-(maxTailFrames-1), err.Trace[maxTailFrames].Elided)
+	assert.Contains(t, err.Traceback(), "  line 2, column 34, in count\n  ... 38 calls elided\n  line 1, column 16, in f\n")
+}
+
+func TestEvalErrors(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; 1 }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"foobar", "identifier not found: foobar"},
+		{"1 / 0", "division by zero"},
+		{"var x = 1; x(2)", "not a function: INTEGER"},
+		{"fn(a) { a }(1, 2)", "wrong number of arguments: want=1, got=2"},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+		{`"a" + 1`, "type mismatch: STRING + INTEGER"},
+		{`var x = 1; x.y`, "not a module: INTEGER"},
+		{`import "lib.mk" as lib;`, "cannot import lib.mk: modules are not available"},
+		{"[1, 2][2]", "index out of range: 2 with length 2"},
+		{"[1][-1]", "index out of range: -1 with length 1"},
+		{"[1][true]", "array index must be INTEGER, got BOOLEAN"},
+		{"1[0]", "index operator not supported: INTEGER"},
+		{"[1, foo]", "identifier not found: foo"},
+		{"{true: 1}", "unusable as hash key: BOOLEAN"},
+		{`{"a": 1}[[]]`, "unusable as hash key: ARRAY"},
+		{`{"a": foo}`, "identifier not found: foo"},
+		{"`a ${1 - true} ${foo}`", "type mismatch: INTEGER - BOOLEAN"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			result := perform(tc.input)
+			err, ok := result.(*object.Error)
+			require.True(t, ok, "expected error object, got %v", result)
+			assert.Equal(t, tc.expected, err.Message)
+		})
+	}
+}
+
+func TestEvalFloats(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{"1.5", 1.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"7 / 2.0", 3.5},
+		{"7 / 2", 3},
+		{"0.1 * 3 > 0.3", true},
+		{"1 == 1.0", true},
+		{"2.5 <= 2", false},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+	assert.Equal(t, "3.0", perform("1.5 * 2").Inspect())
+	assert.Equal(t, "error: division by zero", perform("1.5 / 0").Inspect())
+}
+
+func TestEvalStrings(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" + "b" == "ab"`, true},
+		{`var greet = fn(name) { "hello " + name }; greet("world") == "hello world"`, true},
+		{"`a ${1 + 2} ${true} ${\"s\"} ${[1, \"x\"]}` == \"a 3 true s [1, x]\"", true},
+		{"var name = \"w\"; `hello ${`${name}!`}` == \"hello w!\"", true},
+		{"`line\n${2.5}\\t\\`` == \"line\\n2.5\\t`\"", true},
+		{"`${{\"k\": 1}[\"k\"]}` == \"1\"", true},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+
+	str, ok := perform(`"a\tb" + "!"`).(*object.String)
+	require.True(t, ok)
+	assert.Equal(t, "a\tb!", str.Value)
+}
+
+func TestEvalArrays(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{"[1, 2 * 2, 3][1]", 4},
+		{"var xs = [1, [2, 3]]; xs[1][0]", 2},
+		{"var i = 0; [1, 2][i + 1]", 2},
+		{"var first = fn(xs) { xs[0] }; first([true])", true},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+	assert.Equal(t, "[1, a, [], fn(x)]", perform(`[1, "a", [], fn(x) { x }]`).Inspect())
+}
+
+func TestEvalHashes(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{`{"a": 1, "b": 2}["b"]`, 2},
+		{`var key = "k"; {key + "1": true}["k1"]`, true},
+		{`{1: 10, "1": 20}[1]`, 10},
+		{`{1: 10, "1": 20}["1"]`, 20},
+		{`{"a": 1, "a": 2}["a"]`, 2},
+		{`{"a": 1}["b"]`, nil},
+		{`{}["a"]`, nil},
+		{`var h = {"xs": [1, {"y": 3}]}; h["xs"][1]["y"]`, 3},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+	assert.Equal(t, "{b: 1, a: [2], 3: {}}", perform(`{"b": 1, "a": [2], 3: {}}`).Inspect())
+	assert.Equal(t, "{a: 3, b: 2}", perform(`{"a": 1, "b": 2, "a": 3}`).Inspect())
+}
+
+func TestEvalBuiltins(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("sum", &object.Builtin{Name: "sum", Fn: func(args ...object.Object) object.Object {
+		total := 0
+		for _, a := range args {
+			total += a.(*object.Integer).Value
+		}
+		return &object.Integer{Value: total}
+	}})
+	testInteger(t, New().Eval(parser.Parse(lexer.Tokenize("sum(1, 2, 3)")), env), 6)
+	assert.Equal(t, "builtin sum", New().Eval(parser.Parse(lexer.Tokenize("sum")), env).Inspect())
+}
+
+func TestEvalExceptions(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{`try { throw "boom"; } catch (e) { e.message }`, "boom"},
+		{`try { throw {"code": 2}; } catch (e) { e.payload["code"] }`, "2"},
+		{`try { throw {"code": 2}; } catch (e) { e.message }`, "{code: 2}"},
+		{`try { 1 / 0 } catch (e) { e.message }`, "division by zero"},
+		{`try { 1 / 0 } catch (e) { e.payload }`, "null"},
+		{`try { 1 / 0 } catch (e) { e }`, "error: division by zero"},
+		{`try { 1 } catch (e) { 2 }`, "1"},
+		{`try { throw "a"; } catch (e) { throw "b"; }`, "error: b"},
+		{`try { throw "a"; } finally { 1 }`, "error: a"},
+		{`try { throw "a"; } catch (e) { 1 } finally { throw "b"; }`, "error: b"},
+		{`fn() { try { return 1; } finally { 2 } }()`, "1"},
+		{`fn() { try { return 1; } finally { return 2; } }()`, "2"},
+		{`fn() { try { throw "a"; } finally { return 2; } }()`, "2"},
+		{`fn() { try { throw "a"; } catch (e) { return e.message; } 2 }()`, "a"},
+		{`try { throw "inner"; } catch (e) { try { throw e; } catch (again) { again.message } }`, "inner"},
+		{`try { throw 1; } catch (e) { e.other }`, "error: error has no member other"},
+		{`try { throw 1; } catch (e) { 2 }; e`, "error: identifier not found: e"},
+		{`var f = fn(x) { if (x == 0) { throw "zero"; } else { x } }; var g = fn(x) { try { f(x) } catch (e) { -1 } }; g(0) + g(5)`, "4"},
+		{`throw "top"; 1`, "error: top"},
+		{`throw foo;`, "error: identifier not found: foo"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, perform(tc.input).Inspect())
+		})
+	}
+}
+
+func TestEvalPatterns(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match (-1.5) { -1.5 => "neg", _ => "other" }`, "neg"},
+		{`match (2.0) { 2 => "int", _ => "other" }`, "int"},
+		{`match ("a") { "b" => 1, "a" => 2 }`, "2"},
+		{`match (true) { false => 0, true => 1 }`, "1"},
+		{`match (1) { "1" => "string", true => "bool", 1 => "int" }`, "int"},
+		{`match ([1, 2, 3]) { [a, b] => a + b, [a, ...rest] => rest }`, "[2, 3]"},
+		{`match ([1]) { [a, ...rest] => rest }`, "[]"},
+		{`match ([]) { [a, ..._] => a, [] => "empty" }`, "empty"},
+		{`match ([[1, 2], 3]) { [[a, b], c] => a + b + c }`, "6"},
+		{`match ({"k": 1, "other": 2}) { {"k": v} => v }`, "1"},
+		{`match ({"k": 1}) { {"x": v} => v, {} => "any hash" }`, "any hash"},
+		{`match ({1: [true]}) { {1: [false]} => "no", {1: [t]} => t }`, "true"},
+		{`match (15) { x if x > 10 => "big", x => "small" }`, "big"},
+		{`match (5) { x if x > 10 => "big", x => "small" }`, "small"},
+		{`var x = 1; match (2) { x => x }; x`, "1"},
+		{`match (3) { 1 => 1, 2 => 2 }`, "error: no match for value: 3"},
+		{`match ("x") { [a] => a }`, `error: no match for value: x`},
+		{`match (1) { x if y => x }`, "error: identifier not found: y"},
+		{`var f = fn(xs, acc) { match (xs) { [] => acc, [x, ...rest] => f(rest, acc + x) } }; f([1, 2, 3, 4], 0)`, "10"},
+		{`var [a, b] = [1, 2]; a + b`, "3"},
+		{`var [first, ...rest] = [1, 2, 3]; rest`, "[2, 3]"},
+		{`var {"name": name, "tags": [tag, ..._]} = {"name": "x", "tags": ["a", "b"]}; name + tag`, "xa"},
+		{`var [a, b] = [1];`, "error: cannot destructure [1]: pattern [a,b] does not match"},
+		{`var a = 0; var [a, 2] = [1, 3]; a`, "error: cannot destructure [1, 3]: pattern [a,2] does not match"},
+		{`var [a] = 1 / 0;`, "error: division by zero"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, perform(tc.input).Inspect())
+		})
+	}
+}
+
+func TestEvalStructs(t *testing.T) {
+	const point = "struct Point { x, y }\n"
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{point + `Point(1, 2)`, "Point{x: 1, y: 2}"},
+		{point + `Point`, "struct Point { x, y }"},
+		{point + `var p = Point(1, "a"); p.y`, "a"},
+		{point + `var p = Point(1, 2); p.x = p.x + 10; p`, "Point{x: 11, y: 2}"},
+		{point + `var p = Point(1, 2); var q = p; q.x = 5; p.x`, "5"},
+		{point + `fn (p: Point) sum() { p.x + p.y } Point(3, 4).sum()`, "7"},
+		{point + `fn (p: Point) scale(k) { Point(p.x * k, p.y * k) } Point(1, 2).scale(3)`, "Point{x: 3, y: 6}"},
+		{point + `fn (p: Point) move(dx) { p.x = p.x + dx; p } var p = Point(0, 0); p.move(2); p.move(3).x`, "5"},
+		{point + `fn (p: Point) sum() { p.x + p.y } var f = Point(1, 1).sum; f()`, "2"},
+		{point + `fn (p: Point) sum() { p.x + p.y } Point(1, 1).sum`, "method Point.sum"},
+		{point + `Point(1, 2) == Point(1, 2)`, "true"},
+		{point + `Point(1, 2) == Point(1, 2.0)`, "true"},
+		{point + `Point(1, 2) != Point(2, 1)`, "true"},
+		{point + `Point(Point(1, 2), "s") == Point(Point(1, 2), "s")`, "true"},
+		{point + `struct Other { x, y } Point(1, 2) == Other(1, 2)`, "false"},
+		{point + `match (Point(1, 2)) { p if p == Point(1, 2) => "same", _ => "other" }`, "same"},
+		{point + `var c = spawn Point(1, 2); <-c`, "Point{x: 1, y: 2}"},
+		{point + `fn (p: Point) count(n, acc) { if (n == 0) { acc } else { p.count(n - 1, acc + p.x) } } Point(2, 0).count(10000, 0)`, "20000"},
+		{point + `Point(1)`, "error: wrong number of arguments: want=2, got=1"},
+		{point + `Point(1, 2).z`, "error: unknown field z of Point"},
+		{point + `var p = Point(1, 2); p.z = 3;`, "error: unknown field z of Point"},
+		{point + `var p = 1; p.x = 3;`, "error: cannot assign field x of INTEGER"},
+		{point + `Point(1, 2) + 1`, "error: type mismatch: struct Point + INTEGER"},
+		{`struct INTEGER { x } INTEGER(1) + INTEGER(1)`, "error: unknown operator: struct INTEGER + struct INTEGER"},
+		{`struct FLOAT { x } -FLOAT(1)`, "error: unknown operator: -struct FLOAT"},
+		{`struct N { v, next } var n = N(1, 0); n.next = n; n`, "N{v: 1, next: N{...}}"},
+		{`struct N { v, next } var n = N(1, 0); n.next = [n, n]; n`, "N{v: 1, next: [N{...}, N{...}]}"},
+		{`struct N { v, next } var n = N(1, 0); n.next = n; n == n`, "true"},
+		{`struct N { v, next } var n = N(1, 0); n.next = n; var m = N(1, n); m.next = m; n == m`, "true"},
+		{`struct N { v, next } var n = N(1, 0); n.next = n; var m = N(2, 0); m.next = m; n == m`, "false"},
+		{point + `fn (p: Point) x() { 1 }`, "error: cannot declare method x: Point already has field x"},
+		{`fn (p: Nothing) f() { 1 }`, "error: cannot declare method f: identifier not found: Nothing"},
+		{`var n = 1; fn (p: n) f() { 1 }`, "error: cannot declare method f on INTEGER"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, perform(tc.input).Inspect())
+		})
+	}
+}
+
+func TestEvalMacros(t *testing.T) {
+	const unless = "var unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) };\n"
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(1 + x)`, "QUOTE((1+x))"},
+		{`quote(unquote(1 + 2) + x)`, "QUOTE((3+x))"},
+		{`var a = 5; quote(unquote(a) * unquote(a > 1))`, "QUOTE((5*true))"},
+		{`quote(unquote(quote(4 + 4)) + 1)`, "QUOTE(((4+4)+1))"},
+		{`quote(unquote([1, "a", {"k": 1.5}]))`, `QUOTE([1,"a",{"k":1.5}])`},
+		{`var f = fn(x) { quote(unquote(x) + 1) }; [f(1), f(2)]`, "[QUOTE((1+1)), QUOTE((2+1))]"},
+		{unless + `unless(10 > 5, "not greater", "greater")`, "greater"},
+		{unless + `unless(1 > 5, "not greater", foo)`, "not greater"},
+		{unless + `unless`, "macro(cond, cons, alt)"},
+		{`var m = macro(x) { quote(1) }; m(foo)`, "1"},
+		{`var reverse = macro(a, b) { quote(unquote(b) - unquote(a)) }; reverse(2 + 2, 10 - 5)`, "1"},
+		{`var twice = macro(x) { quote(unquote(x) + unquote(x)) }; var quad = macro(x) { quote(twice(twice(unquote(x)))) }; quad(3)`, "12"},
+		{`var f = fn(a) { a * 2 }; var call = macro(x) { quote(f(unquote(x))) }; var c = spawn call(4); <-c`, "8"},
+		{`var g = fn(n) { n }; var m = macro(x) { quote(if (true) { unquote(x) + 1 } else { unquote(x) }) }; var f = fn(n) { m(g(n)) }; f(1)`, "2"},
+		{`var m = macro(x) { 1 }; m(2)`, "error: macro m must return quoted code, got INTEGER"},
+		{`var m = macro(x) { quote(m(x)) }; m(1)`, "error: macro m expands too deep"},
+		{`var m = macro(x) { x }; m(1, 2)`, "error: wrong number of arguments: want=1, got=2"},
+		{`var m = macro(x) { quote(1) }; spawn m(1)`, "error: m must produce a call to be spawned, got 1"},
+		{`var m = macro() { throw "no"; }; 1; m()`, "error: no"},
+		{`quote(1, 2)`, "error: wrong number of arguments: want=1, got=2"},
+		{`quote(unquote(fn() { 1 }))`, "error: cannot unquote FUNCTION"},
+		{`quote(unquote(foo))`, "error: identifier not found: foo"},
+		{`var f = fn() { macro(x) { x } }; f()(1)`, "error: not a function: MACRO"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, perform(tc.input).Inspect())
+		})
+	}
+
+	// definitions are removed and calls replaced before evaluation, macros stay in the environment
+	program := parser.Parse(lexer.Tokenize(unless + `unless(false, 1, 2);`))
+	env := object.NewEnvironment()
+	assert.Equal(t, "1", New().Eval(program, env).Inspect())
+	assert.Equal(t, "if(!false) 1 else 2", program.String())
+	macro, ok := env.Get("unless")
+	require.True(t, ok)
+	assert.Equal(t, object.MACRO, macro.Type())
+}
+
+func TestStackTrace(t *testing.T) {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(`var inner = fn() {
+	throw "deep";
+};
+var outer = fn() {
+	try { inner(); } catch (e) { throw e; }
+};
+outer();`))
+	require.Len(t, program.Errors, 0)
+	result := New().Eval(program, object.NewEnvironment())
+	err, ok := result.(*object.Error)
+	require.True(t, ok, "expected error object, got %v", result)
+
+	expected := `Traceback (most recent call last):
+  line 7, column 6, in main
+  line 5, column 13, in outer
+  line 2, column 2, in inner
+Error: deep`
+	assert.Equal(t, expected, err.Traceback(), "rethrown error keeps its stack trace")
+
+	program = parser.ParseWithPositions(lexer.TokenizeWithPositions(`var f = fn() { 1 / 0 };
+try { f(); } catch (e) { e.stack }`))
+	require.Len(t, program.Errors, 0)
+	assert.Equal(t, "[{name: main, line: 2, column: 8}, {name: f, line: 1, column: 16}]", New().Eval(program, object.NewEnvironment()).Inspect())
+}
+
+func TestCancellationIsNotCaught(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	env := object.NewEnvironment()
+	env.Set("cancel", &object.Builtin{Name: "cancel", Fn: func(args ...object.Object) object.Object {
+		cancel()
+		return NULL_VAL
+	}})
+	e := New()
+	e.Context = ctx
+	program := parser.Parse(lexer.Tokenize(`try { cancel(); 1; } catch (e) { 2 }`))
+	assert.Equal(t, "error: evaluation cancelled: context canceled", e.Eval(program, env).Inspect())
+}
+
+func TestEvalConcurrency(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{`var f = fn(x) { x * 2 }; <-spawn f(21)`, 42},
+		{`var x = 1; var f = fn() { var x = 2; x }; var c = spawn f(); <-c + x`, 3},
+		{`var c = spawn fn() { 1 }(); <-c; <-c`, nil},
+		{`var fib = fn(n) { if (n < 2) { n } else { <-spawn fib(n - 1) + <-spawn fib(n - 2) } }; fib(12)`, 144},
+		{`var f = fn() { g + 1 }; var g = 1; var c = spawn f(); var h = 2; var i = 3; <-c`, 2},
+		{`var c = spawn fn() { 1 }(); select { case <-c as v { v + 1 } }`, 2},
+		{`var c = spawn fn() { 1 }(); <-c; select { case <-c as v { v } }`, nil},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			testValue(t, perform(tc.input), tc.expected)
+		})
+	}
+
+	env := object.NewEnvironment()
+	env.Set("ch", object.NewChannel(1))
+	testInteger(t, New().Eval(parser.Parse(lexer.Tokenize(`ch <- 5; <-ch`)), env), 5)
+	testInteger(t, New().Eval(parser.Parse(lexer.Tokenize(`select { case ch <- 1 { 2 } default { 3 } }`)), env), 2)
+	testInteger(t, New().Eval(parser.Parse(lexer.Tokenize(`select { case ch <- 1 { 2 } default { 3 } }`)), env), 3)
+	assert.Equal(t, "error: send to non-channel: INTEGER", New().Eval(parser.Parse(lexer.Tokenize(`1 <- 2`)), env).Inspect())
+	assert.Equal(t, "error: receive from non-channel: BOOLEAN", New().Eval(parser.Parse(lexer.Tokenize(`<-true`)), env).Inspect())
+}
+
+func TestBlockedChannelsAreCancelled(t *testing.T) {
+	for _, input := range []string{`<-ch`, `ch <- 1; ch <- 2`, `select { case <-ch { 1 } }`, `<-spawn fn() { <-ch }()`} {
+		t.Run(input, func(t *testing.T) {
+			env := object.NewEnvironment()
+			env.Set("ch", object.NewChannel(1))
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			e := New()
+			e.Context = ctx
+			assert.Equal(t, "error: evaluation cancelled: context deadline exceeded", e.Eval(parser.Parse(lexer.Tokenize(input)), env).Inspect())
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	env := object.NewEnvironment()
+	env.Set("cancel", &object.Builtin{Name: "cancel", Fn: func(args ...object.Object) object.Object {
+		cancel()
+		return NULL_VAL
+	}})
+	env.Set("wait", &object.Builtin{Name: "wait", Blocking: func(ctx context.Context, args ...object.Object) object.Object {
+		<-ctx.Done()
+		return newError("wait: %v", ctx.Err())
+	}})
+
+	e := New()
+	e.Context = ctx
+	program := parser.Parse(lexer.Tokenize(`var x = 1; cancel(); var y = 2;`))
+	assert.Equal(t, "error: evaluation cancelled: context canceled", e.Eval(program, env).Inspect())
+	_, ok := env.Get("y")
+	assert.False(t, ok, "statements after cancellation must not run")
+
+	// blocking builtins get the context
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	e.Context = ctx
+	program = parser.Parse(lexer.Tokenize(`wait()`))
+	assert.Equal(t, "error: wait: context deadline exceeded", e.Eval(program, env).Inspect())
+	testInteger(t, New().Eval(parser.Parse(lexer.Tokenize(`fn() { 1 }()`)), env), 1)
+}
+
+// mapImporter evaluates modules from source kept in memory
+type mapImporter map[string]string
+
+func (m mapImporter) Import(path string) (*object.Module, error) {
+	source, ok := m[path]
+	if !ok {
+		return nil, errors.New("module not found")
+	}
+	env := object.NewEnvironment()
+	e := New()
+	e.Importer = m
+	if result := e.Eval(parser.Parse(lexer.Tokenize(source)), env); isError(result) {
+		return nil, errors.New(result.(*object.Error).Message)
+	}
+	return &object.Module{Name: path, Env: env}, nil
+}
+
+func TestEvalModules(t *testing.T) {
+	importer := mapImporter{
+		"math.mk": `export var double = fn(x) { helper(x) }; var helper = fn(x) { x * 2 };`,
+		"app.mk":  `import "math.mk" as m; export var four = m.double(2);`,
+		"bad.mk":  `export var x = 1 + true;`,
+	}
+	tdt := []struct {
+		input    string
+		expected any
+	}{
+		{`import "math.mk" as m; m.double(21)`, 42},
+		{`import "app.mk" as app; app.four`, 4},
+		{`import "math.mk" as m; var f = m.double; f(1)`, 2},
+		{`import "math.mk" as m; m.helper`, "module math.mk has no export helper"},
+		{`import "missing.mk" as m;`, "cannot import missing.mk: module not found"},
+		{`import "bad.mk" as m;`, "cannot import bad.mk: type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			e := New()
+			e.Importer = importer
+			result := e.Eval(parser.Parse(lexer.Tokenize(tc.input)), object.NewEnvironment())
+			if message, ok := tc.expected.(string); ok {
+				err, ok := result.(*object.Error)
+				require.True(t, ok, "expected error object, got %v", result)
+				assert.Equal(t, message, err.Message)
+				return
+			}
+			testValue(t, result, tc.expected)
+		})
+	}
+}
+
+func TestExportsAreMarked(t *testing.T) {
+	env := object.NewEnvironment()
+	New().Eval(parser.Parse(lexer.Tokenize(`export var a = 1; var b = 2; export var [c, {"d": d}] = [3, {"d": 4}];`)), env)
+	assert.True(t, env.IsExported("a"))
+	assert.False(t, env.IsExported("b"))
+	assert.True(t, env.IsExported("c"))
+	assert.True(t, env.IsExported("d"))
+}
+
+func TestPersistentEnvironment(t *testing.T) {
+	env := object.NewEnvironment()
+	e := New()
+	e.Eval(parser.Parse(lexer.Tokenize("var x = 2;")), env)
+	testInteger(t, e.Eval(parser.Parse(lexer.Tokenize("x * 3")), env), 6)
+}
+
+type recordingHook struct {
+	events []string
+}
+
+func (r *recordingHook) Before(node parser.Node, stack []Frame) {
+	names := []string{}
+	for _, f := range stack {
+		names = append(names, fmt.Sprintf("%s@%v", f.Name, f.Pos))
+	}
+	r.events = append(r.events, fmt.Sprintf("%T %v", node, names))
+}
+
+func TestHook(t *testing.T) {
+	hook := &recordingHook{}
+	e := New()
+	e.Hook = hook
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions("var f = fn(a) {\n\treturn a;\n};\nf(1);"))
+	require.Len(t, program.Errors, 0)
+	testInteger(t, e.Eval(program, object.NewEnvironment()), 1)
+
+	expected := []string{
+		"*parser.VarStatementNode [main@1:1]",
+		"*parser.FunctionLiteralExpression [main@1:1]",
+		"*parser.ExpressionStatementNode [main@4:1]",
+		"*parser.CallExpression [main@4:2]",
+		"*parser.IdentifierExpression [main@4:2]",
+		"*parser.IntegerLiteralExpression [main@4:2]",
+		"*parser.ReturnStatementNode [main@4:2 f@2:2]",
+		"*parser.IdentifierExpression [main@4:2 f@2:2]",
+	}
+	assert.Equal(t, expected, hook.events)
+}
+
+func testValue(t *testing.T, ob object.Object, expected any) {
+	switch v := expected.(type) {
+	case int:
+		testInteger(t, ob, v)
+	case float64:
+		testFloat(t, ob, v)
+	case bool:
+		testBoolean(t, ob, v)
+	case nil:
+		assert.Equal(t, NULL_VAL, ob)
+	}
+}
+
 func testInteger(t *testing.T, ob object.Object, expected int) {
 	integer, ok := ob.(*object.Integer)
 	require.True(t, ok, "expected integer object, not found")
 	assert.Equal(t, expected, integer.Value)
 }
 
+func testFloat(t *testing.T, ob object.Object, expected float64) {
+	float, ok := ob.(*object.Float)
+	require.True(t, ok, "expected float object, not found")
+	assert.Equal(t, expected, float.Value)
+}
+
 func testBoolean(t *testing.T, ob object.Object, expected bool) {
 	boolean, ok := ob.(*object.Boolean)
 	require.True(t, ok, "expected boolean object, not found")
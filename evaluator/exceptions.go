@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"programming-lang/object"
+	"programming-lang/parser"
+)
+
+// evalThrow raises the value as an error. Strings become the message, other values are described by it.
+// Caught errors are raised again as they were
+func (e *Evaluator) evalThrow(node *parser.ThrowStatement, env *object.Environment) object.Object {
+	value := e.eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	switch v := value.(type) {
+	case *object.ErrorValue:
+		return v.Error
+	case *object.String:
+		return &object.Error{Message: v.Value, Payload: v}
+	}
+	return &object.Error{Message: value.Inspect(), Payload: value}
+}
+
+// evalTry runs the catch block for errors of the body, except cancellation which can't be caught.
+// The finally block runs in any case, its own error or return replaces the result
+func (e *Evaluator) evalTry(node *parser.TryStatement, env *object.Environment) object.Object {
+	result := e.eval(node.Body, env)
+	if err, ok := result.(*object.Error); ok && node.Catch != nil && e.cancelled() == nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		catchEnv.Set(node.Name, &object.ErrorValue{Error: err})
+		result = e.eval(node.Catch, catchEnv)
+	}
+
+	if node.Finally != nil {
+		final := e.eval(node.Finally, env)
+		if final != nil && (final.Type() == object.RETURN_VALUE || final.Type() == object.ERROR) {
+			return final
+		}
+	}
+	return result
+}
+
+// trace captures the call stack into the error, unless it was raised before and already has one
+func (e *Evaluator) trace(err *object.Error) {
+	if err.Trace != nil {
+		return
+	}
+	err.Trace = []object.TraceFrame{}
+	for _, f := range e.stack {
+		err.Trace = append(err.Trace, object.TraceFrame{Name: f.Name, Pos: f.Pos, Elided: f.Elided})
+	}
+}
+
+// errorMember returns message, payload or stack of the caught error,
+// the stack is a list of hashes with name, line and column of each call
+func errorMember(caught *object.ErrorValue, member string) object.Object {
+	switch member {
+	case "message":
+		return &object.String{Value: caught.Error.Message}
+	case "payload":
+		if caught.Error.Payload == nil {
+			return NULL_VAL
+		}
+		return caught.Error.Payload
+	case "stack":
+		out := &object.Array{Elements: []object.Object{}}
+		for _, f := range caught.Error.Trace {
+			frame := object.NewHash()
+			frame.Set(&object.String{Value: "name"}, &object.String{Value: f.Name})
+			frame.Set(&object.String{Value: "line"}, &object.Integer{Value: f.Pos.Line})
+			frame.Set(&object.String{Value: "column"}, &object.Integer{Value: f.Pos.Column})
+			out.Elements = append(out.Elements, frame)
+		}
+		return out
+	}
+	return newError("error has no member %s", member)
+}
@@ -0,0 +1,205 @@
+package evaluator
+
+import (
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+)
+
+// maxExpansionDepth limits macros expanding to calls of macros, a macro expanding to itself would never stop
+const maxExpansionDepth = 100
+
+// expandMacros is the phase between parsing and evaluation. Top-level vars holding macro literals are
+// removed from the program and their macros are stored in env, then calls of macros are replaced
+// with the code they return
+func (e *Evaluator) expandMacros(program *parser.Program, env *object.Environment) *object.Error {
+	statements := []parser.StatementNode{}
+	for _, st := range program.Statements {
+		if v, ok := st.(*parser.VarStatementNode); ok && v.Pattern == nil {
+			if macro, ok := v.Value.(*parser.MacroLiteral); ok {
+				env.Set(v.Name, &object.Macro{Parameters: macro.Parameters, Body: macro.Body, Env: env})
+				continue
+			}
+		}
+		statements = append(statements, st)
+	}
+	program.Statements = statements
+
+	_, err := e.expand(program, env, 0)
+	return err
+}
+
+// expand replaces calls of macros in the node, depth counts expansions the node came from
+func (e *Evaluator) expand(node parser.Node, env *object.Environment, depth int) (parser.Node, *object.Error) {
+	spawned := spawnedCalls(node)
+	var err *object.Error
+	out := parser.Rewrite(node, func(n parser.Node) parser.Node {
+		call, ok := n.(*parser.CallExpression)
+		if !ok || err != nil {
+			return n
+		}
+		macro, ok := lookupMacro(call, env)
+		if !ok {
+			return n
+		}
+		name := functionName(call)
+		if depth >= maxExpansionDepth {
+			err = newError("macro %s expands too deep", name)
+			return n
+		}
+
+		code, expandErr := e.expandCall(name, macro, call)
+		if expandErr == nil {
+			code, expandErr = e.expand(code, env, depth+1)
+		}
+		if expandErr == nil && spawned[call] {
+			expandErr = checkSpawned(name, code)
+		}
+		if expandErr != nil {
+			err = expandErr
+			return n
+		}
+		return code
+	})
+	return out, err
+}
+
+// expandCall runs the macro with code of the arguments, the result must be quoted code
+func (e *Evaluator) expandCall(name string, macro *object.Macro, call *parser.CallExpression) (parser.Node, *object.Error) {
+	args := []object.Object{}
+	for _, a := range call.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+	result := e.apply(name, &object.Function{Parameters: macro.Parameters, Body: macro.Body, Env: macro.Env}, args)
+	if err, ok := result.(*object.Error); ok {
+		return nil, err
+	}
+	quote, ok := result.(*object.Quote)
+	if !ok {
+		return nil, newError("macro %s must return quoted code, got %s", name, result.Type())
+	}
+	return quote.Node, nil
+}
+
+func lookupMacro(call *parser.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	id, ok := call.Function.(*parser.IdentifierExpression)
+	if !ok {
+		return nil, false
+	}
+	value, ok := env.Get(id.Name)
+	if !ok {
+		return nil, false
+	}
+	macro, ok := value.(*object.Macro)
+	return macro, ok
+}
+
+// quote returns code of the argument, calls of unquote inside it are replaced with their values
+func (e *Evaluator) quote(node *parser.CallExpression, env *object.Environment) object.Object {
+	if len(node.Arguments) != 1 {
+		return newError("wrong number of arguments: want=1, got=%d", len(node.Arguments))
+	}
+	// the quote may be evaluated again, so the code is copied before unquoting
+	code := parser.Clone(node.Arguments[0])
+	spawned := spawnedCalls(code)
+
+	var err *object.Error
+	code = parser.Rewrite(code, func(n parser.Node) parser.Node {
+		call, ok := n.(*parser.CallExpression)
+		if !ok || !isCallOf(call, "unquote") || err != nil {
+			return n
+		}
+		if len(call.Arguments) != 1 {
+			err = newError("wrong number of arguments: want=1, got=%d", len(call.Arguments))
+			return n
+		}
+		value := e.eval(call.Arguments[0], env)
+		if isError(value) {
+			err = value.(*object.Error)
+			return n
+		}
+		unquoted, unquoteErr := toCode(value, call.Pos)
+		if unquoteErr == nil && spawned[call] {
+			unquoteErr = checkSpawned("unquote", unquoted)
+		}
+		if unquoteErr != nil {
+			err = unquoteErr
+			return n
+		}
+		return unquoted
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: code}
+}
+
+// toCode converts the value to an expression producing it. Quoted code is copied,
+// unquoting it twice mustn't put the same node in two places of the tree
+func toCode(value object.Object, pos lexer.Position) (parser.ExpressionNode, *object.Error) {
+	switch v := value.(type) {
+	case *object.Quote:
+		if exp, ok := v.Node.(parser.ExpressionNode); ok {
+			return parser.Clone(exp).(parser.ExpressionNode), nil
+		}
+	case *object.Integer:
+		return &parser.IntegerLiteralExpression{Value: v.Value, Pos: pos}, nil
+	case *object.Float:
+		return &parser.FloatLiteralExpression{Value: v.Value, Pos: pos}, nil
+	case *object.String:
+		return &parser.StringLiteralExpression{Value: v.Value, Pos: pos}, nil
+	case *object.Boolean:
+		return &parser.BooleanExpression{Value: v.Value, Pos: pos}, nil
+	case *object.Array:
+		out := &parser.ArrayLiteralExpression{Elements: []parser.ExpressionNode{}, Pos: pos, End: pos}
+		for _, el := range v.Elements {
+			code, err := toCode(el, pos)
+			if err != nil {
+				return nil, err
+			}
+			out.Elements = append(out.Elements, code)
+		}
+		return out, nil
+	case *object.Hash:
+		out := &parser.HashLiteralExpression{Keys: []parser.ExpressionNode{}, Values: []parser.ExpressionNode{}, Pos: pos, End: pos}
+		for _, k := range v.Keys {
+			pair := v.Pairs[k]
+			key, err := toCode(pair.Key, pos)
+			if err != nil {
+				return nil, err
+			}
+			value, err := toCode(pair.Value, pos)
+			if err != nil {
+				return nil, err
+			}
+			out.Keys, out.Values = append(out.Keys, key), append(out.Values, value)
+		}
+		return out, nil
+	}
+	return nil, newError("cannot unquote %s", value.Type())
+}
+
+// spawnedCalls finds calls of spawn expressions, they can be replaced only with other calls
+func spawnedCalls(node parser.Node) map[*parser.CallExpression]bool {
+	out := map[*parser.CallExpression]bool{}
+	parser.Inspect(node, func(n parser.Node) bool {
+		if s, ok := n.(*parser.SpawnExpression); ok {
+			out[s.Call] = true
+		}
+		return true
+	})
+	return out
+}
+
+func checkSpawned(name string, code parser.Node) *object.Error {
+	if _, ok := code.(*parser.CallExpression); !ok {
+		return newError("%s must produce a call to be spawned, got %s", name, code)
+	}
+	return nil
+}
+
+// isCallOf reports whether the call is of the function with the name, like quote or unquote
+func isCallOf(call *parser.CallExpression, name string) bool {
+	id, ok := call.Function.(*parser.IdentifierExpression)
+	return ok && id.Name == name
+}
@@ -0,0 +1,146 @@
+package evaluator
+
+import (
+	"programming-lang/object"
+	"programming-lang/parser"
+)
+
+// evalMatch evaluates the body of the first arm whose pattern matches and whose guard holds,
+// names bound by the pattern are visible in the guard and in the body
+func (e *Evaluator) evalMatch(node *parser.MatchExpression, env *object.Environment) object.Object {
+	subject := e.eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		matched, err := e.match(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		} else if !matched {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := e.eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			} else if !IsTruthy(guard) {
+				continue
+			}
+		}
+		return e.eval(arm.Body, armEnv)
+	}
+	return newError("no match for value: %s", subject.Inspect())
+}
+
+// evalDestructuring binds names of the pattern only when the whole value matches
+func (e *Evaluator) evalDestructuring(node *parser.VarStatementNode, env *object.Environment) object.Object {
+	value := e.eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	bound := object.NewEnclosedEnvironment(env)
+	matched, err := e.match(node.Pattern, value, bound)
+	if err != nil {
+		return err
+	} else if !matched {
+		return newError("cannot destructure %s: pattern %s does not match", value.Inspect(), node.Pattern)
+	}
+	for _, b := range parser.Bindings(node.Pattern) {
+		v, _ := bound.Get(b.Name)
+		env.Set(b.Name, v)
+	}
+	return nil
+}
+
+// match reports whether the value matches the pattern, binding its names in env
+func (e *Evaluator) match(pattern parser.Pattern, value object.Object, env *object.Environment) (bool, *object.Error) {
+	switch p := pattern.(type) {
+	case *parser.WildcardPattern:
+		return true, nil
+	case *parser.BindingPattern:
+		env.Set(p.Name, value)
+		return true, nil
+	case *parser.LiteralPattern:
+		literal := e.eval(p.Value, env)
+		if isError(literal) {
+			return false, literal.(*object.Error)
+		}
+		return equal(literal, value), nil
+	case *parser.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok || len(array.Elements) < len(p.Elements) || p.Rest == nil && len(array.Elements) != len(p.Elements) {
+			return false, nil
+		}
+		for i, element := range p.Elements {
+			if matched, err := e.match(element, array.Elements[i], env); !matched || err != nil {
+				return false, err
+			}
+		}
+		if p.Rest == nil {
+			return true, nil
+		}
+		rest := append([]object.Object{}, array.Elements[len(p.Elements):]...)
+		return e.match(p.Rest, &object.Array{Elements: rest}, env)
+	case *parser.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return false, nil
+		}
+		for i := range p.Keys {
+			key := e.eval(p.Keys[i], env)
+			if isError(key) {
+				return false, key.(*object.Error)
+			}
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return false, newError("unusable as hash key: %s", key.Type())
+			}
+			v, ok := hash.Get(hashable)
+			if !ok {
+				return false, nil
+			}
+			if matched, err := e.match(p.Values[i], v, env); !matched || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, newError("unsupported pattern: %s", pattern)
+}
+
+// equal compares like the == operator, without type mismatch errors. Structs are equal when they are
+// instances of the same declaration with equal fields
+func equal(a, b object.Object) bool {
+	return equalStructs(a, b, map[[2]*object.Struct]bool{})
+}
+
+// equalStructs is equal tracking pairs of instances being compared, a pair met again while comparing
+// its fields is assumed equal so cyclic structs are compared in finite time
+func equalStructs(a, b object.Object, comparing map[[2]*object.Struct]bool) bool {
+	switch {
+	case a.Type() == object.INTEGER && b.Type() == object.INTEGER:
+		return a.(*object.Integer).Value == b.(*object.Integer).Value
+	case isNumber(a) && isNumber(b):
+		return ToFloat(a) == ToFloat(b)
+	case a.Type() == object.STRING && b.Type() == object.STRING:
+		return a.(*object.String).Value == b.(*object.String).Value
+	case isStruct(a) && isStruct(b):
+		left, right := a.(*object.Struct), b.(*object.Struct)
+		pair := [2]*object.Struct{left, right}
+		if left == right || comparing[pair] {
+			return true
+		} else if left.Def != right.Def {
+			return false
+		}
+		comparing[pair] = true
+		leftValues, rightValues := left.Values(), right.Values()
+		for i := range leftValues {
+			if !equalStructs(leftValues[i], rightValues[i], comparing) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
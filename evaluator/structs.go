@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"programming-lang/object"
+	"programming-lang/parser"
+)
+
+func (e *Evaluator) evalStruct(node *parser.StructStatement, env *object.Environment) object.Object {
+	env.Set(node.Name, object.NewStructType(node.Name, append([]string{}, node.Fields...)))
+	return nil
+}
+
+// evalMethod adds the method to the struct named by the receiver type, the receiver becomes the first parameter
+func (e *Evaluator) evalMethod(node *parser.MethodStatement, env *object.Environment) object.Object {
+	if node.Receiver.Type == nil {
+		return newError("cannot declare method %s: receiver %s has no type", node.Name, node.Receiver.Name)
+	}
+	typeName := node.Receiver.Type.Name
+	value, ok := env.Get(typeName)
+	if !ok {
+		return newError("cannot declare method %s: identifier not found: %s", node.Name, typeName)
+	}
+	def, ok := value.(*object.StructType)
+	if !ok {
+		return newError("cannot declare method %s on %s", node.Name, value.Type())
+	}
+	if def.Field(node.Name) >= 0 {
+		return newError("cannot declare method %s: %s already has field %s", node.Name, def.Name, node.Name)
+	}
+
+	params := append([]*parser.FunctionParameter{node.Receiver}, node.Function.Parameters...)
+	def.SetMethod(node.Name, &object.Function{Parameters: params, Body: node.Function.Body, Env: env})
+	return nil
+}
+
+func (e *Evaluator) evalAssign(node *parser.AssignStatement, env *object.Environment) object.Object {
+	obj := e.eval(node.Target.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	instance, ok := obj.(*object.Struct)
+	if !ok {
+		return newError("cannot assign field %s of %s", node.Target.Member, obj.Type())
+	}
+	value := e.eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	if !instance.Set(node.Target.Member, value) {
+		return newError("unknown field %s of %s", node.Target.Member, instance.Def.Name)
+	}
+	return nil
+}
+
+// structMember returns the field of the instance, or its method bound to the instance
+func structMember(instance *object.Struct, name string) object.Object {
+	if value, ok := instance.Get(name); ok {
+		return value
+	}
+	if method, ok := instance.Def.Method(name); ok {
+		return &object.BoundMethod{Receiver: instance, Name: name, Method: method}
+	}
+	return newError("unknown field %s of %s", name, instance.Def.Name)
+}
+
+// construct creates an instance, arguments are values of the fields in declaration order
+func construct(def *object.StructType, args []object.Object) object.Object {
+	if len(args) != len(def.Fields) {
+		return newError("wrong number of arguments: want=%d, got=%d", len(def.Fields), len(args))
+	}
+	return object.NewStruct(def, append([]object.Object{}, args...))
+}
+
+func isStruct(obj object.Object) bool {
+	_, ok := obj.(*object.Struct)
+	return ok
+}
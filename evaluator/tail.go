@@ -0,0 +1,35 @@
+package evaluator
+
+import (
+	"programming-lang/object"
+	"programming-lang/parser"
+)
+
+// maxTailFrames bounds the frames kept for callers of tail calls in a row, deep tail recursion elides the rest
+const maxTailFrames = 64
+
+// tailCall is the result of a call in tail position, apply makes the call instead of the callee
+// so deep recursion doesn't grow the host stack. It never leaves apply
+type tailCall struct {
+	name string
+	fn   *object.Function
+	args []object.Object
+}
+
+func (t *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (t *tailCall) Inspect() string         { return "tail call " + t.name }
+
+// analyse remembers tail calls of the function body, each call node belongs to a single body
+func (e *Evaluator) analyse(body *parser.BlockStatement) {
+	if e.analysed[body] {
+		return
+	}
+	if e.analysed == nil {
+		e.analysed = map[*parser.BlockStatement]bool{}
+		e.tail = map[*parser.CallExpression]bool{}
+	}
+	e.analysed[body] = true
+	for call := range parser.TailCalls(body) {
+		e.tail[call] = true
+	}
+}
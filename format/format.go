@@ -0,0 +1,527 @@
+package format
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"strings"
+)
+
+// Program prints the program in canonical style:
+// tab indentation, spaces around infix operators and only required parentheses
+func Program(program *parser.Program) string {
+	p := &printer{}
+	p.printStatements(program.Statements, lexer.Position{})
+	return p.out.String()
+}
+
+// Source formats the code like Program does, additionally keeping comments
+// and single blank lines between statements. Code with syntax errors is not formatted
+func Source(input string) (string, error) {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(input))
+	if len(program.Errors) > 0 {
+		return "", fmt.Errorf("format error - code has %d syntax errors, first: %v", len(program.Errors), program.Errors[0])
+	}
+
+	p := &printer{}
+	tokens, positions := lexer.Comments(input)
+	for i := range tokens {
+		p.comments = append(p.comments, comment{text: strings.TrimRight(tokens[i].Lexeme, " \t\r"), pos: positions[i]})
+	}
+
+	p.printStatements(program.Statements, lexer.Position{})
+	if p.misplaced != nil {
+		// the comment would be moved away from the code it describes
+		return "", fmt.Errorf("format error - comment at %v is inside an expression", p.misplaced.pos)
+	}
+	return p.out.String(), nil
+}
+
+type comment struct {
+	text string
+	pos  lexer.Position
+}
+
+type printer struct {
+	out    strings.Builder
+	indent int
+
+	comments   []comment // not printed yet
+	misplaced  *comment  // first comment found inside an expression, comments are kept only between statements
+	lastLine   int       // source line of the last printed statement or comment
+	blockStart bool      // nothing was printed in the current block yet
+}
+
+// before reports whether a is located before b, zero b is the end of the file
+func before(a, b lexer.Position) bool {
+	if b.Line == 0 {
+		return true
+	}
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func (p *printer) writeIndent() {
+	p.out.WriteString(strings.Repeat("\t", p.indent))
+}
+
+// printStatements prints statements with comments located before end, one per line
+func (p *printer) printStatements(statements []parser.StatementNode, end lexer.Position) {
+	p.blockStart = true
+	for i, st := range statements {
+		pos := st.Position()
+		p.printComments(pos, end)
+		p.printBlankLine(pos.Line)
+
+		p.writeIndent()
+		p.printStatement(st)
+		p.lastLine = endLine(st)
+		if len(p.comments) > 0 && before(p.comments[0].pos, parser.SpanOf(st).End) {
+			p.misplace(p.comments[0])
+		}
+		p.blockStart = false
+
+		nextOnSameLine := i+1 < len(statements) && statements[i+1].Position().Line == p.lastLine
+		if len(p.comments) > 0 && !nextOnSameLine && p.comments[0].pos.Line == p.lastLine && before(p.comments[0].pos, end) {
+			p.out.WriteString(" " + p.comments[0].text)
+			p.comments = p.comments[1:]
+		}
+		p.out.WriteString("\n")
+	}
+	p.printComments(end, end)
+}
+
+// printComments prints comments located before pos, but not after end
+func (p *printer) printComments(pos lexer.Position, end lexer.Position) {
+	for len(p.comments) > 0 && before(p.comments[0].pos, pos) && before(p.comments[0].pos, end) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		p.printBlankLine(c.pos.Line)
+		p.writeIndent()
+		p.out.WriteString(c.text + "\n")
+		p.lastLine = c.pos.Line
+		p.blockStart = false
+	}
+}
+
+func (p *printer) misplace(c comment) {
+	if p.misplaced == nil {
+		p.misplaced = &c
+	}
+}
+
+// printBlankLine keeps a single empty line, if there was any in the source
+func (p *printer) printBlankLine(line int) {
+	if !p.blockStart && line > p.lastLine+1 {
+		p.out.WriteString("\n")
+	}
+}
+
+func (p *printer) printStatement(st parser.StatementNode) {
+	switch s := st.(type) {
+	case *parser.VarStatementNode:
+		p.out.WriteString("var " + s.Name)
+		if s.Pattern != nil {
+			p.printPattern(s.Pattern)
+		}
+		if s.Type != nil {
+			p.out.WriteString(": " + s.Type.String())
+		}
+		if s.Value != nil {
+			p.out.WriteString(" = ")
+			p.printExpression(s.Value)
+		}
+		p.out.WriteString(";")
+	case *parser.ReturnStatementNode:
+		p.out.WriteString("return")
+		if s.Value != nil {
+			p.out.WriteString(" ")
+			p.printExpression(s.Value)
+		}
+		p.out.WriteString(";")
+	case *parser.ExpressionStatementNode:
+		if s.Value == nil {
+			return
+		}
+		p.printExpression(s.Value)
+		switch s.Value.(type) {
+		case *parser.IfExpression, *parser.SelectExpression, *parser.MatchExpression:
+		default:
+			p.out.WriteString(";")
+		}
+	case *parser.BlockStatement:
+		p.printBlock(s)
+	case *parser.ImportStatement:
+		p.out.WriteString(s.String() + ";")
+	case *parser.ExportStatement:
+		p.out.WriteString("export ")
+		p.printStatement(s.Statement)
+	case *parser.ThrowStatement:
+		p.out.WriteString("throw ")
+		p.printExpression(s.Value)
+		p.out.WriteString(";")
+	case *parser.TryStatement:
+		p.out.WriteString("try ")
+		p.printBlock(s.Body)
+		if s.Catch != nil {
+			p.out.WriteString(" catch (" + s.Name + ") ")
+			p.printBlock(s.Catch)
+		}
+		if s.Finally != nil {
+			p.out.WriteString(" finally ")
+			p.printBlock(s.Finally)
+		}
+	case *parser.StructStatement:
+		if len(s.Fields) == 0 {
+			p.out.WriteString("struct " + s.Name + " {}")
+			return
+		}
+		p.out.WriteString("struct " + s.Name + " { " + strings.Join(s.Fields, ", ") + " }")
+	case *parser.MethodStatement:
+		p.out.WriteString("fn (")
+		p.printParameter(s.Receiver)
+		p.out.WriteString(") " + s.Name)
+		p.printFunction(s.Function)
+	case *parser.AssignStatement:
+		p.printExpression(s.Target)
+		p.out.WriteString(" = ")
+		p.printExpression(s.Value)
+		p.out.WriteString(";")
+	default:
+		p.out.WriteString(st.String())
+	}
+}
+
+func (p *printer) printBlock(b *parser.BlockStatement) {
+	if len(p.comments) > 0 && before(p.comments[0].pos, b.Pos) {
+		p.misplace(p.comments[0])
+	}
+	hasComments := len(p.comments) > 0 && before(p.comments[0].pos, b.End)
+	if len(b.Statements) == 0 && !hasComments {
+		p.out.WriteString("{}")
+		return
+	}
+
+	p.out.WriteString("{\n")
+	p.indent++
+	p.printStatements(b.Statements, b.End)
+	p.indent--
+	p.writeIndent()
+	p.out.WriteString("}")
+	p.lastLine = b.End.Line
+}
+
+func (p *printer) printExpression(exp parser.ExpressionNode) {
+	switch e := exp.(type) {
+	case *parser.PrefixExpression:
+		p.out.WriteString(e.Operator)
+		// -(-x) can't be printed as --x, that's a decrement operator
+		nested, ok := e.Right.(*parser.PrefixExpression)
+		doubleMinus := e.Operator == "-" && ok && nested.Operator == "-"
+		p.printOperand(e.Right, precedence(e.Right) < parser.PREFIX || doubleMinus)
+	case *parser.InfixExpression:
+		prec := precedence(e)
+		p.printOperand(e.Left, precedence(e.Left) < prec)
+		p.out.WriteString(" " + e.Operator + " ")
+		// operators are left associative
+		p.printOperand(e.Right, precedence(e.Right) <= prec)
+	case *parser.CallExpression:
+		p.printOperand(e.Function, precedence(e.Function) < parser.CALL)
+		p.out.WriteString("(")
+		for i, a := range e.Arguments {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.printExpression(a)
+		}
+		p.out.WriteString(")")
+	case *parser.ConditionalExpression:
+		// conditionals are right associative, only a conditional condition needs parentheses
+		p.printOperand(e.Condition, precedence(e.Condition) <= parser.TERNARY)
+		p.out.WriteString(" ? ")
+		p.printExpression(e.Consequence)
+		p.out.WriteString(" : ")
+		p.printExpression(e.Alternative)
+	case *parser.MemberExpression:
+		p.printOperand(e.Object, precedence(e.Object) < parser.CALL)
+		if e.Optional {
+			p.out.WriteString("?")
+		}
+		p.out.WriteString("." + e.Member)
+	case *parser.ArrayLiteralExpression:
+		p.out.WriteString("[")
+		for i, el := range e.Elements {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.printExpression(el)
+		}
+		p.out.WriteString("]")
+	case *parser.HashLiteralExpression:
+		p.out.WriteString("{")
+		for i := range e.Keys {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.printExpression(e.Keys[i])
+			p.out.WriteString(": ")
+			p.printExpression(e.Values[i])
+		}
+		p.out.WriteString("}")
+	case *parser.IndexExpression:
+		p.printOperand(e.Left, precedence(e.Left) < parser.CALL)
+		if e.Optional {
+			p.out.WriteString("?.")
+		}
+		p.out.WriteString("[")
+		p.printExpression(e.Index)
+		p.out.WriteString("]")
+	case *parser.IfExpression:
+		p.out.WriteString("if (")
+		p.printExpression(e.Condition)
+		p.out.WriteString(") ")
+		p.printBlock(e.Consequence)
+		if e.Alternative != nil {
+			p.out.WriteString(" else ")
+			p.printBlock(e.Alternative)
+		}
+	case *parser.FunctionLiteralExpression:
+		p.out.WriteString("fn")
+		p.printFunction(e)
+	case *parser.MacroLiteral:
+		p.out.WriteString("macro")
+		p.printFunction(&parser.FunctionLiteralExpression{Parameters: e.Parameters, Body: e.Body})
+	case *parser.SpawnExpression:
+		p.out.WriteString("spawn ")
+		p.printExpression(e.Call)
+	case *parser.SelectExpression:
+		p.printSelect(e)
+	case *parser.MatchExpression:
+		p.printMatch(e)
+	case *parser.InterpolatedString:
+		p.out.WriteString("`" + parser.EscapeTemplate(e.Parts[0]))
+		for i, v := range e.Values {
+			p.out.WriteString("${")
+			p.printExpression(v)
+			p.out.WriteString("}" + parser.EscapeTemplate(e.Parts[i+1]))
+		}
+		p.out.WriteString("`")
+	default:
+		p.out.WriteString(exp.String())
+	}
+}
+
+// printFunction prints parameters, return type and body of the function
+func (p *printer) printFunction(fn *parser.FunctionLiteralExpression) {
+	p.out.WriteString("(")
+	for i, param := range fn.Parameters {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.printParameter(param)
+	}
+	p.out.WriteString(")")
+	if fn.ReturnType != nil {
+		p.out.WriteString(": " + fn.ReturnType.String())
+	}
+	p.out.WriteString(" ")
+	p.printBlock(fn.Body)
+}
+
+func (p *printer) printParameter(param *parser.FunctionParameter) {
+	p.out.WriteString(param.Name)
+	if param.Type != nil {
+		p.out.WriteString(": " + param.Type.String())
+	}
+}
+
+// printMatch prints arms one per line, each followed by a comma
+func (p *printer) printMatch(m *parser.MatchExpression) {
+	p.out.WriteString("match (")
+	p.printExpression(m.Subject)
+	p.out.WriteString(") {\n")
+	p.indent++
+	for _, arm := range m.Arms {
+		p.printComments(arm.Position(), m.End)
+		p.writeIndent()
+		p.printPattern(arm.Pattern)
+		if arm.Guard != nil {
+			p.out.WriteString(" if ")
+			p.printExpression(arm.Guard)
+		}
+		p.out.WriteString(" => ")
+		p.printExpression(arm.Body)
+		p.out.WriteString(",\n")
+	}
+	p.printComments(m.End, m.End)
+	p.indent--
+	p.writeIndent()
+	p.out.WriteString("}")
+	p.lastLine = m.End.Line
+}
+
+func (p *printer) printPattern(pattern parser.Pattern) {
+	switch pt := pattern.(type) {
+	case *parser.LiteralPattern:
+		p.printExpression(pt.Value)
+	case *parser.ArrayPattern:
+		p.out.WriteString("[")
+		for i, e := range pt.Elements {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.printPattern(e)
+		}
+		if pt.Rest != nil {
+			if len(pt.Elements) > 0 {
+				p.out.WriteString(", ")
+			}
+			p.out.WriteString("...")
+			p.printPattern(pt.Rest)
+		}
+		p.out.WriteString("]")
+	case *parser.HashPattern:
+		p.out.WriteString("{")
+		for i := range pt.Keys {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.printExpression(pt.Keys[i])
+			p.out.WriteString(": ")
+			p.printPattern(pt.Values[i])
+		}
+		p.out.WriteString("}")
+	default:
+		p.out.WriteString(pattern.String())
+	}
+}
+
+// printSelect prints cases one per line, comments between them are kept
+func (p *printer) printSelect(s *parser.SelectExpression) {
+	p.out.WriteString("select {\n")
+	p.indent++
+	for _, c := range s.Cases {
+		p.printComments(c.Pos, s.End)
+		p.writeIndent()
+		p.out.WriteString("case ")
+		p.printExpression(c.Comm)
+		if c.Name != "" {
+			p.out.WriteString(" as " + c.Name)
+		}
+		p.out.WriteString(" ")
+		p.printBlock(c.Body)
+		p.out.WriteString("\n")
+	}
+	if s.Default != nil {
+		p.printComments(s.Default.Position(), s.End)
+		p.writeIndent()
+		p.out.WriteString("default ")
+		p.printBlock(s.Default)
+		p.out.WriteString("\n")
+	}
+	p.printComments(s.End, s.End)
+	p.indent--
+	p.writeIndent()
+	p.out.WriteString("}")
+	p.lastLine = s.End.Line
+}
+
+func (p *printer) printOperand(exp parser.ExpressionNode, parenthesize bool) {
+	if parenthesize {
+		p.out.WriteString("(")
+	}
+	p.printExpression(exp)
+	if parenthesize {
+		p.out.WriteString(")")
+	}
+}
+
+// precedence is the binding power of the expression, literals bind the strongest
+func precedence(exp parser.ExpressionNode) int {
+	switch e := exp.(type) {
+	case *parser.InfixExpression:
+		return parser.OperatorPrecedence(e.Operator)
+	case *parser.ConditionalExpression:
+		return parser.TERNARY
+	case *parser.PrefixExpression, *parser.SpawnExpression:
+		return parser.PREFIX
+	case *parser.CallExpression, *parser.MemberExpression, *parser.IndexExpression:
+		return parser.CALL
+	}
+	return parser.CALL + 1
+}
+
+// endLine is the last source line of the node
+func endLine(node parser.Node) int {
+	switch n := node.(type) {
+	case *parser.VarStatementNode:
+		if n.Value != nil {
+			return endLine(n.Value)
+		}
+	case *parser.ReturnStatementNode:
+		if n.Value != nil {
+			return endLine(n.Value)
+		}
+	case *parser.ExportStatement:
+		return endLine(n.Statement)
+	case *parser.ThrowStatement:
+		return endLine(n.Value)
+	case *parser.TryStatement:
+		if n.Finally != nil {
+			return n.Finally.End.Line
+		}
+		return n.Catch.End.Line
+	case *parser.ExpressionStatementNode:
+		if n.Value != nil {
+			return endLine(n.Value)
+		}
+	case *parser.StructStatement:
+		return n.End.Line
+	case *parser.MethodStatement:
+		return n.Function.Body.End.Line
+	case *parser.AssignStatement:
+		return endLine(n.Value)
+	case *parser.BlockStatement:
+		return n.End.Line
+	case *parser.PrefixExpression:
+		return endLine(n.Right)
+	case *parser.InfixExpression:
+		return endLine(n.Right)
+	case *parser.ConditionalExpression:
+		return endLine(n.Alternative)
+	case *parser.IfExpression:
+		if n.Alternative != nil {
+			return n.Alternative.End.Line
+		}
+		return n.Consequence.End.Line
+	case *parser.FunctionLiteralExpression:
+		return n.Body.End.Line
+	case *parser.MacroLiteral:
+		return n.Body.End.Line
+	case *parser.MemberExpression:
+		return n.MemberPos.Line
+	case *parser.ArrayLiteralExpression:
+		return n.End.Line
+	case *parser.IndexExpression:
+		return n.End.Line
+	case *parser.HashLiteralExpression:
+		return n.End.Line
+	case *parser.SelectExpression:
+		return n.End.Line
+	case *parser.MatchExpression:
+		return n.End.Line
+	case *parser.InterpolatedString:
+		return n.End.Line
+	case *parser.SpawnExpression:
+		return endLine(n.Call)
+	case *parser.CallExpression:
+		out := n.Pos.Line
+		for _, a := range n.Arguments {
+			if l := endLine(a); l > out {
+				out = l
+			}
+		}
+		return out
+	}
+	return node.Position().Line
+}
@@ -0,0 +1,164 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatExpressions(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"1+2*3", "1 + 2 * 3;\n"},
+		{"(1+2)*3;", "(1 + 2) * 3;\n"},
+		{"1-(2-3);", "1 - (2 - 3);\n"},
+		{"(1-2)-3;", "1 - 2 - 3;\n"},
+		{"((a));", "a;\n"},
+		{"-(-a);", "-(-a);\n"},
+		{"!-a;", "!-a;\n"},
+		{"-(a+b);", "-(a + b);\n"},
+		{"(1<2)==(3>4);", "1 < 2 == 3 > 4;\n"},
+		{"1<(2==3);", "1 < (2 == 3);\n"},
+		{"add(1,2*3,foo( ));", "add(1, 2 * 3, foo());\n"},
+		{"var x:int=5;", "var x: int = 5;\n"},
+		{"var x ;", "var x;\n"},
+		{"var f = fn(a:int,b):bool{return a>b;};", "var f = fn(a: int, b): bool {\n\treturn a > b;\n};\n"},
+		{"fn(){}();", "fn() {}();\n"},
+		{"var f: fn(int,bool):int;", "var f: fn(int, bool): int;\n"},
+		{"if(a){b}else{c}", "if (a) {\n\tb;\n} else {\n\tc;\n}\n"},
+		{"if(a){if(b){c}}", "if (a) {\n\tif (b) {\n\t\tc;\n\t}\n}\n"},
+		{`import "lib.mk"  as  lib;`, "import \"lib.mk\" as lib;\n"},
+		{"export var x:int=lib.f( \"a\\n\" );", "export var x: int = lib.f(\"a\\n\");\n"},
+		{"(f()).x.y;", "f().x.y;\n"},
+		{"(-a).x;", "(-a).x;\n"},
+		{"1.50*-2.0;", "1.5 * -2.0;\n"},
+		{"[1,2*3,[]][(0)];", "[1, 2 * 3, []][0];\n"},
+		{"(-a)[0]+b[1];", "(-a)[0] + b[1];\n"},
+		{`{ "a" :1,b:{}}["a"];`, "{\"a\": 1, b: {}}[\"a\"];\n"},
+		{"var c=spawn f(1,2);", "var c = spawn f(1, 2);\n"},
+		{"ch<-1+2;<-(<-c);", "ch <- 1 + 2;\n<-<-c;\n"},
+		{"throw  {\"code\":1} ;", "throw {\"code\": 1};\n"},
+		{"try{f()}catch(e){throw e;}finally{g()}", "try {\n\tf();\n} catch (e) {\n\tthrow e;\n} finally {\n\tg();\n}\n"},
+		{"try{}finally{}", "try {} finally {}\n"},
+		{"select{case <-a as v{v}case b<-1{}default{2}}", "select {\n\tcase <-a as v {\n\t\tv;\n\t}\n\tcase b <- 1 {}\n\tdefault {\n\t\t2;\n\t}\n}\n"},
+		{"match(x){-1=>a,[a,...r]if a>0=>r,{\"k\":[..._]}=>{},_=>match(y){true=>1,false=>0}}", "match (x) {\n\t-1 => a,\n\t[a, ...r] if a > 0 => r,\n\t{\"k\": [..._]} => {},\n\t_ => match (y) {\n\t\ttrue => 1,\n\t\tfalse => 0,\n\t},\n}\n"},
+		{"var[a,{1:b}]=f();", "var [a, {1: b}] = f();\n"},
+		{"struct Point{x,y,}struct Empty{}", "struct Point { x, y }\nstruct Empty {}\n"},
+		{"fn(p:Point)norm(k):int{p.x*k}", "fn (p: Point) norm(k): int {\n\tp.x * k;\n}\n"},
+		{"p.x=p.y+1;", "p.x = p.y + 1;\n"},
+		{"var m=macro(a,b){quote(unquote(a)+unquote(b))};", "var m = macro(a, b) {\n\tquote(unquote(a) + unquote(b));\n};\n"},
+		{"`a\\t${x+1}\\${}${`${ f( y ) }`}`;", "`a\t${x + 1}\\${}${`${f(y)}`}`;\n"},
+		{"a?b:c?d:e;", "a ? b : c ? d : e;\n"},
+		{"(a?b:c)?d:(e);", "(a ? b : c) ? d : e;\n"},
+		{"1+(a?b:c);", "1 + (a ? b : c);\n"},
+		{"a??(b??c);", "a ?? (b ?? c);\n"},
+		{"(a??b)==c;", "(a ?? b) == c;\n"},
+		{"a ?. b ?.[0];", "a?.b?.[0];\n"},
+		{"(-a)?.b;", "(-a)?.b;\n"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			program := parser.Parse(lexer.Tokenize(tc.input))
+			require.Len(t, program.Errors, 0)
+			assert.Equal(t, tc.expected, Program(program))
+		})
+	}
+}
+
+func TestFormatSourceWithComments(t *testing.T) {
+	input := `// header
+
+
+var x = 1;   // one
+var y = 2;
+// about f
+
+var f = fn() {
+
+  // inside
+  x // value
+
+};
+// footer`
+
+	expected := `// header
+
+var x = 1; // one
+var y = 2;
+// about f
+
+var f = fn() {
+	// inside
+	x; // value
+};
+// footer
+`
+	got, err := Source(input)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func TestFormatCommentsInsideExpressions(t *testing.T) {
+	tdt := []struct {
+		desc  string
+		input string
+		pos   string
+	}{
+		{"hash literal", "var h = {\n\t\"a\": 1, // first\n\t\"b\": 2\n};\n", "2:10"},
+		{"arguments", "f(\n\t1, // one\n\t2\n);\n", "2:5"},
+		{"parameters", "var f = fn(a, // first\n\tb) {\n\ta;\n};\n", "1:15"},
+		{"between branches", "if (x) {\n\t1;\n} // then\nelse {\n\t2;\n}\n", "3:3"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := Source(tc.input)
+			assert.EqualError(t, err, "format error - comment at "+tc.pos+" is inside an expression")
+		})
+	}
+
+	// comments in bodies of functions passed as arguments are between statements
+	input := "f(fn() {\n\t// inside\n\t1;\n}, 2);\n"
+	got, err := Source(input)
+	require.NoError(t, err)
+	assert.Equal(t, input, got)
+}
+
+func TestFormatInvalidSource(t *testing.T) {
+	_, err := Source(`var x = ;`)
+	assert.Error(t, err)
+}
+
+func TestFormatIsIdempotent(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.mk"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			content, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			once, err := Source(string(content))
+			require.NoError(t, err)
+			twice, err := Source(once)
+			require.NoError(t, err)
+			assert.Equal(t, once, twice)
+
+			// formatting must not change the meaning of the code
+			original := parser.Parse(lexer.Tokenize(string(content)))
+			formatted := parser.Parse(lexer.Tokenize(once))
+			assert.Equal(t, original.String(), formatted.String())
+
+			comments, _ := lexer.Comments(string(content))
+			formattedComments, _ := lexer.Comments(once)
+			assert.Len(t, formattedComments, len(comments))
+		})
+	}
+}
@@ -0,0 +1,12 @@
+var max = fn(a, b) {
+	if (a > b) { a } else { b }
+}; // trailing after block
+
+if (max(1, 2) == 2) {
+	// only a comment
+} else {
+	var x = 1; var y = 2; // belongs to y
+	x + y
+}
+if (true) { 1 } // after if
+// final comment
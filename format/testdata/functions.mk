@@ -0,0 +1,11 @@
+// functions and calls
+var add = fn(a: int, b: int): int { return a+b; };
+var apply=fn(f, x) { f(x) };
+
+
+var twice = fn(f: fn(int): int, x: int): int {
+  // apply twice
+  f(f(x)) // nested call
+};
+apply(fn(x) { x*2 }, add(1, 2));
+fn() {}();
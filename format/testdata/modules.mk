@@ -0,0 +1,6 @@
+// modules
+import "lib/math.mk"   as math;
+
+export var square = fn(x: int): int { math.mul(x,x) };
+export var greeting="hello";   // exported string
+var local = (math.add)(1, 2);
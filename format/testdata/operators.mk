@@ -0,0 +1,10 @@
+var a = (1 + 2) * 3;
+var b = 1 + (2 * 3);
+var c = (1 - 2) - 3;
+var d = 1 - (2 - 3);
+var e = -(-a);
+var f = !(a == b) != (c < d);
+var g = -(a + b) * -c;
+var h = ((a));
+var i = a > 0 ? a : (b ?? c) ? 1 : 2;
+var j = h?.["k"]?.x ?? -1;
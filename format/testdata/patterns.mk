@@ -0,0 +1,15 @@
+// destructuring and match expressions
+var [first, ...rest] = [1, 2, 3];
+var {"name": name} = {"name": "x"};
+
+var describe = fn(value) {
+    match (value) {
+        // literals
+        0 => "zero",
+        [] => "empty",
+        [x, ..._] if x > 10 => "starts big",
+        {"kind": kind} => kind,
+        _ => "other", // anything else
+    }
+};
+describe(rest);
@@ -0,0 +1,16 @@
+// records with methods
+struct Point {x, y}
+struct Empty {}
+
+fn (p: Point) add(other: Point): Point {
+    Point(p.x + other.x, p.y + other.y)
+}
+
+fn (p : Point) move(dx, dy) {
+    p.x = p.x + dx; // fields are mutable
+    p.y=p.y + dy;
+    p
+}
+
+var origin = Point(0, 0);
+origin.move(1, 2).add(Point(3, 4)) == Point(4, 6);
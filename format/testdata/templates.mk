@@ -0,0 +1,6 @@
+var name = "world";
+// templates keep new lines
+var greeting = `hello ${name},
+  sum: ${1 + 2}`; // trailing
+
+var nested = `${`${ name }` + "!"} \` \${literal}`;
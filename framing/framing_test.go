@@ -0,0 +1,47 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, map[string]int{"a": 1}))
+	require.NoError(t, Write(&buf, "ż"))
+	assert.Equal(t, "Content-Length: 7\r\n\r\n{\"a\":1}Content-Length: 4\r\n\r\n\"ż\"", buf.String())
+
+	r := bufio.NewReader(&buf)
+	first, err := Read(r)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(first))
+
+	second, err := Read(r)
+	require.NoError(t, err)
+	assert.Equal(t, `"ż"`, string(second))
+}
+
+func TestOtherHeadersAreIgnored(t *testing.T) {
+	input := "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: 2\r\n\r\n{}"
+	body, err := Read(bufio.NewReader(strings.NewReader(input)))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(body))
+}
+
+func TestInvalidInput(t *testing.T) {
+	inputs := []string{
+		"Content-Type: json\r\n\r\n{}",
+		"Content-Length: abc\r\n\r\n{}",
+		"Content-Length 2\r\n\r\n{}",
+		"Content-Length: 10\r\n\r\n{}",
+	}
+	for _, input := range inputs {
+		_, err := Read(bufio.NewReader(strings.NewReader(input)))
+		assert.Error(t, err, input)
+	}
+}
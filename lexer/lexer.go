@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"strings"
 )
 
 var enableLogs bool = false
@@ -24,6 +25,16 @@ func (t TokenClass) String() string {
 	return classesStrings[t]
 }
 
+// ParseTokenClass is the reverse of TokenClass.String
+func ParseTokenClass(name string) (TokenClass, bool) {
+	for i, s := range classesStrings {
+		if s == name {
+			return TokenClass(i), true
+		}
+	}
+	return 0, false
+}
+
 const (
 	Whitespace TokenClass = iota
 	Keyword
@@ -34,9 +45,17 @@ const (
 
 	OpenParam
 	CloseParam
-	Semicolon
+	Semicolon // lexeme is a new line when the semicolon is inserted at the end of a line
 	Assignment
+	Comma
+	Colon
+	Comment
 	EOF
+	String // double quoted, lexeme keeps the quotes and escapes
+	Dot
+	// text of a backtick template up to the next interpolation or the end, lexeme keeps delimiters and escapes:
+	// `text${ starts the template, }text${ continues it after an interpolation, }text` or `text` ends it
+	Template
 )
 
 var classesStrings = []string{
@@ -50,7 +69,13 @@ var classesStrings = []string{
 	"CloseParam",
 	"Semicolon",
 	"Assignment",
+	"Comma",
+	"Colon",
+	"Comment",
 	"EOF",
+	"String",
+	"Dot",
+	"Template",
 }
 
 type tokenizerEntry struct {
@@ -60,14 +85,30 @@ type tokenizerEntry struct {
 
 var tokenizerEntries []tokenizerEntry = []tokenizerEntry{
 	{regexp.MustCompile(`^(\s+)`), Whitespace},
+	{regexp.MustCompile(`^(//[^\n]*)`), Comment},
 
-	{regexp.MustCompile(`^(if)($|\s|\()`), Keyword},
-	{regexp.MustCompile(`^(else)($|\s|\()`), Keyword},
-	{regexp.MustCompile(`^(for)($|\s|\()`), Keyword},
-	{regexp.MustCompile(`^(var)($|\s|\()`), Keyword},
-	{regexp.MustCompile(`^(return)($|\s|\()`), Keyword},
-	{regexp.MustCompile(`^(fn)($|\s|\()`), Keyword},
+	{regexp.MustCompile(`^(if)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(else)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(for)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(var)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(return)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(fn)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(import)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(export)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(as)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(spawn)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(select)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(case)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(default)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(throw)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(try)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(catch)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(finally)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(match)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(struct)($|\W)`), Keyword},
+	{regexp.MustCompile(`^(macro)($|\W)`), Keyword},
 
+	{regexp.MustCompile(`^(=>)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(==)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(!=)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(\+\+)($|\s?)`), Operator},
@@ -76,31 +117,101 @@ var tokenizerEntries []tokenizerEntry = []tokenizerEntry{
 	{regexp.MustCompile(`^(\-)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(\*)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(\/)($|\s?)`), Operator},
+	{regexp.MustCompile(`^(<-)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(<=)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(>=)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(<)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(>)($|\s?)`), Operator},
 	{regexp.MustCompile(`^(!)($|\s?)`), Operator},
+	{regexp.MustCompile(`^(\?\?)($|\s?)`), Operator},
+	{regexp.MustCompile(`^(\?\.)`), Operator},
+	{regexp.MustCompile(`^(\?)($|\s?)`), Operator},
 
 	{regexp.MustCompile(`^(=)($|\s?)`), Assignment},
 
 	{regexp.MustCompile(`^(;)`), Semicolon},
+	{regexp.MustCompile(`^(,)`), Comma},
+	{regexp.MustCompile(`^(:)`), Colon},
+	{regexp.MustCompile(`^(\.\.\.)`), Operator},
+	{regexp.MustCompile(`^(\.)`), Dot},
+	{regexp.MustCompile(`^("(?:[^"\\\n]|\\.)*")`), String},
 	{regexp.MustCompile(`^(\))`), CloseParam},
 	{regexp.MustCompile(`^(\()`), OpenParam},
 	{regexp.MustCompile(`^({)`), OpenParam},
 	{regexp.MustCompile(`^(})`), CloseParam},
+	{regexp.MustCompile(`^(\[)`), OpenParam},
+	{regexp.MustCompile(`^(\])`), CloseParam},
 
-	{regexp.MustCompile(`^(true)($|\s|;|,\))`), Boolean},
-	{regexp.MustCompile(`^(false)($|\s|;|,\))`), Boolean},
+	{regexp.MustCompile(`^(true)($|\W)`), Boolean},
+	{regexp.MustCompile(`^(false)($|\W)`), Boolean},
 	{regexp.MustCompile(`^([0-9]+\.[0-9]+)`), Number},
 	{regexp.MustCompile(`^([0-9]+)`), Number},
 
 	{regexp.MustCompile(`^(\w+)`), Identifier},
 }
 
+// Position of a token in the source, both line and column start from 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Advance moves the position past consumed text
+func (p Position) Advance(consumed string) Position {
+	for _, c := range consumed {
+		if c == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
+
+// ImplicitSemicolon ends a statement at the end of a line, like in Go it's inserted when the last token
+// of the line is an identifier, a literal, a closing bracket, return, ++ or --. It isn't inserted inside
+// parentheses, square brackets and interpolations, nor before a token which can't start a statement,
+// so arguments, method chains and else may continue on the next line
+var ImplicitSemicolon = Token{Class: Semicolon, Lexeme: "\n"}
+
 func Tokenize(input string) []Token {
+	tokens, _ := TokenizeWithPositions(input)
+	return tokens
+}
+
+// TokenizeWithPositions works like Tokenize, additionally returning position of each token
+func TokenizeWithPositions(input string) ([]Token, []Position) {
+	return tokenize(input, func(class TokenClass) bool {
+		return !(skipWhitespaces && class == Whitespace) && class != Comment
+	})
+}
+
+// Comments returns only the comment tokens with their positions, without EOF
+func Comments(input string) ([]Token, []Position) {
+	tokens, positions := tokenize(input, func(class TokenClass) bool {
+		return class == Comment
+	})
+	return tokens[:len(tokens)-1], positions[:len(positions)-1]
+}
+
+func tokenize(input string, keep func(TokenClass) bool) ([]Token, []Position) {
 	var tokens []Token
+	var positions []Position
 	var idx uint64
+	pos := Position{Line: 1, Column: 1}
+	// count of open curly braces in each interpolation of a template being lexed, innermost last
+	var templates []int
+	// open brackets and interpolations, innermost last
+	var brackets []string
+	// last token which isn't whitespace or comment
+	var last Token
+	// position of the new line where a semicolon is inserted, when the next token can start a statement
+	var newLine *Position
 
 	ln := uint64(len(input))
 	for idx < ln {
@@ -108,22 +219,129 @@ func Tokenize(input string) []Token {
 
 		logLine(idx, ln, rest)
 
-		found, deltaIdx, token := processAvailableTokens(rest)
+		var found bool
+		var deltaIdx int
+		var token Token
+		continuesTemplate := len(templates) > 0 && templates[len(templates)-1] == 0 && rest[0] == '}'
+		if rest[0] == '`' || continuesTemplate {
+			var interpolation bool
+			deltaIdx, interpolation = templateLength(rest)
+			found, token = true, Token{Class: Template, Lexeme: rest[:deltaIdx]}
+			if continuesTemplate {
+				templates = templates[:len(templates)-1]
+				brackets = brackets[:len(brackets)-1]
+			}
+			if interpolation {
+				templates = append(templates, 0)
+				brackets = append(brackets, "${")
+			}
+		} else {
+			found, deltaIdx, token = processAvailableTokens(rest)
+			if found && len(templates) > 0 && token.Lexeme == "{" {
+				templates[len(templates)-1]++
+			} else if found && len(templates) > 0 && token.Lexeme == "}" {
+				templates[len(templates)-1]--
+			}
+		}
 
 		if !found {
 			log.Println("Unknown token at idx", idx)
+			pos = pos.Advance(rest[:1])
 			idx++
 			continue
 		}
 
+		tokenPos := pos
+		pos = pos.Advance(rest[:deltaIdx])
 		idx += uint64(deltaIdx)
-		if skipWhitespaces && token.Class == Whitespace {
+
+		switch token.Class {
+		case Whitespace:
+			if i := strings.Index(token.Lexeme, "\n"); i >= 0 && newLine == nil && endsLine(last, brackets) {
+				at := tokenPos.Advance(token.Lexeme[:i])
+				newLine = &at
+			}
+		case Comment:
+		default:
+			if newLine != nil && startsStatement(token) && keep(Semicolon) {
+				tokens = append(tokens, ImplicitSemicolon)
+				positions = append(positions, *newLine)
+			}
+			newLine = nil
+			last = token
+			brackets = nest(brackets, token)
+		}
+		if !keep(token.Class) {
 			continue
 		}
 		tokens = append(tokens, token)
+		positions = append(positions, tokenPos)
 	}
 	tokens = append(tokens, Token{Class: EOF})
-	return tokens
+	positions = append(positions, pos)
+	return tokens, positions
+}
+
+// endsLine reports whether a semicolon is inserted at the new line after the token
+func endsLine(last Token, brackets []string) bool {
+	if len(brackets) > 0 && brackets[len(brackets)-1] != "{" {
+		return false
+	}
+	switch last.Class {
+	case Identifier, Number, Boolean, String, CloseParam:
+		return true
+	case Template:
+		return strings.HasSuffix(last.Lexeme, "`") && len(last.Lexeme) > 1
+	case Keyword:
+		return last.Lexeme == "return"
+	case Operator:
+		return last.Lexeme == "++" || last.Lexeme == "--"
+	}
+	return false
+}
+
+// startsStatement reports whether the token may be the first one of a statement
+func startsStatement(token Token) bool {
+	switch token.Class {
+	case CloseParam, Semicolon, Comma, Colon, Assignment, Dot:
+		return false
+	case Operator:
+		return token.Lexeme == "-" || token.Lexeme == "!" || token.Lexeme == "<-"
+	case Keyword:
+		switch token.Lexeme {
+		case "else", "catch", "finally", "case", "default", "as":
+			return false
+		}
+	}
+	return true
+}
+
+// nest updates the stack of open brackets with the token
+func nest(brackets []string, token Token) []string {
+	switch {
+	case token.Class == OpenParam:
+		return append(brackets, token.Lexeme)
+	case token.Class == CloseParam && len(brackets) > 0:
+		return brackets[:len(brackets)-1]
+	}
+	return brackets
+}
+
+// templateLength finds the end of template text starting after the first character of input, which is
+// either the opening backtick or the curly brace closing an interpolation. Text ends with a backtick or
+// with an opening of the next interpolation, an unterminated template takes the rest of the input
+func templateLength(input string) (int, bool) {
+	for i := 1; i < len(input); i++ {
+		switch {
+		case input[i] == '\\':
+			i++
+		case input[i] == '`':
+			return i + 1, false
+		case strings.HasPrefix(input[i:], "${"):
+			return i + 2, true
+		}
+	}
+	return len(input), false
 }
 
 func logLine(idx, ln uint64, rest string) {
@@ -3,6 +3,7 @@ package lexer
 import (
 	"testing"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTokenizer(t *testing.T) {
@@ -57,6 +58,7 @@ func TestTokenizer(t *testing.T) {
 				{Number, "1"},
 				{Operator, "-"},
 				{Number, "12"},
+				{Semicolon, "\n"},
 				{Identifier, "x"},
 				{Assignment, "="},
 				{Number, "3"},
@@ -159,6 +161,251 @@ func TestTokenizer(t *testing.T) {
 				{EOF,""},
 			},
 		},
+		{
+			desc:  "function with type annotations",
+			input: `fn(a: int, b: bool): bool { f(true, b) }`,
+			expectedTokens: []Token{
+				{Class: Keyword, Lexeme: "fn"},
+				{Class: OpenParam, Lexeme: "("},
+				{Class: Identifier, Lexeme: "a"},
+				{Class: Colon, Lexeme: ":"},
+				{Class: Identifier, Lexeme: "int"},
+				{Class: Comma, Lexeme: ","},
+				{Class: Identifier, Lexeme: "b"},
+				{Class: Colon, Lexeme: ":"},
+				{Class: Identifier, Lexeme: "bool"},
+				{Class: CloseParam, Lexeme: ")"},
+				{Class: Colon, Lexeme: ":"},
+				{Class: Identifier, Lexeme: "bool"},
+				{Class: OpenParam, Lexeme: "{"},
+				{Class: Identifier, Lexeme: "f"},
+				{Class: OpenParam, Lexeme: "("},
+				{Class: Boolean, Lexeme: "true"},
+				{Class: Comma, Lexeme: ","},
+				{Class: Identifier, Lexeme: "b"},
+				{Class: CloseParam, Lexeme: ")"},
+				{Class: CloseParam, Lexeme: "}"},
+				{EOF,""},
+			},
+		},
+		{
+			desc:  "modules and strings",
+			input: `import "lib/a b.mk" as lib; export var s = "say \"hi\"\n"; lib.f(x.y);`,
+			expectedTokens: []Token{
+				{Keyword, "import"},
+				{String, `"lib/a b.mk"`},
+				{Keyword, "as"},
+				{Identifier, "lib"},
+				{Semicolon, ";"},
+				{Keyword, "export"},
+				{Keyword, "var"},
+				{Identifier, "s"},
+				{Assignment, "="},
+				{String, `"say \"hi\"\n"`},
+				{Semicolon, ";"},
+				{Identifier, "lib"},
+				{Dot, "."},
+				{Identifier, "f"},
+				{OpenParam, "("},
+				{Identifier, "x"},
+				{Dot, "."},
+				{Identifier, "y"},
+				{CloseParam, ")"},
+				{Semicolon, ";"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "arrays",
+			input: `[1,x][0]`,
+			expectedTokens: []Token{
+				{OpenParam, "["},
+				{Number, "1"},
+				{Comma, ","},
+				{Identifier, "x"},
+				{CloseParam, "]"},
+				{OpenParam, "["},
+				{Number, "0"},
+				{CloseParam, "]"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "concurrency",
+			input: `spawn f(); select { case <-ch as v {} default {} } out<-1; x < -1`,
+			expectedTokens: []Token{
+				{Keyword, "spawn"},
+				{Identifier, "f"},
+				{OpenParam, "("},
+				{CloseParam, ")"},
+				{Semicolon, ";"},
+				{Keyword, "select"},
+				{OpenParam, "{"},
+				{Keyword, "case"},
+				{Operator, "<-"},
+				{Identifier, "ch"},
+				{Keyword, "as"},
+				{Identifier, "v"},
+				{OpenParam, "{"},
+				{CloseParam, "}"},
+				{Keyword, "default"},
+				{OpenParam, "{"},
+				{CloseParam, "}"},
+				{CloseParam, "}"},
+				{Identifier, "out"},
+				{Operator, "<-"},
+				{Number, "1"},
+				{Semicolon, ";"},
+				{Identifier, "x"},
+				{Operator, "<"},
+				{Operator, "-"},
+				{Number, "1"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "exceptions",
+			input: `try {} catch (e) { throw e; } finally {}`,
+			expectedTokens: []Token{
+				{Keyword, "try"},
+				{OpenParam, "{"},
+				{CloseParam, "}"},
+				{Keyword, "catch"},
+				{OpenParam, "("},
+				{Identifier, "e"},
+				{CloseParam, ")"},
+				{OpenParam, "{"},
+				{Keyword, "throw"},
+				{Identifier, "e"},
+				{Semicolon, ";"},
+				{CloseParam, "}"},
+				{Keyword, "finally"},
+				{OpenParam, "{"},
+				{CloseParam, "}"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "patterns",
+			input: `match (x) { [a, ...rest] => a, _ if a==b=>1 }`,
+			expectedTokens: []Token{
+				{Keyword, "match"},
+				{OpenParam, "("},
+				{Identifier, "x"},
+				{CloseParam, ")"},
+				{OpenParam, "{"},
+				{OpenParam, "["},
+				{Identifier, "a"},
+				{Comma, ","},
+				{Operator, "..."},
+				{Identifier, "rest"},
+				{CloseParam, "]"},
+				{Operator, "=>"},
+				{Identifier, "a"},
+				{Comma, ","},
+				{Identifier, "_"},
+				{Keyword, "if"},
+				{Identifier, "a"},
+				{Operator, "=="},
+				{Identifier, "b"},
+				{Operator, "=>"},
+				{Number, "1"},
+				{CloseParam, "}"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "structs",
+			input: `struct Point { x, y } p.x = 1;`,
+			expectedTokens: []Token{
+				{Keyword, "struct"},
+				{Identifier, "Point"},
+				{OpenParam, "{"},
+				{Identifier, "x"},
+				{Comma, ","},
+				{Identifier, "y"},
+				{CloseParam, "}"},
+				{Identifier, "p"},
+				{Dot, "."},
+				{Identifier, "x"},
+				{Assignment, "="},
+				{Number, "1"},
+				{Semicolon, ";"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "keywords as prefixes of identifiers",
+			input: `imports asx exported spawned selection cases defaults thrown trying catcher finallyDone matches structure`,
+			expectedTokens: []Token{
+				{Identifier, "imports"},
+				{Identifier, "asx"},
+				{Identifier, "exported"},
+				{Identifier, "spawned"},
+				{Identifier, "selection"},
+				{Identifier, "cases"},
+				{Identifier, "defaults"},
+				{Identifier, "thrown"},
+				{Identifier, "trying"},
+				{Identifier, "catcher"},
+				{Identifier, "finallyDone"},
+				{Identifier, "matches"},
+				{Identifier, "structure"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "templates",
+			input: "`a ${x + f({}) - 1} b ${`n ${y}`}` `\\${no} \\` // not a comment`",
+			expectedTokens: []Token{
+				{Template, "`a ${"},
+				{Identifier, "x"},
+				{Operator, "+"},
+				{Identifier, "f"},
+				{OpenParam, "("},
+				{OpenParam, "{"},
+				{CloseParam, "}"},
+				{CloseParam, ")"},
+				{Operator, "-"},
+				{Number, "1"},
+				{Template, "} b ${"},
+				{Template, "`n ${"},
+				{Identifier, "y"},
+				{Template, "}`"},
+				{Template, "}`"},
+				{Template, "`\\${no} \\` // not a comment`"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "conditional operators",
+			input: `a ? b?.c : d?.[0] ?? e`,
+			expectedTokens: []Token{
+				{Identifier, "a"},
+				{Operator, "?"},
+				{Identifier, "b"},
+				{Operator, "?."},
+				{Identifier, "c"},
+				{Colon, ":"},
+				{Identifier, "d"},
+				{Operator, "?."},
+				{OpenParam, "["},
+				{Number, "0"},
+				{CloseParam, "]"},
+				{Operator, "??"},
+				{Identifier, "e"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "unterminated template",
+			input: "x `a\nb",
+			expectedTokens: []Token{
+				{Identifier, "x"},
+				{Template, "`a\nb"},
+				{EOF, ""},
+			},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -167,3 +414,127 @@ func TestTokenizer(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	tokens, positions := TokenizeWithPositions(`var x = 1;
+  foo(x,
+	true);`)
+
+	require.Len(t, positions, len(tokens))
+	expected := []Position{
+		{1, 1}, {1, 5}, {1, 7}, {1, 9}, {1, 10},
+		{2, 3}, {2, 6}, {2, 7}, {2, 8},
+		{3, 2}, {3, 6}, {3, 7},
+		{3, 8},
+	}
+	assert.Equal(t, expected, positions)
+}
+
+func TestMultilineTemplatePositions(t *testing.T) {
+	tokens, positions := TokenizeWithPositions("`a\n${x}\nb` y")
+
+	assert.Equal(t, []Token{{Template, "`a\n${"}, {Identifier, "x"}, {Template, "}\nb`"}, {Identifier, "y"}, {EOF, ""}}, tokens)
+	assert.Equal(t, []Position{{1, 1}, {2, 3}, {2, 4}, {3, 4}, {3, 5}}, positions)
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+var x = 4 / 2; // trailing
+// footer`
+
+	assert.Equal(t, []Token{
+		{Keyword, "var"},
+		{Identifier, "x"},
+		{Assignment, "="},
+		{Number, "4"},
+		{Operator, "/"},
+		{Number, "2"},
+		{Semicolon, ";"},
+		{EOF, ""},
+	}, Tokenize(input))
+
+	comments, positions := Comments(input)
+	assert.Equal(t, []Token{
+		{Comment, "// header"},
+		{Comment, "// trailing"},
+		{Comment, "// footer"},
+	}, comments)
+	assert.Equal(t, []Position{{1, 1}, {2, 16}, {3, 1}}, positions)
+}
+
+func TestSemicolonInsertion(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		input          string
+		expectedTokens []Token
+	}{
+		{
+			desc:           "after identifier, literal and closing bracket",
+			input:          "a\n1\n\"s\"\ntrue\n`t`\nf()\nxs[0]\n{}\nreturn\ni++\n",
+			expectedTokens: []Token{
+				{Identifier, "a"}, ImplicitSemicolon,
+				{Number, "1"}, ImplicitSemicolon,
+				{String, `"s"`}, ImplicitSemicolon,
+				{Boolean, "true"}, ImplicitSemicolon,
+				{Template, "`t`"}, ImplicitSemicolon,
+				{Identifier, "f"}, {OpenParam, "("}, {CloseParam, ")"}, ImplicitSemicolon,
+				{Identifier, "xs"}, {OpenParam, "["}, {Number, "0"}, {CloseParam, "]"}, ImplicitSemicolon,
+				{OpenParam, "{"}, {CloseParam, "}"}, ImplicitSemicolon,
+				{Keyword, "return"}, ImplicitSemicolon,
+				{Identifier, "i"}, {Operator, "++"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "not after operators and explicit semicolons",
+			input: "a +\nb;\nvar x =\n1 // c\n\n// d\ny",
+			expectedTokens: []Token{
+				{Identifier, "a"}, {Operator, "+"}, {Identifier, "b"}, {Semicolon, ";"},
+				{Keyword, "var"}, {Identifier, "x"}, {Assignment, "="}, {Number, "1"}, ImplicitSemicolon,
+				{Identifier, "y"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "not inside parentheses, brackets and interpolations",
+			input: "f(a,\nb\n)\n[1\n]\n`${x\n}`",
+			expectedTokens: []Token{
+				{Identifier, "f"}, {OpenParam, "("}, {Identifier, "a"}, {Comma, ","}, {Identifier, "b"}, {CloseParam, ")"}, ImplicitSemicolon,
+				{OpenParam, "["}, {Number, "1"}, {CloseParam, "]"}, ImplicitSemicolon,
+				{Template, "`${"}, {Identifier, "x"}, {Template, "}`"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "inside curly braces in parentheses",
+			input: "f(fn() {\na\nb\n})",
+			expectedTokens: []Token{
+				{Identifier, "f"}, {OpenParam, "("}, {Keyword, "fn"}, {OpenParam, "("}, {CloseParam, ")"}, {OpenParam, "{"},
+				{Identifier, "a"}, ImplicitSemicolon, {Identifier, "b"}, {CloseParam, "}"}, {CloseParam, ")"},
+				{EOF, ""},
+			},
+		},
+		{
+			desc:  "not before tokens continuing the statement",
+			input: "a\n.b\n}\nelse\n}\ncatch\nx\n== y\nz\n-1",
+			expectedTokens: []Token{
+				{Identifier, "a"}, {Dot, "."}, {Identifier, "b"}, {CloseParam, "}"},
+				{Keyword, "else"}, {CloseParam, "}"},
+				{Keyword, "catch"}, {Identifier, "x"}, {Operator, "=="}, {Identifier, "y"}, ImplicitSemicolon,
+				{Identifier, "z"}, ImplicitSemicolon, {Operator, "-"}, {Number, "1"},
+				{EOF, ""},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expectedTokens, Tokenize(tC.input))
+		})
+	}
+
+	t.Run("position of new line", func(t *testing.T) {
+		tokens, positions := TokenizeWithPositions("a  // c\nb")
+		assert.Equal(t, []Token{{Identifier, "a"}, ImplicitSemicolon, {Identifier, "b"}, {EOF, ""}}, tokens)
+		assert.Equal(t, []Position{{1, 1}, {1, 8}, {2, 1}, {2, 2}}, positions)
+	})
+}
@@ -0,0 +1,407 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrInterrupted is returned when the line is abandoned with Ctrl-C
+	ErrInterrupted = errors.New("interrupted")
+	ErrNotTerminal = errors.New("not a terminal")
+)
+
+// Completer returns candidates for the word under the cursor, before is the text preceding the word
+type Completer func(before, word string) []string
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyNewline   = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// keys decoded from escape sequences, outside of the unicode range
+const (
+	keyUp rune = unicode.MaxRune + 1 + iota
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// Editor reads lines from a terminal in raw mode, supporting cursor movement,
+// history browsing, reverse search (Ctrl-R) and tab completion
+type Editor struct {
+	History  *History  // optional
+	Complete Completer // optional
+
+	in      *bufio.Reader
+	out     io.Writer
+	makeRaw func() (func() error, error) // nil when input is not a terminal
+}
+
+// New creates editor reading key presses from in, as sent by a terminal in raw mode
+func New(in io.Reader, out io.Writer) *Editor {
+	return &Editor{in: bufio.NewReader(in), out: out}
+}
+
+// NewTerminal creates editor switching the terminal to raw mode while a line is read.
+// Returns ErrNotTerminal when in is not a terminal, e.g. piped input
+func NewTerminal(in *os.File, out io.Writer) (*Editor, error) {
+	fd := int(in.Fd())
+	if !isTerminal(fd) {
+		return nil, ErrNotTerminal
+	}
+	e := New(in, out)
+	e.makeRaw = func() (func() error, error) { return makeRaw(fd) }
+	return e, nil
+}
+
+type state struct {
+	e      *Editor
+	prompt string
+	buf    []rune
+	pos    int
+	// index of history entry shown, equal to history length while editing new line
+	historyIdx int
+	edited     []rune // new line, saved while browsing history
+}
+
+// ReadLine shows the prompt and reads one line, which is added to history.
+// Returns io.EOF on Ctrl-D in empty line and ErrInterrupted on Ctrl-C
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.makeRaw != nil {
+		restore, err := e.makeRaw()
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	s := &state{e: e, prompt: prompt, historyIdx: e.History.Len()}
+	s.refresh()
+	for {
+		key, err := e.readKey()
+		if err == io.EOF && len(s.buf) > 0 {
+			return s.submit(), nil
+		} else if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyEnter, keyNewline:
+			return s.submit(), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(s.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.delete(s.pos)
+		case keyCtrlR:
+			key, err := s.search()
+			if err != nil {
+				return "", err
+			}
+			if key == keyEnter {
+				return s.submit(), nil
+			}
+			s.edit(key)
+		default:
+			s.edit(key)
+		}
+		s.refresh()
+	}
+}
+
+func (s *state) submit() string {
+	fmt.Fprint(s.e.out, "\r\n")
+	line := string(s.buf)
+	// history is best effort, failing to save it shouldn't stop the session
+	_ = s.e.History.Add(line)
+	return line
+}
+
+// edit handles keys changing the line or the cursor
+func (s *state) edit(key rune) {
+	switch key {
+	case keyCtrlA, keyHome:
+		s.pos = 0
+	case keyCtrlE, keyEnd:
+		s.pos = len(s.buf)
+	case keyCtrlB, keyLeft:
+		if s.pos > 0 {
+			s.pos--
+		}
+	case keyCtrlF, keyRight:
+		if s.pos < len(s.buf) {
+			s.pos++
+		}
+	case keyBackspace, keyCtrlH:
+		if s.pos > 0 {
+			s.pos--
+			s.delete(s.pos)
+		}
+	case keyDelete:
+		s.delete(s.pos)
+	case keyCtrlK:
+		s.buf = s.buf[:s.pos]
+	case keyCtrlU:
+		s.buf = append([]rune{}, s.buf[s.pos:]...)
+		s.pos = 0
+	case keyCtrlW:
+		start := s.pos
+		for start > 0 && unicode.IsSpace(s.buf[start-1]) {
+			start--
+		}
+		for start > 0 && !unicode.IsSpace(s.buf[start-1]) {
+			start--
+		}
+		s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+		s.pos = start
+	case keyCtrlP, keyUp:
+		s.browse(s.historyIdx - 1)
+	case keyCtrlN, keyDown:
+		s.browse(s.historyIdx + 1)
+	case keyCtrlL:
+		fmt.Fprint(s.e.out, "\x1b[H\x1b[2J")
+	case keyTab:
+		s.complete()
+	default:
+		if unicode.IsPrint(key) {
+			s.insert([]rune{key})
+		}
+	}
+}
+
+func (s *state) insert(runes []rune) {
+	rest := append(runes, s.buf[s.pos:]...)
+	s.buf = append(s.buf[:s.pos], rest...)
+	s.pos += len(runes)
+}
+
+func (s *state) delete(pos int) {
+	if pos < len(s.buf) {
+		s.buf = append(s.buf[:pos], s.buf[pos+1:]...)
+	}
+}
+
+func (s *state) setLine(line []rune) {
+	s.buf = append([]rune{}, line...)
+	s.pos = len(s.buf)
+}
+
+// browse shows history entry at the index, index past the last entry shows the edited line
+func (s *state) browse(idx int) {
+	entries := s.e.History.Entries()
+	if idx < 0 || idx > len(entries) {
+		return
+	}
+	if s.historyIdx == len(entries) {
+		s.edited = s.buf
+	}
+	s.historyIdx = idx
+	if idx == len(entries) {
+		s.setLine(s.edited)
+	} else {
+		s.setLine([]rune(entries[idx]))
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == ':' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// complete extends the word before the cursor, multiple candidates are extended
+// to their common prefix and listed when no progress can be made
+func (s *state) complete() {
+	if s.e.Complete == nil {
+		return
+	}
+	start := s.pos
+	for start > 0 && isWordRune(s.buf[start-1]) {
+		start--
+	}
+	word := string(s.buf[start:s.pos])
+	candidates := s.e.Complete(string(s.buf[:start]), word)
+	if len(candidates) == 0 {
+		return
+	}
+
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(candidates) == 1 {
+		prefix += " "
+	}
+	if len(prefix) > len(word) && strings.HasPrefix(prefix, word) {
+		s.insert([]rune(prefix[len(word):]))
+		return
+	}
+	if len(candidates) > 1 {
+		fmt.Fprintf(s.e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+// search handles reverse incremental search through history, started by Ctrl-R.
+// Found entry replaces the line. Returns key which ended the search, to be handled by the line,
+// keyEnter when the line should be submitted and 0 when the search was cancelled
+func (s *state) search() (rune, error) {
+	entries := s.e.History.Entries()
+	query := []rune{}
+	match := -1
+	failing := false
+
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(entries[i], string(query)) {
+				match = i
+				failing = false
+				return
+			}
+		}
+		failing = true
+	}
+
+	for {
+		found := ""
+		if match >= 0 {
+			found = entries[match]
+		}
+		label := "reverse-i-search"
+		if failing {
+			label = "failing " + label
+		}
+		fmt.Fprintf(s.e.out, "\r(%s)`%s': %s\x1b[K", label, string(query), found)
+
+		key, err := s.e.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch key {
+		case keyCtrlR:
+			if match > 0 {
+				find(match - 1)
+			}
+		case keyBackspace, keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(entries) - 1)
+			}
+		case keyCtrlG, keyCtrlC:
+			return 0, nil
+		case keyEnter, keyNewline:
+			if match >= 0 {
+				s.setLine([]rune(entries[match]))
+			}
+			return keyEnter, nil
+		default:
+			if !unicode.IsPrint(key) {
+				// any other key ends the search keeping the found entry
+				if match >= 0 {
+					s.setLine([]rune(entries[match]))
+				}
+				return key, nil
+			}
+			query = append(query, key)
+			if match < 0 {
+				find(len(entries) - 1)
+			} else {
+				find(match)
+			}
+		}
+	}
+}
+
+// refresh redraws the line and places the cursor
+func (s *state) refresh() {
+	fmt.Fprintf(s.e.out, "\r%s%s\x1b[K", s.prompt, string(s.buf))
+	if back := len(s.buf) - s.pos; back > 0 {
+		fmt.Fprintf(s.e.out, "\x1b[%dD", back)
+	}
+}
+
+// readKey reads a character, decoding escape sequences of special keys
+func (e *Editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+
+	params := ""
+	for {
+		c, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			return decodeSequence(params, c), nil
+		}
+		params += string(c)
+	}
+}
+
+func decodeSequence(params string, final rune) rune {
+	switch final {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	case '~':
+		switch params {
+		case "1", "7":
+			return keyHome
+		case "4", "8":
+			return keyEnd
+		case "3":
+			return keyDelete
+		}
+	}
+	return keyUnknown
+}
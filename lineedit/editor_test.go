@@ -0,0 +1,200 @@
+package lineedit
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	right = "\x1b[C"
+	left  = "\x1b[D"
+	home  = "\x1b[H"
+	end   = "\x1b[4~"
+	del   = "\x1b[3~"
+)
+
+func readLine(t *testing.T, e *Editor) string {
+	line, err := e.ReadLine(">> ")
+	require.NoError(t, err)
+	return line
+}
+
+func TestEditing(t *testing.T) {
+	tdt := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"plain", "var x = 1;\r", "var x = 1;"},
+		{"newline ends line", "1 + 2\n", "1 + 2"},
+		{"insert in the middle", "1 + 3" + left + "2 \r", "1 + 2 3"},
+		{"backspace", "1 + 22\x7f\r", "1 + 2"},
+		{"backspace at start", "\x7fa\r", "a"},
+		{"home and end", "23" + home + "1" + end + "4\r", "1234"},
+		{"ctrl-a and ctrl-e", "bc\x01a\x05d\r", "abcd"},
+		{"ctrl-b and ctrl-f", "ac\x02b\x06d\r", "abcd"},
+		{"delete", "abc" + home + del + "\r", "bc"},
+		{"ctrl-d deletes under cursor", "abc" + left + "\x04\r", "ab"},
+		{"kill to end", "abc def" + left + left + left + "\x0b\r", "abc "},
+		{"kill to start", "abc def" + left + left + left + "\x15\r", "def"},
+		{"delete word", "var foo bar\x17\x17x\r", "var x"},
+		{"cursor stays in line", left + right + right + "a" + left + left + "b\r", "ba"},
+		{"unknown keys ignored", "a\x1b[5~\x1bxb\r", "ab"},
+		{"unicode", "żó\x7fł\r", "żł"},
+		{"end of input", "1 + 2", "1 + 2"},
+	}
+
+	for _, tt := range tdt {
+		t.Run(tt.desc, func(t *testing.T) {
+			e := New(strings.NewReader(tt.input), io.Discard)
+			assert.Equal(t, tt.expected, readLine(t, e))
+		})
+	}
+}
+
+func TestControlErrors(t *testing.T) {
+	e := New(strings.NewReader("abc\x03\x04"), io.Discard)
+	_, err := e.ReadLine(">> ")
+	assert.Equal(t, ErrInterrupted, err)
+	_, err = e.ReadLine(">> ")
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRefresh(t *testing.T) {
+	var out bytes.Buffer
+	e := New(strings.NewReader("ab"+left+"\r"), &out)
+	readLine(t, e)
+	assert.Equal(t, "\r>> \x1b[K\r>> a\x1b[K\r>> ab\x1b[K\r>> ab\x1b[K\x1b[1D\r\n", out.String())
+}
+
+func TestHistoryBrowsing(t *testing.T) {
+	e := New(strings.NewReader("first\rsecond\r"+up+up+up+"\r"+"new"+up+down+"!\r"+"\x10\x10\x10\x10\x0e\r"), io.Discard)
+	e.History = NewHistory()
+
+	assert.Equal(t, "first", readLine(t, e))
+	assert.Equal(t, "second", readLine(t, e))
+	assert.Equal(t, "first", readLine(t, e))
+	assert.Equal(t, "new!", readLine(t, e))
+	assert.Equal(t, "second", readLine(t, e))
+	assert.Equal(t, []string{"first", "second", "first", "new!", "second"}, e.History.Entries())
+}
+
+func TestReverseSearch(t *testing.T) {
+	history := NewHistory()
+	for _, line := range []string{"var add = fn(a, b) { a + b };", "add(1, 2);", "var x = 10;", "add(x, 3);"} {
+		require.NoError(t, history.Add(line))
+	}
+
+	tdt := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"most recent match", "\x12add\r", "add(x, 3);"},
+		{"older match", "\x12add\x12\r", "add(1, 2);"},
+		{"oldest match stays", "\x12add\x12\x12\x12\x12\r", "var add = fn(a, b) { a + b };"},
+		{"refined query", "\x12a\x12dd(1\r", "add(1, 2);"},
+		{"backspace", "\x12x =\x7f\x7f\x7f\x7f1\r", "var x = 10;"},
+		{"cancel", "abc\x12add\x07d\r", "abcd"},
+		{"edit found entry", "\x12x = 1" + left + "\x7f2\r", "var x = 12;"},
+		{"no match", "\x12zzz\r", ""},
+	}
+
+	for _, tt := range tdt {
+		t.Run(tt.desc, func(t *testing.T) {
+			e := New(strings.NewReader(tt.input), io.Discard)
+			e.History = &History{entries: append([]string{}, history.Entries()...)}
+			assert.Equal(t, tt.expected, readLine(t, e))
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	complete := func(before, word string) []string {
+		out := []string{}
+		for _, name := range []string{"add", "adder", "answer", "var"} {
+			if strings.HasPrefix(name, word) && !strings.HasSuffix(before, "var ") {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+
+	tdt := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"single candidate", "ans\t\r", "answer "},
+		{"common prefix", "1 + ad\t\r", "1 + add"},
+		{"in the middle", "an(1)" + left + left + left + "\t\r", "answer (1)"},
+		{"no candidates", "xyz\t\r", "xyz"},
+		{"context", "var ad\t\r", "var ad"},
+	}
+
+	for _, tt := range tdt {
+		t.Run(tt.desc, func(t *testing.T) {
+			e := New(strings.NewReader(tt.input), io.Discard)
+			e.Complete = complete
+			assert.Equal(t, tt.expected, readLine(t, e))
+		})
+	}
+
+	var out bytes.Buffer
+	e := New(strings.NewReader("add\t\r"), &out)
+	e.Complete = complete
+	readLine(t, e)
+	assert.Contains(t, out.String(), "\r\nadd  adder\r\n")
+}
+
+func TestPersistentHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".monkey_history")
+
+	h, err := LoadHistory(path)
+	require.NoError(t, err)
+	assert.Empty(t, h.Entries())
+	require.NoError(t, h.Add("1 + 2;"))
+	require.NoError(t, h.Add("1 + 2;"))
+	require.NoError(t, h.Add("   "))
+	require.NoError(t, h.Add("var x = 1;"))
+
+	h, err = LoadHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1 + 2;", "var x = 1;"}, h.Entries())
+}
+
+func TestHistoryLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".monkey_history")
+	lines := []string{}
+	for i := 0; i < maxHistory+5; i++ {
+		lines = append(lines, strings.Repeat("x", i+1))
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+	h, err := LoadHistory(path)
+	require.NoError(t, err)
+	assert.Len(t, h.Entries(), maxHistory)
+	assert.Equal(t, lines[5], h.Entries()[0])
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, maxHistory, strings.Count(string(content), "\n"))
+}
+
+func TestNotTerminal(t *testing.T) {
+	file, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = NewTerminal(file, io.Discard)
+	assert.Equal(t, ErrNotTerminal, err)
+}
@@ -0,0 +1,94 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// only the most recent entries are kept
+const maxHistory = 1000
+
+// History of entered lines, optionally persisted to a file with one entry per line
+type History struct {
+	entries []string
+	path    string
+}
+
+// NewHistory creates history kept only in memory
+func NewHistory() *History {
+	return &History{}
+}
+
+// LoadHistory reads history from the file, missing file is treated as empty history.
+// New entries are appended to the file
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("history error - %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if scanner.Text() != "" {
+			h.entries = append(h.entries, scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("history error - %v", err)
+	}
+
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		content := strings.Join(h.entries, "\n") + "\n"
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			return nil, fmt.Errorf("history error - %v", err)
+		}
+	}
+	return h, nil
+}
+
+// Entries from the oldest one
+func (h *History) Entries() []string {
+	if h == nil {
+		return nil
+	}
+	return h.entries
+}
+
+func (h *History) Len() int {
+	return len(h.Entries())
+}
+
+// Add appends the line, blank lines and repeats of the last entry are skipped
+func (h *History) Add(line string) error {
+	if h == nil || strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("history error - %v", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		return fmt.Errorf("history error - %v", err)
+	}
+	return nil
+}
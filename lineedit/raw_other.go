@@ -0,0 +1,13 @@
+//go:build !linux
+
+package lineedit
+
+// raw mode is only implemented for Linux, other systems use plain line reading
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (func() error, error) {
+	return nil, ErrNotTerminal
+}
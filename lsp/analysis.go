@@ -0,0 +1,412 @@
+package lsp
+
+import (
+	"math"
+	"programming-lang/format"
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"programming-lang/typecheck"
+	"sort"
+	"strings"
+)
+
+// keywords offered by completion next to identifiers
+var keywords = []string{"var", "return", "fn", "if", "else", "true", "false", "spawn", "select", "case", "default", "throw", "try", "catch", "finally", "match", "struct", "macro"}
+
+// document is an analyzed version of an open file
+type document struct {
+	tree       *parser.Program
+	typeErrors []error // found by the type checker, only when the file parses
+	tokens     []lexer.Token
+	positions  []lexer.Position
+
+	declarations []*declaration
+	occurrences  []occurrence
+}
+
+// declaration is a var, a function parameter, a module alias, a value received by a select case, a caught error,
+// a name bound by a match arm or a struct
+type declaration struct {
+	name  string
+	pos   lexer.Position
+	node  parser.Node // *parser.VarStatementNode, *parser.FunctionParameter, *parser.ImportStatement, *parser.SelectCase, *parser.TryStatement, *parser.MatchArm or *parser.StructStatement
+	scope parser.Span // function body, or whole document for top-level vars
+}
+
+func (d *declaration) span() parser.Span {
+	return nameSpan(d.pos, d.name)
+}
+
+func (d *declaration) isFunction() bool {
+	v, ok := d.node.(*parser.VarStatementNode)
+	return ok && isFunctionLiteral(v.Value)
+}
+
+// isFunctionLiteral reports whether the value is a function or a macro literal
+func isFunctionLiteral(value parser.ExpressionNode) bool {
+	switch value.(type) {
+	case *parser.FunctionLiteralExpression, *parser.MacroLiteral:
+		return true
+	}
+	return false
+}
+
+func (d *declaration) isStruct() bool {
+	_, ok := d.node.(*parser.StructStatement)
+	return ok
+}
+
+// occurrence is a declaration or a reference of the name in source
+type occurrence struct {
+	span parser.Span
+	decl *declaration
+}
+
+func analyze(text string) *document {
+	tokens, positions := lexer.TokenizeWithPositions(text)
+	doc := &document{
+		tree:      parser.ParseWithPositions(tokens, positions),
+		tokens:    tokens,
+		positions: positions,
+	}
+
+	everything := parser.Span{Start: lexer.Position{Line: 1, Column: 1}, End: lexer.Position{Line: math.MaxInt32}}
+	r := &resolver{doc: doc, scopes: []*scope{newScope(everything)}}
+	parser.Walk(doc.tree, r)
+	if len(doc.tree.Errors) == 0 {
+		doc.typeErrors = typecheck.Check(doc.tree)
+	}
+	return doc
+}
+
+type scope struct {
+	span  parser.Span
+	names map[string]*declaration
+}
+
+func newScope(span parser.Span) *scope {
+	return &scope{span: span, names: map[string]*declaration{}}
+}
+
+// resolver binds identifiers to declarations. Functions and methods open a new scope, so do bodies of select cases
+// naming the received value, catch blocks and match arms, blocks of if expressions share the scope of the enclosing function
+type resolver struct {
+	doc    *document
+	scopes []*scope
+}
+
+func (r *resolver) Enter(node parser.Node) bool {
+	switch n := node.(type) {
+	case *parser.FunctionLiteralExpression, *parser.MacroLiteral:
+		r.scopes = append(r.scopes, newScope(parser.SpanOf(n)))
+	case *parser.FunctionParameter:
+		r.declare(n.Name, n.Pos, n)
+	case *parser.ImportStatement:
+		r.declare(n.Alias, n.AliasPos, n)
+	case *parser.StructStatement:
+		r.declare(n.Name, n.NamePos, n)
+	case *parser.MethodStatement:
+		// the receiver is visible only in the method
+		r.scopes = append(r.scopes, newScope(parser.SpanOf(n)))
+		r.declare(n.Receiver.Name, n.Receiver.Pos, n.Receiver)
+		parser.Walk(n.Function, r)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		return false
+	case *parser.VarStatementNode:
+		// function can call itself, other values can't reference the var being declared
+		if isFunctionLiteral(n.Value) {
+			r.declare(n.Name, n.NamePos, n)
+		}
+	case *parser.IdentifierExpression:
+		if decl := r.lookup(n.Name); decl != nil {
+			r.doc.occurrences = append(r.doc.occurrences, occurrence{span: parser.SpanOf(n), decl: decl})
+		}
+	case *parser.SelectCase:
+		if n.Name == "" {
+			return true
+		}
+		// the name is visible only in the body, not in the received channel
+		parser.Walk(n.Comm, r)
+		r.scopes = append(r.scopes, newScope(parser.SpanOf(n.Body)))
+		r.declare(n.Name, n.NamePos, n)
+		parser.Walk(n.Body, r)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		return false
+	case *parser.TryStatement:
+		if n.Catch == nil {
+			return true
+		}
+		parser.Walk(n.Body, r)
+		r.scopes = append(r.scopes, newScope(parser.SpanOf(n.Catch)))
+		r.declare(n.Name, n.NamePos, n)
+		parser.Walk(n.Catch, r)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		if n.Finally != nil {
+			parser.Walk(n.Finally, r)
+		}
+		return false
+	case *parser.MatchArm:
+		// names bound by the pattern are visible in the guard and the body
+		r.scopes = append(r.scopes, newScope(parser.SpanOf(n)))
+		for _, b := range parser.Bindings(n.Pattern) {
+			r.declare(b.Name, b.Pos, n)
+		}
+		if n.Guard != nil {
+			parser.Walk(n.Guard, r)
+		}
+		parser.Walk(n.Body, r)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		return false
+	}
+	return true
+}
+
+func (r *resolver) Leave(node parser.Node) {
+	switch n := node.(type) {
+	case *parser.FunctionLiteralExpression, *parser.MacroLiteral:
+		r.scopes = r.scopes[:len(r.scopes)-1]
+	case *parser.VarStatementNode:
+		if n.Pattern != nil {
+			for _, b := range parser.Bindings(n.Pattern) {
+				r.declare(b.Name, b.Pos, n)
+			}
+		} else if !isFunctionLiteral(n.Value) {
+			r.declare(n.Name, n.NamePos, n)
+		}
+	}
+}
+
+func (r *resolver) declare(name string, pos lexer.Position, node parser.Node) {
+	current := r.scopes[len(r.scopes)-1]
+	decl := &declaration{name: name, pos: pos, node: node, scope: current.span}
+	current.names[name] = decl
+	r.doc.declarations = append(r.doc.declarations, decl)
+	r.doc.occurrences = append(r.doc.occurrences, occurrence{span: decl.span(), decl: decl})
+}
+
+func (r *resolver) lookup(name string) *declaration {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if decl, ok := r.scopes[i].names[name]; ok {
+			return decl
+		}
+	}
+	return nil
+}
+
+// declarationAt returns the declaration of the name under the cursor, either referenced or declared there
+func (d *document) declarationAt(pos lexer.Position) *declaration {
+	for _, o := range d.occurrences {
+		if !less(pos, o.span.Start) && !less(o.span.End, pos) {
+			return o.decl
+		}
+	}
+	return nil
+}
+
+// references returns all occurrences of the declaration in source order
+func (d *document) references(decl *declaration, includeDeclaration bool) []parser.Span {
+	out := []parser.Span{}
+	for _, o := range d.occurrences {
+		if o.decl != decl || (!includeDeclaration && o.span == decl.span()) {
+			continue
+		}
+		out = append(out, o.span)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return less(out[i].Start, out[j].Start)
+	})
+	return out
+}
+
+// visibleAt returns declarations accessible at the cursor, inner declarations shadow outer ones
+func (d *document) visibleAt(pos lexer.Position) []*declaration {
+	byName := map[string]*declaration{}
+	for _, decl := range d.declarations {
+		if less(decl.pos, pos) && !less(pos, decl.scope.Start) && !less(decl.scope.End, pos) {
+			byName[decl.name] = decl
+		}
+	}
+
+	out := []*declaration{}
+	for _, decl := range byName {
+		out = append(out, decl)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].name < out[j].name
+	})
+	return out
+}
+
+// describe renders the declaration as shown on hover
+func describe(decl *declaration) string {
+	switch n := decl.node.(type) {
+	case *parser.FunctionParameter:
+		if n.Type == nil {
+			return "(parameter) " + n.Name
+		}
+		return "(parameter) " + n.Name + ": " + n.Type.String()
+	case *parser.VarStatementNode:
+		if fn, ok := n.Value.(*parser.FunctionLiteralExpression); ok {
+			return "var " + n.Name + " = " + signature(fn)
+		}
+		if m, ok := n.Value.(*parser.MacroLiteral); ok {
+			return "var " + n.Name + " = " + macroSignature(m)
+		}
+		statement := &parser.Program{Statements: []parser.StatementNode{n}}
+		return strings.TrimSuffix(strings.TrimSpace(format.Program(statement)), ";")
+	case *parser.StructStatement:
+		return strings.TrimSpace(format.Program(&parser.Program{Statements: []parser.StatementNode{n}}))
+	case *parser.ImportStatement:
+		return n.String()
+	case *parser.SelectCase:
+		return "(received) " + n.Name
+	case *parser.TryStatement:
+		return "(caught) " + n.Name
+	case *parser.MatchArm:
+		return "(matched) " + decl.name + " in " + n.Pattern.String()
+	}
+	return decl.name
+}
+
+func signature(fn *parser.FunctionLiteralExpression) string {
+	params := []string{}
+	for _, p := range fn.Parameters {
+		if p.Type == nil {
+			params = append(params, p.Name)
+		} else {
+			params = append(params, p.Name+": "+p.Type.String())
+		}
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if fn.ReturnType != nil {
+		out += ": " + fn.ReturnType.String()
+	}
+	return out
+}
+
+func macroSignature(m *parser.MacroLiteral) string {
+	return "macro" + strings.TrimPrefix(signature(&parser.FunctionLiteralExpression{Parameters: m.Parameters}), "fn")
+}
+
+// diagnostics converts parser errors, type errors and warnings, each one spans the token where it was found
+func (d *document) diagnostics() []diagnostic {
+	out := []diagnostic{}
+	add := func(err error, severity int) {
+		var pos lexer.Position
+		if syntaxErr, ok := err.(*parser.SyntaxError); ok {
+			pos = syntaxErr.Pos
+		}
+		out = append(out, diagnostic{
+			Range:    toRange(parser.Span{Start: pos, End: d.tokenEnd(pos)}),
+			Severity: severity,
+			Source:   "monkey",
+			Message:  err.Error(),
+		})
+	}
+	for _, err := range d.tree.Errors {
+		add(err, severityError)
+	}
+	for _, err := range d.typeErrors {
+		add(err, severityError)
+	}
+	for _, warning := range d.tree.Warnings {
+		add(warning, severityWarning)
+	}
+	return out
+}
+
+func (d *document) tokenEnd(pos lexer.Position) lexer.Position {
+	for i, p := range d.positions {
+		if p == pos {
+			return nameSpan(pos, d.tokens[i].Lexeme).End
+		}
+	}
+	return pos
+}
+
+// symbols lists vars, structs and methods declared in the statements, functions contain vars of their bodies
+func symbols(node parser.Node) []documentSymbol {
+	out := []documentSymbol{}
+	parser.Inspect(node, func(n parser.Node) bool {
+		switch s := n.(type) {
+		case *parser.StructStatement:
+			out = append(out, structSymbol(s))
+			return false
+		case *parser.MethodStatement:
+			out = append(out, documentSymbol{
+				Name:           s.Receiver.Type.Name + "." + s.Name,
+				Detail:         signature(s.Function),
+				Kind:           symbolMethod,
+				Range:          toRange(parser.SpanOf(s)),
+				SelectionRange: toRange(nameSpan(s.NamePos, s.Name)),
+				Children:       symbols(s.Function.Body),
+			})
+			return false
+		}
+		v, ok := n.(*parser.VarStatementNode)
+		if !ok || n == node {
+			return true
+		}
+		if v.Pattern != nil {
+			for _, b := range parser.Bindings(v.Pattern) {
+				out = append(out, documentSymbol{
+					Name:           b.Name,
+					Kind:           symbolVariable,
+					Range:          toRange(parser.SpanOf(v)),
+					SelectionRange: toRange(nameSpan(b.Pos, b.Name)),
+				})
+			}
+			return false
+		}
+
+		sym := documentSymbol{
+			Name:           v.Name,
+			Kind:           symbolVariable,
+			Range:          toRange(parser.SpanOf(v)),
+			SelectionRange: toRange(nameSpan(v.NamePos, v.Name)),
+		}
+		if v.Type != nil {
+			sym.Detail = v.Type.String()
+		}
+		if fn, ok := v.Value.(*parser.FunctionLiteralExpression); ok {
+			sym.Kind = symbolFunction
+			sym.Detail = signature(fn)
+			sym.Children = symbols(fn.Body)
+		}
+		if m, ok := v.Value.(*parser.MacroLiteral); ok {
+			sym.Kind = symbolFunction
+			sym.Detail = macroSignature(m)
+			sym.Children = symbols(m.Body)
+		}
+		out = append(out, sym)
+		return false
+	})
+	return out
+}
+
+func structSymbol(s *parser.StructStatement) documentSymbol {
+	fields := []documentSymbol{}
+	for i, f := range s.Fields {
+		span := nameSpan(s.FieldPos[i], f)
+		fields = append(fields, documentSymbol{Name: f, Kind: symbolField, Range: toRange(span), SelectionRange: toRange(span)})
+	}
+	return documentSymbol{
+		Name:           s.Name,
+		Kind:           symbolStruct,
+		Range:          toRange(parser.SpanOf(s)),
+		SelectionRange: toRange(nameSpan(s.NamePos, s.Name)),
+		Children:       fields,
+	}
+}
+
+func nameSpan(pos lexer.Position, name string) parser.Span {
+	return parser.Span{Start: pos, End: lexer.Position{Line: pos.Line, Column: pos.Column + len([]rune(name))}}
+}
+
+func toRange(span parser.Span) textRange {
+	return textRange{Start: toProtocol(span.Start), End: toProtocol(span.End)}
+}
+
+func less(a, b lexer.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
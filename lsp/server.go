@@ -0,0 +1,269 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"programming-lang/framing"
+)
+
+type server struct {
+	out       io.Writer
+	documents map[string]*document
+	shutdown  bool
+	writeErr  error // first failure of writing a notification
+}
+
+type handler func(s *server, params json.RawMessage) (any, *responseError)
+
+var handlers = map[string]handler{
+	"initialize":                  (*server).initialize,
+	"initialized":                 ignore,
+	"shutdown":                    (*server).shutdownRequest,
+	"textDocument/didOpen":        (*server).didOpen,
+	"textDocument/didChange":      (*server).didChange,
+	"textDocument/didClose":       (*server).didClose,
+	"textDocument/didSave":        ignore,
+	"textDocument/definition":     (*server).definition,
+	"textDocument/references":     (*server).references,
+	"textDocument/hover":          (*server).hover,
+	"textDocument/completion":     (*server).completion,
+	"textDocument/documentSymbol": (*server).documentSymbol,
+}
+
+// Serve runs the language server speaking JSON-RPC over the given streams, until the exit notification.
+// Documents are synchronized in full on every change
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{out: out, documents: map[string]*document{}}
+	reader := bufio.NewReader(in)
+	for {
+		data, err := framing.Read(reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			if err := s.reply(json.RawMessage("null"), nil, &responseError{Code: parseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("lsp error - exit before shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(&req)
+		if s.writeErr != nil {
+			return s.writeErr
+		}
+		if req.isNotification() {
+			continue
+		}
+		if err := s.reply(req.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(req *request) (any, *responseError) {
+	if s.shutdown {
+		return nil, &responseError{Code: invalidRequest, Message: "server is shut down"}
+	}
+	h, ok := handlers[req.Method]
+	if !ok {
+		return nil, &responseError{Code: methodNotFound, Message: "method not found: " + req.Method}
+	}
+	return h(s, req.Params)
+}
+
+func (s *server) reply(id json.RawMessage, result any, rpcErr *responseError) error {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = data
+	}
+	return framing.Write(s.out, resp)
+}
+
+func (s *server) notify(method string, params any) {
+	if s.writeErr == nil {
+		s.writeErr = framing.Write(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+	}
+}
+
+func decode(params json.RawMessage, v any) *responseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: invalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func ignore(*server, json.RawMessage) (any, *responseError) {
+	return nil, nil
+}
+
+func (s *server) initialize(json.RawMessage) (any, *responseError) {
+	result := initializeResult{ServerInfo: serverInfo{Name: "monkey-lsp"}}
+	result.Capabilities.TextDocumentSync = syncFull
+	result.Capabilities.DefinitionProvider = true
+	result.Capabilities.ReferencesProvider = true
+	result.Capabilities.HoverProvider = true
+	result.Capabilities.DocumentSymbolProvider = true
+	result.Capabilities.CompletionProvider.TriggerCharacters = []string{}
+	return result, nil
+}
+
+func (s *server) shutdownRequest(json.RawMessage) (any, *responseError) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *server) didOpen(params json.RawMessage) (any, *responseError) {
+	var p didOpenParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	s.update(p.TextDocument.URI, p.TextDocument.Text)
+	return nil, nil
+}
+
+func (s *server) didChange(params json.RawMessage) (any, *responseError) {
+	var p didChangeParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	// full sync, last change holds the whole text
+	s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+	return nil, nil
+}
+
+func (s *server) didClose(params json.RawMessage) (any, *responseError) {
+	var p didCloseParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.documents, p.TextDocument.URI)
+	s.publish(p.TextDocument.URI, []diagnostic{})
+	return nil, nil
+}
+
+func (s *server) update(uri string, text string) {
+	doc := analyze(text)
+	s.documents[uri] = doc
+	s.publish(uri, doc.diagnostics())
+}
+
+func (s *server) publish(uri string, diagnostics []diagnostic) {
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+// declarationAt finds the document and the declaration under the cursor, nil when not found
+func (s *server) declarationAt(p textDocumentPositionParams) (*document, *declaration) {
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	return doc, doc.declarationAt(fromProtocol(p.Position))
+}
+
+func (s *server) definition(params json.RawMessage) (any, *responseError) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	_, decl := s.declarationAt(p)
+	if decl == nil {
+		return nil, nil
+	}
+	return location{URI: p.TextDocument.URI, Range: toRange(decl.span())}, nil
+}
+
+func (s *server) references(params json.RawMessage) (any, *responseError) {
+	var p referenceParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, decl := s.declarationAt(p.textDocumentPositionParams)
+	if decl == nil {
+		return nil, nil
+	}
+
+	out := []location{}
+	for _, span := range doc.references(decl, p.Context.IncludeDeclaration) {
+		out = append(out, location{URI: p.TextDocument.URI, Range: toRange(span)})
+	}
+	return out, nil
+}
+
+func (s *server) hover(params json.RawMessage) (any, *responseError) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, decl := s.declarationAt(p)
+	if decl == nil {
+		return nil, nil
+	}
+
+	cursor := fromProtocol(p.Position)
+	var word textRange
+	for _, span := range doc.references(decl, true) {
+		if !less(cursor, span.Start) && !less(span.End, cursor) {
+			word = toRange(span)
+		}
+	}
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: "```monkey\n" + describe(decl) + "\n```"},
+		Range:    word,
+	}, nil
+}
+
+func (s *server) completion(params json.RawMessage) (any, *responseError) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	out := []completionItem{}
+	if doc, ok := s.documents[p.TextDocument.URI]; ok {
+		for _, decl := range doc.visibleAt(fromProtocol(p.Position)) {
+			item := completionItem{Label: decl.name, Kind: completionVariable, Detail: describe(decl)}
+			if decl.isFunction() {
+				item.Kind = completionFunction
+			} else if decl.isStruct() {
+				item.Kind = completionStruct
+			}
+			out = append(out, item)
+		}
+	}
+	for _, k := range keywords {
+		out = append(out, completionItem{Label: k, Kind: completionKeyword})
+	}
+	return out, nil
+}
+
+func (s *server) documentSymbol(params json.RawMessage) (any, *responseError) {
+	var p documentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return []documentSymbol{}, nil
+	}
+	return symbols(doc.tree), nil
+}
@@ -0,0 +1,420 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"programming-lang/framing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const uri = "file:///test.mk"
+
+const source = `var add = fn(a: int, b) {
+	var sum = a + b;
+	return sum;
+};
+var x = add(1, 2);`
+
+// script collects client messages, which are then sent to the server at once
+type script struct {
+	t      *testing.T
+	input  bytes.Buffer
+	nextID int
+}
+
+func newScript(t *testing.T) *script {
+	s := &script{t: t}
+	s.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	s.notify("initialized", map[string]any{})
+	return s
+}
+
+func (s *script) request(method string, params any) int {
+	s.nextID++
+	s.send(map[string]any{"jsonrpc": "2.0", "id": s.nextID, "method": method, "params": params})
+	return s.nextID
+}
+
+func (s *script) notify(method string, params any) {
+	s.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *script) send(msg any) {
+	require.NoError(s.t, framing.Write(&s.input, msg))
+}
+
+func (s *script) open(text string) {
+	s.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "monkey", "version": 1, "text": text},
+	})
+}
+
+func (s *script) at(method string, line, character int) int {
+	return s.request(method, map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	})
+}
+
+type serverOutput struct {
+	responses     map[int]response
+	notifications []notification
+}
+
+// run sends the script and ends it with shutdown and exit
+func (s *script) run() serverOutput {
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+	return s.runWithoutShutdown("")
+}
+
+// runWithoutShutdown expects the script to end the session, expectedErr is empty when Serve should succeed
+func (s *script) runWithoutShutdown(expectedErr string) serverOutput {
+	var out bytes.Buffer
+	err := Serve(&s.input, &out)
+	if expectedErr == "" {
+		require.NoError(s.t, err)
+	} else {
+		require.EqualError(s.t, err, expectedErr)
+	}
+
+	result := serverOutput{responses: map[int]response{}}
+	reader := bufio.NewReader(&out)
+	for {
+		data, err := framing.Read(reader)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.t, err)
+
+		var msg struct {
+			response
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		require.NoError(s.t, json.Unmarshal(data, &msg))
+		if msg.Method != "" {
+			result.notifications = append(result.notifications, notification{JSONRPC: msg.JSONRPC, Method: msg.Method, Params: msg.Params})
+			continue
+		}
+		var id int
+		require.NoError(s.t, json.Unmarshal(msg.ID, &id))
+		result.responses[id] = msg.response
+	}
+	return result
+}
+
+func (o serverOutput) result(t *testing.T, id int) string {
+	resp, ok := o.responses[id]
+	require.True(t, ok, "no response for request %d", id)
+	require.Nil(t, resp.Error, "request %d failed", id)
+	return string(resp.Result)
+}
+
+func rangeJson(startLine, startChar, endLine, endChar int) string {
+	out, _ := json.Marshal(textRange{position{startLine, startChar}, position{endLine, endChar}})
+	return string(out)
+}
+
+func TestInitialize(t *testing.T) {
+	out := newScript(t).run()
+
+	expected := `{"capabilities":{"textDocumentSync":1,"definitionProvider":true,"referencesProvider":true,
+		"hoverProvider":true,"documentSymbolProvider":true,"completionProvider":{"triggerCharacters":[]}},
+		"serverInfo":{"name":"monkey-lsp"}}`
+	assert.JSONEq(t, expected, out.result(t, 1))
+	assert.JSONEq(t, `null`, out.result(t, 2))
+	assert.Empty(t, out.notifications)
+}
+
+func TestDiagnosticsArePublishedOnChange(t *testing.T) {
+	s := newScript(t)
+	s.open(source)
+	s.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []any{map[string]any{"text": "var x = 1;\nvar y = 2 var z = 3;"}},
+	})
+	s.notify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	out := s.run()
+
+	require.Len(t, out.notifications, 3)
+	for _, n := range out.notifications {
+		assert.Equal(t, "textDocument/publishDiagnostics", n.Method)
+	}
+	params := func(i int) string {
+		data, err := json.Marshal(out.notifications[i].Params)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","diagnostics":[]}`, params(0))
+	assert.JSONEq(t, `{"uri":"`+uri+`","diagnostics":[{"range":`+rangeJson(1, 8, 1, 9)+`,"severity":1,"source":"monkey",
+		"message":"var error - expected semicolon after expression, got Keyword"}]}`, params(1))
+	assert.JSONEq(t, `{"uri":"`+uri+`","diagnostics":[]}`, params(2))
+}
+
+func TestDefinition(t *testing.T) {
+	tdt := []struct {
+		name            string
+		line, character int
+		expected        string
+	}{
+		{"function", 4, 9, `{"uri":"` + uri + `","range":` + rangeJson(0, 4, 0, 7) + `}`},
+		{"local var", 2, 10, `{"uri":"` + uri + `","range":` + rangeJson(1, 5, 1, 8) + `}`},
+		{"parameter", 1, 15, `{"uri":"` + uri + `","range":` + rangeJson(0, 21, 0, 22) + `}`},
+		{"declaration itself", 0, 5, `{"uri":"` + uri + `","range":` + rangeJson(0, 4, 0, 7) + `}`},
+		{"not an identifier", 4, 12, `null`},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newScript(t)
+			s.open(source)
+			id := s.at("textDocument/definition", tc.line, tc.character)
+			assert.JSONEq(t, tc.expected, s.run().result(t, id))
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	s := newScript(t)
+	s.open(source)
+	params := func(includeDeclaration bool) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": 0, "character": 13},
+			"context":      map[string]any{"includeDeclaration": includeDeclaration},
+		}
+	}
+	withDeclaration := s.request("textDocument/references", params(true))
+	withoutDeclaration := s.request("textDocument/references", params(false))
+	out := s.run()
+
+	declaration := `{"uri":"` + uri + `","range":` + rangeJson(0, 13, 0, 14) + `}`
+	usage := `{"uri":"` + uri + `","range":` + rangeJson(1, 11, 1, 12) + `}`
+	assert.JSONEq(t, `[`+declaration+`,`+usage+`]`, out.result(t, withDeclaration))
+	assert.JSONEq(t, `[`+usage+`]`, out.result(t, withoutDeclaration))
+}
+
+func TestHover(t *testing.T) {
+	tdt := []struct {
+		line, character int
+		expected        string
+	}{
+		{4, 8, "var add = fn(a: int, b)"},
+		{4, 4, "var x = add(1, 2)"},
+		{1, 11, "(parameter) a: int"},
+		{1, 15, "(parameter) b"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.expected, func(t *testing.T) {
+			s := newScript(t)
+			s.open(source)
+			id := s.at("textDocument/hover", tc.line, tc.character)
+
+			var got hover
+			require.NoError(t, json.Unmarshal([]byte(s.run().result(t, id)), &got))
+			assert.Equal(t, "markdown", got.Contents.Kind)
+			assert.Equal(t, "```monkey\n"+tc.expected+"\n```", got.Contents.Value)
+			assert.Equal(t, tc.line, got.Range.Start.Line)
+		})
+	}
+}
+
+func TestModuleAlias(t *testing.T) {
+	s := newScript(t)
+	s.open("import \"lib.mk\" as lib;\nexport var x = lib.f(1);")
+	definition := s.at("textDocument/definition", 1, 16)
+	hoverId := s.at("textDocument/hover", 1, 16)
+	out := s.run()
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 19, 0, 22)+`}`, out.result(t, definition))
+	var got hover
+	require.NoError(t, json.Unmarshal([]byte(out.result(t, hoverId)), &got))
+	assert.Equal(t, "```monkey\nimport \"lib.mk\" as lib\n```", got.Contents.Value)
+}
+
+func TestSelectCase(t *testing.T) {
+	s := newScript(t)
+	s.open("var v = 1;\nselect {\n\tcase <-v as v { v }\n}")
+	received := s.at("textDocument/definition", 2, 17)
+	channel := s.at("textDocument/definition", 2, 9)
+	hoverId := s.at("textDocument/hover", 2, 17)
+	out := s.run()
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(2, 13, 2, 14)+`}`, out.result(t, received))
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 4, 0, 5)+`}`, out.result(t, channel))
+	var got hover
+	require.NoError(t, json.Unmarshal([]byte(out.result(t, hoverId)), &got))
+	assert.Equal(t, "```monkey\n(received) v\n```", got.Contents.Value)
+}
+
+func TestCatch(t *testing.T) {
+	s := newScript(t)
+	s.open("try {\n\tthrow 1;\n} catch (err) {\n\terr;\n}")
+	definition := s.at("textDocument/definition", 3, 2)
+	hoverId := s.at("textDocument/hover", 3, 2)
+	out := s.run()
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(2, 9, 2, 12)+`}`, out.result(t, definition))
+	var got hover
+	require.NoError(t, json.Unmarshal([]byte(out.result(t, hoverId)), &got))
+	assert.Equal(t, "```monkey\n(caught) err\n```", got.Contents.Value)
+}
+
+func TestPatterns(t *testing.T) {
+	s := newScript(t)
+	s.open("var [a, b] = [1, 2];\nmatch (a) {\n\t[x, ...xs] if x => x + b,\n\t_ => 0,\n}")
+	bound := s.at("textDocument/definition", 2, 20)
+	destructured := s.at("textDocument/definition", 2, 24)
+	subject := s.at("textDocument/definition", 1, 7)
+	hoverBound := s.at("textDocument/hover", 2, 15)
+	hoverDestructured := s.at("textDocument/hover", 1, 7)
+	out := s.run()
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(2, 2, 2, 3)+`}`, out.result(t, bound))
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 8, 0, 9)+`}`, out.result(t, destructured))
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 5, 0, 6)+`}`, out.result(t, subject))
+	var got hover
+	require.NoError(t, json.Unmarshal([]byte(out.result(t, hoverBound)), &got))
+	assert.Equal(t, "```monkey\n(matched) x in [x,...xs]\n```", got.Contents.Value)
+	require.NoError(t, json.Unmarshal([]byte(out.result(t, hoverDestructured)), &got))
+	assert.Equal(t, "```monkey\nvar [a, b] = [1, 2]\n```", got.Contents.Value)
+}
+
+func TestStructs(t *testing.T) {
+	s := newScript(t)
+	s.open("struct P { x, y }\nfn (p: P) norm(k) {\n\tp.x * k\n}\nP(1, 2);")
+	receiver := s.at("textDocument/definition", 2, 1)
+	constructor := s.at("textDocument/definition", 4, 0)
+	hoverId := s.at("textDocument/hover", 4, 0)
+	symbolsId := s.request("textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	out := s.run()
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(1, 4, 1, 5)+`}`, out.result(t, receiver))
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 7, 0, 8)+`}`, out.result(t, constructor))
+	var got hover
+	require.NoError(t, json.Unmarshal([]byte(out.result(t, hoverId)), &got))
+	assert.Equal(t, "```monkey\nstruct P { x, y }\n```", got.Contents.Value)
+
+	expected := `[
+		{"name":"P","kind":23,"range":` + rangeJson(0, 0, 0, 17) + `,"selectionRange":` + rangeJson(0, 7, 0, 8) + `,"children":[
+			{"name":"x","kind":8,"range":` + rangeJson(0, 11, 0, 12) + `,"selectionRange":` + rangeJson(0, 11, 0, 12) + `},
+			{"name":"y","kind":8,"range":` + rangeJson(0, 14, 0, 15) + `,"selectionRange":` + rangeJson(0, 14, 0, 15) + `}]},
+		{"name":"P.norm","detail":"fn(k)","kind":6,"range":` + rangeJson(1, 0, 3, 1) + `,"selectionRange":` + rangeJson(1, 10, 1, 14) + `}
+	]`
+	assert.JSONEq(t, expected, out.result(t, symbolsId))
+}
+
+func TestTemplates(t *testing.T) {
+	s := newScript(t)
+	s.open("var name = 1;\n`a\n${name}`;")
+	definition := s.at("textDocument/definition", 2, 3)
+	out := s.run()
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 4, 0, 8)+`}`, out.result(t, definition))
+}
+
+func TestMacros(t *testing.T) {
+	s := newScript(t)
+	s.open("var twice = macro(x) {\n\tquote(unquote(x) + unquote(x))\n};\ntwice(1);")
+	parameter := s.at("textDocument/definition", 1, 16)
+	call := s.at("textDocument/definition", 3, 1)
+	hoverId := s.at("textDocument/hover", 3, 1)
+	out := s.run()
+
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 18, 0, 19)+`}`, out.result(t, parameter))
+	assert.JSONEq(t, `{"uri":"`+uri+`","range":`+rangeJson(0, 4, 0, 9)+`}`, out.result(t, call))
+	var got hover
+	require.NoError(t, json.Unmarshal([]byte(out.result(t, hoverId)), &got))
+	assert.Equal(t, "```monkey\nvar twice = macro(x)\n```", got.Contents.Value)
+}
+
+func TestWarningDiagnostics(t *testing.T) {
+	s := newScript(t)
+	s.open("match (true) { true => 1 }")
+	out := s.run()
+
+	require.Len(t, out.notifications, 1)
+	data, err := json.Marshal(out.notifications[0].Params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"uri":"`+uri+`","diagnostics":[{"range":`+rangeJson(0, 0, 0, 5)+`,"severity":2,"source":"monkey",
+		"message":"match warning - non-exhaustive match over booleans, missing false"}]}`, string(data))
+}
+
+func TestTypeDiagnostics(t *testing.T) {
+	s := newScript(t)
+	s.open("var x = 1;\nvar y: int = x + true;")
+	out := s.run()
+
+	require.Len(t, out.notifications, 1)
+	data, err := json.Marshal(out.notifications[0].Params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"uri":"`+uri+`","diagnostics":[{"range":`+rangeJson(1, 15, 1, 16)+`,"severity":1,"source":"monkey",
+		"message":"typecheck error - operator + not defined for int and bool"}]}`, string(data))
+}
+
+func TestCompletion(t *testing.T) {
+	s := newScript(t)
+	s.open(source)
+	insideFunction := s.at("textDocument/completion", 2, 8)
+	afterFunction := s.at("textDocument/completion", 4, 0)
+	out := s.run()
+
+	labels := func(id int) []string {
+		var items []completionItem
+		require.NoError(t, json.Unmarshal([]byte(out.result(t, id)), &items))
+		out := []string{}
+		for _, item := range items {
+			if item.Kind != completionKeyword {
+				out = append(out, item.Label)
+			}
+		}
+		assert.Len(t, items, len(out)+len(keywords), "keywords should be completed")
+		return out
+	}
+	assert.Equal(t, []string{"a", "add", "b", "sum"}, labels(insideFunction))
+	assert.Equal(t, []string{"add"}, labels(afterFunction))
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	s := newScript(t)
+	s.open(source)
+	id := s.request("textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}})
+
+	expected := `[
+		{"name":"add","detail":"fn(a: int, b)","kind":12,"range":` + rangeJson(0, 0, 3, 1) + `,"selectionRange":` + rangeJson(0, 4, 0, 7) + `,
+			"children":[{"name":"sum","kind":13,"range":` + rangeJson(1, 1, 1, 16) + `,"selectionRange":` + rangeJson(1, 5, 1, 8) + `}]},
+		{"name":"x","kind":13,"range":` + rangeJson(4, 0, 4, 17) + `,"selectionRange":` + rangeJson(4, 4, 4, 5) + `}
+	]`
+	assert.JSONEq(t, expected, s.run().result(t, id))
+}
+
+func TestProtocolErrors(t *testing.T) {
+	s := newScript(t)
+	unknown := s.request("textDocument/rename", map[string]any{})
+	invalid := s.request("textDocument/hover", "not an object")
+	shutdown := s.request("shutdown", nil)
+	afterShutdown := s.at("textDocument/hover", 0, 0)
+	s.notify("exit", nil)
+	out := s.runWithoutShutdown("")
+
+	code := func(id int) int {
+		require.NotNil(t, out.responses[id].Error, "request %d should fail", id)
+		return out.responses[id].Error.Code
+	}
+	assert.Equal(t, methodNotFound, code(unknown))
+	assert.Equal(t, invalidParams, code(invalid))
+	assert.JSONEq(t, `null`, out.result(t, shutdown))
+	assert.Equal(t, invalidRequest, code(afterShutdown))
+}
+
+func TestExitBeforeShutdown(t *testing.T) {
+	s := newScript(t)
+	s.notify("exit", nil)
+	s.runWithoutShutdown("lsp error - exit before shutdown")
+}
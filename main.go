@@ -1,23 +1,54 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"programming-lang/dap"
+	"programming-lang/debugger"
+	"programming-lang/diagram"
+	"programming-lang/format"
 	"programming-lang/lexer"
+	"programming-lang/lineedit"
+	"programming-lang/lsp"
+	"programming-lang/modules"
+	"programming-lang/object"
 	"programming-lang/parser"
+	"programming-lang/repl"
+	"programming-lang/stdlib"
+	"programming-lang/typecheck"
+	"strings"
 	"time"
 )
 
 func main() {
+	// lsp command talks JSON-RPC on stdout, so it can't print anything else
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		runLanguageServer()
+		return
+	}
+	// same for debug adapter
+	if len(os.Args) > 1 && os.Args[1] == "dap" {
+		runDebugAdapter()
+		return
+	}
+
 	start := time.Now()
+	exitCode := 0
+	// banner and timing go to stderr, stdout is kept for formatted code, JSON and graphs
 	defer func() {
-		fmt.Println("\nDone", time.Since(start))
+		fmt.Fprintln(os.Stderr, "\nDone", time.Since(start))
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
 	}()
 
-	fmt.Println("Welcome to my bad compiler")
+	fmt.Fprintln(os.Stderr, "Welcome to my bad compiler")
 	
 	cfg := parseCliArgsToConfig()
 	if err := validate(cfg); err != nil {
@@ -25,6 +56,18 @@ func main() {
 		return
 	}
 	
+	if cfg.strict {
+		parseTokens = parser.ParseStrict
+	}
+	if cfg.seed != 0 {
+		stdlib.Seed(cfg.seed)
+	}
+	if err := stdlib.Allow(cfg.permissions); err != nil {
+		fmt.Println(err)
+		return
+	}
+	stdlib.SetArgs(flag.Args())
+
 	if cfg.runRepl {
 		handleRepl(cfg)
 		return
@@ -33,10 +76,45 @@ func main() {
 		printFromFile(cfg.filePath)
 	}
 	if cfg.parse {
-		printAstFromFile(cfg.filePath)
+		printAstFromFile(cfg.filePath, cfg.outputFormat)
+	}
+	if cfg.typecheck {
+		typecheckFile(cfg.filePath)
+	}
+	if cfg.infer {
+		inferFile(cfg.filePath)
+	}
+	if cfg.fmt {
+		formatFile(cfg.filePath, cfg.write)
+	}
+	if cfg.graph != "" {
+		printGraphFromFile(cfg.filePath, cfg.graph)
+	}
+	if cfg.eval && !evalFile(cfg.filePath, cfg.searchPath, cfg.strict) {
+		exitCode = 1
+	}
+	if cfg.debug {
+		debugFile(cfg.filePath)
+	}
+}
+
+func runLanguageServer() {
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
+func runDebugAdapter() {
+	if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// parseTokens parses tokens of the file, the -strict flag sets it to parser.ParseStrict
+var parseTokens = parser.ParseWithPositions
+
 func readFileContent(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -56,14 +134,53 @@ type config struct {
 	lex bool
 	runRepl bool
 	parse bool
+	typecheck bool
+	infer bool
+	fmt bool
+	write bool
+	outputFormat string
+	graph string
+	eval bool
+	debug bool
+	strict bool
+	searchPath string
+	seed int64
+	permissions stdlib.Permissions
+}
+
+// listFlag collects values of a flag given many times
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
 func parseCliArgsToConfig() config {
 	var cfg config
 	flag.StringVar(&cfg.filePath, "file", "", "path to file with code")
 	flag.BoolVar(&cfg.lex, "lex", false, "prints lexer output")
-	flag.BoolVar(&cfg.runRepl, "repl", false, "run REPL, ignores all other params")
+	flag.BoolVar(&cfg.runRepl, "repl", false, "run REPL, with -lex and -parse also prints tokens and AST of each input")
 	flag.BoolVar(&cfg.parse, "parse", false, "prints parser output")
+	flag.BoolVar(&cfg.typecheck, "typecheck", false, "runs static type checker and prints type errors")
+	flag.BoolVar(&cfg.infer, "infer", false, "infers types of unannotated program and prints types of top-level vars")
+	flag.BoolVar(&cfg.fmt, "fmt", false, "prints file formatted in canonical style")
+	flag.BoolVar(&cfg.write, "w", false, "with -fmt, writes formatted code back to the file")
+	flag.StringVar(&cfg.outputFormat, "format", "text", "output format of -parse, text or json")
+	flag.StringVar(&cfg.graph, "graph", "", "prints parse tree diagram, dot or mermaid")
+	flag.BoolVar(&cfg.eval, "eval", false, "evaluates file and prints the result")
+	flag.BoolVar(&cfg.debug, "debug", false, "runs file in interactive step debugger")
+	flag.BoolVar(&cfg.strict, "strict", false, "requires explicit semicolons, new lines don't end statements")
+	flag.StringVar(&cfg.searchPath, "path", os.Getenv("MONKEY_PATH"), "list of directories searched for imported modules, defaults to MONKEY_PATH")
+	flag.Int64Var(&cfg.seed, "seed", 0, "seed of the rand module for reproducible runs, 0 picks a random one")
+	flag.Var((*listFlag)(&cfg.permissions.Read), "allow-read", "directory readable by the io module, can be repeated")
+	flag.Var((*listFlag)(&cfg.permissions.Write), "allow-write", "directory writable by the io module, can be repeated")
+	flag.BoolVar(&cfg.permissions.Env, "allow-env", false, "allows reading environment variables with os.getenv")
+	flag.BoolVar(&cfg.permissions.Run, "allow-run", false, "allows running programs with os.exec")
 	flag.Parse()
 
 	return cfg
@@ -73,29 +190,55 @@ func validate(c config) error {
 	if !c.runRepl && c.filePath == "" {
 		return fmt.Errorf("filepath not provided")
 	} 
+	if c.outputFormat != "text" && c.outputFormat != "json" {
+		return fmt.Errorf("unknown output format %q", c.outputFormat)
+	}
+	if c.graph != "" && c.graph != "dot" && c.graph != "mermaid" {
+		return fmt.Errorf("unknown graph format %q", c.graph)
+	}
 	return nil
 }
 
 func handleRepl(cfg config) {
-	fmt.Println("Running repl...")
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		text, err := reader.ReadString('\n')
-		if err != nil {
-			break
-		}
-		
-		if cfg.lex {
-			printTokens(text)
-		}
-		if cfg.parse {
-			lexParsePrint(text)
-		}
+	fmt.Println("Running repl, type :help for commands")
+	r := repl.New(os.Stdout)
+	r.ShowTokens = cfg.lex
+	r.ShowAst = cfg.parse
+	r.Strict = cfg.strict
+	if cwd, err := os.Getwd(); err == nil {
+		r.Importer = newLoader(cfg.searchPath, cfg.strict).Importer(cwd)
+	}
+
+	editor, err := lineedit.NewTerminal(os.Stdin, os.Stdout)
+	if err != nil {
+		// piped input, read plain lines
+		err = r.Run(os.Stdin)
+	} else {
+		editor.History = loadHistory()
+		editor.Complete = r.Complete
+		err = r.RunLines(editor)
+	}
+	if err != nil {
+		fmt.Println(err)
 	}
 
 	fmt.Println("Closing repl")
 }
 
+// loadHistory reads ~/.monkey_history, falls back to history kept in memory
+func loadHistory() *lineedit.History {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return lineedit.NewHistory()
+	}
+	history, err := lineedit.LoadHistory(filepath.Join(home, ".monkey_history"))
+	if err != nil {
+		fmt.Println(err)
+		return lineedit.NewHistory()
+	}
+	return history
+}
+
 func printFromFile(filePath string) {
 	fileContent, err := readFileContent(filePath)
 	if err != nil {
@@ -106,21 +249,177 @@ func printFromFile(filePath string) {
 	fmt.Println(tokens)
 }
 
-func printTokens(input string) {
-	fmt.Println(lexer.Tokenize(input))
+func printAstFromFile(filePath string, outputFormat string) {
+	fileContent, err := readFileContent(filePath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	lexParsePrint(fileContent, outputFormat)
+}
+
+func lexParsePrint(input string, outputFormat string) {
+	tree := parseTokens(lexer.TokenizeWithPositions(input))
+	if outputFormat != "json" {
+		fmt.Println(tree)
+		return
+	}
+
+	data, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		fmt.Println("error when serializing tree:", err)
+		return
+	}
+	fmt.Println(string(data))
 }
 
-func printAstFromFile(filePath string) {
+func typecheckFile(filePath string) {
 	fileContent, err := readFileContent(filePath)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	lexParsePrint(fileContent)
+
+	tree := parseTokens(lexer.TokenizeWithPositions(fileContent))
+	for _, w := range tree.Warnings {
+		fmt.Println(w)
+	}
+	errors := append(tree.Errors, typecheck.Check(tree)...)
+	for _, e := range errors {
+		if syntaxErr, ok := e.(*parser.SyntaxError); ok {
+			fmt.Printf("%v: %v\n", syntaxErr.Pos, e)
+		} else {
+			fmt.Println(e)
+		}
+	}
+	if len(errors) == 0 {
+		fmt.Println("no type errors found")
+	}
 }
 
-func lexParsePrint(input string) {
-	tokens := lexer.Tokenize(input)
-	tree := parser.Parse(tokens)
-	fmt.Println(tree)
-}
\ No newline at end of file
+func inferFile(filePath string) {
+	fileContent, err := readFileContent(filePath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	tree := parseTokens(lexer.TokenizeWithPositions(fileContent))
+	if len(tree.Errors) > 0 {
+		for _, e := range tree.Errors {
+			fmt.Println(e)
+		}
+		return
+	}
+
+	bindings, errors := typecheck.Infer(tree)
+	for _, e := range errors {
+		fmt.Println(e)
+	}
+	for _, b := range bindings {
+		fmt.Println(b)
+	}
+}
+
+func formatFile(filePath string, write bool) {
+	fileContent, err := readFileContent(filePath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	formatted, err := format.Source(fileContent)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if !write {
+		fmt.Print(formatted)
+		return
+	}
+	if err := os.WriteFile(filePath, []byte(formatted), 0644); err != nil {
+		fmt.Println("error when writing file:", err)
+	}
+}
+
+func printGraphFromFile(filePath string, graph string) {
+	fileContent, err := readFileContent(filePath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	tree := parseTokens(lexer.TokenizeWithPositions(fileContent))
+	for _, e := range tree.Errors {
+		fmt.Println(e)
+	}
+	if graph == "dot" {
+		fmt.Print(diagram.Dot(tree))
+	} else {
+		fmt.Print(diagram.Mermaid(tree))
+	}
+}
+
+// parseFile reads and parses the file, printing syntax errors, returns nil when it can't be run
+func parseFile(filePath string) (*parser.Program, string) {
+	fileContent, err := readFileContent(filePath)
+	if err != nil {
+		fmt.Println(err)
+		return nil, ""
+	}
+
+	tree := parseTokens(lexer.TokenizeWithPositions(fileContent))
+	if len(tree.Errors) > 0 {
+		for _, e := range tree.Errors {
+			fmt.Println(e)
+		}
+		return nil, ""
+	}
+	return tree, fileContent
+}
+
+// evalFile runs the program, uncaught errors are printed with their stack trace and reported as failure
+func evalFile(filePath string, searchPath string, strict bool) bool {
+	// Ctrl+C stops the program, also while it sleeps
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	loader := newLoader(searchPath, strict)
+	loader.Context = ctx
+	result, err := loader.Run(filePath)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	if uncaught, ok := result.(*object.Error); ok {
+		fmt.Fprintln(os.Stderr, uncaught.Traceback())
+		return false
+	}
+	if result != nil {
+		fmt.Println(result.Inspect())
+	}
+	return true
+}
+
+func newLoader(searchPath string, strict bool) *modules.Loader {
+	dirs := []string{}
+	for _, dir := range filepath.SplitList(searchPath) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	loader := modules.NewLoader(dirs...)
+	loader.Warnings = os.Stderr
+	loader.Strict = strict
+	return loader
+}
+
+func debugFile(filePath string) {
+	tree, source := parseFile(filePath)
+	if tree == nil {
+		return
+	}
+	if err := debugger.Console(tree, source, os.Stdin, os.Stdout); err != nil {
+		fmt.Println("debugger error -", err)
+	}
+}
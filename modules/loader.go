@@ -0,0 +1,185 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"programming-lang/evaluator"
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"programming-lang/stdlib"
+	"strings"
+)
+
+// Loader resolves, evaluates and caches modules imported by programs.
+// Names of the standard library modules take precedence,
+// other paths are resolved relative to the importing file first, then in the search path
+type Loader struct {
+	SearchPath []string
+	Context    context.Context // optional, evaluation of all modules stops when it's done
+	Clock      object.Clock    // optional, told to all modules, the system clock by default
+	Warnings   io.Writer       // optional, parser warnings of loaded files are written to it
+	Strict     bool            // files are parsed with parser.ParseStrict, new lines don't end statements
+
+	root    string                    // directory of the entry file, names in messages are relative to it
+	modules map[string]*object.Module // evaluated modules by absolute path
+	loading []string                  // absolute paths of files being evaluated, outermost first
+}
+
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{SearchPath: searchPath, modules: map[string]*object.Module{}}
+}
+
+// Run evaluates the entry file and returns value of its last statement.
+// Runtime errors are returned as error objects, the error is set when the file can't be read or parsed
+func (l *Loader) Run(path string) (object.Object, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("module error - %v", err)
+	}
+	if l.root == "" {
+		l.root = filepath.Dir(abs)
+	}
+	program, err := l.parse(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	l.loading = append(l.loading, abs)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+	return l.evaluator(abs).Eval(program, object.NewEnvironment()), nil
+}
+
+// Importer resolves imports relative to the directory, used for code that isn't read from a file
+func (l *Loader) Importer(dir string) evaluator.Importer {
+	if l.root == "" {
+		l.root = dir
+	}
+	return &importer{loader: l, dir: dir}
+}
+
+type importer struct {
+	loader *Loader
+	dir    string
+}
+
+func (i *importer) Import(path string) (*object.Module, error) {
+	if module, ok := stdlib.Lookup(path); ok {
+		return module, nil
+	}
+	abs, err := i.loader.resolve(path, i.dir)
+	if err != nil {
+		return nil, err
+	}
+	return i.loader.load(abs)
+}
+
+func (l *Loader) evaluator(file string) *evaluator.Evaluator {
+	e := evaluator.New()
+	e.Importer = &importer{loader: l, dir: filepath.Dir(file)}
+	e.Context = l.Context
+	e.Clock = l.Clock
+	return e
+}
+
+// resolve finds the file, absolute paths are used as they are
+func (l *Loader) resolve(path string, dir string) (string, error) {
+	if filepath.IsAbs(path) {
+		if isFile(path) {
+			return filepath.Clean(path), nil
+		}
+		return "", errors.New("module not found")
+	}
+
+	dirs := append([]string{dir}, l.SearchPath...)
+	for _, d := range dirs {
+		candidate, err := filepath.Abs(filepath.Join(d, path))
+		if err == nil && isFile(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("module not found in %s", strings.Join(dirs, ", "))
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// load evaluates the module once, later imports share its environment
+func (l *Loader) load(abs string) (*object.Module, error) {
+	if module, ok := l.modules[abs]; ok {
+		return module, nil
+	}
+	for i, loading := range l.loading {
+		if loading == abs {
+			chain := []string{}
+			for _, p := range append(l.loading[i:], abs) {
+				chain = append(chain, l.name(p))
+			}
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	program, err := l.parse(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	l.loading = append(l.loading, abs)
+	env := object.NewEnvironment()
+	result := l.evaluator(abs).Eval(program, env)
+	l.loading = l.loading[:len(l.loading)-1]
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errors.New(errObj.Message)
+	}
+
+	module := &object.Module{Name: l.name(abs), Env: env}
+	l.modules[abs] = module
+	return module, nil
+}
+
+func (l *Loader) parse(abs string) (*parser.Program, error) {
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("module error - %v", err)
+	}
+	parse := parser.ParseWithPositions
+	if l.Strict {
+		parse = parser.ParseStrict
+	}
+	program := parse(lexer.TokenizeWithPositions(string(content)))
+	if len(program.Errors) > 0 {
+		err := program.Errors[0]
+		if syntaxErr, ok := err.(*parser.SyntaxError); ok {
+			return nil, fmt.Errorf("syntax error in %s:%v: %v", l.name(abs), syntaxErr.Pos, err)
+		}
+		return nil, fmt.Errorf("syntax error in %s: %v", l.name(abs), err)
+	}
+	if l.Warnings != nil {
+		for _, w := range program.Warnings {
+			if syntaxErr, ok := w.(*parser.SyntaxError); ok {
+				fmt.Fprintf(l.Warnings, "warning in %s:%v: %v\n", l.name(abs), syntaxErr.Pos, w)
+			} else {
+				fmt.Fprintf(l.Warnings, "warning in %s: %v\n", l.name(abs), w)
+			}
+		}
+	}
+	return program, nil
+}
+
+// name is the path shown in messages, relative to the entry file when possible
+func (l *Loader) name(abs string) string {
+	if l.root == "" {
+		return abs
+	}
+	rel, err := filepath.Rel(l.root, abs)
+	if err != nil {
+		return abs
+	}
+	return filepath.ToSlash(rel)
+}
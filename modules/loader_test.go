@@ -0,0 +1,178 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"programming-lang/stdlib"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates the files in a temporary directory and returns its path
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func run(t *testing.T, l *Loader, path string) object.Object {
+	result, err := l.Run(path)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	return result
+}
+
+func TestImportRelativeToFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":          `import "lib/strings.mk" as s; s.greet("you")`,
+		"lib/strings.mk":   `import "helpers.mk" as h; export var greet = fn(name) { h.prefix + name };`,
+		"lib/helpers.mk":   `export var prefix = "hello ";`,
+		"lib/unrelated.mk": `1`,
+	})
+
+	result := run(t, NewLoader(), filepath.Join(dir, "main.mk"))
+	assert.Equal(t, "hello you", result.Inspect())
+}
+
+func TestSearchPath(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app/main.mk":    `import "math.mk" as m; m.square(3)`,
+		"shared/math.mk": `export var square = fn(x) { x * x };`,
+		"other/math.mk":  `export var square = fn(x) { 0 };`,
+	})
+
+	l := NewLoader(filepath.Join(dir, "shared"), filepath.Join(dir, "other"))
+	assert.Equal(t, "9", run(t, l, filepath.Join(dir, "app", "main.mk")).Inspect())
+}
+
+func TestStandardLibrary(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":    `import "strings" as s; import "strings.mk" as local; s.upper(local.name)`,
+		"strings.mk": `export var name = "local";`,
+	})
+	assert.Equal(t, "LOCAL", run(t, NewLoader(), filepath.Join(dir, "main.mk")).Inspect())
+}
+
+func TestModulesAreEvaluatedOnce(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk": `import "a.mk" as a; import "b.mk" as b; import "./c.mk" as c; if (a.c == b.c) { b.c == c } else { false }`,
+		"a.mk":    `import "c.mk" as c; export var c = c;`,
+		"b.mk":    `import "c.mk" as c; export var c = c;`,
+		"c.mk":    `export var x = 1;`,
+	})
+
+	l := NewLoader()
+	result := run(t, l, filepath.Join(dir, "main.mk"))
+	assert.Equal(t, "true", result.Inspect())
+	assert.Len(t, l.modules, 3)
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"cycle.mk":   `import "a.mk" as a;`,
+		"a.mk":       `import "b.mk" as b;`,
+		"b.mk":       `import "cycle.mk" as c;`,
+		"self.mk":    `import "self.mk" as s;`,
+		"missing.mk": `import "nothing.mk" as n;`,
+		"syntax.mk":  `import "broken.mk" as b;`,
+		"broken.mk":  "var x = 1;\nvar = 2;",
+		"runtime.mk": `import "fails.mk" as f;`,
+		"fails.mk":   `export var x = y;`,
+		"private.mk": `import "hidden.mk" as h; h.secret`,
+		"hidden.mk":  `var secret = 1;`,
+	})
+
+	tdt := []struct {
+		file     string
+		expected string
+	}{
+		{"cycle.mk", "cannot import a.mk: cannot import b.mk: cannot import cycle.mk: import cycle: cycle.mk -> a.mk -> b.mk -> cycle.mk"},
+		{"self.mk", "cannot import self.mk: import cycle: self.mk -> self.mk"},
+		{"missing.mk", "cannot import nothing.mk: module not found in " + dir},
+		{"syntax.mk", "cannot import broken.mk: syntax error in broken.mk:2:1: var error - expected identifier, got Assignment"},
+		{"runtime.mk", "cannot import fails.mk: identifier not found: y"},
+		{"private.mk", "module hidden.mk has no export secret"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.file, func(t *testing.T) {
+			result := run(t, NewLoader(), filepath.Join(dir, tc.file))
+			err, ok := result.(*object.Error)
+			require.True(t, ok, "expected error object, got %v", result.Inspect())
+			assert.Equal(t, tc.expected, err.Message)
+		})
+	}
+}
+
+func TestEntryFileErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"broken.mk": "var = 2;"})
+
+	_, err := NewLoader().Run(filepath.Join(dir, "broken.mk"))
+	assert.EqualError(t, err, "syntax error in broken.mk:1:1: var error - expected identifier, got Assignment")
+
+	_, err = NewLoader().Run(filepath.Join(dir, "nothing.mk"))
+	assert.Error(t, err)
+}
+
+func TestStrictMode(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"main.mk": "var x = 1\nx + 1"})
+
+	assert.Equal(t, "2", run(t, NewLoader(), filepath.Join(dir, "main.mk")).Inspect())
+
+	l := NewLoader()
+	l.Strict = true
+	_, err := l.Run(filepath.Join(dir, "main.mk"))
+	assert.EqualError(t, err, "syntax error in main.mk:1:9: var error - expected semicolon after expression, got Identifier")
+}
+
+func TestClock(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk": `import "time" as time; import "lib.mk" as lib; time.now() - lib.started`,
+		"lib.mk":  `import "time" as time; export var started = time.now(); time.sleep(time.minute);`,
+	})
+
+	l := NewLoader()
+	l.Clock = stdlib.NewFrozenClock(time.UnixMilli(0))
+	assert.Equal(t, "60000", run(t, l, filepath.Join(dir, "main.mk")).Inspect())
+}
+
+func TestWarnings(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk": `import "lib.mk" as lib; lib.f(true)`,
+		"lib.mk":  "export var f = fn(b) {\n\tmatch (b) { true => 1 }\n};",
+	})
+
+	var warnings strings.Builder
+	l := NewLoader()
+	l.Warnings = &warnings
+	assert.Equal(t, "1", run(t, l, filepath.Join(dir, "main.mk")).Inspect())
+	assert.Equal(t, "warning in lib.mk:2:2: match warning - non-exhaustive match over booleans, missing false\n", warnings.String())
+}
+
+func TestImporterForCode(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"lib.mk": `export var answer = 42;`})
+
+	l := NewLoader()
+	importer := l.Importer(dir)
+	module, err := importer.Import("lib.mk")
+	require.NoError(t, err)
+	assert.Equal(t, "module lib.mk", module.Inspect())
+
+	again, err := importer.Import(filepath.Join(dir, "lib.mk"))
+	require.NoError(t, err)
+	assert.Same(t, module, again)
+
+	program := parser.Parse(lexer.Tokenize(`import "lib.mk" as lib; lib.answer`))
+	e := l.evaluator(filepath.Join(dir, "repl"))
+	assert.Equal(t, "42", e.Eval(program, object.NewEnvironment()).Inspect())
+}
@@ -0,0 +1,152 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrClosedChannel = errors.New("send on closed channel")
+
+// Channel passes values between goroutines. Closing it doesn't close the underlying Go channel,
+// which would race with senders, values sent before closing can still be received
+type Channel struct {
+	values chan Object
+	done   chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewChannel(capacity int) *Channel {
+	return &Channel{values: make(chan Object, capacity), done: make(chan struct{})}
+}
+
+func (c *Channel) Type() ObjectType {
+	return CHANNEL
+}
+
+func (c *Channel) Inspect() string {
+	return "channel"
+}
+
+// Values is the underlying Go channel, it's never closed
+func (c *Channel) Values() chan Object {
+	return c.values
+}
+
+// Done is closed when the channel is closed
+func (c *Channel) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Channel) Closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Send waits until the value is sent, the channel is closed or the context is done
+func (c *Channel) Send(ctx context.Context, value Object) error {
+	if c.Closed() {
+		return ErrClosedChannel
+	}
+	select {
+	case c.values <- value:
+		return nil
+	case <-c.done:
+		return ErrClosedChannel
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive waits for a value, ok is false when the channel is closed and empty
+func (c *Channel) Receive(ctx context.Context) (value Object, ok bool, err error) {
+	select {
+	case value := <-c.values:
+		return value, true, nil
+	case <-c.done:
+		value, ok := c.Drain()
+		return value, ok, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Drain returns a value left in the closed channel without waiting
+func (c *Channel) Drain() (Object, bool) {
+	select {
+	case value := <-c.values:
+		return value, true
+	default:
+		return nil, false
+	}
+}
+
+func (c *Channel) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errors.New("close of closed channel")
+	}
+	c.closed = true
+	close(c.done)
+	return nil
+}
+
+// WaitGroup waits for a counter of running tasks to drop to zero
+type WaitGroup struct {
+	mu    sync.Mutex
+	count int
+	zero  chan struct{} // closed when the counter drops to zero
+}
+
+func NewWaitGroup() *WaitGroup {
+	return &WaitGroup{}
+}
+
+func (w *WaitGroup) Type() ObjectType {
+	return WAIT_GROUP
+}
+
+func (w *WaitGroup) Inspect() string {
+	return "wait group"
+}
+
+// Add changes the counter by n, which may be negative
+func (w *WaitGroup) Add(n int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.count+n < 0 {
+		return errors.New("negative wait group counter")
+	}
+	if w.count == 0 && n > 0 {
+		w.zero = make(chan struct{})
+	}
+	w.count += n
+	if w.count == 0 && w.zero != nil {
+		close(w.zero)
+		w.zero = nil
+	}
+	return nil
+}
+
+// Wait returns when the counter is zero or the context is done
+func (w *WaitGroup) Wait(ctx context.Context) error {
+	w.mu.Lock()
+	zero := w.zero
+	w.mu.Unlock()
+	if zero == nil {
+		return nil
+	}
+	select {
+	case <-zero:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,45 @@
+package object
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells time to builtins, like those of the time module
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for the duration, it returns the error of the context when it's done earlier
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type clockKey struct{}
+
+// WithClock returns the context carrying the clock to blocking builtins
+func WithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// ClockOf returns the clock carried by the context, the system clock when there's none
+func ClockOf(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return c
+	}
+	return SystemClock{}
+}
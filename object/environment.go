@@ -0,0 +1,77 @@
+package object
+
+import (
+	"sort"
+	"sync"
+)
+
+// Environment binds names to values, lookups fall back to the outer environment.
+// It's safe to use from spawned goroutines sharing a closure
+type Environment struct {
+	mu       sync.RWMutex
+	store    map[string]Object
+	outer    *Environment
+	exported map[string]bool
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: map[string]Object{}}
+}
+
+// NewEnclosedEnvironment creates scope of a function call, nested in the closure of the function
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
+	obj, ok := e.store[name]
+	e.mu.RUnlock()
+	if !ok && e.outer != nil {
+		return e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set defines the name in this environment, shadowing outer definitions
+func (e *Environment) Set(name string, value Object) Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.store[name] = value
+	return value
+}
+
+// Names returns names defined directly in this environment, sorted
+func (e *Environment) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := []string{}
+	for name := range e.store {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Outer returns the enclosing environment, nil for the global one
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Export makes the name defined in this environment visible to importers of the module
+func (e *Environment) Export(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.exported == nil {
+		e.exported = map[string]bool{}
+	}
+	e.exported[name] = true
+}
+
+func (e *Environment) IsExported(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exported[name]
+}
@@ -0,0 +1,38 @@
+package object
+
+import (
+	"programming-lang/parser"
+	"strings"
+)
+
+// Quote is code returned by quote, unquoted parts are already replaced with their values
+type Quote struct {
+	Node parser.Node
+}
+
+func (q *Quote) Type() ObjectType {
+	return QUOTE
+}
+
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+// Macro is called with code of its arguments before the program is evaluated
+type Macro struct {
+	Parameters []*parser.FunctionParameter
+	Body       *parser.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType {
+	return MACRO
+}
+
+func (m *Macro) Inspect() string {
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.Name)
+	}
+	return "macro(" + strings.Join(params, ", ") + ")"
+}
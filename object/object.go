@@ -1,13 +1,35 @@
 package object
 
-import "strconv"
+import (
+	"context"
+	"fmt"
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"strconv"
+	"strings"
+)
 
 type ObjectType string
 
 const (
-	INTEGER ObjectType = "INTEGER"
-	BOOLEAN ObjectType = "BOOLEAN"
-	NULL    ObjectType = "NULL"
+	INTEGER      ObjectType = "INTEGER"
+	BOOLEAN      ObjectType = "BOOLEAN"
+	NULL         ObjectType = "NULL"
+	RETURN_VALUE ObjectType = "RETURN_VALUE"
+	ERROR        ObjectType = "ERROR"
+	FUNCTION     ObjectType = "FUNCTION"
+	STRING       ObjectType = "STRING"
+	MODULE       ObjectType = "MODULE"
+	ARRAY        ObjectType = "ARRAY"
+	BUILTIN      ObjectType = "BUILTIN"
+	FLOAT        ObjectType = "FLOAT"
+	HASH         ObjectType = "HASH"
+	CHANNEL      ObjectType = "CHANNEL"
+	WAIT_GROUP   ObjectType = "WAIT_GROUP"
+	ERROR_VALUE  ObjectType = "ERROR_VALUE"
+	STRUCT       ObjectType = "STRUCT" // declaration of a struct, instances have type "struct <name>"
+	QUOTE        ObjectType = "QUOTE"
+	MACRO        ObjectType = "MACRO"
 )
 
 type Object interface {
@@ -28,6 +50,18 @@ func (i *Integer) Inspect() string {
 }
 
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT
+}
+
+func (f *Float) Inspect() string {
+	return parser.FormatFloat(f.Value)
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -48,4 +82,223 @@ func (n *Null) Type() ObjectType {
 
 func (n *Null) Inspect() string {
 	return "null"
-}
\ No newline at end of file
+}
+
+type ReturnValue struct {
+	Value Object
+}
+
+func (r *ReturnValue) Type() ObjectType {
+	return RETURN_VALUE
+}
+
+func (r *ReturnValue) Inspect() string {
+	return r.Value.Inspect()
+}
+
+// Error stops evaluation until it's caught by try, it's raised by throw or by a failing operation
+type Error struct {
+	Message string
+	Payload Object       // thrown value, nil for runtime errors
+	Trace   []TraceFrame // calls from the outermost one, captured when the error leaves its statement
+}
+
+// TraceFrame is a call of the stack trace, with position of the statement or call being evaluated
+type TraceFrame struct {
+	Name   string
+	Pos    lexer.Position
+	Elided int // calls left out right before this one, the stack keeps a bounded number of tail calls
+}
+
+func (e *Error) Type() ObjectType {
+	return ERROR
+}
+
+func (e *Error) Inspect() string {
+	return "error: " + e.Message
+}
+
+// Traceback describes the error with its stack trace, most recent call last
+func (e *Error) Traceback() string {
+	out := "Traceback (most recent call last):\n"
+	for _, f := range e.Trace {
+		if f.Elided > 0 {
+			out += fmt.Sprintf("  ... %d calls elided\n", f.Elided)
+		}
+		out += fmt.Sprintf("  line %d, column %d, in %s\n", f.Pos.Line, f.Pos.Column, f.Name)
+	}
+	return out + "Error: " + e.Message
+}
+
+// ErrorValue is an error caught by try. Unlike Error it's an ordinary value,
+// throwing it again raises the original error, keeping its stack trace
+type ErrorValue struct {
+	Error *Error
+}
+
+func (e *ErrorValue) Type() ObjectType {
+	return ERROR_VALUE
+}
+
+func (e *ErrorValue) Inspect() string {
+	return e.Error.Inspect()
+}
+
+type Function struct {
+	Parameters []*parser.FunctionParameter
+	Body       *parser.BlockStatement
+	Env        *Environment // closure
+}
+
+func (f *Function) Type() ObjectType {
+	return FUNCTION
+}
+
+func (f *Function) Inspect() string {
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.Name)
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType {
+	return STRING
+}
+
+func (s *String) Inspect() string {
+	return s.Value
+}
+
+// Module is an imported file, only its exported top-level vars are visible
+type Module struct {
+	Name string // path of the file, used in messages
+	Env  *Environment
+}
+
+func (m *Module) Type() ObjectType {
+	return MODULE
+}
+
+func (m *Module) Inspect() string {
+	return "module " + m.Name
+}
+
+// Member returns the exported var of the module
+func (m *Module) Member(name string) (Object, bool) {
+	if !m.Env.IsExported(name) {
+		return nil, false
+	}
+	return m.Env.Get(name)
+}
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType {
+	return ARRAY
+}
+
+func (a *Array) Inspect() string {
+	return a.inspect(map[*Struct]bool{})
+}
+
+func (a *Array) inspect(visiting map[*Struct]bool) string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, inspect(e, visiting))
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashKey identifies a key of a hash, keys are equal when types and values are
+type HashKey struct {
+	Type  ObjectType
+	Value string
+}
+
+// Hashable is implemented by objects usable as keys of a hash
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: INTEGER, Value: strconv.Itoa(i.Value)}
+}
+
+func (s *String) HashKey() HashKey {
+	return HashKey{Type: STRING, Value: s.Value}
+}
+
+type HashPair struct {
+	Key   Hashable
+	Value Object
+}
+
+// Hash maps keys to values, remembering the order in which keys were added
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: map[HashKey]HashPair{}, Keys: []HashKey{}}
+}
+
+func (h *Hash) Type() ObjectType {
+	return HASH
+}
+
+func (h *Hash) Inspect() string {
+	return h.inspect(map[*Struct]bool{})
+}
+
+func (h *Hash) inspect(visiting map[*Struct]bool) string {
+	pairs := []string{}
+	for _, k := range h.Keys {
+		pair := h.Pairs[k]
+		pairs = append(pairs, pair.Key.Inspect()+": "+inspect(pair.Value, visiting))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Set adds the pair, replacing the value of a key that is already present keeps its position
+func (h *Hash) Set(key Hashable, value Object) {
+	k := key.HashKey()
+	if _, ok := h.Pairs[k]; !ok {
+		h.Keys = append(h.Keys, k)
+	}
+	h.Pairs[k] = HashPair{Key: key, Value: value}
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+type BuiltinFunction func(args ...Object) Object
+
+// BlockingFunction is a builtin that waits, like sleep, or tells time. It has to return when the context is done,
+// the clock of the evaluator is carried by the context, see ClockOf
+type BlockingFunction func(ctx context.Context, args ...Object) Object
+
+// Builtin is a function implemented in Go
+type Builtin struct {
+	Name     string
+	Fn       BuiltinFunction
+	Blocking BlockingFunction // called instead of Fn when set, with the context of the evaluator
+}
+
+func (b *Builtin) Type() ObjectType {
+	return BUILTIN
+}
+
+func (b *Builtin) Inspect() string {
+	return "builtin " + b.Name
+}
@@ -0,0 +1,144 @@
+package object
+
+import (
+	"strings"
+	"sync"
+)
+
+// StructType is a declared struct, calling it creates an instance from values of the fields in declaration order
+type StructType struct {
+	Name    string
+	Fields  []string
+	Methods map[string]*Function // receiver is the first parameter
+
+	mu sync.RWMutex // methods may be declared while spawned functions call them
+}
+
+func NewStructType(name string, fields []string) *StructType {
+	return &StructType{Name: name, Fields: fields, Methods: map[string]*Function{}}
+}
+
+func (s *StructType) Type() ObjectType {
+	return STRUCT
+}
+
+func (s *StructType) Inspect() string {
+	return "struct " + s.Name + " { " + strings.Join(s.Fields, ", ") + " }"
+}
+
+// Field returns index of the field, -1 when the struct doesn't have it
+func (s *StructType) Field(name string) int {
+	for i, f := range s.Fields {
+		if f == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *StructType) Method(name string) (*Function, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	method, ok := s.Methods[name]
+	return method, ok
+}
+
+func (s *StructType) SetMethod(name string, method *Function) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Methods[name] = method
+}
+
+// Struct is an instance of a struct, its type is the name of the struct prefixed by "struct ",
+// so it can't clash with built-in types. Fields can be assigned, instances are shared by reference
+type Struct struct {
+	Def *StructType
+
+	mu     sync.Mutex
+	values []Object
+}
+
+func NewStruct(def *StructType, values []Object) *Struct {
+	return &Struct{Def: def, values: values}
+}
+
+func (s *Struct) Type() ObjectType {
+	return ObjectType("struct " + s.Def.Name)
+}
+
+// Inspect writes the fields, an instance nested in itself is written as Name{...}
+func (s *Struct) Inspect() string {
+	return s.inspect(map[*Struct]bool{})
+}
+
+func (s *Struct) inspect(visiting map[*Struct]bool) string {
+	if visiting[s] {
+		return s.Def.Name + "{...}"
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+
+	fields := []string{}
+	for i, v := range s.Values() {
+		fields = append(fields, s.Def.Fields[i]+": "+inspect(v, visiting))
+	}
+	return s.Def.Name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// inspect writes the value, instances being written by callers are tracked so cycles through
+// fields of structs end
+func inspect(obj Object, visiting map[*Struct]bool) string {
+	switch o := obj.(type) {
+	case *Struct:
+		return o.inspect(visiting)
+	case *Array:
+		return o.inspect(visiting)
+	case *Hash:
+		return o.inspect(visiting)
+	}
+	return obj.Inspect()
+}
+
+// Values returns a copy of the field values in declaration order
+func (s *Struct) Values() []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Object{}, s.values...)
+}
+
+func (s *Struct) Get(field string) (Object, bool) {
+	i := s.Def.Field(field)
+	if i < 0 {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[i], true
+}
+
+// Set assigns the field, returns false when the struct doesn't have it
+func (s *Struct) Set(field string, value Object) bool {
+	i := s.Def.Field(field)
+	if i < 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[i] = value
+	return true
+}
+
+// BoundMethod is a method read from an instance, calling it passes the instance as the receiver
+type BoundMethod struct {
+	Receiver *Struct
+	Name     string
+	Method   *Function
+}
+
+func (b *BoundMethod) Type() ObjectType {
+	return FUNCTION
+}
+
+func (b *BoundMethod) Inspect() string {
+	return "method " + b.Receiver.Def.Name + "." + b.Name
+}
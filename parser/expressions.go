@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"programming-lang/lexer"
 	"strconv"
+	"strings"
 )
 
 type ExpressionNode interface {
@@ -13,6 +14,7 @@ type ExpressionNode interface {
 
 type IntegerLiteralExpression struct {
 	Value int
+	Pos   lexer.Position
 }
 
 func (ile *IntegerLiteralExpression) TokenLiteral() string {
@@ -21,10 +23,55 @@ func (ile *IntegerLiteralExpression) TokenLiteral() string {
 func (ile *IntegerLiteralExpression) String() string {
 	return strconv.Itoa(ile.Value)
 }
+func (ile *IntegerLiteralExpression) Position() lexer.Position {
+	return ile.Pos
+}
 func (ile *IntegerLiteralExpression) evaluateExpression() {}
 
+type FloatLiteralExpression struct {
+	Value float64
+	Pos   lexer.Position
+}
+
+func (f *FloatLiteralExpression) TokenLiteral() string {
+	return FormatFloat(f.Value)
+}
+func (f *FloatLiteralExpression) String() string {
+	return FormatFloat(f.Value)
+}
+func (f *FloatLiteralExpression) Position() lexer.Position {
+	return f.Pos
+}
+func (f *FloatLiteralExpression) evaluateExpression() {}
+
+// FormatFloat prints the shortest representation of the number, always with a decimal point
+func FormatFloat(v float64) string {
+	out := strconv.FormatFloat(v, 'f', -1, 64)
+	if !strings.ContainsAny(out, ".IN") {
+		out += ".0"
+	}
+	return out
+}
+
+type StringLiteralExpression struct {
+	Value string
+	Pos   lexer.Position
+}
+
+func (s *StringLiteralExpression) TokenLiteral() string {
+	return strconv.Quote(s.Value)
+}
+func (s *StringLiteralExpression) String() string {
+	return strconv.Quote(s.Value)
+}
+func (s *StringLiteralExpression) Position() lexer.Position {
+	return s.Pos
+}
+func (s *StringLiteralExpression) evaluateExpression() {}
+
 type IdentifierExpression struct {
 	Name string
+	Pos  lexer.Position
 }
 
 func (ide *IdentifierExpression) TokenLiteral() string {
@@ -35,11 +82,16 @@ func (ide *IdentifierExpression) String() string {
 	return ide.Name
 }
 
+func (ide *IdentifierExpression) Position() lexer.Position {
+	return ide.Pos
+}
+
 func (ide *IdentifierExpression) evaluateExpression() {}
 
 type PrefixExpression struct {
 	Operator string
 	Right    ExpressionNode
+	Pos      lexer.Position
 }
 
 func (p *PrefixExpression) TokenLiteral() string {
@@ -50,12 +102,17 @@ func (p *PrefixExpression) String() string {
 	return "(" + p.Operator + p.Right.String() + ")"
 }
 
+func (p *PrefixExpression) Position() lexer.Position {
+	return p.Pos
+}
+
 func (p *PrefixExpression) evaluateExpression() {}
 
 type InfixExpression struct {
 	Operator string
 	Left     ExpressionNode
 	Right    ExpressionNode
+	Pos      lexer.Position // operator
 }
 
 func (i *InfixExpression) TokenLiteral() string {
@@ -65,10 +122,15 @@ func (i *InfixExpression) String() string {
 	return "("+ i.Left.String() + i.Operator + i.Right.String() +")"
 }
 
+func (i *InfixExpression) Position() lexer.Position {
+	return i.Pos
+}
+
 func (i *InfixExpression) evaluateExpression() {}
 
 type BooleanExpression struct {
 	Value    bool
+	Pos      lexer.Position
 }
 
 func (b *BooleanExpression) TokenLiteral() string {
@@ -78,12 +140,17 @@ func (b *BooleanExpression) String() string {
 	return strconv.FormatBool(b.Value)
 }
 
+func (b *BooleanExpression) Position() lexer.Position {
+	return b.Pos
+}
+
 func (b *BooleanExpression) evaluateExpression() {}
 
 type IfExpression struct {
 	Condition ExpressionNode
 	Consequence *BlockStatement
 	Alternative *BlockStatement
+	Pos lexer.Position
 }
 
 func (i *IfExpression) TokenLiteral() string {
@@ -98,35 +165,386 @@ func (i *IfExpression) String() string {
 	return out
 }
 
+func (i *IfExpression) Position() lexer.Position {
+	return i.Pos
+}
+
 func (i *IfExpression) evaluateExpression() {}
 
+// ConditionalExpression is the one-line form of if, cond ? a : b
+type ConditionalExpression struct {
+	Condition   ExpressionNode
+	Consequence ExpressionNode
+	Alternative ExpressionNode
+	Pos         lexer.Position // question mark
+}
+
+func (c *ConditionalExpression) TokenLiteral() string {
+	return "?"
+}
+
+func (c *ConditionalExpression) String() string {
+	return "(" + c.Condition.String() + "?" + c.Consequence.String() + ":" + c.Alternative.String() + ")"
+}
+
+func (c *ConditionalExpression) Position() lexer.Position {
+	return c.Pos
+}
+
+func (c *ConditionalExpression) evaluateExpression() {}
+
+type FunctionParameter struct {
+	Name string
+	Type *TypeAnnotation // nil when not annotated
+	Pos  lexer.Position
+}
+
+func (f *FunctionParameter) TokenLiteral() string {
+	return f.Name
+}
+
+func (f *FunctionParameter) Position() lexer.Position {
+	return f.Pos
+}
+
+func (f *FunctionParameter) String() string {
+	if f.Type == nil {
+		return f.Name
+	}
+	return f.Name + ":" + f.Type.String()
+}
+
+type FunctionLiteralExpression struct {
+	Parameters []*FunctionParameter
+	ReturnType *TypeAnnotation // nil when not annotated
+	Body       *BlockStatement
+	Pos        lexer.Position
+}
+
+func (f *FunctionLiteralExpression) TokenLiteral() string {
+	return "fn"
+}
+
+func (f *FunctionLiteralExpression) String() string {
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+	out := "fn(" + strings.Join(params, ",") + ")"
+	if f.ReturnType != nil {
+		out += ":" + f.ReturnType.String()
+	}
+	return out + " " + f.Body.String()
+}
+
+func (f *FunctionLiteralExpression) Position() lexer.Position {
+	return f.Pos
+}
+
+func (f *FunctionLiteralExpression) evaluateExpression() {}
+
+type CallExpression struct {
+	Function  ExpressionNode // identifier or function literal
+	Arguments []ExpressionNode
+	Pos       lexer.Position // opening brace
+	End       lexer.Position // closing brace
+}
+
+func (c *CallExpression) TokenLiteral() string {
+	return "("
+}
+
+func (c *CallExpression) String() string {
+	args := []string{}
+	for _, a := range c.Arguments {
+		args = append(args, a.String())
+	}
+	return c.Function.String() + "(" + strings.Join(args, ",") + ")"
+}
+
+func (c *CallExpression) Position() lexer.Position {
+	return c.Pos
+}
+
+func (c *CallExpression) evaluateExpression() {}
+
+// MemberExpression accesses a name exported by a module, lib.name.
+// Optional access, lib?.name, gives null when the object is null
+type MemberExpression struct {
+	Object    ExpressionNode
+	Member    string
+	Pos       lexer.Position // dot
+	MemberPos lexer.Position
+	Optional  bool
+}
+
+func (m *MemberExpression) TokenLiteral() string {
+	return "."
+}
+
+func (m *MemberExpression) String() string {
+	if m.Optional {
+		return m.Object.String() + "?." + m.Member
+	}
+	return m.Object.String() + "." + m.Member
+}
+
+func (m *MemberExpression) Position() lexer.Position {
+	return m.Pos
+}
+
+func (m *MemberExpression) evaluateExpression() {}
+
+type ArrayLiteralExpression struct {
+	Elements []ExpressionNode
+	Pos      lexer.Position // opening bracket
+	End      lexer.Position // closing bracket
+}
+
+func (a *ArrayLiteralExpression) TokenLiteral() string {
+	return "["
+}
+
+func (a *ArrayLiteralExpression) String() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.String())
+	}
+	return "[" + strings.Join(elements, ",") + "]"
+}
+
+func (a *ArrayLiteralExpression) Position() lexer.Position {
+	return a.Pos
+}
+
+func (a *ArrayLiteralExpression) evaluateExpression() {}
+
+// IndexExpression reads an element of an array, array[index].
+// Optional access, array?.[index], gives null when the array is null
+type IndexExpression struct {
+	Left     ExpressionNode
+	Index    ExpressionNode
+	Pos      lexer.Position // opening bracket
+	End      lexer.Position // closing bracket
+	Optional bool
+}
+
+func (i *IndexExpression) TokenLiteral() string {
+	return "["
+}
+
+func (i *IndexExpression) String() string {
+	if i.Optional {
+		return "(" + i.Left.String() + "?.[" + i.Index.String() + "])"
+	}
+	return "(" + i.Left.String() + "[" + i.Index.String() + "])"
+}
+
+func (i *IndexExpression) Position() lexer.Position {
+	return i.Pos
+}
+
+func (i *IndexExpression) evaluateExpression() {}
+
+// HashLiteralExpression builds a hash from pairs, {key: value}. Keys and Values have the same length
+type HashLiteralExpression struct {
+	Keys   []ExpressionNode
+	Values []ExpressionNode
+	Pos    lexer.Position // opening curly
+	End    lexer.Position // closing curly
+}
+
+func (h *HashLiteralExpression) TokenLiteral() string {
+	return "{"
+}
+
+func (h *HashLiteralExpression) String() string {
+	pairs := []string{}
+	for i := range h.Keys {
+		pairs = append(pairs, h.Keys[i].String()+":"+h.Values[i].String())
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (h *HashLiteralExpression) Position() lexer.Position {
+	return h.Pos
+}
+
+func (h *HashLiteralExpression) evaluateExpression() {}
+
+// SpawnExpression runs the call on a new goroutine, spawn f(x)
+type SpawnExpression struct {
+	Call *CallExpression
+	Pos  lexer.Position
+}
+
+func (s *SpawnExpression) TokenLiteral() string {
+	return "spawn"
+}
+
+func (s *SpawnExpression) String() string {
+	return "spawn " + s.Call.String()
+}
+
+func (s *SpawnExpression) Position() lexer.Position {
+	return s.Pos
+}
+
+func (s *SpawnExpression) evaluateExpression() {}
+
+// SelectExpression waits for the first channel operation of its cases that can proceed,
+// with default it doesn't wait
+type SelectExpression struct {
+	Cases   []*SelectCase
+	Default *BlockStatement // nil without default
+	Pos     lexer.Position
+	End     lexer.Position // closing curly
+}
+
+func (s *SelectExpression) TokenLiteral() string {
+	return "select"
+}
+
+func (s *SelectExpression) String() string {
+	out := "select {"
+	for _, c := range s.Cases {
+		out += c.String() + " "
+	}
+	if s.Default != nil {
+		out += "default " + s.Default.String()
+	}
+	return out + "}"
+}
+
+func (s *SelectExpression) Position() lexer.Position {
+	return s.Pos
+}
+
+func (s *SelectExpression) evaluateExpression() {}
+
+// SelectCase is a send, case ch <- x { }, or a receive, case <-ch as v { }, naming the value is optional
+type SelectCase struct {
+	Comm    ExpressionNode // infix or prefix expression with the <- operator
+	Name    string         // empty when the received value isn't named
+	NamePos lexer.Position
+	Body    *BlockStatement
+	Pos     lexer.Position // case keyword
+}
+
+func (c *SelectCase) TokenLiteral() string {
+	return "case"
+}
+
+func (c *SelectCase) String() string {
+	out := "case " + c.Comm.String()
+	if c.Name != "" {
+		out += " as " + c.Name
+	}
+	return out + " " + c.Body.String()
+}
+
+func (c *SelectCase) Position() lexer.Position {
+	return c.Pos
+}
+
+// MatchExpression evaluates the body of the first arm whose pattern matches the subject,
+// match (x) { 0 => "zero", n if n > 0 => "positive", _ => "negative" }
+type MatchExpression struct {
+	Subject ExpressionNode
+	Arms    []*MatchArm
+	Pos     lexer.Position
+	End     lexer.Position // closing curly
+}
+
+func (m *MatchExpression) TokenLiteral() string {
+	return "match"
+}
+
+func (m *MatchExpression) String() string {
+	arms := []string{}
+	for _, a := range m.Arms {
+		arms = append(arms, a.String())
+	}
+	return "match" + m.Subject.String() + " {" + strings.Join(arms, ",") + "}"
+}
+
+func (m *MatchExpression) Position() lexer.Position {
+	return m.Pos
+}
+
+func (m *MatchExpression) evaluateExpression() {}
+
+// MatchArm is a pattern with optional guard and the value of the arm, pattern if guard => body
+type MatchArm struct {
+	Pattern Pattern
+	Guard   ExpressionNode // nil without guard
+	Body    ExpressionNode
+}
+
+func (a *MatchArm) TokenLiteral() string {
+	return a.Pattern.TokenLiteral()
+}
+
+func (a *MatchArm) String() string {
+	out := a.Pattern.String()
+	if a.Guard != nil {
+		out += " if " + a.Guard.String()
+	}
+	return out + "=>" + a.Body.String()
+}
+
+func (a *MatchArm) Position() lexer.Position {
+	return a.Pattern.Position()
+}
+
 const (
 	_ int = iota
 	LOWEST
+	TERNARY     // c ? a : b
+	SEND        // ch <- x
+	COALESCE    // a ?? b
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
 	PRODUCT     // *
-	PREFIX      // -X or !X
-	CALL        // myFunction(X)
+	PREFIX      // -X, !X or <-ch
+	CALL        // myFunction(X), lib.member or array[index]
 )
 
 func (p *parser) parseExpression(predescense int) ExpressionNode {
 	tok := p.currentToken
 
 	var left ExpressionNode
-	if bang(tok) || minus(tok) {
+	if bang(tok) || minus(tok) || arrow(tok) {
 		 left = p.parsePrefixExpression()
 	} else if isNumberLiteral(tok){
 		left = p.parseIntegerLiteralExpression()
+	} else if isString(tok) {
+		left = p.parseStringLiteralExpression()
+	} else if isTemplateStart(tok) {
+		left = p.parseInterpolatedString()
 	} else if isBoolean(tok) {
 		left = p.parseBooleanExpression()
 	} else if isIdentifier(tok) {
 		left = p.parseIdentifierExpression()
 	} else if isOpeningParent(tok) {
 		left = p.parseGroupedExpression()
-	}else if ifKeyword(tok){
+	} else if isOpeningBracket(tok) {
+		left = p.parseArrayLiteralExpression()
+	} else if isOpeningCurly(tok) {
+		left = p.parseHashLiteralExpression()
+	} else if ifKeyword(tok){
 		left = p.parseIfExpression()
+	} else if fnKeyword(tok) {
+		left = p.parseFunctionLiteralExpression()
+	} else if spawnKeyword(tok) {
+		left = p.parseSpawnExpression()
+	} else if selectKeyword(tok) {
+		left = p.parseSelectExpression()
+	} else if matchKeyword(tok) {
+		left = p.parseMatchExpression()
+	} else if macroKeyword(tok) {
+		left = p.parseMacroLiteral()
 	} else {
 		p.addError(fmt.Errorf("no prefix parsing function for token %s", tok.Lexeme))
 		return nil
@@ -145,10 +563,27 @@ func (p *parser) parseExpression(predescense int) ExpressionNode {
 			plus(p.nextToken) || 
 			minus(p.nextToken) || 
 			product(p.nextToken) || 
-			divide(p.nextToken) {
+			divide(p.nextToken) ||
+			arrow(p.nextToken) ||
+			coalesce(p.nextToken) {
 			
 			p.advanceToken()
 			left = p.parseInfixExpression(left)
+		} else if isOpeningParent(p.nextToken) {
+			p.advanceToken()
+			left = p.parseCallExpression(left)
+		} else if isDot(p.nextToken) {
+			p.advanceToken()
+			left = p.parseMemberExpression(left)
+		} else if isOpeningBracket(p.nextToken) {
+			p.advanceToken()
+			left = p.parseIndexExpression(left)
+		} else if optionalChain(p.nextToken) {
+			p.advanceToken()
+			left = p.parseOptionalChain(left)
+		} else if question(p.nextToken) {
+			p.advanceToken()
+			left = p.parseConditionalExpression(left)
 		} else {
 			return left
 		}
@@ -159,6 +594,15 @@ func (p *parser) parseExpression(predescense int) ExpressionNode {
 
 func tokensPredescense(tok lexer.Token) int {
 	switch {
+	case question(tok):
+		return TERNARY
+
+	case arrow(tok):
+		return SEND
+
+	case coalesce(tok):
+		return COALESCE
+
 	case equals(tok):
 		return EQUALS
 	case notEquals(tok):
@@ -182,24 +626,59 @@ func tokensPredescense(tok lexer.Token) int {
 		return PRODUCT
 	case divide(tok):
 		return PRODUCT
+
+	case isOpeningParent(tok):
+		return CALL
+	case isDot(tok):
+		return CALL
+	case isOpeningBracket(tok):
+		return CALL
+	case optionalChain(tok):
+		return CALL
 	default:
 		return LOWEST
 	}
 }
 
+// OperatorPrecedence returns binding power of an infix operator, LOWEST for unknown ones
+func OperatorPrecedence(operator string) int {
+	return tokensPredescense(lexer.Token{Class: lexer.Operator, Lexeme: operator})
+}
+
 func (p *parser) parseIntegerLiteralExpression() ExpressionNode {
 	tok := p.currentToken
+	if strings.Contains(tok.Lexeme, ".") {
+		return p.parseFloatLiteralExpression()
+	}
 	v, err := strconv.Atoi(tok.Lexeme)
 	if err != nil {
 		p.addError(fmt.Errorf("int literal expression error - error in parsing integer literal in: %v", tok.Lexeme))
 		return nil
 	}
-	return &IntegerLiteralExpression{Value: v}
+	return &IntegerLiteralExpression{Value: v, Pos: p.currentPos}
+}
+
+func (p *parser) parseFloatLiteralExpression() ExpressionNode {
+	v, err := strconv.ParseFloat(p.currentToken.Lexeme, 64)
+	if err != nil {
+		p.addError(fmt.Errorf("float literal expression error - error in parsing float literal in: %v", p.currentToken.Lexeme))
+		return nil
+	}
+	return &FloatLiteralExpression{Value: v, Pos: p.currentPos}
+}
+
+func (p *parser) parseStringLiteralExpression() ExpressionNode {
+	v, err := strconv.Unquote(p.currentToken.Lexeme)
+	if err != nil {
+		p.addError(fmt.Errorf("string literal error - invalid literal %v", p.currentToken.Lexeme))
+		return nil
+	}
+	return &StringLiteralExpression{Value: v, Pos: p.currentPos}
 }
 
 func (p *parser) parseIdentifierExpression() ExpressionNode {
 	identifierToken := p.currentToken
-	return &IdentifierExpression{Name: identifierToken.Lexeme}
+	return &IdentifierExpression{Name: identifierToken.Lexeme, Pos: p.currentPos}
 }
 
 func (p *parser) parseBooleanExpression() ExpressionNode {
@@ -208,20 +687,22 @@ func (p *parser) parseBooleanExpression() ExpressionNode {
 		p.addError(fmt.Errorf("boolean literal expression error: %v, token: %v", err, p.currentToken))
 		return nil
 	}
-	return &BooleanExpression{v}
+	return &BooleanExpression{Value: v, Pos: p.currentPos}
 }
 
 
 func (p *parser) parsePrefixExpression() ExpressionNode {
 	operator := p.currentToken
+	pos := p.currentPos
 	p.advanceToken()
-	return &PrefixExpression{Operator: operator.Lexeme, Right: p.parseExpression(PREFIX)}
+	return &PrefixExpression{Operator: operator.Lexeme, Right: p.parseExpression(PREFIX), Pos: pos}
 }
 
 func (p *parser) parseInfixExpression(left ExpressionNode) ExpressionNode {
 	out := &InfixExpression{
 		Operator: p.currentToken.Lexeme,
 		Left:     left,
+		Pos:      p.currentPos,
 	}
 	pred := tokensPredescense(p.currentToken)
 	p.advanceToken()
@@ -229,6 +710,28 @@ func (p *parser) parseInfixExpression(left ExpressionNode) ExpressionNode {
 	return out
 }
 
+// parseConditionalExpression continues after the condition, alternative takes the rest of the expression
+// so that nested conditionals are right associated, a ? b : c ? d : e is a ? b : (c ? d : e)
+func (p *parser) parseConditionalExpression(condition ExpressionNode) ExpressionNode {
+	out := &ConditionalExpression{Condition: condition, Pos: p.currentPos}
+	p.advanceToken()
+	out.Consequence = p.parseExpression(LOWEST)
+	if out.Consequence == nil {
+		return nil
+	}
+	if !isColon(p.nextToken) {
+		p.addError(fmt.Errorf("conditional expression error - expected colon, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	p.advanceToken()
+	out.Alternative = p.parseExpression(LOWEST)
+	if out.Alternative == nil {
+		return nil
+	}
+	return out
+}
+
 func (p *parser) parseGroupedExpression() ExpressionNode {
 	p.advanceToken()
 
@@ -243,13 +746,14 @@ func (p *parser) parseGroupedExpression() ExpressionNode {
 }
 
 func (p *parser) parseIfExpression() ExpressionNode {
+	pos := p.currentPos
 	if !isOpeningParent(p.nextToken) {
 		p.addError(fmt.Errorf("if expression error - missing opening brace, got %v", p.nextToken.Lexeme))
 		return nil
 	}
 	p.advanceToken()
 
-	out := &IfExpression{}
+	out := &IfExpression{Pos: pos}
 	out.Condition = p.parseExpression(LOWEST)
 	
 	if !isClosingParent(p.currentToken) {
@@ -276,4 +780,376 @@ func (p *parser) parseIfExpression() ExpressionNode {
 	}
 
 	return out
-}
\ No newline at end of file
+}
+func (p *parser) parseFunctionLiteralExpression() ExpressionNode {
+	if !isOpeningParent(p.nextToken) {
+		p.addError(fmt.Errorf("function literal error - missing opening brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	out := &FunctionLiteralExpression{Pos: p.currentPos}
+	p.advanceToken()
+
+	params, ok := p.parseFunctionParameters()
+	if !ok {
+		return nil
+	}
+	out.Parameters = params
+
+	ret, ok := p.parseOptionalTypeAnnotation()
+	if !ok {
+		return nil
+	}
+	out.ReturnType = ret
+
+	if !isOpeningCurly(p.nextToken) {
+		p.addError(fmt.Errorf("function literal error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	out.Body = p.parseBlockStatement()
+
+	return out
+}
+
+func (p *parser) parseFunctionParameters() ([]*FunctionParameter, bool) {
+	params := []*FunctionParameter{}
+	p.advanceToken()
+
+	for !isClosingParent(p.currentToken) {
+		if !isIdentifier(p.currentToken) {
+			p.addError(fmt.Errorf("function literal error - expected parameter name, got %v", p.currentToken.Class))
+			return nil, false
+		}
+		param := &FunctionParameter{Name: p.currentToken.Lexeme, Pos: p.currentPos}
+
+		paramType, ok := p.parseOptionalTypeAnnotation()
+		if !ok {
+			return nil, false
+		}
+		param.Type = paramType
+		params = append(params, param)
+
+		p.advanceToken()
+		if isComma(p.currentToken) {
+			p.advanceToken()
+		} else if !isClosingParent(p.currentToken) {
+			p.addError(fmt.Errorf("function literal error - expected comma or closing brace, got %v", p.currentToken.Lexeme))
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (p *parser) parseCallExpression(function ExpressionNode) ExpressionNode {
+	pos := p.currentPos
+	args, ok := p.parseExpressionList(isClosingParent)
+	if !ok {
+		return nil
+	}
+	return &CallExpression{Function: function, Arguments: args, Pos: pos, End: p.currentPos}
+}
+
+// parseExpressionList parses comma separated expressions, starting at the opening token
+// and finishing at the token accepted by isClosing
+func (p *parser) parseExpressionList(isClosing func(lexer.Token) bool) ([]ExpressionNode, bool) {
+	out := []ExpressionNode{}
+	if isClosing(p.nextToken) {
+		p.advanceToken()
+		return out, true
+	}
+
+	p.advanceToken()
+	out = append(out, p.parseExpression(LOWEST))
+	for isComma(p.nextToken) {
+		p.advanceToken()
+		p.advanceToken()
+		out = append(out, p.parseExpression(LOWEST))
+	}
+
+	if !isClosing(p.nextToken) {
+		p.addError(fmt.Errorf("expression list error - missing closing brace, got %v", p.nextToken.Lexeme))
+		return nil, false
+	}
+	p.advanceToken()
+	return out, true
+}
+
+func (p *parser) parseMemberExpression(object ExpressionNode) ExpressionNode {
+	pos := p.currentPos
+	if !isIdentifier(p.nextToken) {
+		p.addError(fmt.Errorf("member expression error - expected member name, got %v", p.nextToken.Class))
+		return nil
+	}
+	p.advanceToken()
+	return &MemberExpression{Object: object, Member: p.currentToken.Lexeme, Pos: pos, MemberPos: p.currentPos}
+}
+
+// parseOptionalChain parses member or index access after ?., obj?.name or array?.[index]
+func (p *parser) parseOptionalChain(left ExpressionNode) ExpressionNode {
+	if isOpeningBracket(p.nextToken) {
+		p.advanceToken()
+		out, ok := p.parseIndexExpression(left).(*IndexExpression)
+		if !ok {
+			return nil
+		}
+		out.Optional = true
+		return out
+	}
+	out, ok := p.parseMemberExpression(left).(*MemberExpression)
+	if !ok {
+		return nil
+	}
+	out.Optional = true
+	return out
+}
+
+func (p *parser) parseArrayLiteralExpression() ExpressionNode {
+	pos := p.currentPos
+	elements, ok := p.parseExpressionList(isClosingBracket)
+	if !ok {
+		return nil
+	}
+	return &ArrayLiteralExpression{Elements: elements, Pos: pos, End: p.currentPos}
+}
+
+func (p *parser) parseIndexExpression(left ExpressionNode) ExpressionNode {
+	out := &IndexExpression{Left: left, Pos: p.currentPos}
+	p.advanceToken()
+	out.Index = p.parseExpression(LOWEST)
+	if !isClosingBracket(p.nextToken) {
+		p.addError(fmt.Errorf("index expression error - missing closing bracket, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	out.End = p.currentPos
+	return out
+}
+
+func (p *parser) parseHashLiteralExpression() ExpressionNode {
+	out := &HashLiteralExpression{Keys: []ExpressionNode{}, Values: []ExpressionNode{}, Pos: p.currentPos}
+	for !isClosingCurly(p.nextToken) {
+		if len(out.Keys) > 0 {
+			if !isComma(p.nextToken) {
+				p.addError(fmt.Errorf("hash literal error - expected comma or closing curly, got %v", p.nextToken.Lexeme))
+				return nil
+			}
+			p.advanceToken()
+		}
+		p.advanceToken()
+		key := p.parseExpression(LOWEST)
+		if !isColon(p.nextToken) {
+			p.addError(fmt.Errorf("hash literal error - expected colon after key, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		p.advanceToken()
+		p.advanceToken()
+		out.Keys = append(out.Keys, key)
+		out.Values = append(out.Values, p.parseExpression(LOWEST))
+	}
+	p.advanceToken()
+	out.End = p.currentPos
+	return out
+}
+
+func (p *parser) parseSpawnExpression() ExpressionNode {
+	pos := p.currentPos
+	p.advanceToken()
+	call, ok := p.parseExpression(PREFIX).(*CallExpression)
+	if !ok {
+		p.addError(fmt.Errorf("spawn expression error - expected function call"))
+		return nil
+	}
+	return &SpawnExpression{Call: call, Pos: pos}
+}
+
+func (p *parser) parseSelectExpression() ExpressionNode {
+	out := &SelectExpression{Cases: []*SelectCase{}, Pos: p.currentPos}
+	if !isOpeningCurly(p.nextToken) {
+		p.addError(fmt.Errorf("select expression error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+
+	for !isClosingCurly(p.nextToken) {
+		p.advanceToken()
+		if caseKeyword(p.currentToken) {
+			c := p.parseSelectCase()
+			if c == nil {
+				return nil
+			}
+			out.Cases = append(out.Cases, c)
+		} else if defaultKeyword(p.currentToken) && out.Default == nil {
+			if !isOpeningCurly(p.nextToken) {
+				p.addError(fmt.Errorf("select expression error - missing opening curly brace of default, got %v", p.nextToken.Lexeme))
+				return nil
+			}
+			p.advanceToken()
+			out.Default = p.parseBlockStatement()
+		} else if defaultKeyword(p.currentToken) {
+			p.addError(fmt.Errorf("select expression error - more than one default"))
+			return nil
+		} else {
+			p.addError(fmt.Errorf("select expression error - expected case or default, got %v", p.currentToken.Lexeme))
+			return nil
+		}
+	}
+	p.advanceToken()
+	out.End = p.currentPos
+	return out
+}
+
+func (p *parser) parseSelectCase() *SelectCase {
+	out := &SelectCase{Pos: p.currentPos}
+	p.advanceToken()
+	out.Comm = p.parseExpression(LOWEST)
+
+	send, isSend := out.Comm.(*InfixExpression)
+	receive, isReceive := out.Comm.(*PrefixExpression)
+	if !(isSend && send.Operator == "<-") && !(isReceive && receive.Operator == "<-") {
+		p.addError(fmt.Errorf("select case error - expected send or receive"))
+		return nil
+	}
+
+	if asKeyword(p.nextToken) {
+		p.advanceToken()
+		if isSend {
+			p.addError(fmt.Errorf("select case error - only received values can be named"))
+			return nil
+		} else if !isIdentifier(p.nextToken) {
+			p.addError(fmt.Errorf("select case error - expected name, got %v", p.nextToken.Class))
+			return nil
+		}
+		p.advanceToken()
+		out.Name, out.NamePos = p.currentToken.Lexeme, p.currentPos
+	}
+
+	if !isOpeningCurly(p.nextToken) {
+		p.addError(fmt.Errorf("select case error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	out.Body = p.parseBlockStatement()
+	return out
+}
+
+func (p *parser) parseMatchExpression() ExpressionNode {
+	out := &MatchExpression{Arms: []*MatchArm{}, Pos: p.currentPos}
+	if !isOpeningParent(p.nextToken) {
+		p.addError(fmt.Errorf("match expression error - expected opening paren, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	p.advanceToken()
+	out.Subject = p.parseExpression(LOWEST)
+	if out.Subject == nil {
+		return nil
+	}
+	if !isClosingParent(p.nextToken) {
+		p.addError(fmt.Errorf("match expression error - expected closing paren, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	if !isOpeningCurly(p.nextToken) {
+		p.addError(fmt.Errorf("match expression error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+
+	for !isClosingCurly(p.nextToken) {
+		if len(out.Arms) > 0 {
+			if !isComma(p.nextToken) {
+				p.addError(fmt.Errorf("match expression error - expected comma or closing curly, got %v", p.nextToken.Lexeme))
+				return nil
+			}
+			p.advanceToken()
+			if isClosingCurly(p.nextToken) {
+				break
+			}
+		}
+		p.advanceToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		out.Arms = append(out.Arms, arm)
+	}
+	p.advanceToken()
+	out.End = p.currentPos
+	p.checkArms(out)
+	return out
+}
+
+func (p *parser) parseMatchArm() *MatchArm {
+	pattern := p.parsePattern()
+	if pattern == nil || !p.checkBindings(pattern) {
+		return nil
+	}
+	out := &MatchArm{Pattern: pattern}
+	if ifKeyword(p.nextToken) {
+		p.advanceToken()
+		p.advanceToken()
+		if out.Guard = p.parseExpression(LOWEST); out.Guard == nil {
+			return nil
+		}
+	}
+	if !fatArrow(p.nextToken) {
+		p.addError(fmt.Errorf("match arm error - expected =>, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	p.advanceToken()
+	if out.Body = p.parseExpression(LOWEST); out.Body == nil {
+		return nil
+	}
+	return out
+}
+
+// checkArms warns about arms following ones which match everything they could,
+// and about matches over booleans missing one of the values
+func (p *parser) checkArms(m *MatchExpression) {
+	literals := map[string]bool{} // patterns of arms without guard
+	exhaustive, booleans := false, overBooleans(m.Arms)
+	for _, arm := range m.Arms {
+		if exhaustive || literals[arm.Pattern.String()] {
+			p.addWarning(fmt.Errorf("match warning - unreachable arm %v", arm.Pattern), arm.Position())
+			continue
+		}
+		switch pattern := arm.Pattern.(type) {
+		case *LiteralPattern:
+			if arm.Guard == nil {
+				literals[pattern.String()] = true
+			}
+		case *BindingPattern, *WildcardPattern:
+			exhaustive = arm.Guard == nil
+		}
+		exhaustive = exhaustive || booleans && literals["true"] && literals["false"]
+	}
+
+	if booleans && !exhaustive {
+		missing := []string{}
+		for _, value := range []string{"true", "false"} {
+			if !literals[value] {
+				missing = append(missing, value)
+			}
+		}
+		p.addWarning(fmt.Errorf("match warning - non-exhaustive match over booleans, missing %v", strings.Join(missing, " and ")), m.Pos)
+	}
+}
+
+// overBooleans reports whether the arms match booleans: there is at least one literal pattern,
+// all of them are booleans and there are no array or hash patterns
+func overBooleans(arms []*MatchArm) bool {
+	booleans := false
+	for _, arm := range arms {
+		switch pattern := arm.Pattern.(type) {
+		case *LiteralPattern:
+			if _, ok := pattern.Value.(*BooleanExpression); !ok {
+				return false
+			}
+			booleans = true
+		case *ArrayPattern, *HashPattern:
+			return false
+		}
+	}
+	return booleans
+}
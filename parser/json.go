@@ -0,0 +1,844 @@
+package parser
+
+// JSON schema of the AST, used to exchange trees with external tools.
+//
+// Every node is an object with fields:
+//   "kind" - node type, one of the kinds listed below
+//   "pos"  - position of the token identifying the node, {"line": 1, "column": 1}
+//   "span" - source range of the node, {"start": position, "end": position}, see SpanOf
+// Span is informational only and ignored when reading nodes back.
+// Optional nodes are null when missing, lists are never null.
+//
+// Kinds and their additional fields:
+//   Program             - statements: [statement], errors: [{"message": string, "pos": position}], warnings: [{"message": string, "pos": position}]
+//   VarStatement        - name: string, namePos: position, pattern: pattern|null, type: TypeAnnotation|null, value: expression|null
+//   ReturnStatement     - value: expression|null
+//   ExpressionStatement - token: {"class": string, "lexeme": string}, value: expression|null
+//   BlockStatement      - statements: [statement], close: position
+//   ImportStatement     - path: string, pathPos: position, alias: string, aliasPos: position
+//   ExportStatement     - statement: VarStatement
+//   ThrowStatement      - value: expression
+//   TryStatement        - body: BlockStatement, name: string, namePos: position, catch: BlockStatement|null, finally: BlockStatement|null
+//   StructStatement     - name: string, namePos: position, fields: [string], fieldPositions: [position], close: position
+//   MethodStatement     - receiver: FunctionParameter, name: string, namePos: position, function: FunctionLiteral
+//   AssignStatement     - target: Member, value: expression
+//   IntegerLiteral      - value: number
+//   FloatLiteral        - value: number
+//   StringLiteral       - value: string
+//   InterpolatedString  - parts: [string], values: [expression], close: position
+//   Boolean             - value: bool
+//   Identifier          - name: string
+//   Prefix              - operator: string, right: expression
+//   Infix               - operator: string, left: expression, right: expression
+//   If                  - condition: expression, consequence: BlockStatement, alternative: BlockStatement|null
+//   Conditional         - condition: expression, consequence: expression, alternative: expression
+//   FunctionLiteral     - parameters: [FunctionParameter], returnType: TypeAnnotation|null, body: BlockStatement
+//   FunctionParameter   - name: string, type: TypeAnnotation|null
+//   MacroLiteral        - parameters: [FunctionParameter], body: BlockStatement
+//   Call                - function: expression, arguments: [expression], close: position
+//   Member              - object: expression, member: string, memberPos: position, optional: bool
+//   ArrayLiteral        - elements: [expression], close: position
+//   Index               - left: expression, index: expression, close: position, optional: bool
+//   HashLiteral         - keys: [expression], values: [expression], close: position
+//   Spawn               - call: Call
+//   Select              - cases: [SelectCase], default: BlockStatement|null, close: position
+//   SelectCase          - comm: expression, name: string, namePos: position, body: BlockStatement
+//   Match               - subject: expression, arms: [MatchArm], close: position
+//   MatchArm            - pattern: pattern, guard: expression|null, body: expression
+//   LiteralPattern      - value: expression
+//   BindingPattern      - name: string
+//   WildcardPattern     - no additional fields
+//   ArrayPattern        - elements: [pattern], rest: pattern|null, close: position
+//   HashPattern         - keys: [expression], values: [pattern], close: position
+//   TypeAnnotation      - name: string, parameters: [TypeAnnotation], return: TypeAnnotation|null, close: position
+
+import (
+	"encoding/json"
+	"fmt"
+	"programming-lang/lexer"
+)
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+func toJsonPosition(pos lexer.Position) jsonPosition {
+	return jsonPosition{Line: pos.Line, Column: pos.Column}
+}
+
+func (j jsonPosition) position() lexer.Position {
+	return lexer.Position{Line: j.Line, Column: j.Column}
+}
+
+type jsonError struct {
+	Message string       `json:"message"`
+	Pos     jsonPosition `json:"pos"`
+}
+
+type jsonToken struct {
+	Class  string `json:"class"`
+	Lexeme string `json:"lexeme"`
+}
+
+// jsonFields builds the object of a node, with common fields already set
+func jsonFields(node Node, kind string) map[string]any {
+	span := SpanOf(node)
+	return map[string]any{
+		"kind": kind,
+		"pos":  toJsonPosition(node.Position()),
+		"span": jsonSpan{toJsonPosition(span.Start), toJsonPosition(span.End)},
+	}
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+	out := jsonFields(p, "Program")
+	out["statements"] = nonNil(p.Statements)
+	out["errors"] = toJsonErrors(p.Errors)
+	out["warnings"] = toJsonErrors(p.Warnings)
+	return json.Marshal(out)
+}
+
+func toJsonErrors(list []error) []jsonError {
+	out := []jsonError{}
+	for _, e := range list {
+		err := jsonError{Message: e.Error()}
+		if syntaxErr, ok := e.(*SyntaxError); ok {
+			err.Pos = toJsonPosition(syntaxErr.Pos)
+		}
+		out = append(out, err)
+	}
+	return out
+}
+
+func (vsn *VarStatementNode) MarshalJSON() ([]byte, error) {
+	out := jsonFields(vsn, "VarStatement")
+	out["name"] = vsn.Name
+	out["namePos"] = toJsonPosition(vsn.NamePos)
+	out["pattern"] = vsn.Pattern
+	out["type"] = vsn.Type
+	out["value"] = vsn.Value
+	return json.Marshal(out)
+}
+
+func (r *ReturnStatementNode) MarshalJSON() ([]byte, error) {
+	out := jsonFields(r, "ReturnStatement")
+	out["value"] = r.Value
+	return json.Marshal(out)
+}
+
+func (e *ExpressionStatementNode) MarshalJSON() ([]byte, error) {
+	out := jsonFields(e, "ExpressionStatement")
+	out["token"] = jsonToken{Class: e.Token.Class.String(), Lexeme: e.Token.Lexeme}
+	out["value"] = e.Value
+	return json.Marshal(out)
+}
+
+func (b *BlockStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(b, "BlockStatement")
+	out["statements"] = nonNil(b.Statements)
+	out["close"] = toJsonPosition(b.End)
+	return json.Marshal(out)
+}
+
+func (i *ImportStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(i, "ImportStatement")
+	out["path"] = i.Path
+	out["pathPos"] = toJsonPosition(i.PathPos)
+	out["alias"] = i.Alias
+	out["aliasPos"] = toJsonPosition(i.AliasPos)
+	return json.Marshal(out)
+}
+
+func (e *ExportStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(e, "ExportStatement")
+	out["statement"] = e.Statement
+	return json.Marshal(out)
+}
+
+func (t *ThrowStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(t, "ThrowStatement")
+	out["value"] = t.Value
+	return json.Marshal(out)
+}
+
+func (t *TryStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(t, "TryStatement")
+	out["body"] = t.Body
+	out["name"] = t.Name
+	out["namePos"] = toJsonPosition(t.NamePos)
+	out["catch"] = t.Catch
+	out["finally"] = t.Finally
+	return json.Marshal(out)
+}
+
+func (s *StructStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(s, "StructStatement")
+	out["name"] = s.Name
+	out["namePos"] = toJsonPosition(s.NamePos)
+	out["fields"] = nonNil(s.Fields)
+	positions := []jsonPosition{}
+	for _, pos := range s.FieldPos {
+		positions = append(positions, toJsonPosition(pos))
+	}
+	out["fieldPositions"] = positions
+	out["close"] = toJsonPosition(s.End)
+	return json.Marshal(out)
+}
+
+func (m *MethodStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(m, "MethodStatement")
+	out["receiver"] = m.Receiver
+	out["name"] = m.Name
+	out["namePos"] = toJsonPosition(m.NamePos)
+	out["function"] = m.Function
+	return json.Marshal(out)
+}
+
+func (a *AssignStatement) MarshalJSON() ([]byte, error) {
+	out := jsonFields(a, "AssignStatement")
+	out["target"] = a.Target
+	out["value"] = a.Value
+	return json.Marshal(out)
+}
+
+func (s *StringLiteralExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(s, "StringLiteral")
+	out["value"] = s.Value
+	return json.Marshal(out)
+}
+
+func (s *InterpolatedString) MarshalJSON() ([]byte, error) {
+	out := jsonFields(s, "InterpolatedString")
+	out["parts"] = nonNil(s.Parts)
+	out["values"] = nonNil(s.Values)
+	out["close"] = toJsonPosition(s.End)
+	return json.Marshal(out)
+}
+
+func (ile *IntegerLiteralExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(ile, "IntegerLiteral")
+	out["value"] = ile.Value
+	return json.Marshal(out)
+}
+
+func (f *FloatLiteralExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(f, "FloatLiteral")
+	out["value"] = f.Value
+	return json.Marshal(out)
+}
+
+func (b *BooleanExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(b, "Boolean")
+	out["value"] = b.Value
+	return json.Marshal(out)
+}
+
+func (ide *IdentifierExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(ide, "Identifier")
+	out["name"] = ide.Name
+	return json.Marshal(out)
+}
+
+func (p *PrefixExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(p, "Prefix")
+	out["operator"] = p.Operator
+	out["right"] = p.Right
+	return json.Marshal(out)
+}
+
+func (i *InfixExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(i, "Infix")
+	out["operator"] = i.Operator
+	out["left"] = i.Left
+	out["right"] = i.Right
+	return json.Marshal(out)
+}
+
+func (i *IfExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(i, "If")
+	out["condition"] = i.Condition
+	out["consequence"] = i.Consequence
+	out["alternative"] = i.Alternative
+	return json.Marshal(out)
+}
+
+func (c *ConditionalExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(c, "Conditional")
+	out["condition"] = c.Condition
+	out["consequence"] = c.Consequence
+	out["alternative"] = c.Alternative
+	return json.Marshal(out)
+}
+
+func (f *FunctionLiteralExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(f, "FunctionLiteral")
+	out["parameters"] = nonNil(f.Parameters)
+	out["returnType"] = f.ReturnType
+	out["body"] = f.Body
+	return json.Marshal(out)
+}
+
+func (m *MacroLiteral) MarshalJSON() ([]byte, error) {
+	out := jsonFields(m, "MacroLiteral")
+	out["parameters"] = nonNil(m.Parameters)
+	out["body"] = m.Body
+	return json.Marshal(out)
+}
+
+func (f *FunctionParameter) MarshalJSON() ([]byte, error) {
+	out := jsonFields(f, "FunctionParameter")
+	out["name"] = f.Name
+	out["type"] = f.Type
+	return json.Marshal(out)
+}
+
+func (c *CallExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(c, "Call")
+	out["function"] = c.Function
+	out["arguments"] = nonNil(c.Arguments)
+	out["close"] = toJsonPosition(c.End)
+	return json.Marshal(out)
+}
+
+func (m *MemberExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(m, "Member")
+	out["object"] = m.Object
+	out["member"] = m.Member
+	out["memberPos"] = toJsonPosition(m.MemberPos)
+	out["optional"] = m.Optional
+	return json.Marshal(out)
+}
+
+func (a *ArrayLiteralExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(a, "ArrayLiteral")
+	out["elements"] = nonNil(a.Elements)
+	out["close"] = toJsonPosition(a.End)
+	return json.Marshal(out)
+}
+
+func (i *IndexExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(i, "Index")
+	out["left"] = i.Left
+	out["index"] = i.Index
+	out["close"] = toJsonPosition(i.End)
+	out["optional"] = i.Optional
+	return json.Marshal(out)
+}
+
+func (h *HashLiteralExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(h, "HashLiteral")
+	out["keys"] = nonNil(h.Keys)
+	out["values"] = nonNil(h.Values)
+	out["close"] = toJsonPosition(h.End)
+	return json.Marshal(out)
+}
+
+func (s *SpawnExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(s, "Spawn")
+	out["call"] = s.Call
+	return json.Marshal(out)
+}
+
+func (s *SelectExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(s, "Select")
+	out["cases"] = nonNil(s.Cases)
+	out["default"] = s.Default
+	out["close"] = toJsonPosition(s.End)
+	return json.Marshal(out)
+}
+
+func (c *SelectCase) MarshalJSON() ([]byte, error) {
+	out := jsonFields(c, "SelectCase")
+	out["comm"] = c.Comm
+	out["name"] = c.Name
+	out["namePos"] = toJsonPosition(c.NamePos)
+	out["body"] = c.Body
+	return json.Marshal(out)
+}
+
+func (m *MatchExpression) MarshalJSON() ([]byte, error) {
+	out := jsonFields(m, "Match")
+	out["subject"] = m.Subject
+	out["arms"] = nonNil(m.Arms)
+	out["close"] = toJsonPosition(m.End)
+	return json.Marshal(out)
+}
+
+func (a *MatchArm) MarshalJSON() ([]byte, error) {
+	out := jsonFields(a, "MatchArm")
+	out["pattern"] = a.Pattern
+	out["guard"] = a.Guard
+	out["body"] = a.Body
+	return json.Marshal(out)
+}
+
+func (l *LiteralPattern) MarshalJSON() ([]byte, error) {
+	out := jsonFields(l, "LiteralPattern")
+	out["value"] = l.Value
+	return json.Marshal(out)
+}
+
+func (b *BindingPattern) MarshalJSON() ([]byte, error) {
+	out := jsonFields(b, "BindingPattern")
+	out["name"] = b.Name
+	return json.Marshal(out)
+}
+
+func (w *WildcardPattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFields(w, "WildcardPattern"))
+}
+
+func (a *ArrayPattern) MarshalJSON() ([]byte, error) {
+	out := jsonFields(a, "ArrayPattern")
+	out["elements"] = nonNil(a.Elements)
+	out["rest"] = a.Rest
+	out["close"] = toJsonPosition(a.End)
+	return json.Marshal(out)
+}
+
+func (h *HashPattern) MarshalJSON() ([]byte, error) {
+	out := jsonFields(h, "HashPattern")
+	out["keys"] = nonNil(h.Keys)
+	out["values"] = nonNil(h.Values)
+	out["close"] = toJsonPosition(h.End)
+	return json.Marshal(out)
+}
+
+func (t *TypeAnnotation) MarshalJSON() ([]byte, error) {
+	out := jsonFields(t, "TypeAnnotation")
+	out["name"] = t.Name
+	out["parameters"] = nonNil(t.Parameters)
+	out["return"] = t.Return
+	out["close"] = toJsonPosition(t.End)
+	return json.Marshal(out)
+}
+
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+func (p *Program) UnmarshalJSON(data []byte) error {
+	node, err := UnmarshalNode(data)
+	if err != nil {
+		return err
+	}
+	program, ok := node.(*Program)
+	if !ok {
+		return fmt.Errorf("json error - expected Program, got %T", node)
+	}
+	*p = *program
+	return nil
+}
+
+// UnmarshalNode reads any node in the format produced by json.Marshal
+func UnmarshalNode(data []byte) (Node, error) {
+	d := &jsonDecoder{}
+	out := d.node(data)
+	return out, d.err
+}
+
+// Clone returns a deep copy of the node, rewriting the copy leaves the original tree untouched
+func Clone(node Node) Node {
+	data, err := json.Marshal(node)
+	var out Node
+	if err == nil {
+		out, err = UnmarshalNode(data)
+	}
+	if err != nil {
+		// every node survives the round trip, see TestJsonRoundTrip
+		panic(fmt.Sprintf("cannot clone %T: %v", node, err))
+	}
+	return out
+}
+
+// jsonDecoder keeps the first error, so nodes can be read without checking every field
+type jsonDecoder struct {
+	err error
+}
+
+func (d *jsonDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *jsonDecoder) fields(data []byte) map[string]json.RawMessage {
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(data, &out); err != nil {
+		d.fail(fmt.Errorf("json error - invalid node: %v", err))
+	}
+	return out
+}
+
+func (d *jsonDecoder) value(raw json.RawMessage, name string, target any) {
+	if raw == nil {
+		d.fail(fmt.Errorf("json error - missing field %q", name))
+		return
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		d.fail(fmt.Errorf("json error - invalid field %q: %v", name, err))
+	}
+}
+
+func (d *jsonDecoder) position(raw json.RawMessage, name string) lexer.Position {
+	var out jsonPosition
+	d.value(raw, name, &out)
+	return out.position()
+}
+
+func (d *jsonDecoder) parameters(raw json.RawMessage) []*FunctionParameter {
+	out := []*FunctionParameter{}
+	for _, p := range d.list(raw, "parameters") {
+		param, ok := d.node(p).(*FunctionParameter)
+		if !ok {
+			d.fail(fmt.Errorf("json error - expected FunctionParameter"))
+		}
+		out = append(out, param)
+	}
+	return out
+}
+
+func (d *jsonDecoder) list(raw json.RawMessage, name string) []json.RawMessage {
+	var out []json.RawMessage
+	d.value(raw, name, &out)
+	return out
+}
+
+func isNull(raw json.RawMessage) bool {
+	return raw == nil || string(raw) == "null"
+}
+
+func (d *jsonDecoder) node(data []byte) Node {
+	f := d.fields(data)
+	if d.err != nil {
+		return nil
+	}
+
+	var kind string
+	d.value(f["kind"], "kind", &kind)
+	pos := d.position(f["pos"], "pos")
+
+	switch kind {
+	case "Program":
+		out := &Program{}
+		for _, s := range d.list(f["statements"], "statements") {
+			out.Statements = append(out.Statements, d.statement(s))
+		}
+		out.Errors = d.errors(f["errors"], "errors")
+		if f["warnings"] != nil {
+			out.Warnings = d.errors(f["warnings"], "warnings")
+		}
+		return out
+	case "VarStatement":
+		out := &VarStatementNode{Pos: pos, NamePos: d.position(f["namePos"], "namePos"), Type: d.typeAnnotation(f["type"]), Value: d.optionalExpression(f["value"])}
+		if !isNull(f["pattern"]) {
+			out.Pattern = d.pattern(f["pattern"])
+		}
+		d.value(f["name"], "name", &out.Name)
+		return out
+	case "ReturnStatement":
+		return &ReturnStatementNode{Pos: pos, Value: d.optionalExpression(f["value"])}
+	case "ExpressionStatement":
+		out := &ExpressionStatementNode{Pos: pos, Value: d.optionalExpression(f["value"])}
+		var tok jsonToken
+		d.value(f["token"], "token", &tok)
+		class, ok := lexer.ParseTokenClass(tok.Class)
+		if !ok {
+			d.fail(fmt.Errorf("json error - unknown token class %q", tok.Class))
+		}
+		out.Token = lexer.Token{Class: class, Lexeme: tok.Lexeme}
+		return out
+	case "BlockStatement":
+		out := &BlockStatement{Pos: pos, Statements: []StatementNode{}, End: d.position(f["close"], "close")}
+		for _, s := range d.list(f["statements"], "statements") {
+			out.Statements = append(out.Statements, d.statement(s))
+		}
+		return out
+	case "ImportStatement":
+		out := &ImportStatement{Pos: pos, PathPos: d.position(f["pathPos"], "pathPos"), AliasPos: d.position(f["aliasPos"], "aliasPos")}
+		d.value(f["path"], "path", &out.Path)
+		d.value(f["alias"], "alias", &out.Alias)
+		return out
+	case "ExportStatement":
+		st, ok := d.node(f["statement"]).(*VarStatementNode)
+		if !ok {
+			d.fail(fmt.Errorf("json error - expected VarStatement"))
+		}
+		return &ExportStatement{Pos: pos, Statement: st}
+	case "ThrowStatement":
+		return &ThrowStatement{Pos: pos, Value: d.expression(f["value"])}
+	case "TryStatement":
+		out := &TryStatement{Pos: pos, Body: d.block(f["body"]), NamePos: d.position(f["namePos"], "namePos")}
+		d.value(f["name"], "name", &out.Name)
+		if !isNull(f["catch"]) {
+			out.Catch = d.block(f["catch"])
+		}
+		if !isNull(f["finally"]) {
+			out.Finally = d.block(f["finally"])
+		}
+		return out
+	case "StructStatement":
+		out := &StructStatement{Pos: pos, NamePos: d.position(f["namePos"], "namePos"), Fields: []string{}, FieldPos: []lexer.Position{}, End: d.position(f["close"], "close")}
+		d.value(f["name"], "name", &out.Name)
+		d.value(f["fields"], "fields", &out.Fields)
+		for _, raw := range d.list(f["fieldPositions"], "fieldPositions") {
+			out.FieldPos = append(out.FieldPos, d.position(raw, "fieldPositions"))
+		}
+		if len(out.Fields) != len(out.FieldPos) {
+			d.fail(fmt.Errorf("json error - struct has %d fields and %d field positions", len(out.Fields), len(out.FieldPos)))
+		}
+		return out
+	case "MethodStatement":
+		out := &MethodStatement{Pos: pos, NamePos: d.position(f["namePos"], "namePos")}
+		d.value(f["name"], "name", &out.Name)
+		receiver, ok := d.node(f["receiver"]).(*FunctionParameter)
+		if !ok {
+			d.fail(fmt.Errorf("json error - expected FunctionParameter"))
+		}
+		function, ok := d.node(f["function"]).(*FunctionLiteralExpression)
+		if !ok {
+			d.fail(fmt.Errorf("json error - expected FunctionLiteral"))
+		}
+		out.Receiver, out.Function = receiver, function
+		return out
+	case "AssignStatement":
+		target, ok := d.node(f["target"]).(*MemberExpression)
+		if !ok {
+			d.fail(fmt.Errorf("json error - expected Member"))
+		}
+		return &AssignStatement{Pos: pos, Target: target, Value: d.expression(f["value"])}
+	case "StringLiteral":
+		out := &StringLiteralExpression{Pos: pos}
+		d.value(f["value"], "value", &out.Value)
+		return out
+	case "InterpolatedString":
+		out := &InterpolatedString{Pos: pos, Parts: []string{}, Values: []ExpressionNode{}, End: d.position(f["close"], "close")}
+		d.value(f["parts"], "parts", &out.Parts)
+		for _, v := range d.list(f["values"], "values") {
+			out.Values = append(out.Values, d.expression(v))
+		}
+		if len(out.Parts) != len(out.Values)+1 {
+			d.fail(fmt.Errorf("json error - interpolated string has %d parts and %d values", len(out.Parts), len(out.Values)))
+		}
+		return out
+	case "IntegerLiteral":
+		out := &IntegerLiteralExpression{Pos: pos}
+		d.value(f["value"], "value", &out.Value)
+		return out
+	case "FloatLiteral":
+		out := &FloatLiteralExpression{Pos: pos}
+		d.value(f["value"], "value", &out.Value)
+		return out
+	case "Boolean":
+		out := &BooleanExpression{Pos: pos}
+		d.value(f["value"], "value", &out.Value)
+		return out
+	case "Identifier":
+		out := &IdentifierExpression{Pos: pos}
+		d.value(f["name"], "name", &out.Name)
+		return out
+	case "Prefix":
+		out := &PrefixExpression{Pos: pos, Right: d.expression(f["right"])}
+		d.value(f["operator"], "operator", &out.Operator)
+		return out
+	case "Infix":
+		out := &InfixExpression{Pos: pos, Left: d.expression(f["left"]), Right: d.expression(f["right"])}
+		d.value(f["operator"], "operator", &out.Operator)
+		return out
+	case "If":
+		out := &IfExpression{Pos: pos, Condition: d.expression(f["condition"]), Consequence: d.block(f["consequence"])}
+		if !isNull(f["alternative"]) {
+			out.Alternative = d.block(f["alternative"])
+		}
+		return out
+	case "Conditional":
+		return &ConditionalExpression{Pos: pos, Condition: d.expression(f["condition"]), Consequence: d.expression(f["consequence"]), Alternative: d.expression(f["alternative"])}
+	case "FunctionLiteral":
+		return &FunctionLiteralExpression{Pos: pos, Parameters: d.parameters(f["parameters"]), ReturnType: d.typeAnnotation(f["returnType"]), Body: d.block(f["body"])}
+	case "MacroLiteral":
+		return &MacroLiteral{Pos: pos, Parameters: d.parameters(f["parameters"]), Body: d.block(f["body"])}
+	case "FunctionParameter":
+		out := &FunctionParameter{Pos: pos, Type: d.typeAnnotation(f["type"])}
+		d.value(f["name"], "name", &out.Name)
+		return out
+	case "Call":
+		out := &CallExpression{Pos: pos, Function: d.expression(f["function"]), Arguments: []ExpressionNode{}, End: d.position(f["close"], "close")}
+		for _, a := range d.list(f["arguments"], "arguments") {
+			out.Arguments = append(out.Arguments, d.expression(a))
+		}
+		return out
+	case "Member":
+		out := &MemberExpression{Pos: pos, Object: d.expression(f["object"]), MemberPos: d.position(f["memberPos"], "memberPos")}
+		d.value(f["member"], "member", &out.Member)
+		d.value(f["optional"], "optional", &out.Optional)
+		return out
+	case "ArrayLiteral":
+		out := &ArrayLiteralExpression{Pos: pos, Elements: []ExpressionNode{}, End: d.position(f["close"], "close")}
+		for _, e := range d.list(f["elements"], "elements") {
+			out.Elements = append(out.Elements, d.expression(e))
+		}
+		return out
+	case "Index":
+		out := &IndexExpression{Pos: pos, Left: d.expression(f["left"]), Index: d.expression(f["index"]), End: d.position(f["close"], "close")}
+		d.value(f["optional"], "optional", &out.Optional)
+		return out
+	case "HashLiteral":
+		out := &HashLiteralExpression{Pos: pos, Keys: []ExpressionNode{}, Values: []ExpressionNode{}, End: d.position(f["close"], "close")}
+		for _, k := range d.list(f["keys"], "keys") {
+			out.Keys = append(out.Keys, d.expression(k))
+		}
+		for _, v := range d.list(f["values"], "values") {
+			out.Values = append(out.Values, d.expression(v))
+		}
+		if len(out.Keys) != len(out.Values) {
+			d.fail(fmt.Errorf("json error - hash literal has %d keys and %d values", len(out.Keys), len(out.Values)))
+		}
+		return out
+	case "Spawn":
+		call, ok := d.node(f["call"]).(*CallExpression)
+		if !ok {
+			d.fail(fmt.Errorf("json error - expected Call"))
+		}
+		return &SpawnExpression{Pos: pos, Call: call}
+	case "Select":
+		out := &SelectExpression{Pos: pos, Cases: []*SelectCase{}, End: d.position(f["close"], "close")}
+		for _, c := range d.list(f["cases"], "cases") {
+			selectCase, ok := d.node(c).(*SelectCase)
+			if !ok {
+				d.fail(fmt.Errorf("json error - expected SelectCase"))
+			}
+			out.Cases = append(out.Cases, selectCase)
+		}
+		if !isNull(f["default"]) {
+			out.Default = d.block(f["default"])
+		}
+		return out
+	case "SelectCase":
+		out := &SelectCase{Pos: pos, Comm: d.expression(f["comm"]), NamePos: d.position(f["namePos"], "namePos"), Body: d.block(f["body"])}
+		d.value(f["name"], "name", &out.Name)
+		return out
+	case "Match":
+		out := &MatchExpression{Pos: pos, Subject: d.expression(f["subject"]), Arms: []*MatchArm{}, End: d.position(f["close"], "close")}
+		for _, a := range d.list(f["arms"], "arms") {
+			arm, ok := d.node(a).(*MatchArm)
+			if !ok {
+				d.fail(fmt.Errorf("json error - expected MatchArm"))
+			}
+			out.Arms = append(out.Arms, arm)
+		}
+		return out
+	case "MatchArm":
+		return &MatchArm{Pattern: d.pattern(f["pattern"]), Guard: d.optionalExpression(f["guard"]), Body: d.expression(f["body"])}
+	case "LiteralPattern":
+		return &LiteralPattern{Value: d.expression(f["value"])}
+	case "BindingPattern":
+		out := &BindingPattern{Pos: pos}
+		d.value(f["name"], "name", &out.Name)
+		return out
+	case "WildcardPattern":
+		return &WildcardPattern{Pos: pos}
+	case "ArrayPattern":
+		out := &ArrayPattern{Pos: pos, Elements: []Pattern{}, End: d.position(f["close"], "close")}
+		for _, e := range d.list(f["elements"], "elements") {
+			out.Elements = append(out.Elements, d.pattern(e))
+		}
+		if !isNull(f["rest"]) {
+			out.Rest = d.pattern(f["rest"])
+		}
+		return out
+	case "HashPattern":
+		out := &HashPattern{Pos: pos, Keys: []ExpressionNode{}, Values: []Pattern{}, End: d.position(f["close"], "close")}
+		for _, k := range d.list(f["keys"], "keys") {
+			out.Keys = append(out.Keys, d.expression(k))
+		}
+		for _, v := range d.list(f["values"], "values") {
+			out.Values = append(out.Values, d.pattern(v))
+		}
+		if len(out.Keys) != len(out.Values) {
+			d.fail(fmt.Errorf("json error - hash pattern has %d keys and %d values", len(out.Keys), len(out.Values)))
+		}
+		return out
+	case "TypeAnnotation":
+		out := &TypeAnnotation{Pos: pos, Return: d.typeAnnotation(f["return"]), End: d.position(f["close"], "close")}
+		d.value(f["name"], "name", &out.Name)
+		if out.Name == "fn" {
+			out.Parameters = []*TypeAnnotation{}
+		}
+		for _, p := range d.list(f["parameters"], "parameters") {
+			out.Parameters = append(out.Parameters, d.typeAnnotation(p))
+		}
+		return out
+	}
+
+	d.fail(fmt.Errorf("json error - unknown node kind %q", kind))
+	return nil
+}
+
+func (d *jsonDecoder) statement(raw json.RawMessage) StatementNode {
+	st, ok := d.node(raw).(StatementNode)
+	if !ok {
+		d.fail(fmt.Errorf("json error - expected statement"))
+	}
+	return st
+}
+
+func (d *jsonDecoder) expression(raw json.RawMessage) ExpressionNode {
+	exp, ok := d.node(raw).(ExpressionNode)
+	if !ok {
+		d.fail(fmt.Errorf("json error - expected expression"))
+	}
+	return exp
+}
+
+func (d *jsonDecoder) optionalExpression(raw json.RawMessage) ExpressionNode {
+	if isNull(raw) {
+		return nil
+	}
+	return d.expression(raw)
+}
+
+func (d *jsonDecoder) pattern(raw json.RawMessage) Pattern {
+	pattern, ok := d.node(raw).(Pattern)
+	if !ok {
+		d.fail(fmt.Errorf("json error - expected pattern"))
+	}
+	return pattern
+}
+
+func (d *jsonDecoder) errors(raw json.RawMessage, name string) []error {
+	var list []jsonError
+	d.value(raw, name, &list)
+	var out []error
+	for _, e := range list {
+		out = append(out, &SyntaxError{Message: e.Message, Pos: e.Pos.position()})
+	}
+	return out
+}
+
+func (d *jsonDecoder) block(raw json.RawMessage) *BlockStatement {
+	b, ok := d.node(raw).(*BlockStatement)
+	if !ok {
+		d.fail(fmt.Errorf("json error - expected BlockStatement"))
+	}
+	return b
+}
+
+func (d *jsonDecoder) typeAnnotation(raw json.RawMessage) *TypeAnnotation {
+	if isNull(raw) {
+		return nil
+	}
+	t, ok := d.node(raw).(*TypeAnnotation)
+	if !ok {
+		d.fail(fmt.Errorf("json error - expected TypeAnnotation"))
+	}
+	return t
+}
@@ -0,0 +1,242 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"programming-lang/lexer"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseWithPositions(input string) *Program {
+	return ParseWithPositions(lexer.TokenizeWithPositions(input))
+}
+
+func TestJsonSchema(t *testing.T) {
+	tree := parseWithPositions(`var x: int = -y + 1;`)
+	assertNoErrors(t, tree.Errors)
+
+	data, err := json.Marshal(tree)
+	require.NoError(t, err)
+
+	pos := func(line, col int) string {
+		return fmt.Sprintf(`{"line":%d,"column":%d}`, line, col)
+	}
+	span := func(start, end string) string {
+		return `{"start":` + start + `,"end":` + end + `}`
+	}
+	expected := `{"errors":[],"kind":"Program","pos":` + pos(1, 1) + `,"span":` + span(pos(1, 1), pos(1, 20)) + `,"statements":[` +
+		`{"kind":"VarStatement","name":"x","namePos":` + pos(1, 5) + `,"pattern":null,"pos":` + pos(1, 1) + `,"span":` + span(pos(1, 1), pos(1, 20)) + `,` +
+		`"type":{"close":` + pos(0, 0) + `,"kind":"TypeAnnotation","name":"int","parameters":[],"pos":` + pos(1, 8) + `,"return":null,"span":` + span(pos(1, 8), pos(1, 11)) + `},` +
+		`"value":{"kind":"Infix","left":` +
+		`{"kind":"Prefix","operator":"-","pos":` + pos(1, 14) + `,"right":{"kind":"Identifier","name":"y","pos":` + pos(1, 15) + `,"span":` + span(pos(1, 15), pos(1, 16)) + `},"span":` + span(pos(1, 14), pos(1, 16)) + `},` +
+		`"operator":"+","pos":` + pos(1, 17) + `,` +
+		`"right":{"kind":"IntegerLiteral","pos":` + pos(1, 19) + `,"span":` + span(pos(1, 19), pos(1, 20)) + `,"value":1},` +
+		`"span":` + span(pos(1, 14), pos(1, 20)) + `}}],"warnings":[]}`
+	assert.JSONEq(t, expected, string(data))
+}
+
+func TestJsonRoundTrip(t *testing.T) {
+	inputs := []string{
+		`var x;`,
+		`var f: fn(int, fn(bool): int): int;`,
+		`var add = fn(a: int, b): int { return a + b; };`,
+		`if (x < y) { x } else { y }`,
+		`if (true) { }`,
+		`add(1, 2 * 3, foo())(4);`,
+		`fn() {}();`,
+		`-!x == (1 + 2) * 3;`,
+		`var x = 1 var y = ;`,
+		`import "lib/a.mk" as a; export var s = a.b.c("x\ty");`,
+		`var xs = [1, [], f(2)[0]]; xs[1 + 1][0];`,
+		`var x = 1.5 * 2.0 - 0.25;`,
+		`var h = {"a": [1], 2: {}}; h["a"];`,
+		`spawn f(<-ch); select { case <-in as v { out <- v; } case <-done {} default { 1 } }`,
+		`try { throw "x"; } catch (e) { e } finally {} try {} finally { 1 }`,
+		`var [a, {"k": -1, 2: [_, ...rest]}] = xs; match (x) { true => 1, "s" if a => [], _ => 2, 3 => 4 }`,
+		"struct Point { x, y }\nfn (p: Point) scale(k: int): Point { Point(p.x * k, p.y * k) }\np.x = p.scale(2).y;",
+		"var s = `a ${x + 1}\n\\t${`${y}`}`;",
+		`var unless = macro(c, a) { quote(if (!unquote(c)) { unquote(a) }) };`,
+		`var x = a ? b?.c : d?.[0] ?? 1;`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			assertJsonRoundTrip(t, parseWithPositions(input))
+		})
+	}
+}
+
+// property test - randomly generated programs must survive serialization unchanged
+func TestJsonRoundTripRandomPrograms(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 200; i++ {
+		input := randomProgram(r)
+		tree := parseWithPositions(input)
+		require.Len(t, tree.Errors, 0, "generated invalid program %q: %v", input, tree.Errors)
+		assertJsonRoundTrip(t, tree)
+	}
+}
+
+// trees of incomplete code miss operands, they are serialized as null
+func TestJsonIncompleteCode(t *testing.T) {
+	tdt := []struct {
+		input string
+		span  Span
+	}{
+		{"1 +", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 4}}},
+		{"-", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 2}}},
+		{"!", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 2}}},
+		{"1 ??", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 5}}},
+		{"return -", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 9}}},
+		{"throw -", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 8}}},
+		{"x.y = 1 +", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 10}}},
+		{"a ? b :", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 2}}},
+		{"if (x) { 1 + }", Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 16}}},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parseWithPositions(tc.input)
+			require.NotEmpty(t, tree.Errors)
+			require.NotEmpty(t, tree.Statements)
+			assert.Equal(t, tc.span, SpanOf(tree.Statements[0]))
+
+			_, err := json.Marshal(tree)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestJsonInvalidInput(t *testing.T) {
+	inputs := []string{
+		`[]`,
+		`{"kind":"Unknown","pos":{"line":1,"column":1}}`,
+		`{"kind":"Program","pos":{"line":1,"column":1},"statements":[{"kind":"Identifier","name":"x","pos":{"line":1,"column":1}}],"errors":[]}`,
+		`{"kind":"Infix","pos":{"line":1,"column":1},"operator":"+"}`,
+	}
+	for _, input := range inputs {
+		var program Program
+		assert.Error(t, json.Unmarshal([]byte(input), &program), input)
+	}
+}
+
+func assertJsonRoundTrip(t *testing.T, tree *Program) {
+	data, err := json.Marshal(tree)
+	require.NoError(t, err)
+
+	var decoded Program
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, tree, &decoded)
+}
+
+func randomProgram(r *rand.Rand) string {
+	statements := []string{}
+	for i := 0; i <= r.Intn(4); i++ {
+		statements = append(statements, randomStatement(r, 3))
+	}
+	return strings.Join(statements, "\n")
+}
+
+func randomStatement(r *rand.Rand, depth int) string {
+	switch r.Intn(4) {
+	case 0:
+		return "var " + randomName(r) + " = " + randomExpression(r, depth) + ";"
+	case 1:
+		return "var " + randomName(r) + ": " + randomType(r, 2) + ";"
+	case 2:
+		return "return " + randomExpression(r, depth) + ";"
+	}
+	return randomExpression(r, depth) + ";"
+}
+
+func randomBlock(r *rand.Rand, depth int) string {
+	statements := []string{}
+	for i := 0; i < r.Intn(3); i++ {
+		statements = append(statements, randomStatement(r, depth))
+	}
+	return "{ " + strings.Join(statements, "\n") + " }"
+}
+
+func randomName(r *rand.Rand) string {
+	return []string{"a", "b", "foo", "bar"}[r.Intn(4)]
+}
+
+func randomType(r *rand.Rand, depth int) string {
+	if depth == 0 || r.Intn(3) > 0 {
+		return []string{"int", "bool", "string"}[r.Intn(3)]
+	}
+	params := []string{}
+	for i := 0; i < r.Intn(3); i++ {
+		params = append(params, randomType(r, depth-1))
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if r.Intn(2) == 0 {
+		out += ": " + randomType(r, depth-1)
+	}
+	return out
+}
+
+func randomExpression(r *rand.Rand, depth int) string {
+	if depth == 0 {
+		switch r.Intn(3) {
+		case 0:
+			return fmt.Sprint(r.Intn(1000))
+		case 1:
+			return []string{"true", "false"}[r.Intn(2)]
+		}
+		return randomName(r)
+	}
+
+	switch r.Intn(7) {
+	case 0:
+		// space keeps the lexer from reading -- operator
+		return []string{"-", "!"}[r.Intn(2)] + " " + randomExpression(r, depth-1)
+	case 1:
+		operators := []string{"+", "-", "*", "/", "<", ">", "<=", ">=", "==", "!="}
+		return randomExpression(r, depth-1) + " " + operators[r.Intn(len(operators))] + " " + randomExpression(r, depth-1)
+	case 2:
+		args := []string{}
+		for i := 0; i < r.Intn(3); i++ {
+			args = append(args, randomExpression(r, depth-1))
+		}
+		return randomName(r) + "(" + strings.Join(args, ", ") + ")"
+	case 3:
+		out := "if (" + randomExpression(r, depth-1) + ") " + randomBlock(r, depth-1)
+		if r.Intn(2) == 0 {
+			out += " else " + randomBlock(r, depth-1)
+		}
+		return out
+	case 4:
+		params := []string{}
+		for i := 0; i < r.Intn(3); i++ {
+			param := randomName(r)
+			if r.Intn(2) == 0 {
+				param += ": " + randomType(r, 1)
+			}
+			params = append(params, param)
+		}
+		return "fn(" + strings.Join(params, ", ") + ") " + randomBlock(r, depth-1)
+	case 5:
+		return "(" + randomExpression(r, depth-1) + ")"
+	}
+	return randomExpression(r, 0)
+}
+
+func TestClone(t *testing.T) {
+	tree := parseWithPositions(`var f = fn(a) { [a, {"k": a + 1}] };`)
+	assertNoErrors(t, tree.Errors)
+
+	clone := Clone(tree)
+	assert.Equal(t, tree, clone)
+	Rewrite(clone, func(n Node) Node {
+		if id, ok := n.(*IdentifierExpression); ok {
+			id.Name = "b"
+		}
+		return n
+	})
+	assert.Equal(t, "var f=fn(a) [a,{\"k\":(a+1)}]", tree.String())
+	assert.Equal(t, "var f=fn(a) [b,{\"k\":(b+1)}]", clone.String())
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"strings"
+)
+
+// MacroLiteral is a function run before evaluation, var unless = macro(cond, body) { quote(...) }.
+// Its arguments are the code of the call site and its result is quoted code replacing the call
+type MacroLiteral struct {
+	Parameters []*FunctionParameter // never annotated
+	Body       *BlockStatement
+	Pos        lexer.Position
+}
+
+func (m *MacroLiteral) TokenLiteral() string {
+	return "macro"
+}
+
+func (m *MacroLiteral) String() string {
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	return "macro(" + strings.Join(params, ",") + ") " + m.Body.String()
+}
+
+func (m *MacroLiteral) Position() lexer.Position {
+	return m.Pos
+}
+
+func (m *MacroLiteral) evaluateExpression() {}
+
+func (p *parser) parseMacroLiteral() ExpressionNode {
+	if !isOpeningParent(p.nextToken) {
+		p.addError(fmt.Errorf("macro literal error - missing opening brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	out := &MacroLiteral{Pos: p.currentPos}
+	p.advanceToken()
+
+	params, ok := p.parseFunctionParameters()
+	if !ok {
+		return nil
+	}
+	for _, param := range params {
+		if param.Type != nil {
+			p.addError(fmt.Errorf("macro literal error - parameter %v can't have a type, macros receive code", param.Name))
+			return nil
+		}
+	}
+	out.Parameters = params
+
+	if !isOpeningCurly(p.nextToken) {
+		p.addError(fmt.Errorf("macro literal error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	out.Body = p.parseBlockStatement()
+	return out
+}
@@ -7,12 +7,20 @@ import (
 type Program struct {
 	Statements []StatementNode
 	Errors []error
+	Warnings []error // suspicious code that still parses, like unreachable match arms
 }
 
 func (p *Program) TokenLiteral() string {
 	return p.Statements[0].TokenLiteral()
 }
 
+func (p *Program) Position() lexer.Position {
+	if len(p.Statements) == 0 {
+		return lexer.Position{}
+	}
+	return p.Statements[0].Position()
+}
+
 func (p *Program) String() string {
 	var out string
 	for _, s := range p.Statements {
@@ -22,17 +30,46 @@ func (p *Program) String() string {
 }
 
 func Parse(tokens []lexer.Token) *Program {
-	p := &parser{tokens: tokens}
+	return ParseWithPositions(tokens, nil)
+}
+
+// ParseWithPositions works like Parse, additionally storing token positions in the nodes.
+// Positions are expected to be in the form returned by lexer.TokenizeWithPositions
+func ParseWithPositions(tokens []lexer.Token, positions []lexer.Position) *Program {
+	return parseProgram(&parser{tokens: tokens, positions: positions})
+}
+
+// ParseStrict works like ParseWithPositions with explicit statement termination. Semicolons inserted
+// by the lexer at line ends are dropped, var, return, import, throw and assignment statements must end
+// with a semicolon while it's optional after expressions
+func ParseStrict(tokens []lexer.Token, positions []lexer.Position) *Program {
+	p := &parser{strict: true}
+	for i, t := range tokens {
+		if isImplicitSemicolon(t) {
+			continue
+		}
+		p.tokens = append(p.tokens, t)
+		if i < len(positions) {
+			p.positions = append(p.positions, positions[i])
+		}
+	}
+	return parseProgram(p)
+}
 
+func parseProgram(p *parser) *Program {
 	// populate current and next
 	p.advanceToken()
 
 	for !p.eof(){
+		if isImplicitSemicolon(p.currentToken) {
+			p.advanceToken()
+			continue
+		}
 		p.addStatement(p.parseStatement())
 		p.advanceToken()
 	}
 
-	return &Program{p.statements, p.errors}
+	return &Program{p.statements, p.errors, p.warnings}
 }
 
 func (p *parser) parseStatement() StatementNode {
@@ -40,6 +77,18 @@ func (p *parser) parseStatement() StatementNode {
 		return p.parseVarStatement()
 	} else if isReturnKeyword(p.currentToken) {
 		return p.parseReturnStatement()
+	} else if importKeyword(p.currentToken) {
+		return p.parseImportStatement()
+	} else if exportKeyword(p.currentToken) {
+		return p.parseExportStatement()
+	} else if throwKeyword(p.currentToken) {
+		return p.parseThrowStatement()
+	} else if tryKeyword(p.currentToken) {
+		return p.parseTryStatement()
+	} else if structKeyword(p.currentToken) {
+		return p.parseStructStatement()
+	} else if fnKeyword(p.currentToken) && p.isMethodStatement() {
+		return p.parseMethodStatement()
 	}
 	return p.parseExpressionStatement()
 }
@@ -50,14 +99,20 @@ type parser struct {
 	currentToken lexer.Token
 	nextToken lexer.Token
 
+	positions []lexer.Position
+	currentPos lexer.Position
+
 	errors []error
+	warnings []error
 	statements []StatementNode
+	strict bool // statements aren't terminated by new lines, see ParseStrict
 }
 
 // Node is an interface mostly for debugging and testing
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Position() lexer.Position // zero when parsed without positions
 }
 
 func (p *parser) advanceToken() {
@@ -69,6 +124,9 @@ func (p *parser) advanceToken() {
 	}
 
 	p.currentToken = currentToken()
+	if p.idx < len(p.positions) {
+		p.currentPos = p.positions[p.idx]
+	}
 	p.idx++
 	p.nextToken = currentToken()
 }
@@ -77,12 +135,44 @@ func (p *parser) eof() bool {
 	return eof(p.currentToken)
 }
 
+// endStatement moves past the semicolon terminating the statement, reports whether the statement is terminated.
+// Outside of strict mode a statement may also end with a curly brace or be followed by one or by the end of input
+func (p *parser) endStatement() bool {
+	if isSemicolon(p.nextToken) || isImplicitSemicolon(p.nextToken) {
+		p.advanceToken()
+		return true
+	}
+	return !p.strict && (isClosingCurly(p.currentToken) || isClosingCurly(p.nextToken) || eof(p.nextToken))
+}
+
+// skipSemicolon moves past the semicolon optionally following a statement that ends with a curly brace
+func (p *parser) skipSemicolon() {
+	if isSemicolon(p.nextToken) {
+		p.advanceToken()
+	}
+}
+
+// SyntaxError is a parser error located at the token where parsing failed
+type SyntaxError struct {
+	Message string
+	Pos     lexer.Position
+}
+
+func (s *SyntaxError) Error() string {
+	return s.Message
+}
+
 func (p *parser) addError(err error) {
 	if err != nil {
-		p.errors = append(p.errors, err)
+		p.errors = append(p.errors, &SyntaxError{Message: err.Error(), Pos: p.currentPos})
 	}
 }
 
+// addWarning reports a problem found at the position, unlike errors warnings keep the node
+func (p *parser) addWarning(err error, pos lexer.Position) {
+	p.warnings = append(p.warnings, &SyntaxError{Message: err.Error(), Pos: pos})
+}
+
 func (p *parser) addStatement(st StatementNode) {
 	if st != nil {
 		p.statements = append(p.statements, st)
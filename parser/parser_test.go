@@ -20,6 +20,10 @@ func parse(input string) *Program {
 	return Parse(lexer.Tokenize(input))
 }
 
+func parseStrict(input string) *Program {
+	return ParseStrict(lexer.TokenizeWithPositions(input))
+}
+
 func assertVarStatement(t *testing.T, st StatementNode, name string) *VarStatementNode {
 	varSt, ok := st.(*VarStatementNode)
 	assert.True(t, ok, "expected var statement")
@@ -110,7 +114,7 @@ func TestInvalidVarStatements(t *testing.T) {
 	var = 432;
 	var x = foo`
 
-	tree := parse(input)
+	tree := parseStrict(input)
 	assert.Len(t, tree.Errors, 4)
 }
 
@@ -118,7 +122,7 @@ func TestFirstVarNotTerminated_SecondExpressionles(t *testing.T) {
 	input := `var asd = 4
 	var asd = ;`
 
-	tree := parse(input)
+	tree := parseStrict(input)
 	assert.Len(t, tree.Errors, 3)
 }
 
@@ -350,6 +354,18 @@ func TestOperatorPredescence(t *testing.T) {
 		{"2 / (5 + 5);", "(2/(5+5))" },
 		{"-(5 + 5);", "(-(5+5))" },
 		{"!(true == true);", "(!(true==true))" },
+		{"a ? b : c;", "(a?b:c)"},
+		{"a ? b : c ? d : e;", "(a?b:(c?d:e))"},
+		{"a ? b ? c : d : e;", "(a?(b?c:d):e)"},
+		{"a == 1 ? b + 1 : -c;", "((a==1)?(b+1):(-c))"},
+		{"a ?? b ?? c;", "((a??b)??c)"},
+		{"a ?? b == c;", "(a??(b==c))"},
+		{"a + b ?? c;", "((a+b)??c)"},
+		{"a ?? b ? c : d ?? e;", "((a??b)?c:(d??e))"},
+		{"ch <- a ?? b;", "(ch<-(a??b))"},
+		{"a?.b?.c + 1;", "(a?.b?.c+1)"},
+		{"a?.[0]?.b;", "(a?.[0])?.b"},
+		{"-a?.b(1);", "(-a?.b(1))"},
 	}
 
 	for _, tc := range tdt {
@@ -454,10 +470,837 @@ func TestIfExpression(t *testing.T) {
 	})
 }
 
+func assertFunctionLiteral(t *testing.T, expression ExpressionNode) *FunctionLiteralExpression {
+	fn, ok := expression.(*FunctionLiteralExpression)
+	require.True(t, ok, "function literal not found")
+	return fn
+}
+
 func TestFunctionLiteral(t *testing.T) {
-	t.Skip("todo")
+	t.Run("Without parameters", func(t *testing.T) {
+		tree := parse(`fn() { 5 }`)
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+
+		fn := assertFunctionLiteral(t, assertExpressionStatement(t, tree.Statements[0]).Value)
+		assert.Len(t, fn.Parameters, 0)
+		assert.Nil(t, fn.ReturnType)
+		require.Len(t, fn.Body.Statements, 1)
+		assertInteger(t, assertExpressionStatement(t, fn.Body.Statements[0]).Value, 5)
+	})
+
+	t.Run("With parameters", func(t *testing.T) {
+		tree := parse(`fn(x, y) { x + y; }`)
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+
+		fn := assertFunctionLiteral(t, assertExpressionStatement(t, tree.Statements[0]).Value)
+		require.Len(t, fn.Parameters, 2)
+		assert.Equal(t, "x", fn.Parameters[0].Name)
+		assert.Equal(t, "y", fn.Parameters[1].Name)
+		assert.Nil(t, fn.Parameters[0].Type)
+
+		require.Len(t, fn.Body.Statements, 1)
+		inf := assertInfixExpr(t, assertExpressionStatement(t, fn.Body.Statements[0]).Value, "+")
+		assertIdentifier(t, inf.Left, "x")
+		assertIdentifier(t, inf.Right, "y")
+	})
+
+	t.Run("Assigned to var", func(t *testing.T) {
+		tree := parse(`var add = fn(x, y) { return x + y; };`)
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+
+		varSt := assertVarStatement(t, tree.Statements[0], "add")
+		fn := assertFunctionLiteral(t, varSt.Value)
+		assert.Len(t, fn.Parameters, 2)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for _, input := range []string{`fn(1) {}`, `fn(x y) {}`, `fn(x {}`, `fn x {}`} {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
 }
 
 func TestFunctionCall(t *testing.T) {
-	t.Skip("todo")
-}
\ No newline at end of file
+	t.Run("Simple call", func(t *testing.T) {
+		tree := parse(`add(1, 2 * 3, foo);`)
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+
+		call, ok := assertExpressionStatement(t, tree.Statements[0]).Value.(*CallExpression)
+		require.True(t, ok, "call expression not found")
+		assertIdentifier(t, call.Function, "add")
+		require.Len(t, call.Arguments, 3)
+		assertInteger(t, call.Arguments[0], 1)
+		assertInfixExpr(t, call.Arguments[1], "*")
+		assertIdentifier(t, call.Arguments[2], "foo")
+	})
+
+	t.Run("Without arguments", func(t *testing.T) {
+		tree := parse(`foo();`)
+		assertNoErrors(t, tree.Errors)
+		call, ok := assertExpressionStatement(t, tree.Statements[0]).Value.(*CallExpression)
+		require.True(t, ok, "call expression not found")
+		assert.Len(t, call.Arguments, 0)
+	})
+
+	t.Run("Missing closing brace", func(t *testing.T) {
+		assertSomeErrors(t, parse(`foo(1, 2;`).Errors)
+	})
+
+	tdt := []struct {
+		input string
+		expected string
+	}{
+		{"a + add(b * c) + d;", "((a+add((b*c)))+d)"},
+		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8));", "add(a,b,1,(2*3),(4+5),add(6,(7*8)))"},
+		{"add(a + b + c * d / f + g);", "add((((a+b)+((c*d)/f))+g))"},
+		{"-foo(1);", "(-foo(1))"},
+		{"fn(x) { x }(5);", "fn(x) x(5)"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+}
+
+func TestModules(t *testing.T) {
+	t.Run("Import and export", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions(`import "lib/math.mk" as math;
+export var twice = fn(x) { math.double(x) };`))
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 2)
+
+		imp, ok := tree.Statements[0].(*ImportStatement)
+		require.True(t, ok, "import statement not found")
+		assert.Equal(t, "lib/math.mk", imp.Path)
+		assert.Equal(t, "math", imp.Alias)
+		assert.Equal(t, lexer.Position{Line: 1, Column: 8}, imp.PathPos)
+		assert.Equal(t, lexer.Position{Line: 1, Column: 25}, imp.AliasPos)
+
+		exp, ok := tree.Statements[1].(*ExportStatement)
+		require.True(t, ok, "export statement not found")
+		assert.Equal(t, lexer.Position{Line: 2, Column: 1}, exp.Pos)
+		fn := assertFunctionLiteral(t, assertVarStatement(t, exp.Statement, "twice").Value)
+
+		call, ok := assertExpressionStatement(t, fn.Body.Statements[0]).Value.(*CallExpression)
+		require.True(t, ok, "call expression not found")
+		member, ok := call.Function.(*MemberExpression)
+		require.True(t, ok, "member expression not found")
+		assertIdentifier(t, member.Object, "math")
+		assert.Equal(t, "double", member.Member)
+		assert.Equal(t, lexer.Position{Line: 2, Column: 33}, member.MemberPos)
+	})
+
+	t.Run("String literal", func(t *testing.T) {
+		tree := parse(`"say \"hi\"\n";`)
+		assertNoErrors(t, tree.Errors)
+		str, ok := assertExpressionStatement(t, tree.Statements[0]).Value.(*StringLiteralExpression)
+		require.True(t, ok, "string literal not found")
+		assert.Equal(t, "say \"hi\"\n", str.Value)
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{`import "a.mk" as a;`, `import "a.mk" as a`},
+		{`export var x = 1;`, "export var x=1"},
+		{"a.b.c;", "a.b.c"},
+		{"-a.b;", "(-a.b)"},
+		{"a.b(1).c + 2;", "(a.b(1).c+2)"},
+		{`"a" + "b";`, `("a"+"b")`},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, input := range []string{
+			`import a.mk as a;`, `import "a.mk";`, `import "a.mk" as;`,
+			`export 1;`, `export var;`, `a.;`, `a.1;`,
+			`fn() { import "a.mk" as a; }`, `if (true) { export var x = 1; }`,
+		} {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestConditionals(t *testing.T) {
+	tree := ParseWithPositions(lexer.TokenizeWithPositions("var x = a ? b : c;"))
+	assertNoErrors(t, tree.Errors)
+	cond, ok := assertVarStatement(t, tree.Statements[0], "x").Value.(*ConditionalExpression)
+	require.True(t, ok, "conditional expression not found")
+	assert.Equal(t, lexer.Position{Line: 1, Column: 11}, cond.Position())
+	assert.Equal(t, Span{lexer.Position{Line: 1, Column: 9}, lexer.Position{Line: 1, Column: 18}}, SpanOf(cond))
+
+	member, ok := parse("a?.b;").Statements[0].(*ExpressionStatementNode).Value.(*MemberExpression)
+	require.True(t, ok, "member expression not found")
+	assert.True(t, member.Optional)
+
+	index, ok := parse("a?.[0];").Statements[0].(*ExpressionStatementNode).Value.(*IndexExpression)
+	require.True(t, ok, "index expression not found")
+	assert.True(t, index.Optional)
+
+	t.Run("Invalid", func(t *testing.T) {
+		inputs := []string{
+			`a ? b;`,
+			`a ? : c;`,
+			`a ? b : ;`,
+			`a ?? ;`,
+			`a?.1;`,
+			`a?.[0;`,
+			`?a;`,
+		}
+		for _, input := range inputs {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestFloatLiterals(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected float64
+		str      string
+	}{
+		{"1.5", 1.5, "1.5"},
+		{"2.0", 2, "2.0"},
+		{"0.125", 0.125, "0.125"},
+		{"100.50", 100.5, "100.5"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			f, ok := assertExpressionStatement(t, tree.Statements[0]).Value.(*FloatLiteralExpression)
+			require.True(t, ok, "float literal not found")
+			assert.Equal(t, tc.expected, f.Value)
+			assert.Equal(t, tc.str, f.String())
+		})
+	}
+	assert.Equal(t, "((-1.5)*x)", parse("-1.5 * x").String())
+}
+
+func TestArrays(t *testing.T) {
+	t.Run("Literal and index", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions(`[1, x + 2][y]`))
+		assertNoErrors(t, tree.Errors)
+		index, ok := assertExpressionStatement(t, tree.Statements[0]).Value.(*IndexExpression)
+		require.True(t, ok, "index expression not found")
+		assertIdentifier(t, index.Index, "y")
+		assert.Equal(t, lexer.Position{Line: 1, Column: 11}, index.Pos)
+		assert.Equal(t, lexer.Position{Line: 1, Column: 13}, index.End)
+
+		array, ok := index.Left.(*ArrayLiteralExpression)
+		require.True(t, ok, "array literal not found")
+		require.Len(t, array.Elements, 2)
+		assertInteger(t, array.Elements[0], 1)
+		assert.Equal(t, lexer.Position{Line: 1, Column: 1}, array.Pos)
+		assert.Equal(t, lexer.Position{Line: 1, Column: 10}, array.End)
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"[];", "[]"},
+		{"[1, 2 * 3, [true]];", "[1,(2*3),[true]]"},
+		{"a[1][2];", "((a[1])[2])"},
+		{"-a[0];", "(-(a[0]))"},
+		{"a * b[0];", "(a*(b[0]))"},
+		{"f(a)[0](b);", "(f(a)[0])(b)"},
+		{"lib.xs[0];", "(lib.xs[0])"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, input := range []string{`[1, 2`, `[1 2]`, `a[1`, `a[]`, `[,]`} {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestHashes(t *testing.T) {
+	t.Run("Literal", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions(`{"a": 1, b: x + 2}`))
+		assertNoErrors(t, tree.Errors)
+		hash, ok := assertExpressionStatement(t, tree.Statements[0]).Value.(*HashLiteralExpression)
+		require.True(t, ok, "hash literal not found")
+		require.Len(t, hash.Keys, 2)
+		require.Len(t, hash.Values, 2)
+		assertIdentifier(t, hash.Keys[1], "b")
+		assertInteger(t, hash.Values[0], 1)
+		assert.Equal(t, lexer.Position{Line: 1, Column: 1}, hash.Pos)
+		assert.Equal(t, lexer.Position{Line: 1, Column: 18}, hash.End)
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"{};", "{}"},
+		{`{"a": [1], 2: {true: -x}};`, `{"a":[1],2:{true:(-x)}}`},
+		{`{"a": 1}["a"];`, `({"a":1}["a"])`},
+		{`var h = {"k": fn(x) { x }};`, `var h={"k":fn(x) x}`},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, input := range []string{`{"a": 1`, `{"a" 1}`, `{"a": 1 "b": 2}`, `{,}`, `{"a":}`} {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestConcurrency(t *testing.T) {
+	t.Run("Select", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions(`select {
+	case <-in as v { v }
+	case out <- 1 {}
+	default { 2 }
+}`))
+		assertNoErrors(t, tree.Errors)
+		sel, ok := assertExpressionStatement(t, tree.Statements[0]).Value.(*SelectExpression)
+		require.True(t, ok, "select expression not found")
+		require.Len(t, sel.Cases, 2)
+		require.NotNil(t, sel.Default)
+		assert.Equal(t, lexer.Position{Line: 5, Column: 1}, sel.End)
+
+		receive := sel.Cases[0]
+		assert.Equal(t, "v", receive.Name)
+		assert.Equal(t, lexer.Position{Line: 2, Column: 2}, receive.Pos)
+		assert.Equal(t, lexer.Position{Line: 2, Column: 15}, receive.NamePos)
+		assert.Equal(t, "(<-in)", receive.Comm.String())
+		assert.Equal(t, "", sel.Cases[1].Name)
+		assert.Equal(t, "(out<-1)", sel.Cases[1].Comm.String())
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn f(1, x);", "spawn f(1,x)"},
+		{"spawn lib.f()(2);", "spawn lib.f()(2)"},
+		{"ch <- a + 1;", "(ch<-(a+1))"},
+		{"<-ch + 1;", "((<-ch)+1)"},
+		{"<-<-chs;", "(<-(<-chs))"},
+		{"x < -1;", "(x<(-1))"},
+		{"var v = select { case <-a {} };", "var v=select {case (<-a)  }"},
+		{"select { default { 1 } }", "select {default 1}"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		inputs := []string{
+			`spawn f;`,
+			`spawn 1 + f();`,
+			`select { case a {} }`,
+			`select { case a == b {} }`,
+			`select { case ch <- 1 as v {} }`,
+			`select { case <-ch as {} }`,
+			`select { case <-ch }`,
+			`select { default {} default {} }`,
+			`select { 1 }`,
+			`select { case <-ch {}`,
+			`select case`,
+		}
+		for _, input := range inputs {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestExceptions(t *testing.T) {
+	t.Run("Try", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions(`try {
+	throw {"code": 1};
+} catch (err) {
+	err
+} finally {
+	done()
+}`))
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+		try, ok := tree.Statements[0].(*TryStatement)
+		require.True(t, ok, "try statement not found")
+		assert.Equal(t, "err", try.Name)
+		assert.Equal(t, lexer.Position{Line: 3, Column: 10}, try.NamePos)
+		require.Len(t, try.Body.Statements, 1)
+		throw, ok := try.Body.Statements[0].(*ThrowStatement)
+		require.True(t, ok, "throw statement not found")
+		assert.Equal(t, lexer.Position{Line: 2, Column: 2}, throw.Pos)
+		assert.Equal(t, `{"code":1}`, throw.Value.String())
+		require.NotNil(t, try.Catch)
+		require.NotNil(t, try.Finally)
+		assert.Equal(t, Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 7, Column: 2}}, SpanOf(try))
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "boom";`, `throw "boom"`},
+		{`throw f(1) + 2;`, `throw (f(1)+2)`},
+		{`try { a } catch (e) { e }`, `try a catch (e) e`},
+		{`try { a } finally { b }`, `try a finally b`},
+		{`try { a } catch (e) { e }; b;`, `try a catch (e) eb`},
+		{`try { a } finally { b };`, `try a finally b`},
+		{`fn() { try { return 1; } catch (e) { throw e; } }`, `fn() try return 1 catch (e) throw e`},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		inputs := []string{
+			`throw;`,
+			`try { a }`,
+			`try a catch (e) {}`,
+			`try {} catch e {}`,
+			`try {} catch () {}`,
+			`try {} catch (e {}`,
+			`try {} catch (e) b`,
+			`try {} finally b`,
+			`try {} finally {} catch (e) {}`,
+		}
+		for _, input := range inputs {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestPatterns(t *testing.T) {
+	t.Run("Match", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions(`match (x) {
+	[a, ...rest] => a,
+	n if n > 10 => n,
+	_ => 0,
+}`))
+		assertNoErrors(t, tree.Errors)
+		assert.Len(t, tree.Warnings, 0)
+		require.Len(t, tree.Statements, 1)
+		match, ok := tree.Statements[0].(*ExpressionStatementNode).Value.(*MatchExpression)
+		require.True(t, ok, "match expression not found")
+		require.Len(t, match.Arms, 3)
+
+		array, ok := match.Arms[0].Pattern.(*ArrayPattern)
+		require.True(t, ok, "array pattern not found")
+		assert.Equal(t, lexer.Position{Line: 2, Column: 2}, array.Pos)
+		assert.Equal(t, &BindingPattern{Name: "rest", Pos: lexer.Position{Line: 2, Column: 9}}, array.Rest)
+		assert.Nil(t, match.Arms[0].Guard)
+		assert.Equal(t, "(n>10)", match.Arms[1].Guard.String())
+		assert.IsType(t, &WildcardPattern{}, match.Arms[2].Pattern)
+		assert.Equal(t, []*BindingPattern{{Name: "a", Pos: lexer.Position{Line: 2, Column: 3}}, {Name: "rest", Pos: lexer.Position{Line: 2, Column: 9}}}, Bindings(array))
+		assert.Equal(t, Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 5, Column: 2}}, SpanOf(match))
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { 1 => "one", -2.5 => "neg", "s" => true, false => 0 }`, `matchx {1=>"one",(-2.5)=>"neg","s"=>true,false=>0}`},
+		{`match (f(x)) { [] => 0, [a] => a, [_, ...r] => r, [..._] => 1 }`, `matchf(x) {[]=>0,[a]=>a,[_,...r]=>r,[..._]=>1}`},
+		{`match (h) { {"k": v, 1: [w]} => v + w, {} => 0 }`, `matchh {{"k":v,1:[w]}=>(v+w),{}=>0}`},
+		{`match (x) { n if n > 0 => {"pos": n}, _ => {} }`, `matchx {n if (n>0)=>{"pos":n},_=>{}}`},
+		{`var [a, b] = pair;`, `var [a,b]=pair`},
+		{`var {"x": x, "y": [y, ...ys]} = point;`, `var {"x":x,"y":[y,...ys]}=point`},
+		{`export var [a] = f();`, `export var [a]=f()`},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Warnings", func(t *testing.T) {
+		tdt := []struct {
+			input    string
+			expected []string
+		}{
+			{`match (b) { true => 1, false => 0 }`, nil},
+			{`match (b) { true => 1 }`, []string{"match warning - non-exhaustive match over booleans, missing false"}},
+			{`match (b) { true if x => 1, false => 0 }`, []string{"match warning - non-exhaustive match over booleans, missing true"}},
+			{`match (b) { true => 1, x => 0 }`, nil},
+			{`match (x) { _ => 1, 2 => 2 }`, []string{"match warning - unreachable arm 2"}},
+			{`match (x) { y if y => 1, 2 => 2 }`, nil},
+			{`match (x) { 1 => 1, 1 => 2, [a] => 3 }`, []string{"match warning - unreachable arm 1"}},
+			{`match (b) { true => 1, false => 0, _ => 2 }`, []string{"match warning - unreachable arm _"}},
+			{`match (x) { true => 1, false => 0, 3 => 2 }`, nil},
+			{`match (x) { true => 1, 2 => 2 }`, nil},
+			{`match (x) { true => 1, [a] => 2 }`, nil},
+		}
+		for _, tc := range tdt {
+			tree := ParseWithPositions(lexer.TokenizeWithPositions(tc.input))
+			assertNoErrors(t, tree.Errors)
+			var messages []string
+			for _, w := range tree.Warnings {
+				messages = append(messages, w.Error())
+			}
+			assert.Equal(t, tc.expected, messages, tc.input)
+		}
+
+		tree := ParseWithPositions(lexer.TokenizeWithPositions("match (x) {\n\t_ => 1,\n\t[a] => 2\n}"))
+		require.Len(t, tree.Warnings, 1)
+		assert.Equal(t, lexer.Position{Line: 3, Column: 2}, tree.Warnings[0].(*SyntaxError).Pos)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		inputs := []string{
+			`match x { _ => 1 }`,
+			`match (x) _ => 1`,
+			`match (x) { _ => 1 _ => 2 }`,
+			`match (x) { _ 1 }`,
+			`match (x) { _ => }`,
+			`match (x) { f(a) => 1 }`,
+			`match (x) { [a, a] => 1 }`,
+			`match (x) { [...a, b] => 1 }`,
+			`match (x) { [...1] => 1 }`,
+			`match (x) { {k: v} => 1 }`,
+			`match (x) { {"k" v} => 1 }`,
+			`match (x) { _ if => 1 }`,
+			`var [a, b];`,
+			`var {"a": x, "b": x} = h;`,
+		}
+		for _, input := range inputs {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestStructs(t *testing.T) {
+	t.Run("Declarations", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions(`struct Point { x, y }
+fn (p: Point) scale(k) { p.x = p.x * k; p }`))
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 2)
+
+		st, ok := tree.Statements[0].(*StructStatement)
+		require.True(t, ok, "struct statement not found")
+		assert.Equal(t, "Point", st.Name)
+		assert.Equal(t, []string{"x", "y"}, st.Fields)
+		assert.Equal(t, []lexer.Position{{Line: 1, Column: 16}, {Line: 1, Column: 19}}, st.FieldPos)
+		assert.Equal(t, Span{lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 22}}, SpanOf(st))
+
+		method, ok := tree.Statements[1].(*MethodStatement)
+		require.True(t, ok, "method statement not found")
+		assert.Equal(t, "p:Point", method.Receiver.String())
+		assert.Equal(t, "scale", method.Name)
+		assert.Equal(t, lexer.Position{Line: 2, Column: 15}, method.NamePos)
+		require.Len(t, method.Function.Parameters, 1)
+		require.Len(t, method.Function.Body.Statements, 2)
+		assign, ok := method.Function.Body.Statements[0].(*AssignStatement)
+		require.True(t, ok, "assign statement not found")
+		assert.Equal(t, "p.x", assign.Target.String())
+		assert.Equal(t, lexer.Position{Line: 2, Column: 26}, assign.Pos)
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Empty {}`, `struct Empty{}`},
+		{`struct P { a, b, }`, `struct P{a,b}`},
+		{`struct P { a }; b;`, `struct P{a}b`},
+		{`fn (p: P) f() { p }; b;`, `fn(p:P) f() pb`},
+		{`fn (p: P) f(a, b: int): int { a }`, `fn(p:P) f(a,b:int):int a`},
+		{`fn(p: P) { p }`, `fn(p:P) p`},
+		{`a.b.c = f(1) + 2;`, `a.b.c=(f(1)+2)`},
+		{`P(1, 2).a;`, `P(1,2).a`},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		inputs := []string{
+			`struct { x }`,
+			`struct P x, y`,
+			`struct P { x y }`,
+			`struct P { x, x }`,
+			`struct P { 1 }`,
+			`fn (p: P) f { p }`,
+			`x = 1;`,
+			`f(a) = 1;`,
+			`a.b = ;`,
+		}
+		for _, input := range inputs {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	t.Run("Parts", func(t *testing.T) {
+		tree := ParseWithPositions(lexer.TokenizeWithPositions("var s = `a\\t${x + {\"k\": 1}[\"k\"]} \\`b\\${c}\n${`${y}`}`;"))
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+
+		s, ok := tree.Statements[0].(*VarStatementNode).Value.(*InterpolatedString)
+		require.True(t, ok, "interpolated string not found")
+		assert.Equal(t, []string{"a\t", " `b${c}\n", ""}, s.Parts)
+		require.Len(t, s.Values, 2)
+		assert.Equal(t, `(x+({"k":1}["k"]))`, s.Values[0].String())
+		assert.Equal(t, "`${y}`", s.Values[1].String())
+		assert.Equal(t, Span{lexer.Position{Line: 1, Column: 9}, lexer.Position{Line: 2, Column: 11}}, SpanOf(s))
+	})
+
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"``;", "``"},
+		{"`plain`;", "`plain`"},
+		{"`${a}${b}`;", "`${a}${b}`"},
+		{"`\\\\ \\${}`;", "`\\\\ \\${}`"},
+		{"f(`${1}`, 2);", "f(`${1}`,2)"},
+		{"`a` + `b`;", "(`a`+`b`)"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		inputs := []string{
+			"`abc",
+			"`a ${x",
+			"`a ${}`",
+			"`a ${x y}`",
+			"`\\q`",
+		}
+		for _, input := range inputs {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestMacros(t *testing.T) {
+	tree := ParseWithPositions(lexer.TokenizeWithPositions(`var unless = macro(cond, body) {
+	quote(if (!unquote(cond)) { unquote(body) })
+};`))
+	assertNoErrors(t, tree.Errors)
+	require.Len(t, tree.Statements, 1)
+
+	macro, ok := tree.Statements[0].(*VarStatementNode).Value.(*MacroLiteral)
+	require.True(t, ok, "macro literal not found")
+	require.Len(t, macro.Parameters, 2)
+	assert.Equal(t, "body", macro.Parameters[1].Name)
+	assert.Equal(t, "macro(cond,body) quote(if(!unquote(cond)) unquote(body))", macro.String())
+	assert.Equal(t, Span{lexer.Position{Line: 1, Column: 14}, lexer.Position{Line: 3, Column: 2}}, SpanOf(macro))
+
+	t.Run("Invalid", func(t *testing.T) {
+		inputs := []string{
+			`macro { 1 }`,
+			`macro(a: int) { a }`,
+			`macro(a) a`,
+			`macro(a, 1) { a }`,
+		}
+		for _, input := range inputs {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	t.Run("Var statement", func(t *testing.T) {
+		tree := parse(`var x: int = 5; var y: bool;`)
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 2)
+
+		x := assertVarStatement(t, tree.Statements[0], "x")
+		require.NotNil(t, x.Type)
+		assert.Equal(t, "int", x.Type.String())
+		assertInteger(t, x.Value, 5)
+
+		y := assertVarStatement(t, tree.Statements[1], "y")
+		require.NotNil(t, y.Type)
+		assert.Equal(t, "bool", y.Type.String())
+		assert.Nil(t, y.Value)
+	})
+
+	t.Run("Function literal", func(t *testing.T) {
+		tree := parse(`fn(a: int, b: string, c): bool { true }`)
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+
+		fn := assertFunctionLiteral(t, assertExpressionStatement(t, tree.Statements[0]).Value)
+		require.Len(t, fn.Parameters, 3)
+		assert.Equal(t, "int", fn.Parameters[0].Type.String())
+		assert.Equal(t, "string", fn.Parameters[1].Type.String())
+		assert.Nil(t, fn.Parameters[2].Type)
+		require.NotNil(t, fn.ReturnType)
+		assert.Equal(t, "bool", fn.ReturnType.String())
+	})
+
+	t.Run("Function type", func(t *testing.T) {
+		tree := parse(`var apply: fn(fn(int): int, int): int;`)
+		assertNoErrors(t, tree.Errors)
+		require.Len(t, tree.Statements, 1)
+
+		v := assertVarStatement(t, tree.Statements[0], "apply")
+		require.NotNil(t, v.Type)
+		assert.Equal(t, "fn(fn(int): int, int): int", v.Type.String())
+	})
+
+	t.Run("Invalid annotations", func(t *testing.T) {
+		for _, input := range []string{`var x: = 5;`, `var x: 5;`, `fn(a:) {}`, `fn(): {}`, `var f: fn(int;`} {
+			assertSomeErrors(t, parse(input).Errors)
+		}
+	})
+}
+
+func TestNodePositions(t *testing.T) {
+	tree := ParseWithPositions(lexer.TokenizeWithPositions(`var x = 1;
+add(x, !true);`))
+	assertNoErrors(t, tree.Errors)
+	require.Len(t, tree.Statements, 2)
+
+	varSt := assertVarStatement(t, tree.Statements[0], "x")
+	assert.Equal(t, lexer.Position{Line: 1, Column: 1}, varSt.Position())
+	assert.Equal(t, lexer.Position{Line: 1, Column: 5}, varSt.NamePos)
+	assert.Equal(t, lexer.Position{Line: 1, Column: 9}, varSt.Value.Position())
+
+	exp := assertExpressionStatement(t, tree.Statements[1])
+	assert.Equal(t, lexer.Position{Line: 2, Column: 1}, exp.Position())
+
+	call, ok := exp.Value.(*CallExpression)
+	require.True(t, ok, "call expression not found")
+	assert.Equal(t, lexer.Position{Line: 2, Column: 4}, call.Position())
+	assert.Equal(t, lexer.Position{Line: 2, Column: 1}, call.Function.Position())
+	assert.Equal(t, lexer.Position{Line: 2, Column: 5}, call.Arguments[0].Position())
+
+	not := assertPrefixExpr(t, call.Arguments[1], "!")
+	assert.Equal(t, lexer.Position{Line: 2, Column: 8}, not.Position())
+	assert.Equal(t, lexer.Position{Line: 2, Column: 9}, not.Right.Position())
+}
+
+func TestSyntaxErrorPositions(t *testing.T) {
+	tree := ParseWithPositions(lexer.TokenizeWithPositions(`var x = 1;
+var y = 2 var z = 3;`))
+	require.Len(t, tree.Errors, 1)
+
+	err, ok := tree.Errors[0].(*SyntaxError)
+	require.True(t, ok, "syntax error expected, got %T", tree.Errors[0])
+	assert.Equal(t, "var error - expected semicolon after expression, got Keyword", err.Error())
+	assert.Equal(t, lexer.Position{Line: 2, Column: 9}, err.Pos)
+}
+
+func TestStatementsString(t *testing.T) {
+	tdt := []struct {
+		input    string
+		expected string
+	}{
+		{"var x = 1 + 2;", "var x=(1+2)"},
+		{"var x: int;", "var x:int"},
+		{"return a * b;", "return (a*b)"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.input, func(t *testing.T) {
+			tree := parse(tc.input)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+}
+
+func TestSemicolonInsertion(t *testing.T) {
+	tdt := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"statements on lines", "var x = 1\nvar y = x + 2\ny", "var x=1var y=(x+2)y"},
+		{"new line ends expression", "var x = a\n(b)\n-c", "var x=ab(-c)"},
+		{"comments and blank lines", "a // first\n\n// second\nb", "ab"},
+		{"arguments on lines", "f(1,\n\t2\n)", "f(1,2)"},
+		{"method chain", "a\n\t.b(1)\n\t.c", "a.b(1).c"},
+		{"else on next line", "if (a) {\n\tb\n}\nelse {\n\tc\n}", "ifa b else c"},
+		{"catch on next line", "try {\n\ta()\n}\ncatch (e) {\n\te\n}", "try a() catch (e) e"},
+		{"last statement of block", "fn() { var x = 1 }", "fn() var x=1"},
+		{"return without value", "fn() {\n\treturn\n}", "fn() return"},
+		{"statement ending with block", "struct P { x } var p = P(1)", "struct P{x}var p=P(1)"},
+		{"hash on lines", "var h = {\n\t\"a\": 1,\n\t\"b\": 2\n}", `var h={"a":1,"b":2}`},
+		{"interpolation on lines", "`${a +\n\tb}`", "`${(a+b)}`"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			tree := ParseWithPositions(lexer.TokenizeWithPositions(tc.input))
+			assertNoErrors(t, tree.Errors)
+			assert.Equal(t, tc.expected, tree.String())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		tdt := []struct {
+			input         string
+			expectedError string
+		}{
+			{"1 2", "expression error - expected semicolon after expression, got Number"},
+			{"var x = 1 var y = 2", "var error - expected semicolon after expression, got Keyword"},
+			{"return 1 2", "return error - expected semicolon after expression, got Number"},
+			{"import \"a.mk\" as a b", "import error - expected semicolon after alias, got Identifier"},
+		}
+		for _, tc := range tdt {
+			tree := parse(tc.input)
+			require.NotEmpty(t, tree.Errors, tc.input)
+			assert.EqualError(t, tree.Errors[0], tc.expectedError)
+		}
+	})
+
+	t.Run("Strict mode", func(t *testing.T) {
+		for _, input := range []string{
+			"var x = 1\nvar y = 2;",
+			"fn() { return 1 }",
+			"throw 1",
+			`import "a.mk" as a`,
+			"var [a, b] = pair",
+			"a.b = 1",
+		} {
+			assertSomeErrors(t, parseStrict(input).Errors)
+		}
+
+		tree := parseStrict("var x = a\n(b);\nreturn\n1;\nx\ny")
+		assertNoErrors(t, tree.Errors)
+		assert.Equal(t, "var x=a(b)return 1xy", tree.String())
+	})
+}
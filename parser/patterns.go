@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"strings"
+)
+
+// Pattern is matched against a value by match arms and destructuring var statements,
+// names bound by the pattern get parts of the value
+type Pattern interface {
+	Node
+	matchPattern()
+}
+
+// LiteralPattern matches values equal to the literal, 1, -1.5, "s" or true
+type LiteralPattern struct {
+	Value ExpressionNode // integer, float, string or boolean literal, negative numbers are prefix expressions
+}
+
+func (l *LiteralPattern) TokenLiteral() string {
+	return l.Value.TokenLiteral()
+}
+
+func (l *LiteralPattern) String() string {
+	return l.Value.String()
+}
+
+func (l *LiteralPattern) Position() lexer.Position {
+	return l.Value.Position()
+}
+
+func (l *LiteralPattern) matchPattern() {}
+
+// BindingPattern matches any value and binds it to the name
+type BindingPattern struct {
+	Name string
+	Pos  lexer.Position
+}
+
+func (b *BindingPattern) TokenLiteral() string {
+	return b.Name
+}
+
+func (b *BindingPattern) String() string {
+	return b.Name
+}
+
+func (b *BindingPattern) Position() lexer.Position {
+	return b.Pos
+}
+
+func (b *BindingPattern) matchPattern() {}
+
+// WildcardPattern matches any value without binding it, _
+type WildcardPattern struct {
+	Pos lexer.Position
+}
+
+func (w *WildcardPattern) TokenLiteral() string {
+	return "_"
+}
+
+func (w *WildcardPattern) String() string {
+	return "_"
+}
+
+func (w *WildcardPattern) Position() lexer.Position {
+	return w.Pos
+}
+
+func (w *WildcardPattern) matchPattern() {}
+
+// ArrayPattern matches arrays element by element, [a, b, ...rest]. Without rest the lengths must be equal
+type ArrayPattern struct {
+	Elements []Pattern
+	Rest     Pattern        // binding or wildcard getting the remaining elements, nil without rest
+	Pos      lexer.Position // opening bracket
+	End      lexer.Position // closing bracket
+}
+
+func (a *ArrayPattern) TokenLiteral() string {
+	return "["
+}
+
+func (a *ArrayPattern) String() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.String())
+	}
+	if a.Rest != nil {
+		elements = append(elements, "..."+a.Rest.String())
+	}
+	return "[" + strings.Join(elements, ",") + "]"
+}
+
+func (a *ArrayPattern) Position() lexer.Position {
+	return a.Pos
+}
+
+func (a *ArrayPattern) matchPattern() {}
+
+// HashPattern matches hashes containing all the keys, {"k": v}. Other keys are ignored
+type HashPattern struct {
+	Keys   []ExpressionNode // literals
+	Values []Pattern
+	Pos    lexer.Position // opening curly
+	End    lexer.Position // closing curly
+}
+
+func (h *HashPattern) TokenLiteral() string {
+	return "{"
+}
+
+func (h *HashPattern) String() string {
+	pairs := []string{}
+	for i := range h.Keys {
+		pairs = append(pairs, h.Keys[i].String()+":"+h.Values[i].String())
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (h *HashPattern) Position() lexer.Position {
+	return h.Pos
+}
+
+func (h *HashPattern) matchPattern() {}
+
+// Bindings lists names bound by the pattern in source order
+func Bindings(pattern Pattern) []*BindingPattern {
+	out := []*BindingPattern{}
+	Inspect(pattern, func(n Node) bool {
+		if b, ok := n.(*BindingPattern); ok {
+			out = append(out, b)
+		}
+		return true
+	})
+	return out
+}
+
+func (p *parser) parsePattern() Pattern {
+	tok := p.currentToken
+	literal := func(value ExpressionNode) Pattern {
+		if value == nil {
+			return nil
+		}
+		return &LiteralPattern{Value: value}
+	}
+
+	switch {
+	case isIdentifier(tok) && tok.Lexeme == "_":
+		return &WildcardPattern{Pos: p.currentPos}
+	case isIdentifier(tok):
+		return &BindingPattern{Name: tok.Lexeme, Pos: p.currentPos}
+	case isNumberLiteral(tok):
+		return literal(p.parseIntegerLiteralExpression())
+	case minus(tok) && isNumberLiteral(p.nextToken):
+		pos := p.currentPos
+		p.advanceToken()
+		number := p.parseIntegerLiteralExpression()
+		if number == nil {
+			return nil
+		}
+		return literal(&PrefixExpression{Operator: "-", Right: number, Pos: pos})
+	case isString(tok):
+		return literal(p.parseStringLiteralExpression())
+	case isBoolean(tok):
+		return literal(p.parseBooleanExpression())
+	case isOpeningBracket(tok):
+		return p.parseArrayPattern()
+	case isOpeningCurly(tok):
+		return p.parseHashPattern()
+	}
+	p.addError(fmt.Errorf("pattern error - unexpected %v", tok.Lexeme))
+	return nil
+}
+
+func (p *parser) parseArrayPattern() Pattern {
+	out := &ArrayPattern{Elements: []Pattern{}, Pos: p.currentPos}
+	for !isClosingBracket(p.nextToken) {
+		if out.Rest != nil {
+			p.addError(fmt.Errorf("pattern error - rest must be the last element, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		if len(out.Elements) > 0 {
+			if !isComma(p.nextToken) {
+				p.addError(fmt.Errorf("pattern error - expected comma or closing bracket, got %v", p.nextToken.Lexeme))
+				return nil
+			}
+			p.advanceToken()
+		}
+		p.advanceToken()
+
+		if ellipsis(p.currentToken) {
+			if !isIdentifier(p.nextToken) {
+				p.addError(fmt.Errorf("pattern error - expected name after ..., got %v", p.nextToken.Class))
+				return nil
+			}
+			p.advanceToken()
+			out.Rest = p.parsePattern()
+			continue
+		}
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		out.Elements = append(out.Elements, element)
+	}
+	p.advanceToken()
+	out.End = p.currentPos
+	return out
+}
+
+func (p *parser) parseHashPattern() Pattern {
+	out := &HashPattern{Keys: []ExpressionNode{}, Values: []Pattern{}, Pos: p.currentPos}
+	for !isClosingCurly(p.nextToken) {
+		if len(out.Keys) > 0 {
+			if !isComma(p.nextToken) {
+				p.addError(fmt.Errorf("pattern error - expected comma or closing curly, got %v", p.nextToken.Lexeme))
+				return nil
+			}
+			p.advanceToken()
+		}
+		p.advanceToken()
+
+		var key ExpressionNode
+		switch {
+		case isString(p.currentToken):
+			key = p.parseStringLiteralExpression()
+		case isNumberLiteral(p.currentToken):
+			key = p.parseIntegerLiteralExpression()
+		case isBoolean(p.currentToken):
+			key = p.parseBooleanExpression()
+		default:
+			p.addError(fmt.Errorf("pattern error - expected literal key, got %v", p.currentToken.Lexeme))
+			return nil
+		}
+		if key == nil {
+			return nil
+		}
+		if !isColon(p.nextToken) {
+			p.addError(fmt.Errorf("pattern error - expected colon after key, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		p.advanceToken()
+		p.advanceToken()
+		value := p.parsePattern()
+		if value == nil {
+			return nil
+		}
+		out.Keys = append(out.Keys, key)
+		out.Values = append(out.Values, value)
+	}
+	p.advanceToken()
+	out.End = p.currentPos
+	return out
+}
+
+// checkBindings reports names bound more than once by the pattern
+func (p *parser) checkBindings(pattern Pattern) bool {
+	seen := map[string]bool{}
+	for _, b := range Bindings(pattern) {
+		if seen[b.Name] {
+			p.addError(fmt.Errorf("pattern error - %v is bound more than once", b.Name))
+			return false
+		}
+		seen[b.Name] = true
+	}
+	return true
+}
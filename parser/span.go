@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"programming-lang/lexer"
+	"strconv"
+)
+
+// Span is the source range of a node, End points right after the last character
+type Span struct {
+	Start lexer.Position
+	End   lexer.Position
+}
+
+// SpanOf computes source range of the node from positions of its tokens.
+// Parentheses of grouped expressions and trailing semicolons are not included.
+// Nodes missing in trees of incomplete code have empty span
+func SpanOf(node Node) Span {
+	if isNilNode(node) {
+		return Span{}
+	}
+	switch n := node.(type) {
+	case *Program:
+		if len(n.Statements) == 0 {
+			return Span{}
+		}
+		return Span{SpanOf(n.Statements[0]).Start, SpanOf(n.Statements[len(n.Statements)-1]).End}
+	case *VarStatementNode:
+		end := after(n.NamePos, n.Name)
+		if n.Value != nil {
+			end = SpanOf(n.Value).End
+		} else if n.Type != nil {
+			end = SpanOf(n.Type).End
+		}
+		return Span{n.Pos, end}
+	case *ReturnStatementNode:
+		if n.Value == nil {
+			return Span{n.Pos, after(n.Pos, "return")}
+		}
+		return Span{n.Pos, SpanOf(n.Value).End}
+	case *ExpressionStatementNode:
+		if n.Value == nil {
+			return Span{n.Pos, after(n.Pos, n.Token.Lexeme)}
+		}
+		return Span{n.Pos, SpanOf(n.Value).End}
+	case *BlockStatement:
+		return Span{n.Pos, after(n.End, "}")}
+	case *ImportStatement:
+		return Span{n.Pos, after(n.AliasPos, n.Alias)}
+	case *ExportStatement:
+		return Span{n.Pos, SpanOf(n.Statement).End}
+	case *ThrowStatement:
+		return Span{n.Pos, endOf(n.Value, after(n.Pos, "throw"))}
+	case *TryStatement:
+		if n.Finally != nil {
+			return Span{n.Pos, SpanOf(n.Finally).End}
+		}
+		return Span{n.Pos, SpanOf(n.Catch).End}
+	case *StructStatement:
+		return Span{n.Pos, after(n.End, "}")}
+	case *MethodStatement:
+		return Span{n.Pos, SpanOf(n.Function).End}
+	case *AssignStatement:
+		return Span{n.Pos, endOf(n.Value, SpanOf(n.Target).End)}
+	case *IntegerLiteralExpression:
+		return Span{n.Pos, after(n.Pos, strconv.Itoa(n.Value))}
+	case *FloatLiteralExpression:
+		return Span{n.Pos, after(n.Pos, n.String())}
+	case *IdentifierExpression:
+		return Span{n.Pos, after(n.Pos, n.Name)}
+	case *StringLiteralExpression:
+		return Span{n.Pos, after(n.Pos, n.String())}
+	case *InterpolatedString:
+		return Span{n.Pos, after(n.End, "`")}
+	case *BooleanExpression:
+		return Span{n.Pos, after(n.Pos, n.String())}
+	case *PrefixExpression:
+		return Span{n.Pos, endOf(n.Right, after(n.Pos, n.Operator))}
+	case *InfixExpression:
+		return Span{SpanOf(n.Left).Start, endOf(n.Right, after(n.Pos, n.Operator))}
+	case *IfExpression:
+		if n.Alternative != nil {
+			return Span{n.Pos, SpanOf(n.Alternative).End}
+		}
+		return Span{n.Pos, SpanOf(n.Consequence).End}
+	case *ConditionalExpression:
+		return Span{SpanOf(n.Condition).Start, endOf(n.Alternative, after(n.Pos, "?"))}
+	case *FunctionLiteralExpression:
+		return Span{n.Pos, SpanOf(n.Body).End}
+	case *MacroLiteral:
+		return Span{n.Pos, SpanOf(n.Body).End}
+	case *FunctionParameter:
+		if n.Type != nil {
+			return Span{n.Pos, SpanOf(n.Type).End}
+		}
+		return Span{n.Pos, after(n.Pos, n.Name)}
+	case *CallExpression:
+		return Span{SpanOf(n.Function).Start, after(n.End, ")")}
+	case *MemberExpression:
+		return Span{SpanOf(n.Object).Start, after(n.MemberPos, n.Member)}
+	case *ArrayLiteralExpression:
+		return Span{n.Pos, after(n.End, "]")}
+	case *IndexExpression:
+		return Span{SpanOf(n.Left).Start, after(n.End, "]")}
+	case *HashLiteralExpression:
+		return Span{n.Pos, after(n.End, "}")}
+	case *SpawnExpression:
+		return Span{n.Pos, SpanOf(n.Call).End}
+	case *SelectExpression:
+		return Span{n.Pos, after(n.End, "}")}
+	case *SelectCase:
+		return Span{n.Pos, SpanOf(n.Body).End}
+	case *MatchExpression:
+		return Span{n.Pos, after(n.End, "}")}
+	case *MatchArm:
+		return Span{SpanOf(n.Pattern).Start, SpanOf(n.Body).End}
+	case *LiteralPattern:
+		return SpanOf(n.Value)
+	case *BindingPattern:
+		return Span{n.Pos, after(n.Pos, n.Name)}
+	case *WildcardPattern:
+		return Span{n.Pos, after(n.Pos, "_")}
+	case *ArrayPattern:
+		return Span{n.Pos, after(n.End, "]")}
+	case *HashPattern:
+		return Span{n.Pos, after(n.End, "}")}
+	case *TypeAnnotation:
+		if n.Return != nil {
+			return Span{n.Pos, SpanOf(n.Return).End}
+		} else if n.Name == "fn" {
+			return Span{n.Pos, after(n.End, ")")}
+		}
+		return Span{n.Pos, after(n.Pos, n.Name)}
+	}
+	return Span{node.Position(), node.Position()}
+}
+
+// endOf is the end of the node, or the fallback when the node is missing in incomplete code
+func endOf(node Node, fallback lexer.Position) lexer.Position {
+	if isNilNode(node) {
+		return fallback
+	}
+	return SpanOf(node).End
+}
+
+// after is the position following single line text starting at pos
+func after(pos lexer.Position, text string) lexer.Position {
+	return lexer.Position{Line: pos.Line, Column: pos.Column + len([]rune(text))}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"programming-lang/lexer"
+	"strconv"
 )
 
 type StatementNode interface {
@@ -13,7 +14,11 @@ type StatementNode interface {
 
 type VarStatementNode struct {
 	Name  string
+	Type  *TypeAnnotation // nil when not annotated
 	Value ExpressionNode
+	Pos   lexer.Position
+	NamePos lexer.Position
+	Pattern Pattern // destructuring array or hash pattern, var [a, b] = pair; Name is empty then
 }
 
 func (vsn *VarStatementNode) TokenLiteral() string {
@@ -22,16 +27,27 @@ func (vsn *VarStatementNode) TokenLiteral() string {
 
 func (vsn *VarStatementNode) String() string {
 	str := "var " + vsn.Name
+	if vsn.Pattern != nil {
+		str = "var " + vsn.Pattern.String()
+	}
+	if vsn.Type != nil {
+		str += ":" + vsn.Type.String()
+	}
 	if vsn.Value != nil {
-		str += vsn.String()
+		str += "=" + vsn.Value.String()
 	}
 	return str
 }
 
+func (vsn *VarStatementNode) Position() lexer.Position {
+	return vsn.Pos
+}
+
 func (vsn *VarStatementNode) evaluateStatement() {}
 
 type ReturnStatementNode struct {
 	Value ExpressionNode
+	Pos   lexer.Position
 }
 
 func (r *ReturnStatementNode) TokenLiteral() string {
@@ -40,16 +56,20 @@ func (r *ReturnStatementNode) TokenLiteral() string {
 func (r *ReturnStatementNode) String() string {
 	str := "return"
 	if r.Value != nil {
-		str += r.String()
+		str += " " + r.Value.String()
 	}
 	return str
 }
+func (r *ReturnStatementNode) Position() lexer.Position {
+	return r.Pos
+}
 func (r *ReturnStatementNode) evaluateStatement() {}
 
 // Statement wrapper for expressions, required for pratt parsing
 type ExpressionStatementNode struct {
 	Token lexer.Token //first token
 	Value ExpressionNode
+	Pos   lexer.Position
 }
 
 func (e *ExpressionStatementNode) TokenLiteral() string {
@@ -63,11 +83,17 @@ func (e *ExpressionStatementNode) String() string {
 	return e.Value.String()
 }
 
+func (e *ExpressionStatementNode) Position() lexer.Position {
+	return e.Pos
+}
+
 func (e *ExpressionStatementNode) evaluateStatement() {}
 
 
 type BlockStatement struct {
 	Statements []StatementNode
+	Pos        lexer.Position
+	End        lexer.Position // closing curly brace
 }
 
 func (b *BlockStatement) TokenLiteral() string {
@@ -82,21 +108,130 @@ func (b *BlockStatement) String() string {
 	return out
 }
 
+func (b *BlockStatement) Position() lexer.Position {
+	return b.Pos
+}
+
 func (b *BlockStatement) evaluateStatement() {}
 
 
+// ImportStatement binds module loaded from the path to the alias, import "lib.mk" as lib;
+type ImportStatement struct {
+	Path     string
+	Alias    string
+	Pos      lexer.Position
+	PathPos  lexer.Position
+	AliasPos lexer.Position
+}
+
+func (i *ImportStatement) TokenLiteral() string {
+	return "import"
+}
+
+func (i *ImportStatement) String() string {
+	return "import " + strconv.Quote(i.Path) + " as " + i.Alias
+}
+
+func (i *ImportStatement) Position() lexer.Position {
+	return i.Pos
+}
+
+func (i *ImportStatement) evaluateStatement() {}
+
+// ExportStatement makes the declared var visible to modules importing this one
+type ExportStatement struct {
+	Statement *VarStatementNode
+	Pos       lexer.Position
+}
+
+func (e *ExportStatement) TokenLiteral() string {
+	return "export"
+}
+
+func (e *ExportStatement) String() string {
+	return "export " + e.Statement.String()
+}
+
+func (e *ExportStatement) Position() lexer.Position {
+	return e.Pos
+}
+
+func (e *ExportStatement) evaluateStatement() {}
+
+// ThrowStatement raises the value as an error, throw "message";
+type ThrowStatement struct {
+	Value ExpressionNode
+	Pos   lexer.Position
+}
+
+func (t *ThrowStatement) TokenLiteral() string {
+	return "throw"
+}
+
+func (t *ThrowStatement) String() string {
+	return "throw " + t.Value.String()
+}
+
+func (t *ThrowStatement) Position() lexer.Position {
+	return t.Pos
+}
+
+func (t *ThrowStatement) evaluateStatement() {}
+
+// TryStatement runs the body, its errors are handled by the catch block and the finally block runs anyway,
+// try { } catch (e) { } finally { }. One of catch and finally may be left out
+type TryStatement struct {
+	Body    *BlockStatement
+	Name    string // caught error, empty without catch
+	NamePos lexer.Position
+	Catch   *BlockStatement // nil without catch
+	Finally *BlockStatement // nil without finally
+	Pos     lexer.Position
+}
+
+func (t *TryStatement) TokenLiteral() string {
+	return "try"
+}
+
+func (t *TryStatement) String() string {
+	out := "try " + t.Body.String()
+	if t.Catch != nil {
+		out += " catch (" + t.Name + ") " + t.Catch.String()
+	}
+	if t.Finally != nil {
+		out += " finally " + t.Finally.String()
+	}
+	return out
+}
+
+func (t *TryStatement) Position() lexer.Position {
+	return t.Pos
+}
+
+func (t *TryStatement) evaluateStatement() {}
+
 func (p *parser) parseVarStatement() StatementNode {	
+	pos := p.currentPos
+	if isOpeningBracket(p.nextToken) || isOpeningCurly(p.nextToken) {
+		return p.parseDestructuring()
+	}
 	if !isIdentifier(p.nextToken) {
 		p.addError(fmt.Errorf("var error - expected identifier, got %v", p.nextToken.Class))
 		return nil
 	}
 	p.advanceToken()
 	identifierTok := p.currentToken
+	namePos := p.currentPos
 
-	if isSemicolon(p.nextToken) {
-		p.advanceToken()
-		return &VarStatementNode{Name: identifierTok.Lexeme}
-	} else if !isAssignmentOperator(p.nextToken) {
+	varType, ok := p.parseOptionalTypeAnnotation()
+	if !ok {
+		return nil
+	}
+
+	if !isAssignmentOperator(p.nextToken) {
+		if p.endStatement() {
+			return &VarStatementNode{Name: identifierTok.Lexeme, Type: varType, Pos: pos, NamePos: namePos}
+		}
 		p.addError(fmt.Errorf("var error - expected assignment after identifier, got %v", p.nextToken.Class))
 		return nil
 	}
@@ -105,55 +240,221 @@ func (p *parser) parseVarStatement() StatementNode {
 	p.advanceToken() // expression
 
 	exp := p.parseExpression(LOWEST)
-	out := &VarStatementNode{Name: identifierTok.Lexeme, Value: exp}
-	if !isSemicolon(p.nextToken) {
+	out := &VarStatementNode{Name: identifierTok.Lexeme, Type: varType, Value: exp, Pos: pos, NamePos: namePos}
+	if !p.endStatement() {
 		p.addError(fmt.Errorf("var error - expected semicolon after expression, got %v", p.nextToken.Class))
 		return nil
 	}
+	return out
+}
+
+// parseDestructuring parses var statement binding parts of the value, var [a, ...rest] = xs;
+func (p *parser) parseDestructuring() StatementNode {
+	pos := p.currentPos
 	p.advanceToken()
+	pattern := p.parsePattern()
+	if pattern == nil || !p.checkBindings(pattern) {
+		return nil
+	}
+	if !isAssignmentOperator(p.nextToken) {
+		p.addError(fmt.Errorf("var error - expected assignment after pattern, got %v", p.nextToken.Class))
+		return nil
+	}
+	p.advanceToken()
+	p.advanceToken()
+
+	out := &VarStatementNode{Pattern: pattern, Value: p.parseExpression(LOWEST), Pos: pos}
+	if out.Value == nil {
+		return nil
+	}
+	if !p.endStatement() {
+		p.addError(fmt.Errorf("var error - expected semicolon after expression, got %v", p.nextToken.Class))
+		return nil
+	}
 	return out
 }
 
 func (p *parser) parseReturnStatement() StatementNode {
+	pos := p.currentPos
+	// return without value ends with the line outside of strict mode
+	if !p.strict && p.endStatement() {
+		return &ReturnStatementNode{Pos: pos}
+	}
 	p.advanceToken()
 
 	exp := p.parseExpression(LOWEST)
-	out := &ReturnStatementNode{exp}
-	if !isSemicolon(p.nextToken) {
+	out := &ReturnStatementNode{Value: exp, Pos: pos}
+	if !p.endStatement() {
 		p.addError(fmt.Errorf("return error - expected semicolon after expression, got %v", p.nextToken.Class))
 		return nil
 	}
-	p.advanceToken()
 	return out
 }
 
 func (p *parser) parseExpressionStatement() StatementNode {
 	tok := p.currentToken
+	pos := p.currentPos
 	exp := p.parseExpression(LOWEST)
+	if exp != nil && isAssignmentOperator(p.nextToken) {
+		return p.parseAssignStatement(exp, pos)
+	}
 
-	if isSemicolon(p.nextToken) {
-		p.advanceToken()
+	if p.strict {
+		if isSemicolon(p.nextToken) {
+			p.advanceToken()
+		}
+	} else if exp != nil && !p.endStatement() {
+		p.addError(fmt.Errorf("expression error - expected semicolon after expression, got %v", p.nextToken.Class))
+		return nil
 	}
 
 	return &ExpressionStatementNode{
 		Token: tok,
 		Value: exp,
+		Pos:   pos,
 	}
 }
 
 func (p *parser) parseBlockStatement() *BlockStatement {
-	out := &BlockStatement{}
+	out := &BlockStatement{Pos: p.currentPos}
 	out.Statements = []StatementNode{}
 
 	p.advanceToken()
 
 	for !isClosingCurly(p.currentToken) && !p.eof() {
+		if isImplicitSemicolon(p.currentToken) {
+			p.advanceToken()
+			continue
+		}
+		topLevelOnly := importKeyword(p.currentToken) || exportKeyword(p.currentToken)
+		if topLevelOnly {
+			p.addError(fmt.Errorf("block error - %v is only allowed at top level", p.currentToken.Lexeme))
+		}
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if stmt != nil && !topLevelOnly {
 			out.Statements = append(out.Statements, stmt)
 		}
 		p.advanceToken()
 	}
+	out.End = p.currentPos
+
+	return out
+}
+
+func (p *parser) parseImportStatement() StatementNode {
+	out := &ImportStatement{Pos: p.currentPos}
+	if !isString(p.nextToken) {
+		p.addError(fmt.Errorf("import error - expected module path, got %v", p.nextToken.Class))
+		return nil
+	}
+	p.advanceToken()
+	path, err := strconv.Unquote(p.currentToken.Lexeme)
+	if err != nil {
+		p.addError(fmt.Errorf("import error - invalid module path %v", p.currentToken.Lexeme))
+		return nil
+	}
+	out.Path = path
+	out.PathPos = p.currentPos
+
+	if !asKeyword(p.nextToken) {
+		p.addError(fmt.Errorf("import error - expected as after module path, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	if !isIdentifier(p.nextToken) {
+		p.addError(fmt.Errorf("import error - expected module alias, got %v", p.nextToken.Class))
+		return nil
+	}
+	p.advanceToken()
+	out.Alias = p.currentToken.Lexeme
+	out.AliasPos = p.currentPos
+
+	if !p.endStatement() {
+		p.addError(fmt.Errorf("import error - expected semicolon after alias, got %v", p.nextToken.Class))
+		return nil
+	}
+	return out
+}
+
+func (p *parser) parseExportStatement() StatementNode {
+	pos := p.currentPos
+	if !isVarKeyword(p.nextToken) {
+		p.addError(fmt.Errorf("export error - expected var declaration, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	st, ok := p.parseVarStatement().(*VarStatementNode)
+	if !ok {
+		return nil
+	}
+	return &ExportStatement{Statement: st, Pos: pos}
+}
+
+func (p *parser) parseThrowStatement() StatementNode {
+	pos := p.currentPos
+	if isSemicolon(p.nextToken) || isImplicitSemicolon(p.nextToken) || eof(p.nextToken) {
+		p.addError(fmt.Errorf("throw error - expected value, got %v", p.nextToken.Class))
+		return nil
+	}
+	p.advanceToken()
+
+	out := &ThrowStatement{Value: p.parseExpression(LOWEST), Pos: pos}
+	if !p.endStatement() {
+		p.addError(fmt.Errorf("throw error - expected semicolon after expression, got %v", p.nextToken.Class))
+		return nil
+	}
+	return out
+}
+
+func (p *parser) parseTryStatement() StatementNode {
+	out := &TryStatement{Pos: p.currentPos}
+	if !isOpeningCurly(p.nextToken) {
+		p.addError(fmt.Errorf("try error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+	out.Body = p.parseBlockStatement()
+
+	if catchKeyword(p.nextToken) {
+		p.advanceToken()
+		if !isOpeningParent(p.nextToken) {
+			p.addError(fmt.Errorf("catch error - expected opening paren, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		p.advanceToken()
+		if !isIdentifier(p.nextToken) {
+			p.addError(fmt.Errorf("catch error - expected name, got %v", p.nextToken.Class))
+			return nil
+		}
+		p.advanceToken()
+		out.Name, out.NamePos = p.currentToken.Lexeme, p.currentPos
+		if !isClosingParent(p.nextToken) {
+			p.addError(fmt.Errorf("catch error - expected closing paren, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		p.advanceToken()
+		if !isOpeningCurly(p.nextToken) {
+			p.addError(fmt.Errorf("catch error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		p.advanceToken()
+		out.Catch = p.parseBlockStatement()
+	}
+
+	if finallyKeyword(p.nextToken) {
+		p.advanceToken()
+		if !isOpeningCurly(p.nextToken) {
+			p.addError(fmt.Errorf("finally error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		p.advanceToken()
+		out.Finally = p.parseBlockStatement()
+	}
 
+	if out.Catch == nil && out.Finally == nil {
+		p.addError(fmt.Errorf("try error - expected catch or finally, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.skipSemicolon()
 	return out
 }
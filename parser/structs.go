@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"strings"
+)
+
+// StructStatement declares a record type with named fields, struct Point { x, y }.
+// Calling the struct with values of the fields in declaration order creates an instance
+type StructStatement struct {
+	Name     string
+	Fields   []string
+	Pos      lexer.Position
+	NamePos  lexer.Position
+	FieldPos []lexer.Position
+	End      lexer.Position // closing curly
+}
+
+func (s *StructStatement) TokenLiteral() string {
+	return "struct"
+}
+
+func (s *StructStatement) String() string {
+	return "struct " + s.Name + "{" + strings.Join(s.Fields, ",") + "}"
+}
+
+func (s *StructStatement) Position() lexer.Position {
+	return s.Pos
+}
+
+func (s *StructStatement) evaluateStatement() {}
+
+// MethodStatement adds a function to the struct named by the type of the receiver,
+// fn (p: Point) norm() { p.x * p.x + p.y * p.y }. The receiver is passed before the other arguments
+type MethodStatement struct {
+	Receiver *FunctionParameter // always annotated with the struct name
+	Name     string
+	Function *FunctionLiteralExpression // parameters without the receiver, positioned at the name
+	Pos      lexer.Position
+	NamePos  lexer.Position
+}
+
+func (m *MethodStatement) TokenLiteral() string {
+	return "fn"
+}
+
+func (m *MethodStatement) String() string {
+	return "fn(" + m.Receiver.String() + ") " + m.Name + strings.TrimPrefix(m.Function.String(), "fn")
+}
+
+func (m *MethodStatement) Position() lexer.Position {
+	return m.Pos
+}
+
+func (m *MethodStatement) evaluateStatement() {}
+
+// AssignStatement changes a field of a struct instance, p.x = 1;
+type AssignStatement struct {
+	Target *MemberExpression
+	Value  ExpressionNode
+	Pos    lexer.Position
+}
+
+func (a *AssignStatement) TokenLiteral() string {
+	return "="
+}
+
+func (a *AssignStatement) String() string {
+	return a.Target.String() + "=" + a.Value.String()
+}
+
+func (a *AssignStatement) Position() lexer.Position {
+	return a.Pos
+}
+
+func (a *AssignStatement) evaluateStatement() {}
+
+func (p *parser) parseStructStatement() StatementNode {
+	out := &StructStatement{Fields: []string{}, FieldPos: []lexer.Position{}, Pos: p.currentPos}
+	if !isIdentifier(p.nextToken) {
+		p.addError(fmt.Errorf("struct error - expected name, got %v", p.nextToken.Class))
+		return nil
+	}
+	p.advanceToken()
+	out.Name, out.NamePos = p.currentToken.Lexeme, p.currentPos
+
+	if !isOpeningCurly(p.nextToken) {
+		p.addError(fmt.Errorf("struct error - missing opening curly brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	p.advanceToken()
+
+	for !isClosingCurly(p.nextToken) {
+		if len(out.Fields) > 0 {
+			if !isComma(p.nextToken) {
+				p.addError(fmt.Errorf("struct error - expected comma or closing curly, got %v", p.nextToken.Lexeme))
+				return nil
+			}
+			p.advanceToken()
+			// trailing comma
+			if isClosingCurly(p.nextToken) {
+				break
+			}
+		}
+		if !isIdentifier(p.nextToken) {
+			p.addError(fmt.Errorf("struct error - expected field name, got %v", p.nextToken.Class))
+			return nil
+		}
+		p.advanceToken()
+		for _, f := range out.Fields {
+			if f == p.currentToken.Lexeme {
+				p.addError(fmt.Errorf("struct error - field %v declared more than once", f))
+				return nil
+			}
+		}
+		out.Fields = append(out.Fields, p.currentToken.Lexeme)
+		out.FieldPos = append(out.FieldPos, p.currentPos)
+	}
+	p.advanceToken()
+	out.End = p.currentPos
+	p.skipSemicolon()
+	return out
+}
+
+// isMethodStatement looks ahead for fn (name: Type) name, a function literal never has a name after parameters
+func (p *parser) isMethodStatement() bool {
+	expected := []func(lexer.Token) bool{isOpeningParent, isIdentifier, isColon, isIdentifier, isClosingParent, isIdentifier}
+	for i, matches := range expected {
+		// tokens[p.idx] is the next token
+		idx := p.idx + i
+		if idx >= len(p.tokens) || !matches(p.tokens[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) parseMethodStatement() StatementNode {
+	out := &MethodStatement{Pos: p.currentPos}
+	p.advanceToken()
+	p.advanceToken()
+	out.Receiver = &FunctionParameter{Name: p.currentToken.Lexeme, Pos: p.currentPos}
+	receiverType, ok := p.parseOptionalTypeAnnotation()
+	if !ok {
+		return nil
+	}
+	out.Receiver.Type = receiverType
+	p.advanceToken()
+	p.advanceToken()
+	out.Name, out.NamePos = p.currentToken.Lexeme, p.currentPos
+
+	fn, ok := p.parseFunctionLiteralExpression().(*FunctionLiteralExpression)
+	if !ok {
+		return nil
+	}
+	out.Function = fn
+	p.skipSemicolon()
+	return out
+}
+
+// parseAssignStatement continues the statement after the target, only fields can be assigned
+func (p *parser) parseAssignStatement(target ExpressionNode, pos lexer.Position) StatementNode {
+	member, ok := target.(*MemberExpression)
+	if !ok {
+		p.addError(fmt.Errorf("assignment error - only fields can be assigned, got %v", target))
+		return nil
+	}
+	p.advanceToken()
+	p.advanceToken()
+
+	out := &AssignStatement{Target: member, Value: p.parseExpression(LOWEST), Pos: pos}
+	if out.Value == nil {
+		return nil
+	}
+	if !p.endStatement() {
+		p.addError(fmt.Errorf("assignment error - expected semicolon after expression, got %v", p.nextToken.Class))
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package parser
+
+// TailCalls returns calls in tail position of the function body, their result is the result
+// of the function, so the call can replace the frame of the caller instead of nesting in it.
+// Tail positions are the last statement of the body, branches of if, select and match expressions in tail
+// position and values of return statements. Calls inside try statements are never in tail position,
+// catch and finally blocks still need the frame, nested functions and macros are analysed on their own
+func TailCalls(body *BlockStatement) map[*CallExpression]bool {
+	out := map[*CallExpression]bool{}
+	markTail(body, true, out)
+	return out
+}
+
+func markTail(node Node, tail bool, out map[*CallExpression]bool) {
+	switch n := node.(type) {
+	case *CallExpression:
+		if tail {
+			out[n] = true
+		}
+	case *BlockStatement:
+		for i, st := range n.Statements {
+			markTail(st, tail && i == len(n.Statements)-1, out)
+		}
+		return
+	case *ExpressionStatementNode:
+		if n.Value != nil {
+			markTail(n.Value, tail, out)
+		}
+		return
+	case *ReturnStatementNode:
+		if n.Value != nil {
+			markTail(n.Value, true, out)
+		}
+		return
+	case *IfExpression:
+		markTail(n.Condition, false, out)
+		markTail(n.Consequence, tail, out)
+		if n.Alternative != nil {
+			markTail(n.Alternative, tail, out)
+		}
+		return
+	case *ConditionalExpression:
+		markTail(n.Condition, false, out)
+		markTail(n.Consequence, tail, out)
+		markTail(n.Alternative, tail, out)
+		return
+	case *SelectExpression:
+		for _, c := range n.Cases {
+			markTail(c.Comm, false, out)
+			markTail(c.Body, tail, out)
+		}
+		if n.Default != nil {
+			markTail(n.Default, tail, out)
+		}
+		return
+	case *MatchExpression:
+		markTail(n.Subject, false, out)
+		for _, a := range n.Arms {
+			if a.Guard != nil {
+				markTail(a.Guard, false, out)
+			}
+			markTail(a.Body, tail, out)
+		}
+		return
+	case *FunctionLiteralExpression, *MacroLiteral, *TryStatement:
+		return
+	}
+	// return statements may be nested deeper, like in blocks of an if expression used as a value
+	for _, child := range children(node) {
+		markTail(child, false, out)
+	}
+}
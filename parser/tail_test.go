@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"programming-lang/lexer"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTailCalls(t *testing.T) {
+	tdt := []struct {
+		body     string
+		expected []string
+	}{
+		{`{ f(1) }`, []string{"f(1)"}},
+		{`{ f(1); g(2) }`, []string{"g(2)"}},
+		{`{ return f(g(1)); }`, []string{"f(g(1))"}},
+		{`{ 1 + f(1) }`, []string{}},
+		{`{ if (f(1)) { g(1) } else { h(1) } }`, []string{"g(1)", "h(1)"}},
+		{`{ if (a) { g(1) }; h(1) }`, []string{"h(1)"}},
+		{`{ f(1) ? g(1) : h(1) }`, []string{"g(1)", "h(1)"}},
+		{`{ var x = if (a) { return g(1); } else { h(1) }; x }`, []string{"g(1)"}},
+		{`{ select { case <-c as v { f(v) } default { g(1) } } }`, []string{"f(v)", "g(1)"}},
+		{`{ try { return f(1); } catch (e) { g(1) } }`, []string{}},
+		{`{ fn() { f(1) } }`, []string{}},
+		{`{ spawn f(1) }`, []string{}},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.body, func(t *testing.T) {
+			program := Parse(lexer.Tokenize("fn() " + tc.body))
+			assertNoErrors(t, program.Errors)
+			function := program.Statements[0].(*ExpressionStatementNode).Value.(*FunctionLiteralExpression)
+
+			calls := []string{}
+			for call := range TailCalls(function.Body) {
+				calls = append(calls, call.String())
+			}
+			sort.Strings(calls)
+			assert.Equal(t, tc.expected, calls)
+		})
+	}
+}
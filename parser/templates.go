@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"strconv"
+	"strings"
+)
+
+// InterpolatedString is a template literal with embedded expressions, `sum: ${a + b}`.
+// Text parts are unescaped, there is one more of them than of values
+type InterpolatedString struct {
+	Parts  []string
+	Values []ExpressionNode
+	Pos    lexer.Position
+	End    lexer.Position // closing backtick
+}
+
+func (s *InterpolatedString) TokenLiteral() string {
+	return "`"
+}
+
+func (s *InterpolatedString) String() string {
+	out := "`" + EscapeTemplate(s.Parts[0])
+	for i, v := range s.Values {
+		out += "${" + v.String() + "}" + EscapeTemplate(s.Parts[i+1])
+	}
+	return out + "`"
+}
+
+func (s *InterpolatedString) Position() lexer.Position {
+	return s.Pos
+}
+
+func (s *InterpolatedString) evaluateExpression() {}
+
+var templateEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "${", `\${`)
+
+// EscapeTemplate prepares text to be printed inside a template literal, new lines are kept
+func EscapeTemplate(text string) string {
+	return templateEscaper.Replace(text)
+}
+
+func (p *parser) parseInterpolatedString() ExpressionNode {
+	out := &InterpolatedString{Parts: []string{}, Values: []ExpressionNode{}, Pos: p.currentPos}
+	for {
+		lexeme := p.currentToken.Lexeme
+		text, interpolation, err := unescapeTemplate(lexeme)
+		if err != nil {
+			p.addError(fmt.Errorf("template error - %v", err))
+			return nil
+		}
+		out.Parts = append(out.Parts, text)
+		if !interpolation {
+			out.End = p.currentPos.Advance(lexeme[:len(lexeme)-1])
+			return out
+		}
+
+		p.advanceToken()
+		if isTemplateContinuation(p.currentToken) {
+			p.addError(fmt.Errorf("template error - empty interpolation"))
+			return nil
+		}
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		out.Values = append(out.Values, value)
+		if !isTemplateContinuation(p.nextToken) {
+			p.addError(fmt.Errorf("template error - expected closing curly brace of interpolation, got %v", p.nextToken.Lexeme))
+			return nil
+		}
+		p.advanceToken()
+	}
+}
+
+// unescapeTemplate decodes text of a template token without its delimiters,
+// reports whether an interpolation follows the text
+func unescapeTemplate(lexeme string) (string, bool, error) {
+	var out strings.Builder
+	rest := lexeme[1:]
+	for len(rest) > 0 {
+		switch {
+		case rest == "`":
+			return out.String(), false, nil
+		case rest == "${":
+			return out.String(), true, nil
+		case len(rest) > 1 && rest[0] == '\\' && strings.ContainsRune("`$\"'", rune(rest[1])):
+			out.WriteByte(rest[1])
+			rest = rest[2:]
+		default:
+			value, _, tail, err := strconv.UnquoteChar(rest, '`')
+			if err != nil {
+				return "", false, fmt.Errorf("invalid escape in %v", lexeme)
+			}
+			out.WriteRune(value)
+			rest = tail
+		}
+	}
+	return "", false, fmt.Errorf("unterminated template literal")
+}
@@ -1,6 +1,9 @@
 package parser
 
-import "programming-lang/lexer"
+import (
+	"programming-lang/lexer"
+	"strings"
+)
 
 func isVarKeyword(token lexer.Token) bool {
 	return token.Class == lexer.Keyword && token.Lexeme == "var"
@@ -14,6 +17,11 @@ func isSemicolon(token lexer.Token) bool {
 	return token.Class == lexer.Semicolon && token.Lexeme == ";"
 }
 
+// isImplicitSemicolon reports whether the lexer inserted the semicolon at the end of a line
+func isImplicitSemicolon(token lexer.Token) bool {
+	return token == lexer.ImplicitSemicolon
+}
+
 func isNumberLiteral(token lexer.Token) bool {
 	return token.Class == lexer.Number
 }
@@ -42,6 +50,14 @@ func isClosingCurly(token lexer.Token) bool {
 	return token.Class == lexer.CloseParam && token.Lexeme == "}"
 }
 
+func isOpeningBracket(token lexer.Token) bool {
+	return token.Class == lexer.OpenParam && token.Lexeme == "["
+}
+
+func isClosingBracket(token lexer.Token) bool {
+	return token.Class == lexer.CloseParam && token.Lexeme == "]"
+}
+
 func isReturnKeyword(token lexer.Token) bool {
 	return token.Class == lexer.Keyword && token.Lexeme == "return"
 }
@@ -101,3 +117,112 @@ func greaterThan(token lexer.Token) bool {
 func greaterEqThan(token lexer.Token) bool {
 	return token.Class == lexer.Operator && token.Lexeme == ">="
 }
+
+func coalesce(token lexer.Token) bool {
+	return token.Class == lexer.Operator && token.Lexeme == "??"
+}
+
+func question(token lexer.Token) bool {
+	return token.Class == lexer.Operator && token.Lexeme == "?"
+}
+
+func optionalChain(token lexer.Token) bool {
+	return token.Class == lexer.Operator && token.Lexeme == "?."
+}
+
+func isComma(token lexer.Token) bool {
+	return token.Class == lexer.Comma && token.Lexeme == ","
+}
+
+func isColon(token lexer.Token) bool {
+	return token.Class == lexer.Colon && token.Lexeme == ":"
+}
+
+func fnKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "fn"
+}
+
+func isString(token lexer.Token) bool {
+	return token.Class == lexer.String
+}
+
+func isDot(token lexer.Token) bool {
+	return token.Class == lexer.Dot
+}
+
+func isTemplateStart(token lexer.Token) bool {
+	return token.Class == lexer.Template && strings.HasPrefix(token.Lexeme, "`")
+}
+
+// isTemplateContinuation is the text after an interpolation, starting with the closing curly brace
+func isTemplateContinuation(token lexer.Token) bool {
+	return token.Class == lexer.Template && strings.HasPrefix(token.Lexeme, "}")
+}
+
+func importKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "import"
+}
+
+func exportKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "export"
+}
+
+func asKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "as"
+}
+
+func arrow(token lexer.Token) bool {
+	return token.Class == lexer.Operator && token.Lexeme == "<-"
+}
+
+func spawnKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "spawn"
+}
+
+func selectKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "select"
+}
+
+func caseKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "case"
+}
+
+func defaultKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "default"
+}
+
+func throwKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "throw"
+}
+
+func tryKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "try"
+}
+
+func catchKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "catch"
+}
+
+func finallyKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "finally"
+}
+
+func matchKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "match"
+}
+
+func structKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "struct"
+}
+
+func macroKeyword(token lexer.Token) bool {
+	return token.Class == lexer.Keyword && token.Lexeme == "macro"
+}
+
+func fatArrow(token lexer.Token) bool {
+	return token.Class == lexer.Operator && token.Lexeme == "=>"
+}
+
+func ellipsis(token lexer.Token) bool {
+	return token.Class == lexer.Operator && token.Lexeme == "..."
+}
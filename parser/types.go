@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"strings"
+)
+
+// TypeAnnotation is an optional type written after a binding or a parameter list.
+// e.g. `int`, `bool` or `fn(int, bool): string`
+type TypeAnnotation struct {
+	Name       string
+	Parameters []*TypeAnnotation // only for fn types
+	Return     *TypeAnnotation   // only for fn types, nil when not provided
+	Pos        lexer.Position
+	End        lexer.Position // closing brace of fn types
+}
+
+func (t *TypeAnnotation) TokenLiteral() string {
+	return t.Name
+}
+
+func (t *TypeAnnotation) Position() lexer.Position {
+	return t.Pos
+}
+
+func (t *TypeAnnotation) String() string {
+	if t.Name != "fn" {
+		return t.Name
+	}
+
+	params := []string{}
+	for _, p := range t.Parameters {
+		params = append(params, p.String())
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if t.Return != nil {
+		out += ": " + t.Return.String()
+	}
+	return out
+}
+
+// parseOptionalTypeAnnotation consumes `: type` when the next token is a colon.
+// Returns false when annotation was present, but invalid
+func (p *parser) parseOptionalTypeAnnotation() (*TypeAnnotation, bool) {
+	if !isColon(p.nextToken) {
+		return nil, true
+	}
+	p.advanceToken() // colon
+	p.advanceToken() // type
+
+	out := p.parseTypeAnnotation()
+	return out, out != nil
+}
+
+func (p *parser) parseTypeAnnotation() *TypeAnnotation {
+	if isIdentifier(p.currentToken) {
+		return &TypeAnnotation{Name: p.currentToken.Lexeme, Pos: p.currentPos}
+	} else if !fnKeyword(p.currentToken) {
+		p.addError(fmt.Errorf("type annotation error - expected type name, got %v", p.currentToken.Class))
+		return nil
+	}
+
+	if !isOpeningParent(p.nextToken) {
+		p.addError(fmt.Errorf("type annotation error - missing opening brace, got %v", p.nextToken.Lexeme))
+		return nil
+	}
+	out := &TypeAnnotation{Name: "fn", Parameters: []*TypeAnnotation{}, Pos: p.currentPos}
+	p.advanceToken()
+	p.advanceToken()
+
+	for !isClosingParent(p.currentToken) {
+		param := p.parseTypeAnnotation()
+		if param == nil {
+			return nil
+		}
+		out.Parameters = append(out.Parameters, param)
+
+		p.advanceToken()
+		if isComma(p.currentToken) {
+			p.advanceToken()
+		} else if !isClosingParent(p.currentToken) {
+			p.addError(fmt.Errorf("type annotation error - expected comma or closing brace, got %v", p.currentToken.Lexeme))
+			return nil
+		}
+	}
+
+	out.End = p.currentPos
+
+	ret, ok := p.parseOptionalTypeAnnotation()
+	if !ok {
+		return nil
+	}
+	out.Return = ret
+	return out
+}
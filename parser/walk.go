@@ -0,0 +1,443 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Visitor is notified about every node of the tree, in depth first order
+type Visitor interface {
+	// Enter is called before children of the node, returning false skips the children and Leave
+	Enter(node Node) bool
+	// Leave is called after all children of the node were visited
+	Leave(node Node)
+}
+
+// Walk traverses the tree starting at node
+func Walk(node Node, v Visitor) {
+	if node == nil || !v.Enter(node) {
+		return
+	}
+	for _, child := range children(node) {
+		Walk(child, v)
+	}
+	v.Leave(node)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Enter(node Node) bool {
+	return f(node)
+}
+
+func (f inspector) Leave(Node) {}
+
+// Inspect calls f for every node of the tree, children are skipped when f returns false
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+// children returns direct children of the node in source order, missing optional nodes are skipped
+func children(node Node) []Node {
+	out := []Node{}
+	add := func(nodes ...Node) {
+		for _, n := range nodes {
+			if !isNilNode(n) {
+				out = append(out, n)
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, st := range n.Statements {
+			add(st)
+		}
+	case *VarStatementNode:
+		add(n.Pattern, n.Type, n.Value)
+	case *ReturnStatementNode:
+		add(n.Value)
+	case *ExpressionStatementNode:
+		add(n.Value)
+	case *BlockStatement:
+		for _, st := range n.Statements {
+			add(st)
+		}
+	case *ImportStatement:
+	case *ExportStatement:
+		add(n.Statement)
+	case *ThrowStatement:
+		add(n.Value)
+	case *TryStatement:
+		add(n.Body, n.Catch, n.Finally)
+	case *StructStatement:
+	case *MethodStatement:
+		add(n.Receiver, n.Function)
+	case *AssignStatement:
+		add(n.Target, n.Value)
+	case *IntegerLiteralExpression, *FloatLiteralExpression, *BooleanExpression, *IdentifierExpression, *StringLiteralExpression:
+	case *PrefixExpression:
+		add(n.Right)
+	case *InfixExpression:
+		add(n.Left, n.Right)
+	case *IfExpression:
+		add(n.Condition, n.Consequence, n.Alternative)
+	case *ConditionalExpression:
+		add(n.Condition, n.Consequence, n.Alternative)
+	case *FunctionLiteralExpression:
+		for _, p := range n.Parameters {
+			add(p)
+		}
+		add(n.ReturnType, n.Body)
+	case *MacroLiteral:
+		for _, p := range n.Parameters {
+			add(p)
+		}
+		add(n.Body)
+	case *FunctionParameter:
+		add(n.Type)
+	case *CallExpression:
+		add(n.Function)
+		for _, a := range n.Arguments {
+			add(a)
+		}
+	case *MemberExpression:
+		add(n.Object)
+	case *ArrayLiteralExpression:
+		for _, e := range n.Elements {
+			add(e)
+		}
+	case *IndexExpression:
+		add(n.Left, n.Index)
+	case *HashLiteralExpression:
+		for i := range n.Keys {
+			add(n.Keys[i], n.Values[i])
+		}
+	case *SpawnExpression:
+		add(n.Call)
+	case *InterpolatedString:
+		for _, v := range n.Values {
+			add(v)
+		}
+	case *SelectExpression:
+		for _, c := range n.Cases {
+			add(c)
+		}
+		add(n.Default)
+	case *SelectCase:
+		add(n.Comm, n.Body)
+	case *MatchExpression:
+		add(n.Subject)
+		for _, a := range n.Arms {
+			add(a)
+		}
+	case *MatchArm:
+		add(n.Pattern, n.Guard, n.Body)
+	case *LiteralPattern:
+		add(n.Value)
+	case *BindingPattern, *WildcardPattern:
+	case *ArrayPattern:
+		for _, e := range n.Elements {
+			add(e)
+		}
+		add(n.Rest)
+	case *HashPattern:
+		for i := range n.Keys {
+			add(n.Keys[i], n.Values[i])
+		}
+	case *TypeAnnotation:
+		for _, p := range n.Parameters {
+			add(p)
+		}
+		add(n.Return)
+	default:
+		panic(fmt.Sprintf("walk error - unsupported node %T", node))
+	}
+	return out
+}
+
+// isNilNode reports missing nodes, including typed nil pointers of optional fields
+func isNilNode(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case *TypeAnnotation:
+		return n == nil
+	case *FunctionParameter:
+		return n == nil
+	case *FunctionLiteralExpression:
+		return n == nil
+	case *MemberExpression:
+		return n == nil
+	case *BlockStatement:
+		return n == nil
+	case *VarStatementNode:
+		return n == nil
+	case *CallExpression:
+		return n == nil
+	}
+	return false
+}
+
+// Rewrite replaces nodes of the tree with results of f, children are rewritten before their parents.
+// Returning nil removes the node from lists (statements, arguments, parameters)
+// or leaves the field empty. Returns the new root
+func Rewrite(node Node, f func(Node) Node) Node {
+	if isNilNode(node) {
+		return node
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = rewriteStatements(n.Statements, f)
+	case *VarStatementNode:
+		n.Pattern = rewritePattern(n.Pattern, f)
+		n.Type = rewriteType(n.Type, f)
+		n.Value = rewriteExpression(n.Value, f)
+	case *ReturnStatementNode:
+		n.Value = rewriteExpression(n.Value, f)
+	case *ExpressionStatementNode:
+		n.Value = rewriteExpression(n.Value, f)
+	case *BlockStatement:
+		n.Statements = rewriteStatements(n.Statements, f)
+	case *ImportStatement:
+	case *ExportStatement:
+		rewritten := Rewrite(n.Statement, f)
+		n.Statement = nil
+		if rewritten != nil {
+			n.Statement = mustBe[*VarStatementNode](rewritten)
+		}
+	case *ThrowStatement:
+		n.Value = rewriteExpression(n.Value, f)
+	case *TryStatement:
+		n.Body = rewriteBlock(n.Body, f)
+		n.Catch = rewriteBlock(n.Catch, f)
+		n.Finally = rewriteBlock(n.Finally, f)
+	case *StructStatement:
+	case *MethodStatement:
+		rewritten := Rewrite(n.Receiver, f)
+		n.Receiver = nil
+		if rewritten != nil {
+			n.Receiver = mustBe[*FunctionParameter](rewritten)
+		}
+		rewritten = Rewrite(n.Function, f)
+		n.Function = nil
+		if rewritten != nil {
+			n.Function = mustBe[*FunctionLiteralExpression](rewritten)
+		}
+	case *AssignStatement:
+		rewritten := Rewrite(n.Target, f)
+		n.Target = nil
+		if rewritten != nil {
+			n.Target = mustBe[*MemberExpression](rewritten)
+		}
+		n.Value = rewriteExpression(n.Value, f)
+	case *IntegerLiteralExpression, *FloatLiteralExpression, *BooleanExpression, *IdentifierExpression, *StringLiteralExpression:
+	case *PrefixExpression:
+		n.Right = rewriteExpression(n.Right, f)
+	case *InfixExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+	case *IfExpression:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Consequence = rewriteBlock(n.Consequence, f)
+		n.Alternative = rewriteBlock(n.Alternative, f)
+	case *ConditionalExpression:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Consequence = rewriteExpression(n.Consequence, f)
+		n.Alternative = rewriteExpression(n.Alternative, f)
+	case *FunctionLiteralExpression:
+		n.Parameters = rewriteParameters(n.Parameters, f)
+		n.ReturnType = rewriteType(n.ReturnType, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *MacroLiteral:
+		n.Parameters = rewriteParameters(n.Parameters, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *FunctionParameter:
+		n.Type = rewriteType(n.Type, f)
+	case *CallExpression:
+		n.Function = rewriteExpression(n.Function, f)
+		n.Arguments = rewriteExpressions(n.Arguments, f)
+	case *MemberExpression:
+		n.Object = rewriteExpression(n.Object, f)
+	case *ArrayLiteralExpression:
+		n.Elements = rewriteExpressions(n.Elements, f)
+	case *IndexExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Index = rewriteExpression(n.Index, f)
+	case *HashLiteralExpression:
+		keys, values := []ExpressionNode{}, []ExpressionNode{}
+		for i := range n.Keys {
+			key, value := rewriteExpression(n.Keys[i], f), rewriteExpression(n.Values[i], f)
+			if key != nil && value != nil {
+				keys, values = append(keys, key), append(values, value)
+			}
+		}
+		n.Keys, n.Values = keys, values
+	case *InterpolatedString:
+		// text around a removed value is joined
+		parts, values := []string{n.Parts[0]}, []ExpressionNode{}
+		for i, v := range n.Values {
+			if rewritten := rewriteExpression(v, f); rewritten != nil {
+				parts, values = append(parts, n.Parts[i+1]), append(values, rewritten)
+			} else {
+				parts[len(parts)-1] += n.Parts[i+1]
+			}
+		}
+		n.Parts, n.Values = parts, values
+	case *SpawnExpression:
+		rewritten := Rewrite(n.Call, f)
+		n.Call = nil
+		if rewritten != nil {
+			n.Call = mustBe[*CallExpression](rewritten)
+		}
+	case *SelectExpression:
+		cases := []*SelectCase{}
+		for _, c := range n.Cases {
+			if rewritten := Rewrite(c, f); rewritten != nil {
+				cases = append(cases, mustBe[*SelectCase](rewritten))
+			}
+		}
+		n.Cases = cases
+		n.Default = rewriteBlock(n.Default, f)
+	case *SelectCase:
+		n.Comm = rewriteExpression(n.Comm, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *MatchExpression:
+		n.Subject = rewriteExpression(n.Subject, f)
+		arms := []*MatchArm{}
+		for _, a := range n.Arms {
+			if rewritten := Rewrite(a, f); rewritten != nil {
+				arms = append(arms, mustBe[*MatchArm](rewritten))
+			}
+		}
+		n.Arms = arms
+	case *MatchArm:
+		n.Pattern = rewritePattern(n.Pattern, f)
+		n.Guard = rewriteExpression(n.Guard, f)
+		n.Body = rewriteExpression(n.Body, f)
+	case *LiteralPattern:
+		n.Value = rewriteExpression(n.Value, f)
+	case *BindingPattern, *WildcardPattern:
+	case *ArrayPattern:
+		elements := []Pattern{}
+		for _, e := range n.Elements {
+			if rewritten := rewritePattern(e, f); rewritten != nil {
+				elements = append(elements, rewritten)
+			}
+		}
+		n.Elements = elements
+		n.Rest = rewritePattern(n.Rest, f)
+	case *HashPattern:
+		keys, values := []ExpressionNode{}, []Pattern{}
+		for i := range n.Keys {
+			key, value := rewriteExpression(n.Keys[i], f), rewritePattern(n.Values[i], f)
+			if key != nil && value != nil {
+				keys, values = append(keys, key), append(values, value)
+			}
+		}
+		n.Keys, n.Values = keys, values
+	case *TypeAnnotation:
+		if n.Parameters != nil {
+			params := []*TypeAnnotation{}
+			for _, p := range n.Parameters {
+				if rewritten := rewriteType(p, f); rewritten != nil {
+					params = append(params, rewritten)
+				}
+			}
+			n.Parameters = params
+		}
+		n.Return = rewriteType(n.Return, f)
+	default:
+		panic(fmt.Sprintf("rewrite error - unsupported node %T", node))
+	}
+	return f(node)
+}
+
+// mustBe converts rewritten node to the type required by its parent
+func mustBe[T Node](node Node) T {
+	out, ok := node.(T)
+	if !ok {
+		expected := reflect.TypeOf((*T)(nil)).Elem()
+		panic(fmt.Sprintf("rewrite error - %T is not %v", node, expected))
+	}
+	return out
+}
+
+func rewriteStatements(statements []StatementNode, f func(Node) Node) []StatementNode {
+	var out []StatementNode
+	if statements != nil {
+		out = []StatementNode{}
+	}
+	for _, st := range statements {
+		if rewritten := Rewrite(st, f); rewritten != nil {
+			out = append(out, mustBe[StatementNode](rewritten))
+		}
+	}
+	return out
+}
+
+func rewriteExpression(exp ExpressionNode, f func(Node) Node) ExpressionNode {
+	if exp == nil {
+		return nil
+	}
+	rewritten := Rewrite(exp, f)
+	if rewritten == nil {
+		return nil
+	}
+	return mustBe[ExpressionNode](rewritten)
+}
+
+// rewriteExpressions rewrites the list, removed expressions are dropped
+func rewriteExpressions(list []ExpressionNode, f func(Node) Node) []ExpressionNode {
+	out := []ExpressionNode{}
+	for _, e := range list {
+		if rewritten := rewriteExpression(e, f); rewritten != nil {
+			out = append(out, rewritten)
+		}
+	}
+	return out
+}
+
+func rewriteParameters(list []*FunctionParameter, f func(Node) Node) []*FunctionParameter {
+	out := []*FunctionParameter{}
+	for _, p := range list {
+		if rewritten := Rewrite(p, f); rewritten != nil {
+			out = append(out, mustBe[*FunctionParameter](rewritten))
+		}
+	}
+	return out
+}
+
+func rewriteBlock(block *BlockStatement, f func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	rewritten := Rewrite(block, f)
+	if rewritten == nil {
+		return nil
+	}
+	return mustBe[*BlockStatement](rewritten)
+}
+
+func rewritePattern(pattern Pattern, f func(Node) Node) Pattern {
+	if pattern == nil {
+		return nil
+	}
+	rewritten := Rewrite(pattern, f)
+	if rewritten == nil {
+		return nil
+	}
+	return mustBe[Pattern](rewritten)
+}
+
+func rewriteType(t *TypeAnnotation, f func(Node) Node) *TypeAnnotation {
+	if t == nil {
+		return nil
+	}
+	rewritten := Rewrite(t, f)
+	if rewritten == nil {
+		return nil
+	}
+	return mustBe[*TypeAnnotation](rewritten)
+}
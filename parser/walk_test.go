@@ -0,0 +1,199 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path/filepath"
+	"programming-lang/lexer"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// covers every node type
+const everyNode = `import "lib.mk" as lib;
+export var f: fn(int): int = fn(a: int): bool { if (!a) { return a + 1.5; } else { lib.f(true, ["s"][0], {"k": 2}, spawn a(), select { case <-a as v { v } default {} }) } };
+try { throw 1; } catch (e) {} finally {}
+var [x, ...xs] = match (f) { 1 => 2, [_, y] if y => {"k": -1.5}, {"k": z} => z };
+struct P { a } fn (p: P) m() { p.a = ` + "`${p.a}`" + `; }
+var m = macro(a) { quote(unquote(a)) };
+var c = a ? b?.c : d?.[0] ?? 1;`
+
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) Enter(node Node) bool {
+	r.events = append(r.events, fmt.Sprintf("enter %T", node))
+	return true
+}
+
+func (r *recorder) Leave(node Node) {
+	r.events = append(r.events, fmt.Sprintf("leave %T", node))
+}
+
+func TestWalk(t *testing.T) {
+	tree := Parse(lexer.Tokenize(`var x = -a + 1;`))
+	assertNoErrors(t, tree.Errors)
+
+	r := &recorder{}
+	Walk(tree, r)
+
+	expected := []string{
+		"enter *parser.Program",
+		"enter *parser.VarStatementNode",
+		"enter *parser.InfixExpression",
+		"enter *parser.PrefixExpression",
+		"enter *parser.IdentifierExpression",
+		"leave *parser.IdentifierExpression",
+		"leave *parser.PrefixExpression",
+		"enter *parser.IntegerLiteralExpression",
+		"leave *parser.IntegerLiteralExpression",
+		"leave *parser.InfixExpression",
+		"leave *parser.VarStatementNode",
+		"leave *parser.Program",
+	}
+	assert.Equal(t, expected, r.events)
+}
+
+func TestInspect(t *testing.T) {
+	tree := Parse(lexer.Tokenize(`f(a, fn(b) { c });`))
+	assertNoErrors(t, tree.Errors)
+
+	names := []string{}
+	Inspect(tree, func(n Node) bool {
+		if id, ok := n.(*IdentifierExpression); ok {
+			names = append(names, id.Name)
+		}
+		_, isFunction := n.(*FunctionLiteralExpression)
+		return !isFunction
+	})
+	assert.Equal(t, []string{"f", "a"}, names)
+}
+
+func TestRewrite(t *testing.T) {
+	tree := Parse(lexer.Tokenize(`var x = 1 + 2; if (true) { 3; 4 } else { return 5; }`))
+	assertNoErrors(t, tree.Errors)
+
+	rewritten := Rewrite(tree, func(n Node) Node {
+		switch n := n.(type) {
+		case *IntegerLiteralExpression:
+			n.Value *= 10
+		case *BooleanExpression:
+			return &PrefixExpression{Operator: "!", Right: &BooleanExpression{Value: !n.Value}}
+		case *ExpressionStatementNode:
+			// drops statements made of literals only
+			if _, ok := n.Value.(*IntegerLiteralExpression); ok {
+				return nil
+			}
+		}
+		return n
+	})
+
+	assert.Same(t, tree, rewritten)
+	assert.Equal(t, "var x=(10+20)if(!false)  else return 50", rewritten.String())
+}
+
+func TestRewriteInvalidReplacement(t *testing.T) {
+	tree := Parse(lexer.Tokenize(`var x = 1;`))
+	assertNoErrors(t, tree.Errors)
+
+	assert.PanicsWithValue(t, "rewrite error - *parser.BlockStatement is not parser.ExpressionNode", func() {
+		Rewrite(tree, func(n Node) Node {
+			if _, ok := n.(*IntegerLiteralExpression); ok {
+				return &BlockStatement{}
+			}
+			return n
+		})
+	})
+}
+
+// declared node types are compared with cases of the walker, a new node has to be added to both
+func TestWalkerSupportsAllNodes(t *testing.T) {
+	declared := declaredNodeTypes(t)
+
+	for _, fn := range []string{"children", "Rewrite"} {
+		assert.Equal(t, declared, typeSwitchCases(t, "walk.go", fn), "node types not supported by %v", fn)
+	}
+
+	visited := map[string]bool{}
+	tree := Parse(lexer.Tokenize(everyNode))
+	assertNoErrors(t, tree.Errors)
+	Inspect(tree, func(n Node) bool {
+		visited[strings.TrimPrefix(fmt.Sprintf("%T", n), "*parser.")] = true
+		return true
+	})
+	assert.Equal(t, declared, sortedKeys(visited), "test program should contain every node type")
+}
+
+// declaredNodeTypes returns receivers of TokenLiteral, which every node implements
+func declaredNodeTypes(t *testing.T) []string {
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	out := map[string]bool{}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := goparser.ParseFile(token.NewFileSet(), file, nil, 0)
+		require.NoError(t, err)
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "TokenLiteral" {
+				continue
+			}
+			out[receiverName(fn.Recv.List[0].Type)] = true
+		}
+	}
+	return sortedKeys(out)
+}
+
+func typeSwitchCases(t *testing.T, file string, function string) []string {
+	f, err := goparser.ParseFile(token.NewFileSet(), file, nil, 0)
+	require.NoError(t, err)
+
+	out := map[string]bool{}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != function {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			clause, ok := n.(*ast.CaseClause)
+			if !ok {
+				return true
+			}
+			for _, exp := range clause.List {
+				out[receiverName(exp)] = true
+			}
+			return false
+		})
+	}
+	return sortedKeys(out)
+}
+
+func receiverName(exp ast.Expr) string {
+	if star, ok := exp.(*ast.StarExpr); ok {
+		exp = star.X
+	}
+	if id, ok := exp.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := []string{}
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
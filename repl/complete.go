@@ -0,0 +1,68 @@
+package repl
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	expressionKeywords = []string{"false", "fn", "if", "true"}
+	statementKeywords  = []string{"return", "var"}
+)
+
+var metaCommands = []string{":ast", ":env", ":help", ":load", ":quit", ":reset", ":tokens", ":type"}
+
+// Complete returns candidates for the word being typed, given the text before it.
+// Meta-commands are completed at the start of a line, file paths after :load,
+// otherwise keywords valid at the place and names defined in the session. Names of new vars are not completed
+func (r *Repl) Complete(before, word string) []string {
+	trimmed := strings.TrimSpace(before)
+	switch {
+	case len(r.pending) == 0 && trimmed == "" && strings.HasPrefix(word, ":"):
+		return withPrefix(metaCommands, word)
+	case trimmed == ":load":
+		return completePath(word)
+	case strings.HasSuffix(trimmed, "var") && strings.HasSuffix(before, " "):
+		return nil
+	case word == "":
+		return nil
+	}
+
+	candidates := withPrefix(expressionKeywords, word)
+	if trimmed == "" || strings.HasSuffix(trimmed, ";") || strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "}") {
+		candidates = append(candidates, withPrefix(statementKeywords, word)...)
+	}
+	if strings.HasSuffix(trimmed, "}") {
+		candidates = append(candidates, withPrefix([]string{"else"}, word)...)
+	}
+	candidates = append(candidates, withPrefix(r.env.Names(), word)...)
+	sort.Strings(candidates)
+	return unique(candidates)
+}
+
+func withPrefix(names []string, prefix string) []string {
+	out := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// unique removes repeats from the sorted list
+func unique(sorted []string) []string {
+	out := []string{}
+	for i, s := range sorted {
+		if i == 0 || sorted[i-1] != s {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func completePath(word string) []string {
+	matches, _ := filepath.Glob(word + "*")
+	return matches
+}
@@ -0,0 +1,259 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"programming-lang/evaluator"
+	"programming-lang/lexer"
+	"programming-lang/lineedit"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"programming-lang/typecheck"
+	"strings"
+)
+
+const (
+	Prompt             = ">> "
+	ContinuationPrompt = ".. "
+)
+
+const help = `meta-commands:
+  :env           print variables defined in the session
+  :ast <code>    print parse tree of the code
+  :tokens <code> print tokens of the code
+  :type <expr>   print inferred type of the expression
+  :load <file>   evaluate file in the session
+  :reset         forget all definitions
+  :quit          exit the REPL
+input continues on the next line while brackets are left open`
+
+// Repl evaluates input line by line, definitions are kept between inputs
+type Repl struct {
+	ShowTokens bool // print tokens of each input before evaluating it
+	ShowAst    bool // print parse tree of each input before evaluating it
+	Strict     bool // parse input with parser.ParseStrict, new lines don't end statements
+	// resolves import statements, imports fail when it's not set
+	Importer evaluator.Importer
+
+	out       io.Writer
+	evaluator *evaluator.Evaluator
+	env       *object.Environment
+	// var statements evaluated so far, used to infer types of names they define
+	definitions []parser.StatementNode
+	pending     []string // lines of unfinished input
+	quit        bool
+}
+
+func New(out io.Writer) *Repl {
+	return &Repl{out: out, evaluator: evaluator.New(), env: object.NewEnvironment()}
+}
+
+// LineReader reads one line of input after showing the prompt
+type LineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// Run reads lines from in until the end of input or :quit
+func (r *Repl) Run(in io.Reader) error {
+	return r.RunLines(&scannerReader{scanner: bufio.NewScanner(in), out: r.out})
+}
+
+// RunLines reads lines until the end of input or :quit.
+// Interrupted line drops unfinished input
+func (r *Repl) RunLines(lines LineReader) error {
+	for !r.quit {
+		line, err := lines.ReadLine(r.Prompt())
+		if err == lineedit.ErrInterrupted {
+			r.pending = nil
+			continue
+		} else if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		r.Line(line)
+	}
+	return nil
+}
+
+// scannerReader reads lines without editing, used when input isn't a terminal
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (s *scannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(s.out, prompt)
+	if !s.scanner.Scan() {
+		fmt.Fprintln(s.out)
+		if err := s.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.scanner.Text(), nil
+}
+
+// Prompt to show before the next line, depends on whether input is unfinished
+func (r *Repl) Prompt() string {
+	if len(r.pending) > 0 {
+		return ContinuationPrompt
+	}
+	return Prompt
+}
+
+// Line handles one line of input. Code is evaluated once its brackets are balanced
+func (r *Repl) Line(line string) {
+	if len(r.pending) == 0 {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ":") {
+			r.meta(trimmed)
+			return
+		}
+		if trimmed == "" {
+			return
+		}
+	}
+
+	r.pending = append(r.pending, line)
+	input := strings.Join(r.pending, "\n")
+	if openBrackets(input) > 0 {
+		return
+	}
+	r.pending = nil
+	r.eval(input)
+}
+
+// openBrackets counts brackets not closed yet, negative when there are more closing ones.
+// Interpolations of templates count as brackets, so does a template without the closing backtick
+func openBrackets(input string) int {
+	depth := 0
+	for _, t := range lexer.Tokenize(input) {
+		switch t.Class {
+		case lexer.OpenParam:
+			depth++
+		case lexer.CloseParam:
+			depth--
+		case lexer.Template:
+			if strings.HasPrefix(t.Lexeme, "}") {
+				depth--
+			}
+			if strings.HasSuffix(t.Lexeme, "${") || !strings.HasSuffix(t.Lexeme, "`") || t.Lexeme == "`" {
+				depth++
+			}
+		}
+	}
+	return depth
+}
+
+func (r *Repl) parse(input string) (*parser.Program, bool) {
+	parse := parser.ParseWithPositions
+	if r.Strict {
+		parse = parser.ParseStrict
+	}
+	program := parse(lexer.TokenizeWithPositions(input))
+	for _, e := range program.Errors {
+		fmt.Fprintln(r.out, e)
+	}
+	for _, w := range program.Warnings {
+		fmt.Fprintln(r.out, w)
+	}
+	return program, len(program.Errors) == 0
+}
+
+func (r *Repl) eval(input string) {
+	if r.ShowTokens {
+		fmt.Fprintln(r.out, lexer.Tokenize(input))
+	}
+	program, ok := r.parse(input)
+	if !ok {
+		return
+	}
+	if r.ShowAst {
+		fmt.Fprintln(r.out, program)
+	}
+
+	r.evaluator.Importer = r.Importer
+	result := r.evaluator.Eval(program, r.env)
+	if result != nil {
+		fmt.Fprintln(r.out, result.Inspect())
+	}
+	if result == nil || result.Type() != object.ERROR {
+		for _, st := range program.Statements {
+			if _, ok := st.(*parser.VarStatementNode); ok {
+				r.definitions = append(r.definitions, st)
+			}
+		}
+	}
+}
+
+func (r *Repl) meta(line string) {
+	command, args, _ := strings.Cut(line, " ")
+	args = strings.TrimSpace(args)
+	switch command {
+	case ":env":
+		for _, name := range r.env.Names() {
+			value, _ := r.env.Get(name)
+			fmt.Fprintf(r.out, "%s = %s\n", name, value.Inspect())
+		}
+	case ":ast":
+		if program, ok := r.parse(args); ok {
+			fmt.Fprintln(r.out, program)
+		}
+	case ":tokens":
+		fmt.Fprintln(r.out, lexer.Tokenize(args))
+	case ":type":
+		r.printType(args)
+	case ":load":
+		r.load(args)
+	case ":reset":
+		r.env = object.NewEnvironment()
+		r.definitions = nil
+	case ":quit", ":q":
+		r.quit = true
+	case ":help", ":h":
+		fmt.Fprintln(r.out, help)
+	default:
+		fmt.Fprintf(r.out, "unknown command %s, try :help\n", command)
+	}
+}
+
+// printType infers the expression together with definitions made so far
+func (r *Repl) printType(input string) {
+	program, ok := r.parse(input)
+	if !ok {
+		return
+	}
+	if len(program.Statements) != 1 {
+		fmt.Fprintln(r.out, "expected a single expression")
+		return
+	}
+	st, ok := program.Statements[0].(*parser.ExpressionStatementNode)
+	if !ok {
+		fmt.Fprintln(r.out, "expected a single expression")
+		return
+	}
+
+	statements := append([]parser.StatementNode{}, r.definitions...)
+	statements = append(statements, &parser.VarStatementNode{Name: "it", Value: st.Value, Pos: st.Pos})
+	bindings, errors := typecheck.Infer(&parser.Program{Statements: statements})
+	if len(errors) > 0 {
+		for _, e := range errors {
+			fmt.Fprintln(r.out, e)
+		}
+		return
+	}
+	binding := bindings[len(bindings)-1]
+	fmt.Fprintln(r.out, strings.TrimPrefix(binding.String(), binding.Name+": "))
+}
+
+func (r *Repl) load(path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(r.out, "error when reading file:", err)
+		return
+	}
+	r.eval(string(content))
+}
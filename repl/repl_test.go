@@ -0,0 +1,156 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"programming-lang/lineedit"
+	"programming-lang/modules"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run feeds the lines to a new REPL, returns output without prompts
+func run(t *testing.T, lines ...string) string {
+	var out bytes.Buffer
+	r := New(&out)
+	require.NoError(t, r.Run(strings.NewReader(strings.Join(lines, "\n"))))
+	output := strings.ReplaceAll(out.String(), Prompt, "")
+	return strings.ReplaceAll(output, ContinuationPrompt, "")
+}
+
+func TestRepl(t *testing.T) {
+	tdt := []struct {
+		desc     string
+		lines    []string
+		expected string
+	}{
+		{"expression", []string{"1 + 2 * 3;"}, "7\n\n"},
+		{"definitions persist", []string{"var x = 5;", "var double = fn(a) { a * 2 };", "double(x);"}, "10\n\n"},
+		{"errors don't end session", []string{"y;", "1 + true;", "var y = 2;", "y;"},
+			"error: identifier not found: y\nerror: type mismatch: INTEGER + BOOLEAN\n2\n\n"},
+		{"syntax error", []string{"var = 1;", "3;"}, "var error - expected identifier, got Assignment\nno prefix parsing function for token =\n3\n\n"},
+		{"warning", []string{"match (1) { _ => 1, 2 => 2 };"}, "match warning - unreachable arm 2\n1\n\n"},
+		{
+			"multi-line input",
+			[]string{"var add = fn(a, b) {", "  a + b", "};", "add(1,", "2);"},
+			"3\n\n",
+		},
+		{
+			"multi-line template",
+			[]string{"var s = `a", "${1 +", "2}`;", "s;"},
+			"a\n3\n\n",
+		},
+		{"empty lines", []string{"", "  ", "1;"}, "1\n\n"},
+		{"env", []string{"var b = true;", "var a = 1;", ":env"}, "a = 1\nb = true\n\n"},
+		{"reset", []string{"var a = 1;", ":reset", "a;"}, "error: identifier not found: a\n\n"},
+		{"ast", []string{":ast var x = 1 + 2;"}, "var x=(1+2)\n\n"},
+		{"tokens", []string{":tokens x + 1"}, "[{Identifier \"x\"} {Operator \"+\"} {Number \"1\"} {EOF \"\"}]\n\n"},
+		{"type", []string{"var id = fn(x) { x };", ":type id", ":type id(1) < 2"}, "fn(a): a\nbool\n\n"},
+		{"type error", []string{":type 1 + true"}, "typecheck error - cannot unify bool (1:5) with int (1:3)\n\n"},
+		{"quit", []string{"1;", ":quit", "2;"}, "1\n"},
+		{"unknown command", []string{":foo"}, "unknown command :foo, try :help\n\n"},
+	}
+
+	for _, tt := range tdt {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.expected, run(t, tt.lines...))
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	var out bytes.Buffer
+	r := New(&out)
+	r.Strict = true
+	require.NoError(t, r.Run(strings.NewReader("var f = fn() {\nreturn 1\n};\nvar x = 2\nx;")))
+	assert.Equal(t, "return error - expected semicolon after expression, got CloseParam\nvar error - expected semicolon after expression, got EOF\nerror: identifier not found: x\n\n", strings.NewReplacer(Prompt, "", ContinuationPrompt, "").Replace(out.String()))
+}
+
+func TestPrompts(t *testing.T) {
+	var out bytes.Buffer
+	r := New(&out)
+	require.NoError(t, r.Run(strings.NewReader("if (true) {\n1\n}\n")))
+	assert.Equal(t, ">> .. .. 1\n>> \n", out.String())
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.mk")
+	require.NoError(t, os.WriteFile(path, []byte("var square = fn(x) { x * x };\nsquare(3);"), 0644))
+
+	assert.Equal(t, "9\n16\n\n", run(t, ":load "+path, "square(4);"))
+	assert.Contains(t, run(t, ":load missing.mk"), "error when reading file")
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.mk"), []byte(`export var name = "lib";`), 0644))
+
+	var out bytes.Buffer
+	r := New(&out)
+	r.Importer = modules.NewLoader().Importer(dir)
+	require.NoError(t, r.Run(strings.NewReader("import \"lib.mk\" as lib;\nlib.name\nlib")))
+	assert.Equal(t, "lib\nmodule lib.mk\n\n", strings.ReplaceAll(out.String(), Prompt, ""))
+
+	assert.Contains(t, run(t, `import "lib.mk" as lib;`), "cannot import lib.mk: modules are not available")
+}
+
+// lines is a LineReader returning the lines in order, nil error stands for Ctrl-C
+type lines []any
+
+func (l *lines) ReadLine(prompt string) (string, error) {
+	if len(*l) == 0 {
+		return "", io.EOF
+	}
+	next := (*l)[0]
+	*l = (*l)[1:]
+	if line, ok := next.(string); ok {
+		return line, nil
+	}
+	return "", lineedit.ErrInterrupted
+}
+
+func TestInterruptDropsPendingInput(t *testing.T) {
+	var out bytes.Buffer
+	r := New(&out)
+	require.NoError(t, r.RunLines(&lines{"var f = fn(x) {", nil, "var x = 1;", "x;"}))
+	assert.Equal(t, "1\n", out.String())
+}
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.mk"), nil, 0644))
+
+	r := New(io.Discard)
+	require.NoError(t, r.Run(strings.NewReader("var value = 1; var variance = fn(x) { x };")))
+
+	tdt := []struct {
+		desc     string
+		before   string
+		word     string
+		expected []string
+	}{
+		{"keywords and names", "", "va", []string{"value", "var", "variance"}},
+		{"inside expression", "1 + ", "v", []string{"value", "variance"}},
+		{"keywords", "", "f", []string{"false", "fn"}},
+		{"statement keywords", "var x = 1; ", "re", []string{"return"}},
+		{"else", "if (true) { 1 } ", "e", []string{"else"}},
+		{"no statement keywords in expression", "1 + ", "re", []string{}},
+		{"meta-commands", "", ":t", []string{":tokens", ":type"}},
+		{"meta-command argument", ":type ", "vari", []string{"variance"}},
+		{"file path", ":load ", filepath.Join(dir, "l"), []string{filepath.Join(dir, "lib.mk")}},
+		{"new var name", "var ", "va", nil},
+		{"empty word", "1 + ", "", nil},
+		{"no match", "", "zz", []string{}},
+	}
+
+	for _, tt := range tdt {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.expected, r.Complete(tt.before, tt.word))
+		})
+	}
+}
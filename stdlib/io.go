@@ -0,0 +1,214 @@
+package stdlib
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+	"strings"
+	"sync"
+)
+
+// Functions of the io module, working with files in directories granted by Allow
+var ioFunctions = map[string]object.BuiltinFunction{
+	"read_file":  readFile,
+	"write_file": writeFile,
+	"list_dir":   listDir,
+	"exists":     exists,
+}
+
+// Permissions are capabilities of scripts granted by flags of the interpreter, everything is denied by default
+type Permissions struct {
+	Read  []string // directories readable by the io module, with everything inside
+	Write []string // directories writable by the io module
+	Env   bool     // os.getenv
+	Run   bool     // os.exec
+}
+
+var (
+	permissionsMu sync.RWMutex
+	permissions   Permissions
+)
+
+// Allow replaces permissions of the io and os modules. Directories have to exist,
+// they are stored as canonical paths
+func Allow(p Permissions) error {
+	var err error
+	if p.Read, err = canonicalDirs(p.Read); err != nil {
+		return err
+	}
+	if p.Write, err = canonicalDirs(p.Write); err != nil {
+		return err
+	}
+	permissionsMu.Lock()
+	defer permissionsMu.Unlock()
+	permissions = p
+	return nil
+}
+
+func canonicalDirs(dirs []string) ([]string, error) {
+	out := []string{}
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, fmt.Errorf("can't allow access to %s: %v", dir, err)
+		}
+		if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("can't allow access to %s: not a directory", dir)
+		}
+		out = append(out, resolved)
+	}
+	return out, nil
+}
+
+// canonical resolves symlinks and `..` of the path the way the system does. Missing files
+// are resolved by their closest existing parent, the rest of the path is only cleaned.
+// Cleaning `missing/..` away may leave a symlink in the rest, so such path is resolved again.
+// A dangling symlink is missing too, but writing to it creates its target, so it's followed
+func canonical(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		// not filepath.Join, cleaning link/.. would skip the directory the link points to
+		path = cwd + string(filepath.Separator) + path
+	}
+
+	dir, rest := path, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil && parentRef(rest) {
+			return canonical(filepath.Join(resolved, rest))
+		} else if err == nil {
+			return filepath.Join(resolved, rest), nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if target, err := os.Readlink(dir); err == nil {
+			if !filepath.IsAbs(target) {
+				target = filepath.Dir(dir) + string(filepath.Separator) + target
+			}
+			return canonical(target + string(filepath.Separator) + rest)
+		}
+		i := strings.LastIndex(dir, string(filepath.Separator))
+		if i < 0 {
+			return "", err
+		}
+		dir, rest = dir[:i], dir[i+1:]+string(filepath.Separator)+rest
+		if dir == "" || strings.HasSuffix(dir, ":") {
+			dir += string(filepath.Separator)
+		}
+	}
+}
+
+func parentRef(path string) bool {
+	for _, part := range strings.Split(path, string(filepath.Separator)) {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether the canonical path is one of the dirs or inside of one
+func within(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// permitted returns the canonical path of the path argument when access is granted
+func permitted(name string, arg object.Object, write bool) (string, *object.Error) {
+	path, err := canonical(str(arg))
+	if err != nil {
+		return "", newError(name, "%v", err)
+	}
+
+	permissionsMu.RLock()
+	defer permissionsMu.RUnlock()
+	if write && !within(path, permissions.Write) {
+		return "", newError(name, "write access to %s denied, allow it with --allow-write", path)
+	} else if !write && !within(path, permissions.Read) {
+		return "", newError(name, "read access to %s denied, allow it with --allow-read", path)
+	}
+	return path, nil
+}
+
+// io.read_file(path) returns content of the file
+func readFile(args ...object.Object) object.Object {
+	if err := checkArgs("io.read_file", args, object.STRING); err != nil {
+		return err
+	}
+	path, denied := permitted("io.read_file", args[0], false)
+	if denied != nil {
+		return denied
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return newError("io.read_file", "%v", err)
+	}
+	return &object.String{Value: string(content)}
+}
+
+// io.write_file(path, content) creates or replaces the file
+func writeFile(args ...object.Object) object.Object {
+	if err := checkArgs("io.write_file", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	path, denied := permitted("io.write_file", args[0], true)
+	if denied != nil {
+		return denied
+	}
+	if err := os.WriteFile(path, []byte(str(args[1])), 0644); err != nil {
+		return newError("io.write_file", "%v", err)
+	}
+	return evaluator.NULL_VAL
+}
+
+// io.list_dir(path) returns sorted names of entries of the directory
+func listDir(args ...object.Object) object.Object {
+	if err := checkArgs("io.list_dir", args, object.STRING); err != nil {
+		return err
+	}
+	path, denied := permitted("io.list_dir", args[0], false)
+	if denied != nil {
+		return denied
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return newError("io.list_dir", "%v", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return toStrings(names)
+}
+
+// io.exists(path) reports whether the file or directory exists, it needs read access
+func exists(args ...object.Object) object.Object {
+	if err := checkArgs("io.exists", args, object.STRING); err != nil {
+		return err
+	}
+	path, denied := permitted("io.exists", args[0], false)
+	if denied != nil {
+		return denied
+	}
+	_, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return newError("io.exists", "%v", err)
+	}
+	return toBoolean(err == nil)
+}
@@ -0,0 +1,172 @@
+package stdlib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"programming-lang/evaluator"
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eval(t *testing.T, code string) object.Object {
+	program := parser.Parse(lexer.Tokenize(`import "io" as io; import "os" as os; ` + code))
+	require.Empty(t, program.Errors)
+	e := evaluator.New()
+	e.Importer = stdlibImporter{}
+	return e.Eval(program, object.NewEnvironment())
+}
+
+func allow(t *testing.T, p Permissions) {
+	require.NoError(t, Allow(p))
+	t.Cleanup(func() {
+		require.NoError(t, Allow(Permissions{}))
+	})
+}
+
+// sandbox creates dir/sandbox with a file, a secret file next to it and a link from the sandbox to dir
+func sandbox(t *testing.T) string {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sandbox"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sandbox", "data.txt"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(dir, filepath.Join(dir, "sandbox", "link")))
+	return dir
+}
+
+func TestCapabilitiesDeniedByDefault(t *testing.T) {
+	dir := sandbox(t)
+	data := filepath.Join(dir, "sandbox", "data.txt")
+
+	tdt := []struct {
+		code     string
+		expected string
+	}{
+		{fmt.Sprintf(`io.read_file(%q)`, data), "io.read_file: read access to " + data + " denied, allow it with --allow-read"},
+		{fmt.Sprintf(`io.exists(%q)`, data), "io.exists: read access to " + data + " denied, allow it with --allow-read"},
+		{fmt.Sprintf(`io.write_file(%q, "x")`, data), "io.write_file: write access to " + data + " denied, allow it with --allow-write"},
+		{`os.getenv("HOME")`, "os.getenv: environment access denied, allow it with --allow-env"},
+		{`os.exec("ls")`, "os.exec: running ls denied, allow it with --allow-run"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.code, func(t *testing.T) {
+			assert.Equal(t, "error: "+tc.expected, eval(t, tc.code).Inspect())
+		})
+	}
+}
+
+func TestReadAndWrite(t *testing.T) {
+	dir := sandbox(t)
+	box := filepath.Join(dir, "sandbox")
+	allow(t, Permissions{Read: []string{box}, Write: []string{box}})
+
+	assert.Equal(t, "data", eval(t, fmt.Sprintf(`io.read_file(%q)`, filepath.Join(box, "data.txt"))).Inspect())
+	assert.Equal(t, "[data.txt, link]", eval(t, fmt.Sprintf(`io.list_dir(%q)`, box)).Inspect())
+	assert.Equal(t, "true", eval(t, fmt.Sprintf(`io.exists(%q)`, filepath.Join(box, "data.txt"))).Inspect())
+	assert.Equal(t, "false", eval(t, fmt.Sprintf(`io.exists(%q)`, filepath.Join(box, "missing.txt"))).Inspect())
+
+	out := filepath.Join(box, "out.txt")
+	assert.Equal(t, "null", eval(t, fmt.Sprintf(`io.write_file(%q, "result")`, out)).Inspect())
+	assert.Equal(t, "result", eval(t, fmt.Sprintf(`io.read_file(%q)`, out)).Inspect())
+
+	missing := filepath.Join(box, "missing.txt")
+	assert.Contains(t, eval(t, fmt.Sprintf(`io.read_file(%q)`, missing)).Inspect(), "io.read_file: open "+missing+": no such file or directory")
+}
+
+func TestRelativePaths(t *testing.T) {
+	dir := sandbox(t)
+	t.Chdir(filepath.Join(dir, "sandbox"))
+	allow(t, Permissions{Read: []string{"."}})
+
+	assert.Equal(t, "data", eval(t, `io.read_file("data.txt")`).Inspect())
+	assert.Equal(t, "data", eval(t, `io.read_file("./link/sandbox/data.txt")`).Inspect())
+	assert.Equal(t, "error: io.read_file: read access to "+filepath.Join(dir, "secret.txt")+" denied, allow it with --allow-read",
+		eval(t, `io.read_file("../secret.txt")`).Inspect())
+}
+
+func TestSandboxEscapes(t *testing.T) {
+	dir := sandbox(t)
+	box := filepath.Join(dir, "sandbox")
+	allow(t, Permissions{Read: []string{box}, Write: []string{box}})
+	secret := filepath.Join(dir, "secret.txt")
+	require.NoError(t, os.Symlink(filepath.Join(dir, "pwn.txt"), filepath.Join(box, "dangling")))
+	require.NoError(t, os.Symlink("../other.txt", filepath.Join(box, "relative")))
+
+	tdt := []struct {
+		desc string
+		code string
+		path string
+	}{
+		{"parent", fmt.Sprintf(`io.read_file(%q)`, box+"/../secret.txt"), secret},
+		{"symlink", fmt.Sprintf(`io.read_file(%q)`, box+"/link/secret.txt"), secret},
+		{"parent of symlink", fmt.Sprintf(`io.read_file(%q)`, box+"/link/../secret.txt"), filepath.Join(filepath.Dir(dir), "secret.txt")},
+		{"symlink to allowed dir itself", fmt.Sprintf(`io.list_dir(%q)`, box+"/link"), dir},
+		{"write through symlink", fmt.Sprintf(`io.write_file(%q, "x")`, box+"/link/new.txt"), filepath.Join(dir, "new.txt")},
+		{"write to missing dir", fmt.Sprintf(`io.write_file(%q, "x")`, box+"/missing/../../new.txt"), filepath.Join(dir, "new.txt")},
+		{"write through symlink after missing dir", fmt.Sprintf(`io.write_file(%q, "x")`, box+"/missing/../link/new.txt"), filepath.Join(dir, "new.txt")},
+		{"write through dangling symlink", fmt.Sprintf(`io.write_file(%q, "x")`, box+"/dangling"), filepath.Join(dir, "pwn.txt")},
+		{"write through relative dangling symlink", fmt.Sprintf(`io.write_file(%q, "x")`, box+"/relative"), filepath.Join(dir, "other.txt")},
+		{"read through symlink after missing dir", fmt.Sprintf(`io.read_file(%q)`, box+"/missing/../link/secret.txt"), secret},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			result := eval(t, tc.code)
+			require.Equal(t, object.ERROR, result.Type(), "escaped the sandbox: %v", result.Inspect())
+			assert.Contains(t, result.Inspect(), "access to "+tc.path+" denied")
+		})
+	}
+	assert.NoFileExists(t, filepath.Join(dir, "new.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "pwn.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "other.txt"))
+}
+
+func TestAllowRequiresDirectories(t *testing.T) {
+	dir := sandbox(t)
+	t.Cleanup(func() {
+		require.NoError(t, Allow(Permissions{}))
+	})
+
+	err := Allow(Permissions{Read: []string{filepath.Join(dir, "missing")}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't allow access to "+filepath.Join(dir, "missing"))
+	assert.EqualError(t, Allow(Permissions{Write: []string{filepath.Join(dir, "secret.txt")}}), "can't allow access to "+filepath.Join(dir, "secret.txt")+": not a directory")
+}
+
+func TestEnvironmentAndProcesses(t *testing.T) {
+	allow(t, Permissions{Env: true, Run: true})
+	t.Setenv("MONKEY_TEST", "banana")
+
+	assert.Equal(t, "banana", eval(t, `os.getenv("MONKEY_TEST")`).Inspect())
+	assert.Equal(t, "null", eval(t, `os.getenv("MONKEY_TEST_MISSING")`).Inspect())
+
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no shell to run")
+	}
+	assert.Equal(t, "{code: 3, stdout: out\n, stderr: err\n}", eval(t, `os.exec("/bin/sh", "-c", "echo out; echo err >&2; exit 3")`).Inspect())
+	assert.Contains(t, eval(t, `os.exec("/missing/program")`).Inspect(), "error: os.exec: fork/exec /missing/program")
+	assert.Equal(t, "error: os.exec: argument 2 must be STRING, got INTEGER", eval(t, `os.exec("ls", 1)`).Inspect())
+}
+
+func TestArgsAndExit(t *testing.T) {
+	SetArgs([]string{"a", "b"})
+	t.Cleanup(func() {
+		SetArgs(nil)
+	})
+	assert.Equal(t, "[a, b]", eval(t, `os.args()`).Inspect())
+
+	codes := []int{}
+	exit = func(code int) {
+		codes = append(codes, code)
+	}
+	t.Cleanup(func() {
+		exit = os.Exit
+	})
+	eval(t, `os.exit(2)`)
+	assert.Equal(t, []int{2}, codes)
+}
@@ -0,0 +1,241 @@
+package stdlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"strconv"
+	"strings"
+)
+
+// Functions of the json module, converting between JSON text and values of scripts:
+// objects are hashes, arrays are arrays, numbers are integers or floats and null is null
+var jsonFunctions = map[string]object.BuiltinFunction{
+	"parse":     parseJSON,
+	"stringify": stringify,
+}
+
+// json.parse(text) returns the value encoded in text, errors point at the line and column of the problem
+func parseJSON(args ...object.Object) object.Object {
+	if err := checkArgs("json.parse", args, object.STRING); err != nil {
+		return err
+	}
+	source := str(args[0])
+	d := &jsonDecoder{source: source, dec: json.NewDecoder(strings.NewReader(source))}
+	d.dec.UseNumber()
+
+	value, err := d.value()
+	if err == nil {
+		rest := source[d.dec.InputOffset():]
+		if _, err = d.dec.Token(); err == io.EOF {
+			return value
+		} else if err == nil {
+			offset := len(source) - len(strings.TrimLeft(rest, " \t\r\n"))
+			return d.error(errors.New("unexpected data after value"), int64(offset))
+		}
+	}
+	return d.error(err, d.dec.InputOffset())
+}
+
+type jsonDecoder struct {
+	source string
+	dec    *json.Decoder
+}
+
+func (d *jsonDecoder) value() (object.Object, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			return d.array()
+		}
+		return d.object()
+	case string:
+		return &object.String{Value: tok}, nil
+	case json.Number:
+		if i, err := strconv.Atoi(tok.String()); err == nil {
+			return &object.Integer{Value: i}, nil
+		}
+		f, err := tok.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("number %s out of range", tok)
+		}
+		return &object.Float{Value: f}, nil
+	case bool:
+		return toBoolean(tok), nil
+	}
+	return evaluator.NULL_VAL, nil
+}
+
+func (d *jsonDecoder) array() (object.Object, error) {
+	out := &object.Array{Elements: []object.Object{}}
+	for d.dec.More() {
+		element, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		out.Elements = append(out.Elements, element)
+	}
+	return out, d.closing()
+}
+
+func (d *jsonDecoder) object() (object.Object, error) {
+	out := object.NewHash()
+	for d.dec.More() {
+		key, err := d.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		out.Set(&object.String{Value: key.(string)}, value)
+	}
+	return out, d.closing()
+}
+
+// closing reads the delimiter ending an array or an object
+func (d *jsonDecoder) closing() error {
+	_, err := d.dec.Token()
+	return err
+}
+
+// error locates the problem, syntax errors know their offset, others happen at the given one
+func (d *jsonDecoder) error(err error, offset int64) *object.Error {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) && syntax.Error() != "unexpected end of JSON input" {
+		offset = syntax.Offset - 1
+	} else if syntax != nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errors.New("unexpected end of input")
+		offset = int64(len(d.source))
+	}
+
+	line, column := 1, 1
+	for _, r := range d.source[:offset] {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return newError("json.parse", "%v at line %d, column %d", err, line, column)
+}
+
+// json.stringify(value) returns compact JSON text, json.stringify(value, indent) puts
+// elements on separate lines, indented with the string or the number of spaces
+func stringify(args ...object.Object) object.Object {
+	indent := ""
+	if len(args) == 2 {
+		switch i := args[1].(type) {
+		case *object.Integer:
+			if i.Value < 0 {
+				return newError("json.stringify", "negative indent %d", i.Value)
+			}
+			indent = strings.Repeat(" ", i.Value)
+		case *object.String:
+			indent = i.Value
+		default:
+			return newError("json.stringify", "argument 2 must be INTEGER or STRING, got %s", args[1].Type())
+		}
+	} else if err := checkArgs("json.stringify", args, ANY); err != nil {
+		return err
+	}
+
+	e := &jsonEncoder{visiting: map[object.Object]bool{}}
+	if err := e.encode(args[0], "$"); err != nil {
+		return newError("json.stringify", "%v", err)
+	}
+	if indent == "" {
+		return &object.String{Value: e.out.String()}
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, e.out.Bytes(), "", indent); err != nil {
+		return newError("json.stringify", "%v", err)
+	}
+	return &object.String{Value: out.String()}
+}
+
+type jsonEncoder struct {
+	out      bytes.Buffer
+	visiting map[object.Object]bool // arrays and hashes being encoded, seeing one again is a cycle
+}
+
+// encode writes the value, path describes where it is in the encoded structure, like $["a"][0]
+func (e *jsonEncoder) encode(value object.Object, path string) error {
+	switch v := value.(type) {
+	case *object.Integer:
+		e.out.WriteString(v.Inspect())
+	case *object.Float:
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			return fmt.Errorf("can't serialise %s at %s", v.Inspect(), path)
+		}
+		e.out.WriteString(parser.FormatFloat(v.Value))
+	case *object.String:
+		e.string(v.Value)
+	case *object.Boolean:
+		e.out.WriteString(v.Inspect())
+	case *object.Null:
+		e.out.WriteString("null")
+	case *object.Array:
+		if e.visiting[v] {
+			return fmt.Errorf("cyclic structure at %s", path)
+		}
+		e.visiting[v] = true
+		defer delete(e.visiting, v)
+
+		e.out.WriteString("[")
+		for i, el := range v.Elements {
+			if i > 0 {
+				e.out.WriteString(",")
+			}
+			if err := e.encode(el, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		e.out.WriteString("]")
+	case *object.Hash:
+		if e.visiting[v] {
+			return fmt.Errorf("cyclic structure at %s", path)
+		}
+		e.visiting[v] = true
+		defer delete(e.visiting, v)
+
+		e.out.WriteString("{")
+		for i, k := range v.Keys {
+			if i > 0 {
+				e.out.WriteString(",")
+			}
+			// integer keys are written as strings, JSON has no other keys
+			pair := v.Pairs[k]
+			e.string(pair.Key.Inspect())
+			e.out.WriteString(":")
+			if err := e.encode(pair.Value, fmt.Sprintf("%s[%s]", path, strconv.Quote(pair.Key.Inspect()))); err != nil {
+				return err
+			}
+		}
+		e.out.WriteString("}")
+	default:
+		return fmt.Errorf("can't serialise %s at %s", value.Type(), path)
+	}
+	return nil
+}
+
+// string writes the quoted text, unlike json.Marshal it leaves <, > and & as they are
+func (e *jsonEncoder) string(s string) {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	e.out.Write(bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+}
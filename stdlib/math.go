@@ -0,0 +1,218 @@
+package stdlib
+
+import (
+	"math"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+)
+
+// Functions of the math module. They accept integers and floats,
+// results are integers when that's exact, like abs(-2) or pow(2, 10)
+var mathFunctions = map[string]object.BuiltinFunction{
+	"abs":   abs,
+	"min":   minimum,
+	"max":   maximum,
+	"pow":   pow,
+	"sqrt":  sqrt,
+	"floor": rounding("math.floor", math.Floor),
+	"ceil":  rounding("math.ceil", math.Ceil),
+	"round": rounding("math.round", math.Round),
+	"sin":   unary("math.sin", math.Sin),
+	"cos":   unary("math.cos", math.Cos),
+	"tan":   unary("math.tan", math.Tan),
+	"asin":  unary("math.asin", math.Asin),
+	"acos":  unary("math.acos", math.Acos),
+	"atan":  unary("math.atan", math.Atan),
+	"atan2": atan2,
+	"gcd":   gcd,
+	"lcm":   lcm,
+	"clamp": clamp,
+}
+
+var mathValues = map[string]object.Object{
+	"pi": &object.Float{Value: math.Pi},
+	"e":  &object.Float{Value: math.E},
+}
+
+// abs(x) returns the absolute value, keeping the type of x
+func abs(args ...object.Object) object.Object {
+	if err := checkArgs("math.abs", args, NUMBER); err != nil {
+		return err
+	}
+	if i, ok := args[0].(*object.Integer); ok {
+		if i.Value < 0 {
+			return &object.Integer{Value: -i.Value}
+		}
+		return i
+	}
+	return &object.Float{Value: math.Abs(evaluator.ToFloat(args[0]))}
+}
+
+// min(x, ...) returns the smallest of the numbers
+func minimum(args ...object.Object) object.Object {
+	return extreme("math.min", args, func(a, b float64) bool { return a < b })
+}
+
+// max(x, ...) returns the largest of the numbers
+func maximum(args ...object.Object) object.Object {
+	return extreme("math.max", args, func(a, b float64) bool { return a > b })
+}
+
+// extreme returns the first argument that no other one is better than
+func extreme(name string, args []object.Object, better func(a, b float64) bool) object.Object {
+	if len(args) == 0 {
+		return newError(name, "wrong number of arguments: want at least 1, got 0")
+	}
+	var out object.Object
+	for i, a := range args {
+		if !isNumber(a) {
+			return newError(name, "argument %d must be %s, got %s", i+1, NUMBER, a.Type())
+		}
+		if out == nil || better(evaluator.ToFloat(a), evaluator.ToFloat(out)) {
+			out = a
+		}
+	}
+	return out
+}
+
+// pow(x, y) raises x to the power y, integer for integer x and non-negative integer y
+func pow(args ...object.Object) object.Object {
+	if err := checkArgs("math.pow", args, NUMBER, NUMBER); err != nil {
+		return err
+	}
+	base, baseIsInt := args[0].(*object.Integer)
+	exp, expIsInt := args[1].(*object.Integer)
+	if !baseIsInt || !expIsInt || exp.Value < 0 {
+		return &object.Float{Value: math.Pow(evaluator.ToFloat(args[0]), evaluator.ToFloat(args[1]))}
+	}
+
+	out, b, e := 1, base.Value, exp.Value
+	for e > 0 {
+		var ok bool
+		if e%2 == 1 {
+			if out, ok = multiply(out, b); !ok {
+				return newError("math.pow", "integer overflow for %d ** %d", base.Value, exp.Value)
+			}
+		}
+		if e /= 2; e == 0 {
+			break
+		}
+		if b, ok = multiply(b, b); !ok {
+			return newError("math.pow", "integer overflow for %d ** %d", base.Value, exp.Value)
+		}
+	}
+	return &object.Integer{Value: out}
+}
+
+// sqrt(x) returns the square root as a float
+func sqrt(args ...object.Object) object.Object {
+	if err := checkArgs("math.sqrt", args, NUMBER); err != nil {
+		return err
+	}
+	x := evaluator.ToFloat(args[0])
+	if x < 0 {
+		return newError("math.sqrt", "negative argument %s", args[0].Inspect())
+	}
+	return &object.Float{Value: math.Sqrt(x)}
+}
+
+// rounding builds floor, ceil and round, they return integers
+func rounding(name string, f func(float64) float64) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgs(name, args, NUMBER); err != nil {
+			return err
+		}
+		if i, ok := args[0].(*object.Integer); ok {
+			return i
+		}
+		v := f(evaluator.ToFloat(args[0]))
+		if math.IsNaN(v) || v > math.MaxInt64 || v < math.MinInt64 {
+			return newError(name, "%s can't be represented as an integer", args[0].Inspect())
+		}
+		return &object.Integer{Value: int(v)}
+	}
+}
+
+// unary builds functions of one float, like trigonometric ones
+func unary(name string, f func(float64) float64) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgs(name, args, NUMBER); err != nil {
+			return err
+		}
+		return &object.Float{Value: f(evaluator.ToFloat(args[0]))}
+	}
+}
+
+// atan2(y, x) returns the angle of the point (x, y)
+func atan2(args ...object.Object) object.Object {
+	if err := checkArgs("math.atan2", args, NUMBER, NUMBER); err != nil {
+		return err
+	}
+	return &object.Float{Value: math.Atan2(evaluator.ToFloat(args[0]), evaluator.ToFloat(args[1]))}
+}
+
+// gcd(a, b) returns the greatest common divisor of integers, always non-negative
+func gcd(args ...object.Object) object.Object {
+	if err := checkArgs("math.gcd", args, object.INTEGER, object.INTEGER); err != nil {
+		return err
+	}
+	return &object.Integer{Value: greatestDivisor(integer(args[0]), integer(args[1]))}
+}
+
+// lcm(a, b) returns the least common multiple of integers, 0 when either is 0
+func lcm(args ...object.Object) object.Object {
+	if err := checkArgs("math.lcm", args, object.INTEGER, object.INTEGER); err != nil {
+		return err
+	}
+	a, b := integer(args[0]), integer(args[1])
+	if a == 0 || b == 0 {
+		return &object.Integer{Value: 0}
+	}
+	out, ok := multiply(a/greatestDivisor(a, b), b)
+	if ok && out < 0 {
+		out, ok = -out, out != math.MinInt
+	}
+	if !ok {
+		return newError("math.lcm", "integer overflow for %d and %d", a, b)
+	}
+	return &object.Integer{Value: out}
+}
+
+// multiply returns a*b and whether it fits in an int
+func multiply(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	out := a * b
+	if out/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return out, false
+	}
+	return out, true
+}
+
+func greatestDivisor(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// clamp(x, lo, hi) limits x to the range, returns the bound it crossed
+func clamp(args ...object.Object) object.Object {
+	if err := checkArgs("math.clamp", args, NUMBER, NUMBER, NUMBER); err != nil {
+		return err
+	}
+	x, lo, hi := evaluator.ToFloat(args[0]), evaluator.ToFloat(args[1]), evaluator.ToFloat(args[2])
+	if lo > hi {
+		return newError("math.clamp", "empty range [%s, %s]", args[1].Inspect(), args[2].Inspect())
+	}
+	if x < lo {
+		return args[1]
+	} else if x > hi {
+		return args[2]
+	}
+	return args[0]
+}
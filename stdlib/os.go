@@ -0,0 +1,111 @@
+package stdlib
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+	"sync"
+)
+
+// Functions of the os module, environment and processes need permissions granted by Allow
+var osFunctions = map[string]object.BuiltinFunction{
+	"getenv": getenv,
+	"args":   scriptArgs,
+	"exit":   exitScript,
+	"exec":   run,
+}
+
+var (
+	argsMu sync.Mutex
+	args   = []string{}
+
+	// exit ends the process, replaced in tests
+	exit = os.Exit
+)
+
+// SetArgs sets arguments of the script returned by os.args
+func SetArgs(values []string) {
+	argsMu.Lock()
+	defer argsMu.Unlock()
+	args = append([]string{}, values...)
+}
+
+// os.getenv(name) returns the environment variable or null when it isn't set
+func getenv(arguments ...object.Object) object.Object {
+	if err := checkArgs("os.getenv", arguments, object.STRING); err != nil {
+		return err
+	}
+	permissionsMu.RLock()
+	allowed := permissions.Env
+	permissionsMu.RUnlock()
+	if !allowed {
+		return newError("os.getenv", "environment access denied, allow it with --allow-env")
+	}
+
+	value, ok := os.LookupEnv(str(arguments[0]))
+	if !ok {
+		return evaluator.NULL_VAL
+	}
+	return &object.String{Value: value}
+}
+
+// os.args() returns arguments given to the script after the interpreter flags
+func scriptArgs(arguments ...object.Object) object.Object {
+	if err := checkArgs("os.args", arguments); err != nil {
+		return err
+	}
+	argsMu.Lock()
+	defer argsMu.Unlock()
+	return toStrings(args)
+}
+
+// os.exit(code) ends the program with the exit code
+func exitScript(arguments ...object.Object) object.Object {
+	if err := checkArgs("os.exit", arguments, object.INTEGER); err != nil {
+		return err
+	}
+	exit(integer(arguments[0]))
+	return evaluator.NULL_VAL
+}
+
+// os.exec(name, args...) runs the program without a shell and waits for it,
+// returns a hash with its exit code, stdout and stderr
+func run(arguments ...object.Object) object.Object {
+	if len(arguments) == 0 {
+		return newError("os.exec", "wrong number of arguments: want at least 1, got 0")
+	}
+	commandArgs := []string{}
+	for i, a := range arguments {
+		if a.Type() != object.STRING {
+			return newError("os.exec", "argument %d must be %s, got %s", i+1, object.STRING, a.Type())
+		}
+		commandArgs = append(commandArgs, str(a))
+	}
+	permissionsMu.RLock()
+	allowed := permissions.Run
+	permissionsMu.RUnlock()
+	if !allowed {
+		return newError("os.exec", "running %s denied, allow it with --allow-run", commandArgs[0])
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(commandArgs[0], commandArgs[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return newError("os.exec", "%v", err)
+		}
+		code = exitErr.ExitCode()
+	}
+
+	out := object.NewHash()
+	out.Set(&object.String{Value: "code"}, &object.Integer{Value: code})
+	out.Set(&object.String{Value: "stdout"}, &object.String{Value: stdout.String()})
+	out.Set(&object.String{Value: "stderr"}, &object.String{Value: stderr.String()})
+	return out
+}
@@ -0,0 +1,99 @@
+package stdlib
+
+import (
+	"math"
+	"math/rand"
+	"programming-lang/object"
+	"sync"
+	"time"
+)
+
+// Functions of the rand module, a pseudo-random generator shared by the whole program
+var randFunctions = map[string]object.BuiltinFunction{
+	"int":     randomInt,
+	"float":   randomFloat,
+	"choice":  choice,
+	"shuffle": shuffle,
+}
+
+var (
+	randomMu sync.Mutex
+	random   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Seed restarts the generator of the rand module, runs with the same seed get the same numbers
+func Seed(seed int64) {
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	random = rand.New(rand.NewSource(seed))
+}
+
+// rand.int(n) returns an integer from [0, n), rand.int(lo, hi) from [lo, hi)
+func randomInt(args ...object.Object) object.Object {
+	lo, hi := 0, 0
+	if len(args) == 1 {
+		if err := checkArgs("rand.int", args, object.INTEGER); err != nil {
+			return err
+		}
+		hi = integer(args[0])
+	} else {
+		if err := checkArgs("rand.int", args, object.INTEGER, object.INTEGER); err != nil {
+			return err
+		}
+		lo, hi = integer(args[0]), integer(args[1])
+	}
+	if hi <= lo {
+		return newError("rand.int", "empty range [%d, %d)", lo, hi)
+	}
+
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	// hi-lo can overflow an int, the unsigned span can't
+	span := uint64(hi) - uint64(lo)
+	if span <= math.MaxInt {
+		return &object.Integer{Value: lo + random.Intn(int(span))}
+	}
+	n := random.Uint64()
+	for n >= span {
+		n = random.Uint64()
+	}
+	return &object.Integer{Value: int(uint64(lo) + n)}
+}
+
+// rand.float() returns a float from [0, 1)
+func randomFloat(args ...object.Object) object.Object {
+	if err := checkArgs("rand.float", args); err != nil {
+		return err
+	}
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	return &object.Float{Value: random.Float64()}
+}
+
+// rand.choice(array) returns a random element of the array
+func choice(args ...object.Object) object.Object {
+	if err := checkArgs("rand.choice", args, object.ARRAY); err != nil {
+		return err
+	}
+	elements := args[0].(*object.Array).Elements
+	if len(elements) == 0 {
+		return newError("rand.choice", "empty array")
+	}
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	return elements[random.Intn(len(elements))]
+}
+
+// rand.shuffle(array) returns a new array with elements in random order
+func shuffle(args ...object.Object) object.Object {
+	if err := checkArgs("rand.shuffle", args, object.ARRAY); err != nil {
+		return err
+	}
+	elements := append([]object.Object{}, args[0].(*object.Array).Elements...)
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	random.Shuffle(len(elements), func(i, j int) {
+		elements[i], elements[j] = elements[j], elements[i]
+	})
+	return &object.Array{Elements: elements}
+}
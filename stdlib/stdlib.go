@@ -0,0 +1,123 @@
+// Package stdlib implements modules of the standard library as Go builtins.
+// They are imported by name, without the file extension:
+//
+//	import "strings" as strings;
+//	strings.upper("hi");
+//
+// Every builtin reports wrong arguments as an error object prefixed with its name,
+// like "strings.split: argument 1 must be STRING, got INTEGER".
+//
+// Module sync creates channels and wait groups for spawned calls.
+//
+// Modules io and os reach outside of the interpreter, their functions fail
+// unless the capability is granted with Allow.
+package stdlib
+
+import (
+	"fmt"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+	"sort"
+)
+
+var modules = map[string]*object.Module{}
+
+// register builds the module, all functions and values are exported
+func register(name string, functions map[string]object.BuiltinFunction, values map[string]object.Object) {
+	env := object.NewEnvironment()
+	for fnName, fn := range functions {
+		env.Set(fnName, &object.Builtin{Name: name + "." + fnName, Fn: fn})
+		env.Export(fnName)
+	}
+	for valueName, value := range values {
+		env.Set(valueName, value)
+		env.Export(valueName)
+	}
+	modules[name] = &object.Module{Name: name, Env: env}
+}
+
+func init() {
+	register("strings", stringFunctions, nil)
+	register("math", mathFunctions, mathValues)
+	register("rand", randFunctions, nil)
+	register("json", jsonFunctions, nil)
+	register("io", ioFunctions, nil)
+	register("os", osFunctions, nil)
+	register("time", timeFunctions, timeValues)
+	register("sync", syncFunctions, syncValues)
+}
+
+// Lookup returns the standard library module, modules are shared by all importers
+func Lookup(name string) (*object.Module, bool) {
+	module, ok := modules[name]
+	return module, ok
+}
+
+// Names lists modules of the standard library, sorted
+func Names() []string {
+	out := []string{}
+	for name := range modules {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+const (
+	// ANY accepts arguments of every type in checkArgs
+	ANY object.ObjectType = ""
+	// NUMBER accepts integers and floats in checkArgs
+	NUMBER object.ObjectType = "NUMBER"
+)
+
+// checkArgs verifies count and types of arguments of the builtin
+func checkArgs(name string, args []object.Object, types ...object.ObjectType) *object.Error {
+	if len(args) != len(types) {
+		return newError(name, "wrong number of arguments: want=%d, got=%d", len(types), len(args))
+	}
+	return checkTypes(name, args, types...)
+}
+
+// checkTypes verifies types of the leading arguments, the rest is not checked
+func checkTypes(name string, args []object.Object, types ...object.ObjectType) *object.Error {
+	for i, t := range types {
+		if i >= len(args) || t == ANY || (t == NUMBER && isNumber(args[i])) {
+			continue
+		}
+		if args[i].Type() != t {
+			return newError(name, "argument %d must be %s, got %s", i+1, t, args[i].Type())
+		}
+	}
+	return nil
+}
+
+func newError(name string, format string, args ...any) *object.Error {
+	return &object.Error{Message: name + ": " + fmt.Sprintf(format, args...)}
+}
+
+func toBoolean(v bool) object.Object {
+	if v {
+		return evaluator.TRUE_VAL
+	}
+	return evaluator.FALSE_VAL
+}
+
+func toStrings(values []string) *object.Array {
+	out := &object.Array{Elements: []object.Object{}}
+	for _, v := range values {
+		out.Elements = append(out.Elements, &object.String{Value: v})
+	}
+	return out
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER || obj.Type() == object.FLOAT
+}
+
+func str(obj object.Object) string {
+	return obj.(*object.String).Value
+}
+
+func integer(obj object.Object) int {
+	return obj.(*object.Integer).Value
+}
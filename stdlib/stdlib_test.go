@@ -0,0 +1,228 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"programming-lang/evaluator"
+	"programming-lang/lexer"
+	"programming-lang/object"
+	"programming-lang/parser"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stdlibImporter struct{}
+
+func (stdlibImporter) Import(path string) (*object.Module, error) {
+	if module, ok := Lookup(path); ok {
+		return module, nil
+	}
+	return nil, fmt.Errorf("module not found")
+}
+
+// runScript evaluates test script statement by statement. Two statements are checked:
+//
+//	expect(actual, expected);
+//	expectError(expression, "message");
+//
+// Failures are reported with the line of the statement. The clock is frozen at 2024-01-02T03:04:05.678Z
+func runScript(t *testing.T, path string) {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(string(content)))
+	require.Empty(t, program.Errors)
+
+	e := evaluator.New()
+	e.Importer = stdlibImporter{}
+	e.Clock = NewFrozenClock(time.Date(2024, 1, 2, 3, 4, 5, 678e6, time.UTC))
+	env := object.NewEnvironment()
+	for _, st := range program.Statements {
+		line := fmt.Sprintf("%s:%d", filepath.Base(path), st.Position().Line)
+		call := expectCall(st)
+		if call == nil {
+			result := e.Eval(st, env)
+			require.False(t, isError(result), "%s: %v", line, result)
+			continue
+		}
+
+		require.Len(t, call.Arguments, 2, "%s: expect takes 2 arguments", line)
+		actual := e.Eval(call.Arguments[0], env)
+		expected := e.Eval(call.Arguments[1], env)
+		require.False(t, isError(expected), "%s: %v", line, expected)
+		if call.Function.String() == "expectError" {
+			if assert.True(t, isError(actual), "%s: expected error, got %v", line, actual) {
+				assert.Equal(t, expected.Inspect(), actual.(*object.Error).Message, line)
+			}
+			continue
+		}
+		assert.True(t, equal(actual, expected), "%s: expected %v (%v), got %v (%v)", line, expected.Inspect(), expected.Type(), actual.Inspect(), actual.Type())
+	}
+}
+
+func expectCall(st parser.StatementNode) *parser.CallExpression {
+	exp, ok := st.(*parser.ExpressionStatementNode)
+	if !ok {
+		return nil
+	}
+	call, ok := exp.Value.(*parser.CallExpression)
+	if !ok {
+		return nil
+	}
+	if name := call.Function.String(); name != "expect" && name != "expectError" {
+		return nil
+	}
+	return call
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR
+}
+
+// equal compares values by type and content, arrays element by element, hashes key by key in order
+func equal(a, b object.Object) bool {
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+	if hashA, ok := a.(*object.Hash); ok {
+		hashB := b.(*object.Hash)
+		if len(hashA.Keys) != len(hashB.Keys) {
+			return false
+		}
+		for i, k := range hashA.Keys {
+			if k != hashB.Keys[i] || !equal(hashA.Pairs[k].Value, hashB.Pairs[k].Value) {
+				return false
+			}
+		}
+		return true
+	}
+	arrA, ok := a.(*object.Array)
+	if !ok {
+		return a.Inspect() == b.Inspect()
+	}
+	arrB := b.(*object.Array)
+	if len(arrA.Elements) != len(arrB.Elements) {
+		return false
+	}
+	for i := range arrA.Elements {
+		if !equal(arrA.Elements[i], arrB.Elements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestScripts(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.mk"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			runScript(t, file)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	module, ok := Lookup("strings")
+	require.True(t, ok)
+	upper, ok := module.Member("upper")
+	require.True(t, ok)
+	assert.Equal(t, "builtin strings.upper", upper.Inspect())
+
+	_, ok = Lookup("strings.mk")
+	assert.False(t, ok)
+	assert.Contains(t, Names(), "strings")
+}
+
+func TestSeed(t *testing.T) {
+	module, _ := Lookup("rand")
+	intFn, _ := module.Member("int")
+	shuffleFn, _ := module.Member("shuffle")
+	draw := func() []string {
+		out := []string{}
+		for i := 0; i < 5; i++ {
+			out = append(out, intFn.(*object.Builtin).Fn(&object.Integer{Value: 1000}).Inspect())
+		}
+		array := &object.Array{Elements: []object.Object{
+			&object.Integer{Value: 1}, &object.Integer{Value: 2}, &object.Integer{Value: 3}, &object.Integer{Value: 4},
+		}}
+		shuffled := shuffleFn.(*object.Builtin).Fn(array)
+		assert.Equal(t, "[1, 2, 3, 4]", array.Inspect(), "shuffle must not modify the argument")
+		return append(out, shuffled.Inspect())
+	}
+
+	Seed(42)
+	first := draw()
+	Seed(42)
+	assert.Equal(t, first, draw())
+}
+
+func TestPatternCacheIsBounded(t *testing.T) {
+	for i := 0; i < 2*maxPatterns; i++ {
+		_, err := compile("strings.regexMatch", fmt.Sprintf("a{%d}", i))
+		assert.Nil(t, err)
+	}
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+	assert.LessOrEqual(t, len(patterns), maxPatterns)
+}
+
+func TestStringifyCycles(t *testing.T) {
+	module, _ := Lookup("json")
+	stringifyFn, _ := module.Member("stringify")
+	stringify := stringifyFn.(*object.Builtin).Fn
+
+	hash := object.NewHash()
+	array := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}, hash}}
+	hash.Set(&object.String{Value: "xs"}, array)
+	assert.Equal(t, `json.stringify: cyclic structure at $["xs"][1]`, stringify(hash).Inspect()[len("error: "):])
+
+	// the same value twice is not a cycle
+	shared := &object.Array{Elements: []object.Object{}}
+	assert.Equal(t, "[[],[]]", stringify(&object.Array{Elements: []object.Object{shared, shared}}).Inspect())
+}
+
+func TestClockOfEvaluator(t *testing.T) {
+	code := `import "time" as time; var start = time.now(); time.sleep(time.second); time.now() - start`
+	clocks := []*FrozenClock{NewFrozenClock(time.UnixMilli(0)), NewFrozenClock(time.UnixMilli(5000))}
+	results := make([]object.Object, len(clocks))
+
+	var wg sync.WaitGroup
+	for i, clock := range clocks {
+		wg.Add(1)
+		go func(i int, clock *FrozenClock) {
+			defer wg.Done()
+			e := evaluator.New()
+			e.Importer = stdlibImporter{}
+			e.Clock = clock
+			results[i] = e.Eval(parser.Parse(lexer.Tokenize(code)), object.NewEnvironment())
+		}(i, clock)
+	}
+	wg.Wait()
+
+	for i, clock := range clocks {
+		assert.Equal(t, "1000", results[i].Inspect())
+		assert.Equal(t, int64(1000+5000*i), clock.Now().UnixMilli())
+	}
+}
+
+func TestSleepIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	program := parser.Parse(lexer.Tokenize(`import "time" as time; time.sleep(time.hour); 1`))
+	e := evaluator.New()
+	e.Importer = stdlibImporter{}
+	e.Context = ctx
+
+	start := time.Now()
+	result := e.Eval(program, object.NewEnvironment())
+	assert.Equal(t, "error: time.sleep: context deadline exceeded", result.Inspect())
+	assert.Less(t, time.Since(start), time.Minute)
+}
@@ -0,0 +1,310 @@
+package stdlib
+
+import (
+	"fmt"
+	"math"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Functions of the strings module. Indexes and lengths count runes, not bytes
+var stringFunctions = map[string]object.BuiltinFunction{
+	"split":        split,
+	"join":         join,
+	"trim":         trim,
+	"contains":     contains,
+	"index":        index,
+	"replace":      replace,
+	"upper":        upper,
+	"lower":        lower,
+	"repeat":       repeat,
+	"startsWith":   startsWith,
+	"endsWith":     endsWith,
+	"length":       length,
+	"substring":    substring,
+	"sprintf":      sprintf,
+	"format":       format,
+	"regexMatch":   regexMatch,
+	"regexFind":    regexFind,
+	"regexFindAll": regexFindAll,
+	"regexReplace": regexReplace,
+}
+
+// split(s, sep) returns parts of s between separators, empty sep splits into runes
+func split(args ...object.Object) object.Object {
+	if err := checkArgs("strings.split", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	return toStrings(strings.Split(str(args[0]), str(args[1])))
+}
+
+// join(parts, sep) concatenates the array of strings, placing sep between them
+func join(args ...object.Object) object.Object {
+	if err := checkArgs("strings.join", args, object.ARRAY, object.STRING); err != nil {
+		return err
+	}
+	parts := []string{}
+	for i, e := range args[0].(*object.Array).Elements {
+		s, ok := e.(*object.String)
+		if !ok {
+			return newError("strings.join", "element %d must be STRING, got %s", i, e.Type())
+		}
+		parts = append(parts, s.Value)
+	}
+	return &object.String{Value: strings.Join(parts, str(args[1]))}
+}
+
+// trim(s) removes leading and trailing whitespace, trim(s, cutset) removes runes of the cutset instead
+func trim(args ...object.Object) object.Object {
+	if len(args) == 1 {
+		if err := checkArgs("strings.trim", args, object.STRING); err != nil {
+			return err
+		}
+		return &object.String{Value: strings.TrimSpace(str(args[0]))}
+	}
+	if err := checkArgs("strings.trim", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.Trim(str(args[0]), str(args[1]))}
+}
+
+// contains(s, sub) reports whether sub is within s
+func contains(args ...object.Object) object.Object {
+	if err := checkArgs("strings.contains", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	return toBoolean(strings.Contains(str(args[0]), str(args[1])))
+}
+
+// index(s, sub) returns rune index of the first sub in s, -1 when it's missing
+func index(args ...object.Object) object.Object {
+	if err := checkArgs("strings.index", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	s := str(args[0])
+	i := strings.Index(s, str(args[1]))
+	if i > 0 {
+		i = utf8.RuneCountInString(s[:i])
+	}
+	return &object.Integer{Value: i}
+}
+
+// replace(s, old, new) replaces all occurrences of old
+func replace(args ...object.Object) object.Object {
+	if err := checkArgs("strings.replace", args, object.STRING, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ReplaceAll(str(args[0]), str(args[1]), str(args[2]))}
+}
+
+// upper(s) maps all letters to upper case
+func upper(args ...object.Object) object.Object {
+	if err := checkArgs("strings.upper", args, object.STRING); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToUpper(str(args[0]))}
+}
+
+// lower(s) maps all letters to lower case
+func lower(args ...object.Object) object.Object {
+	if err := checkArgs("strings.lower", args, object.STRING); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToLower(str(args[0]))}
+}
+
+// repeat(s, n) concatenates n copies of s
+func repeat(args ...object.Object) object.Object {
+	if err := checkArgs("strings.repeat", args, object.STRING, object.INTEGER); err != nil {
+		return err
+	}
+	if integer(args[1]) < 0 {
+		return newError("strings.repeat", "negative count %d", integer(args[1]))
+	}
+	if count := integer(args[1]); count > 0 && len(str(args[0])) > math.MaxInt/count {
+		return newError("strings.repeat", "result too long for count %d", count)
+	}
+	return &object.String{Value: strings.Repeat(str(args[0]), integer(args[1]))}
+}
+
+// startsWith(s, prefix) reports whether s begins with the prefix
+func startsWith(args ...object.Object) object.Object {
+	if err := checkArgs("strings.startsWith", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	return toBoolean(strings.HasPrefix(str(args[0]), str(args[1])))
+}
+
+// endsWith(s, suffix) reports whether s ends with the suffix
+func endsWith(args ...object.Object) object.Object {
+	if err := checkArgs("strings.endsWith", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	return toBoolean(strings.HasSuffix(str(args[0]), str(args[1])))
+}
+
+// length(s) returns number of runes in s
+func length(args ...object.Object) object.Object {
+	if err := checkArgs("strings.length", args, object.STRING); err != nil {
+		return err
+	}
+	return &object.Integer{Value: utf8.RuneCountInString(str(args[0]))}
+}
+
+// substring(s, start, end) returns runes from start up to, but not including, end
+func substring(args ...object.Object) object.Object {
+	if err := checkArgs("strings.substring", args, object.STRING, object.INTEGER, object.INTEGER); err != nil {
+		return err
+	}
+	runes := []rune(str(args[0]))
+	start, end := integer(args[1]), integer(args[2])
+	if start < 0 || end > len(runes) || start > end {
+		return newError("strings.substring", "range [%d:%d] out of bounds for length %d", start, end, len(runes))
+	}
+	return &object.String{Value: string(runes[start:end])}
+}
+
+// sprintf(format, args...) formats like Go's fmt.Sprintf, e.g. %d, %s, %v, %5.2q
+func sprintf(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("strings.sprintf", "wrong number of arguments: want at least 1, got 0")
+	}
+	if err := checkTypes("strings.sprintf", args, object.STRING); err != nil {
+		return err
+	}
+	values := []any{}
+	for _, a := range args[1:] {
+		switch v := a.(type) {
+		case *object.Integer:
+			values = append(values, v.Value)
+		case *object.String:
+			values = append(values, v.Value)
+		case *object.Boolean:
+			values = append(values, v.Value)
+		default:
+			values = append(values, a.Inspect())
+		}
+	}
+	return &object.String{Value: fmt.Sprintf(str(args[0]), values...)}
+}
+
+// format(template, args...) replaces each {} with the next argument, {{ and }} stand for literal braces
+func format(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("strings.format", "wrong number of arguments: want at least 1, got 0")
+	}
+	if err := checkTypes("strings.format", args, object.STRING); err != nil {
+		return err
+	}
+
+	template := str(args[0])
+	values := args[1:]
+	var out strings.Builder
+	for i := 0; i < len(template); i++ {
+		rest := template[i:]
+		switch {
+		case strings.HasPrefix(rest, "{{"), strings.HasPrefix(rest, "}}"):
+			out.WriteByte(template[i])
+			i++
+		case strings.HasPrefix(rest, "{}"):
+			if len(values) == 0 {
+				return newError("strings.format", "not enough arguments for placeholders in %q", template)
+			}
+			out.WriteString(values[0].Inspect())
+			values = values[1:]
+			i++
+		default:
+			out.WriteByte(template[i])
+		}
+	}
+	if len(values) > 0 {
+		return newError("strings.format", "%d arguments left without placeholders", len(values))
+	}
+	return &object.String{Value: out.String()}
+}
+
+// maxPatterns bounds the cache, scripts building patterns from data would grow it forever
+const maxPatterns = 256
+
+var (
+	patternsMu sync.Mutex
+	patterns   = map[string]*regexp.Regexp{}
+)
+
+// compile caches compiled patterns, scripts tend to use the same ones in loops.
+// When the cache is full an arbitrary pattern is evicted.
+func compile(name string, pattern string) (*regexp.Regexp, *object.Error) {
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newError(name, "invalid pattern: %v", err)
+	}
+	if len(patterns) >= maxPatterns {
+		for old := range patterns {
+			delete(patterns, old)
+			break
+		}
+	}
+	patterns[pattern] = re
+	return re, nil
+}
+
+// regexMatch(pattern, s) reports whether s contains a match of the pattern, uses Go regexp syntax
+func regexMatch(args ...object.Object) object.Object {
+	if err := checkArgs("strings.regexMatch", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	re, err := compile("strings.regexMatch", str(args[0]))
+	if err != nil {
+		return err
+	}
+	return toBoolean(re.MatchString(str(args[1])))
+}
+
+// regexFind(pattern, s) returns the first match, null when there's none
+func regexFind(args ...object.Object) object.Object {
+	if err := checkArgs("strings.regexFind", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	re, err := compile("strings.regexFind", str(args[0]))
+	if err != nil {
+		return err
+	}
+	loc := re.FindStringIndex(str(args[1]))
+	if loc == nil {
+		return evaluator.NULL_VAL
+	}
+	return &object.String{Value: str(args[1])[loc[0]:loc[1]]}
+}
+
+// regexFindAll(pattern, s) returns array of all matches
+func regexFindAll(args ...object.Object) object.Object {
+	if err := checkArgs("strings.regexFindAll", args, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	re, err := compile("strings.regexFindAll", str(args[0]))
+	if err != nil {
+		return err
+	}
+	return toStrings(re.FindAllString(str(args[1]), -1))
+}
+
+// regexReplace(pattern, s, replacement) replaces all matches, $1 or ${name} in the replacement expand to groups
+func regexReplace(args ...object.Object) object.Object {
+	if err := checkArgs("strings.regexReplace", args, object.STRING, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	re, err := compile("strings.regexReplace", str(args[0]))
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: re.ReplaceAllString(str(args[1]), str(args[2]))}
+}
@@ -0,0 +1,90 @@
+package stdlib
+
+import (
+	"context"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+)
+
+// Functions of the sync module, channels are used with the <- operator and select:
+//
+//	var results = sync.channel(10);
+//	results <- 1;
+//	<-results;
+var syncFunctions = map[string]object.BuiltinFunction{
+	"channel":   channel,
+	"close":     closeChannel,
+	"waitGroup": waitGroup,
+	"add":       addToGroup,
+	"done":      doneInGroup,
+}
+
+var syncValues = map[string]object.Object{
+	"wait": &object.Builtin{Name: "sync.wait", Blocking: waitForGroup},
+}
+
+// sync.channel() returns an unbuffered channel, sync.channel(capacity) a buffered one
+func channel(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return object.NewChannel(0)
+	}
+	if err := checkArgs("sync.channel", args, object.INTEGER); err != nil {
+		return err
+	}
+	if integer(args[0]) < 0 {
+		return newError("sync.channel", "negative capacity %d", integer(args[0]))
+	}
+	return object.NewChannel(integer(args[0]))
+}
+
+// sync.close(ch) closes the channel, receiving from it gives null once it's empty
+func closeChannel(args ...object.Object) object.Object {
+	if err := checkArgs("sync.close", args, object.CHANNEL); err != nil {
+		return err
+	}
+	if err := args[0].(*object.Channel).Close(); err != nil {
+		return newError("sync.close", "%v", err)
+	}
+	return evaluator.NULL_VAL
+}
+
+// sync.waitGroup() returns a wait group with zero counter
+func waitGroup(args ...object.Object) object.Object {
+	if err := checkArgs("sync.waitGroup", args); err != nil {
+		return err
+	}
+	return object.NewWaitGroup()
+}
+
+// sync.add(wg, n) adds n to the counter of the wait group
+func addToGroup(args ...object.Object) object.Object {
+	if err := checkArgs("sync.add", args, object.WAIT_GROUP, object.INTEGER); err != nil {
+		return err
+	}
+	if err := args[0].(*object.WaitGroup).Add(integer(args[1])); err != nil {
+		return newError("sync.add", "%v", err)
+	}
+	return evaluator.NULL_VAL
+}
+
+// sync.done(wg) decrements the counter of the wait group
+func doneInGroup(args ...object.Object) object.Object {
+	if err := checkArgs("sync.done", args, object.WAIT_GROUP); err != nil {
+		return err
+	}
+	if err := args[0].(*object.WaitGroup).Add(-1); err != nil {
+		return newError("sync.done", "%v", err)
+	}
+	return evaluator.NULL_VAL
+}
+
+// sync.wait(wg) waits until the counter of the wait group drops to zero
+func waitForGroup(ctx context.Context, args ...object.Object) object.Object {
+	if err := checkArgs("sync.wait", args, object.WAIT_GROUP); err != nil {
+		return err
+	}
+	if err := args[0].(*object.WaitGroup).Wait(ctx); err != nil {
+		return newError("sync.wait", "%v", err)
+	}
+	return evaluator.NULL_VAL
+}
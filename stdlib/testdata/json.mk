@@ -0,0 +1,48 @@
+// json module
+import "json" as json;
+
+var null = if (false) { 1 };
+
+expect(json.parse("1"), 1);
+expect(json.parse("-2.5"), -2.5);
+expect(json.parse("1e2"), 100.0);
+expect(json.parse("\"a\\nb\""), "a\nb");
+expect(json.parse("true"), true);
+expect(json.parse(" null "), null);
+expect(json.parse("[1, \"a\", [], {}]"), [1, "a", [], {}]);
+expect(json.parse("{\"b\": 1, \"a\": {\"c\": [true, null]}}"), {"b": 1, "a": {"c": [true, null]}});
+expect(json.parse("{\"a\": 1, \"a\": 2}"), {"a": 2});
+
+var config = json.parse("{\"name\": \"app\", \"ports\": [80, 443]}");
+expect(config["name"], "app");
+expect(config["ports"][1], 443);
+expect(config["missing"], null);
+
+expectError(json.parse("{\"a\": x}"), "json.parse: invalid character 'x' looking for beginning of value at line 1, column 7");
+expectError(json.parse("{\n  \"a\": 1,\n  \"b\" 2\n}"), "json.parse: invalid character '2' after object key at line 3, column 7");
+expectError(json.parse("[1, 2"), "json.parse: unexpected end of input at line 1, column 6");
+expectError(json.parse(""), "json.parse: unexpected end of input at line 1, column 1");
+expectError(json.parse("\"abc"), "json.parse: unexpected end of input at line 1, column 5");
+expectError(json.parse("1 2"), "json.parse: unexpected data after value at line 1, column 3");
+expectError(json.parse("{} }"), "json.parse: invalid character '}' looking for beginning of value at line 1, column 4");
+expectError(json.parse(1), "json.parse: argument 1 must be STRING, got INTEGER");
+
+expect(json.stringify(1), "1");
+expect(json.stringify(2.0), "2.0");
+expect(json.stringify("a\"<b>"), "\"a\\\"<b>\"");
+expect(json.stringify(null), "null");
+expect(json.stringify([1, true, null, []]), "[1,true,null,[]]");
+expect(json.stringify({"b": 1, "a": [2], 3: {}}), "{\"b\":1,\"a\":[2],\"3\":{}}");
+expect(json.stringify({"a": [1, 2], "b": {}}, 2), "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {}\n}");
+expect(json.stringify([1], "\t"), "[\n\t1\n]");
+expect(json.stringify([], 2), "[]");
+
+var text = "{\"name\":\"app\",\"ports\":[80,443],\"ratio\":0.5,\"debug\":false,\"parent\":null}";
+expect(json.stringify(json.parse(text)), text);
+
+expectError(json.stringify(fn(x) { x }), "json.stringify: can't serialise FUNCTION at $");
+expectError(json.stringify({"a": [1, json.parse]}), "json.stringify: can't serialise BUILTIN at $[\"a\"][1]");
+expectError(json.stringify({"m": json}), "json.stringify: can't serialise MODULE at $[\"m\"]");
+expectError(json.stringify(1, -1), "json.stringify: negative indent -1");
+expectError(json.stringify(1, true), "json.stringify: argument 2 must be INTEGER or STRING, got BOOLEAN");
+expectError(json.stringify(), "json.stringify: wrong number of arguments: want=1, got=0");
@@ -0,0 +1,56 @@
+// math module
+import "math" as m;
+
+expect(m.abs(-3), 3);
+expect(m.abs(3), 3);
+expect(m.abs(-2.5), 2.5);
+expectError(m.abs("x"), "math.abs: argument 1 must be NUMBER, got STRING");
+
+expect(m.min(3, 1, 2), 1);
+expect(m.max(3, 1, 2), 3);
+expect(m.min(2, 1.5), 1.5);
+expect(m.max(7), 7);
+expectError(m.min(), "math.min: wrong number of arguments: want at least 1, got 0");
+expectError(m.max(1, "2"), "math.max: argument 2 must be NUMBER, got STRING");
+
+expect(m.pow(2, 10), 1024);
+expect(m.pow(3, 0), 1);
+expect(m.pow(2, -1), 0.5);
+expect(m.pow(4, 0.5), 2.0);
+expect(m.pow(-2, 63), -9223372036854775807 - 1);
+expect(m.pow(3, 39), 4052555153018976267);
+expectError(m.pow(2, 100), "math.pow: integer overflow for 2 ** 100");
+expectError(m.pow(2, 63), "math.pow: integer overflow for 2 ** 63");
+expect(m.sqrt(16), 4.0);
+expect(m.sqrt(2.25), 1.5);
+expectError(m.sqrt(-1), "math.sqrt: negative argument -1");
+
+expect(m.floor(2.7), 2);
+expect(m.floor(-2.5), -3);
+expect(m.ceil(2.1), 3);
+expect(m.round(2.5), 3);
+expect(m.round(-2.4), -2);
+expect(m.round(7), 7);
+
+expect(m.sin(0), 0.0);
+expect(m.cos(0), 1.0);
+expect(m.round(m.tan(m.pi / 4) * 1000), 1000);
+expect(m.round(m.asin(1) * 2 * 1000), m.round(m.pi * 1000));
+expect(m.acos(1), 0.0);
+expect(m.atan(0), 0.0);
+expect(m.round(m.atan2(1, 1) * 4 * 1000), m.round(m.pi * 1000));
+expect(m.round(m.e * 1000), 2718);
+
+expect(m.gcd(12, 18), 6);
+expect(m.gcd(-12, 18), 6);
+expect(m.gcd(0, 5), 5);
+expect(m.lcm(4, 6), 12);
+expect(m.lcm(-4, 6), 12);
+expect(m.lcm(0, 6), 0);
+expectError(m.lcm(9223372036854775807, 2), "math.lcm: integer overflow for 9223372036854775807 and 2");
+expectError(m.gcd(1.5, 2), "math.gcd: argument 1 must be INTEGER, got FLOAT");
+
+expect(m.clamp(5, 0, 10), 5);
+expect(m.clamp(-5, 0, 10), 0);
+expect(m.clamp(15, 0, 10.5), 10.5);
+expectError(m.clamp(1, 10, 0), "math.clamp: empty range [10, 0]");
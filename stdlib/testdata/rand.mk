@@ -0,0 +1,23 @@
+// rand module, values depend on the seed so only ranges are checked
+import "rand" as r;
+
+var inRange = fn(x, lo, hi) { if (x < lo) { false } else { x < hi } };
+
+expect(inRange(r.int(10), 0, 10), true);
+expect(inRange(r.int(-5, 5), -5, 5), true);
+expect(r.int(3, 4), 3);
+expect(inRange(r.int(-9223372036854775807, 9223372036854775807), -9223372036854775807, 9223372036854775807), true);
+expectError(r.int(0), "rand.int: empty range [0, 0)");
+expectError(r.int(5, 1), "rand.int: empty range [5, 1)");
+expectError(r.int(1.5), "rand.int: argument 1 must be INTEGER, got FLOAT");
+
+expect(inRange(r.float(), 0, 1), true);
+expectError(r.float(1), "rand.float: wrong number of arguments: want=0, got=1");
+
+expect(r.choice(["only"]), "only");
+expectError(r.choice([]), "rand.choice: empty array");
+
+var items = [1, 2, 3];
+expect(r.shuffle([]), []);
+expect(r.shuffle([1]), [1]);
+expect(items, [1, 2, 3]);
@@ -0,0 +1,60 @@
+// strings module
+import "strings" as s;
+
+// there's no null literal, if without else evaluates to null
+var null = if (false) { 1 };
+
+expect(s.split("a,b,c", ","), ["a", "b", "c"]);
+expect(s.split("abc", ""), ["a", "b", "c"]);
+expect(s.split("", ","), [""]);
+expect(s.join(["a", "b", "c"], ", "), "a, b, c");
+expect(s.join([], "-"), "");
+expectError(s.join(["a", 1], ""), "strings.join: element 1 must be STRING, got INTEGER");
+
+expect(s.trim("  hi \n"), "hi");
+expect(s.trim("--hi-", "-"), "hi");
+expect(s.contains("seafood", "foo"), true);
+expect(s.contains("seafood", "bar"), false);
+expect(s.index("chicken", "ken"), 4);
+expect(s.index("żółw", "w"), 3);
+expect(s.index("chicken", "dmr"), -1);
+expect(s.replace("oink oink", "k", "ky"), "oinky oinky");
+
+expect(s.upper("Gopher ż"), "GOPHER Ż");
+expect(s.lower("Gopher"), "gopher");
+expect(s.repeat("na", 3), "nanana");
+expect(s.repeat("na", 0), "");
+expectError(s.repeat("na", -1), "strings.repeat: negative count -1");
+expectError(s.repeat("na", 9223372036854775807), "strings.repeat: result too long for count 9223372036854775807");
+expect(s.startsWith("monkey", "mon"), true);
+expect(s.startsWith("monkey", "key"), false);
+expect(s.endsWith("monkey", "key"), true);
+
+expect(s.length("żółw"), 4);
+expect(s.substring("żółw", 1, 3), "ół");
+expect(s.substring("abc", 0, 0), "");
+expectError(s.substring("abc", 2, 4), "strings.substring: range [2:4] out of bounds for length 3");
+expectError(s.substring("abc", 2, 1), "strings.substring: range [2:1] out of bounds for length 3");
+
+expect(s.sprintf("%s is %d, %v", "x", 42, true), "x is 42, true");
+expect(s.sprintf("%05d|%-3s|", 7, "a"), "00007|a  |");
+expect(s.sprintf("%v", [1, "a"]), "[1, a]");
+expect(s.format("{} + {} = {}", 1, 2, 3), "1 + 2 = 3");
+expect(s.format("{{}} {}", "x"), "{} x");
+expect(s.format("no placeholders"), "no placeholders");
+expectError(s.format("{} {}", 1), "strings.format: not enough arguments for placeholders in \"{} {}\"");
+expectError(s.format("{}", 1, 2), "strings.format: 1 arguments left without placeholders");
+expectError(s.format(), "strings.format: wrong number of arguments: want at least 1, got 0");
+
+expect(s.regexMatch("^[a-z]+\\d$", "abc1"), true);
+expect(s.regexMatch("^[a-z]+$", "abc1"), false);
+expect(s.regexFind("\\d+", "a12b345"), "12");
+expect(s.regexFind("\\d+", "abc"), null);
+expect(s.regexFindAll("\\d+", "a12b345"), ["12", "345"]);
+expect(s.regexFindAll("\\d+", "abc"), []);
+expect(s.regexReplace("(\\w+)@(\\w+)", "me@home you@work", "$2:$1"), "home:me work:you");
+expectError(s.regexMatch("(", "x"), "strings.regexMatch: invalid pattern: error parsing regexp: missing closing ): `(`");
+
+expectError(s.split(1, ","), "strings.split: argument 1 must be STRING, got INTEGER");
+expectError(s.upper(), "strings.upper: wrong number of arguments: want=1, got=0");
+expectError(s.contains("a", "b", "c"), "strings.contains: wrong number of arguments: want=2, got=3");
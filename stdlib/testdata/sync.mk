@@ -0,0 +1,71 @@
+// spawn, channels, select and the sync module
+import "sync" as sync;
+
+var null = if (false) { 1 };
+
+var square = fn(x) { x * x };
+var squared = spawn square(4);
+expect(<-squared, 16);
+expect(<-squared, null);
+
+var failing = spawn fn() { 1 + true }();
+expectError(<-failing, "type mismatch: INTEGER + BOOLEAN");
+expectError(spawn 1(2), "not a function: INTEGER");
+
+var buffered = sync.channel(2);
+buffered <- 1;
+buffered <- 2;
+expect(<-buffered, 1);
+sync.close(buffered);
+expect(<-buffered, 2);
+expect(<-buffered, null);
+expectError(buffered <- 3, "send on closed channel");
+expectError(sync.close(buffered), "sync.close: close of closed channel");
+expectError(1 <- 2, "send to non-channel: INTEGER");
+expectError(<-"a", "receive from non-channel: STRING");
+expectError(sync.channel(-1), "sync.channel: negative capacity -1");
+
+// values are passed through an unbuffered channel until it's closed
+var produce = fn(out, i, n) {
+	if (i < n) {
+		out <- i;
+		produce(out, i + 1, n)
+	} else {
+		sync.close(out)
+	}
+};
+var consume = fn(in, sum) {
+	var v = <-in;
+	if (!v) { sum } else { consume(in, sum + v) }
+};
+var numbers = sync.channel();
+spawn produce(numbers, 1, 11);
+expect(consume(numbers, 0), 55);
+
+var box = sync.channel(1);
+expect(select { case <-box as v { v } default { "empty" } }, "empty");
+box <- 5;
+expect(select { case <-box as v { v * 2 } default { "empty" } }, 10);
+box <- 1;
+expect(select { case box <- 2 { "sent" } default { "full" } }, "full");
+expect(select { case <-box { "received" } }, "received");
+sync.close(box);
+expect(select { case <-box as v { v } }, null);
+expectError(select { case box <- 1 { 1 } }, "send on closed channel");
+expectError(select { case <-1 { 1 } }, "receive from non-channel: INTEGER");
+
+var wg = sync.waitGroup();
+var results = sync.channel(3);
+var worker = fn(n) {
+	results <- n * 10;
+	sync.done(wg)
+};
+sync.add(wg, 3);
+spawn worker(1);
+spawn worker(2);
+spawn worker(3);
+sync.wait(wg);
+expect((<-results) + (<-results) + (<-results), 60);
+sync.wait(wg);
+expectError(sync.done(wg), "sync.done: negative wait group counter");
+expectError(sync.add(1, 1), "sync.add: argument 1 must be WAIT_GROUP, got INTEGER");
@@ -0,0 +1,30 @@
+// time module, tests run with the clock frozen at 2024-01-02T03:04:05.678Z
+import "time" as time;
+
+var start = time.now();
+expect(start, 1704164645678);
+expect(time.unix(start), 1704164645);
+expect(time.unix(-1), -1);
+expect(time.fromUnix(1704164645), 1704164645000);
+
+expect(time.format(start, time.rfc3339), "2024-01-02T03:04:05Z");
+expect(time.format(start, "2006-01-02 15:04:05.000"), "2024-01-02 03:04:05.678");
+expect(time.format(start, time.dateTime, "Europe/Warsaw"), "2024-01-02 04:04:05");
+expect(time.format(0, time.dateOnly), "1970-01-01");
+expectError(time.format(0, time.dateOnly, "Mars/Olympus"), "time.format: unknown time zone Mars/Olympus");
+expectError(time.format("now", time.dateOnly), "time.format: argument 1 must be INTEGER, got STRING");
+
+expect(time.parse("2024-01-02", time.dateOnly), 1704153600000);
+expect(time.parse("2024-01-02T04:04:05+01:00", time.rfc3339), 1704164645000);
+expect(time.parse("2024-01-02 04:04:05", time.dateTime, "Europe/Warsaw"), 1704164645000);
+expectError(time.parse("x", time.dateOnly), "time.parse: parsing time \"x\" as \"2006-01-02\": cannot parse \"x\" as \"2006\"");
+
+expect(time.now() + 2 * time.hour - start, 7200000);
+expect(time.parseDuration("1h30m"), 90 * time.minute);
+expect(time.parseDuration("250ms"), 250);
+expectError(time.parseDuration("soon"), "time.parseDuration: time: invalid duration \"soon\"");
+expect(time.formatDuration(90 * time.minute + 1), "1h30m0.001s");
+
+time.sleep(5 * time.second);
+expect(time.now() - start, 5000);
+expectError(time.sleep("1s"), "time.sleep: argument 1 must be INTEGER, got STRING");
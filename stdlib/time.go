@@ -0,0 +1,177 @@
+package stdlib
+
+import (
+	"context"
+	"programming-lang/evaluator"
+	"programming-lang/object"
+	"sync"
+	"time"
+)
+
+// Functions of the time module. Times are integers, milliseconds since the Unix epoch,
+// durations are milliseconds too, so they are added and multiplied like numbers:
+//
+//	time.now() + 2 * time.hour
+var timeFunctions = map[string]object.BuiltinFunction{
+	"unix":           unix,
+	"fromUnix":       fromUnix,
+	"format":         formatTime,
+	"parse":          parseTime,
+	"parseDuration":  parseDuration,
+	"formatDuration": formatDuration,
+}
+
+var timeValues = map[string]object.Object{
+	"now":         &object.Builtin{Name: "time.now", Blocking: now},
+	"sleep":       &object.Builtin{Name: "time.sleep", Blocking: sleep},
+	"millisecond": &object.Integer{Value: 1},
+	"second":      &object.Integer{Value: 1000},
+	"minute":      &object.Integer{Value: 60 * 1000},
+	"hour":        &object.Integer{Value: 60 * 60 * 1000},
+	"rfc3339":     &object.String{Value: time.RFC3339},
+	"dateTime":    &object.String{Value: time.DateTime},
+	"dateOnly":    &object.String{Value: time.DateOnly},
+	"timeOnly":    &object.String{Value: time.TimeOnly},
+}
+
+// FrozenClock stands still until Sleep or Advance moves it, sleeping doesn't wait
+type FrozenClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFrozenClock(now time.Time) *FrozenClock {
+	return &FrozenClock{now: now}
+}
+
+func (c *FrozenClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FrozenClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *FrozenClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d > 0 {
+		c.Advance(d)
+	}
+	return nil
+}
+
+func toMillis(t time.Time) object.Object {
+	return &object.Integer{Value: int(t.UnixMilli())}
+}
+
+// location reads the optional time zone argument, UTC by default
+func location(name string, args []object.Object, i int) (*time.Location, *object.Error) {
+	if len(args) <= i {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(str(args[i]))
+	if err != nil {
+		return nil, newError(name, "%v", err)
+	}
+	return loc, nil
+}
+
+// time.now() returns the current time told by the clock of the evaluator
+func now(ctx context.Context, args ...object.Object) object.Object {
+	if err := checkArgs("time.now", args); err != nil {
+		return err
+	}
+	return toMillis(object.ClockOf(ctx).Now())
+}
+
+// time.unix(t) returns the time in seconds since the Unix epoch
+func unix(args ...object.Object) object.Object {
+	if err := checkArgs("time.unix", args, object.INTEGER); err != nil {
+		return err
+	}
+	return &object.Integer{Value: int(time.UnixMilli(int64(integer(args[0]))).Unix())}
+}
+
+// time.fromUnix(seconds) returns the time of the Unix timestamp
+func fromUnix(args ...object.Object) object.Object {
+	if err := checkArgs("time.fromUnix", args, object.INTEGER); err != nil {
+		return err
+	}
+	return toMillis(time.Unix(int64(integer(args[0])), 0))
+}
+
+// time.format(t, layout) writes the time in UTC using the Go layout, like "2006-01-02",
+// time.format(t, layout, zone) in the named time zone
+func formatTime(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		if err := checkArgs("time.format", args, object.INTEGER, object.STRING); err != nil {
+			return err
+		}
+	} else if err := checkArgs("time.format", args, object.INTEGER, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	loc, err := location("time.format", args, 2)
+	if err != nil {
+		return err
+	}
+	t := time.UnixMilli(int64(integer(args[0]))).In(loc)
+	return &object.String{Value: t.Format(str(args[1]))}
+}
+
+// time.parse(text, layout) reads the time written with the Go layout, times without zone are in UTC,
+// time.parse(text, layout, zone) in the named time zone
+func parseTime(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		if err := checkArgs("time.parse", args, object.STRING, object.STRING); err != nil {
+			return err
+		}
+	} else if err := checkArgs("time.parse", args, object.STRING, object.STRING, object.STRING); err != nil {
+		return err
+	}
+	loc, locErr := location("time.parse", args, 2)
+	if locErr != nil {
+		return locErr
+	}
+	t, err := time.ParseInLocation(str(args[1]), str(args[0]), loc)
+	if err != nil {
+		return newError("time.parse", "%v", err)
+	}
+	return toMillis(t)
+}
+
+// time.parseDuration(text) reads durations like "1h30m" or "250ms"
+func parseDuration(args ...object.Object) object.Object {
+	if err := checkArgs("time.parseDuration", args, object.STRING); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(str(args[0]))
+	if err != nil {
+		return newError("time.parseDuration", "%v", err)
+	}
+	return &object.Integer{Value: int(d.Milliseconds())}
+}
+
+// time.formatDuration(d) writes the duration like "1h30m0s"
+func formatDuration(args ...object.Object) object.Object {
+	if err := checkArgs("time.formatDuration", args, object.INTEGER); err != nil {
+		return err
+	}
+	return &object.String{Value: (time.Duration(integer(args[0])) * time.Millisecond).String()}
+}
+
+// time.sleep(d) waits for the duration, it stops with an error when evaluation is cancelled
+func sleep(ctx context.Context, args ...object.Object) object.Object {
+	if err := checkArgs("time.sleep", args, object.INTEGER); err != nil {
+		return err
+	}
+	if err := object.ClockOf(ctx).Sleep(ctx, time.Duration(integer(args[0]))*time.Millisecond); err != nil {
+		return newError("time.sleep", "%v", err)
+	}
+	return evaluator.NULL_VAL
+}
@@ -0,0 +1,765 @@
+package typecheck
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"strings"
+)
+
+// Binding is an inferred type of a top-level var
+type Binding struct {
+	Name string
+	Type Type
+	Pos  lexer.Position
+}
+
+// String prints the type with type variables renamed to a, b, c...
+func (b Binding) String() string {
+	return b.Name + ": " + describe(b.Type, map[*TypeVariable]string{})
+}
+
+// Infer computes principal types of the program with Hindley-Milner inference.
+// Annotations are optional, functions bound with var are polymorphic.
+// Returns types of all top-level vars in order of declaration
+func Infer(program *parser.Program) ([]Binding, []error) {
+	in := &inferrer{env: newEnvironment(nil), structs: map[string]*StructType{}}
+	bindings := []Binding{}
+
+	for _, st := range program.Statements {
+		in.inferStatement(st)
+		if e, ok := st.(*parser.ExportStatement); ok {
+			st = e.Statement
+		}
+		if v, ok := st.(*parser.VarStatementNode); ok && v.Pattern != nil {
+			for _, b := range parser.Bindings(v.Pattern) {
+				bindings = append(bindings, Binding{Name: b.Name, Type: resolve(in.env.lookup(b.Name).t), Pos: b.Pos})
+			}
+		} else if ok {
+			s := in.env.lookup(v.Name)
+			bindings = append(bindings, Binding{Name: v.Name, Type: resolve(s.t), Pos: v.Pos})
+		}
+	}
+	return bindings, in.errors
+}
+
+// TypeVariable is a placeholder for not yet known type.
+// When unified with other type, instance is set along with the location it came from
+type TypeVariable struct {
+	id       int
+	instance Type
+	boundAt  lexer.Position
+}
+
+func (t *TypeVariable) String() string {
+	if t.instance != nil {
+		return t.instance.String()
+	}
+	return fmt.Sprintf("t%d", t.id)
+}
+
+// scheme is a type quantified over type variables, e.g. forall a. fn(a): a
+type scheme struct {
+	vars []*TypeVariable
+	t    Type
+}
+
+type environment struct {
+	vars  map[string]*scheme
+	outer *environment
+}
+
+func newEnvironment(outer *environment) *environment {
+	return &environment{vars: map[string]*scheme{}, outer: outer}
+}
+
+func (e *environment) lookup(name string) *scheme {
+	for env := e; env != nil; env = env.outer {
+		if s, ok := env.vars[name]; ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// return type of function being inferred, with location it was declared at
+type returnType struct {
+	t   Type
+	pos lexer.Position
+}
+
+type inferrer struct {
+	env         *environment
+	nextVarId   int
+	returnTypes []returnType
+	errors      []error
+	structs     map[string]*StructType
+}
+
+func (in *inferrer) addError(format string, args ...any) {
+	in.errors = append(in.errors, fmt.Errorf("typecheck error - "+format, args...))
+}
+
+func (in *inferrer) newVar() *TypeVariable {
+	in.nextVarId++
+	return &TypeVariable{id: in.nextVarId}
+}
+
+// prune follows instances of bound type variables.
+// Returned position is where the type was determined, if it came from a variable
+func prune(t Type, pos lexer.Position) (Type, lexer.Position) {
+	for {
+		v, ok := t.(*TypeVariable)
+		if !ok || v.instance == nil {
+			return t, pos
+		}
+		t, pos = v.instance, v.boundAt
+	}
+}
+
+// resolve substitutes all bound type variables
+func resolve(t Type) Type {
+	t, _ = prune(t, lexer.Position{})
+	fn, ok := t.(*FunctionType)
+	if !ok {
+		return t
+	}
+
+	out := &FunctionType{Parameters: []Type{}, Return: resolve(fn.Return)}
+	for _, p := range fn.Parameters {
+		out.Parameters = append(out.Parameters, resolve(p))
+	}
+	return out
+}
+
+func occurs(v *TypeVariable, t Type) bool {
+	t, _ = prune(t, lexer.Position{})
+	switch tt := t.(type) {
+	case *TypeVariable:
+		return tt == v
+	case *FunctionType:
+		for _, p := range tt.Parameters {
+			if occurs(v, p) {
+				return true
+			}
+		}
+		return occurs(v, tt.Return)
+	}
+	return false
+}
+
+// unify makes both types equal, positions are locations in the source
+// where each of the types came from, used for reporting conflicts
+func (in *inferrer) unify(a Type, aPos lexer.Position, b Type, bPos lexer.Position) bool {
+	a, aPos = prune(a, aPos)
+	b, bPos = prune(b, bPos)
+
+	if v, ok := a.(*TypeVariable); ok {
+		if a == b {
+			return true
+		} else if occurs(v, b) {
+			in.addError("recursive type %v", describePair(a, aPos, b, bPos))
+			return false
+		}
+		v.instance = b
+		v.boundAt = bPos
+		return true
+	} else if _, ok := b.(*TypeVariable); ok {
+		return in.unify(b, bPos, a, aPos)
+	}
+
+	aFn, aIsFn := a.(*FunctionType)
+	bFn, bIsFn := b.(*FunctionType)
+	if aIsFn && bIsFn && len(aFn.Parameters) == len(bFn.Parameters) {
+		for i := range aFn.Parameters {
+			if !in.unify(aFn.Parameters[i], aPos, bFn.Parameters[i], bPos) {
+				return false
+			}
+		}
+		return in.unify(aFn.Return, aPos, bFn.Return, bPos)
+	} else if !aIsFn && !bIsFn && a == b {
+		return true
+	}
+
+	in.addError("cannot unify %v", describePair(a, aPos, b, bPos))
+	return false
+}
+
+func (in *inferrer) freeInEnvironment(v *TypeVariable) bool {
+	for env := in.env; env != nil; env = env.outer {
+		for _, s := range env.vars {
+			if occurs(v, s.t) && !quantified(s, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func quantified(s *scheme, v *TypeVariable) bool {
+	for _, sv := range s.vars {
+		if sv == v {
+			return true
+		}
+	}
+	return false
+}
+
+func freeVariables(t Type, acc []*TypeVariable) []*TypeVariable {
+	t, _ = prune(t, lexer.Position{})
+	switch tt := t.(type) {
+	case *TypeVariable:
+		for _, v := range acc {
+			if v == tt {
+				return acc
+			}
+		}
+		return append(acc, tt)
+	case *FunctionType:
+		for _, p := range tt.Parameters {
+			acc = freeVariables(p, acc)
+		}
+		return freeVariables(tt.Return, acc)
+	}
+	return acc
+}
+
+func (in *inferrer) generalize(t Type) *scheme {
+	out := &scheme{t: t}
+	for _, v := range freeVariables(t, nil) {
+		if !in.freeInEnvironment(v) {
+			out.vars = append(out.vars, v)
+		}
+	}
+	return out
+}
+
+func containsAny(t Type, vars []*TypeVariable) bool {
+	for _, v := range vars {
+		if occurs(v, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (in *inferrer) instantiate(s *scheme) Type {
+	mapping := map[*TypeVariable]Type{}
+	for _, v := range s.vars {
+		mapping[v] = in.newVar()
+	}
+
+	var copyType func(t Type) Type
+	copyType = func(t Type) Type {
+		switch tt := t.(type) {
+		case *TypeVariable:
+			if fresh, ok := mapping[tt]; ok {
+				return fresh
+			} else if tt.instance != nil && containsAny(tt.instance, s.vars) {
+				return copyType(tt.instance)
+			}
+			// keep bound variables, so the location of the type is not lost
+			return tt
+		case *FunctionType:
+			out := &FunctionType{Parameters: []Type{}, Return: copyType(tt.Return)}
+			for _, p := range tt.Parameters {
+				out.Parameters = append(out.Parameters, copyType(p))
+			}
+			return out
+		}
+		return t
+	}
+	return copyType(s.t)
+}
+
+// located wraps the type in a bound variable, so conflicts can point to its location
+func (in *inferrer) located(t Type, pos lexer.Position) Type {
+	v := in.newVar()
+	v.instance = t
+	v.boundAt = pos
+	return v
+}
+
+// fromAnnotation converts optional annotation, missing one becomes a fresh type variable
+func (in *inferrer) fromAnnotation(annotation *parser.TypeAnnotation) Type {
+	if annotation == nil {
+		return in.newVar()
+	}
+
+	switch annotation.Name {
+	case "int":
+		return Int
+	case "float":
+		return Float
+	case "bool":
+		return Bool
+	case "string":
+		return String
+	case "fn":
+		out := &FunctionType{Parameters: []Type{}, Return: in.fromAnnotation(annotation.Return)}
+		for _, p := range annotation.Parameters {
+			out.Parameters = append(out.Parameters, in.fromAnnotation(p))
+		}
+		return out
+	}
+	if st, ok := in.structs[annotation.Name]; ok {
+		return st
+	}
+	in.addError("unknown type %v (%v)", annotation.Name, annotation.Pos)
+	return in.newVar()
+}
+
+func (in *inferrer) inferStatement(st parser.StatementNode) Type {
+	switch s := st.(type) {
+	case *parser.VarStatementNode:
+		in.inferVarStatement(s)
+		return Null
+	case *parser.ReturnStatementNode:
+		return in.inferReturnStatement(s)
+	case *parser.ExpressionStatementNode:
+		return in.infer(s.Value)
+	case *parser.BlockStatement:
+		return in.inferBlock(s)
+	case *parser.ImportStatement:
+		in.env.vars[s.Alias] = &scheme{t: in.newVar()}
+		return Null
+	case *parser.ExportStatement:
+		in.inferVarStatement(s.Statement)
+		return Null
+	case *parser.ThrowStatement:
+		// the statement never completes, so it fits wherever a value is expected
+		in.infer(s.Value)
+		return in.newVar()
+	case *parser.TryStatement:
+		in.inferTry(s)
+		return in.newVar()
+	case *parser.StructStatement:
+		in.inferStruct(s)
+		return Null
+	case *parser.MethodStatement:
+		// the receiver is the first parameter, methods are looked up at runtime so the type isn't kept
+		in.inferFunctionLiteral(&parser.FunctionLiteralExpression{
+			Parameters: append([]*parser.FunctionParameter{s.Receiver}, s.Function.Parameters...),
+			ReturnType: s.Function.ReturnType,
+			Body:       s.Function.Body,
+			Pos:        s.Function.Pos,
+		})
+		return Null
+	case *parser.AssignStatement:
+		in.infer(s.Target.Object)
+		in.infer(s.Value)
+		return Null
+	}
+	return in.newVar()
+}
+
+// inferStruct declares the type and its constructor, fields aren't typed so the constructor is polymorphic
+func (in *inferrer) inferStruct(s *parser.StructStatement) {
+	st := &StructType{Name: s.Name, Fields: s.Fields, Methods: map[string]*FunctionType{}}
+	in.structs[s.Name] = st
+
+	constructor := &FunctionType{Parameters: []Type{}, Return: st}
+	for range s.Fields {
+		constructor.Parameters = append(constructor.Parameters, in.newVar())
+	}
+	in.env.vars[s.Name] = in.generalize(constructor)
+}
+
+// inferTry infers all blocks, the caught error gets a fresh type variable
+func (in *inferrer) inferTry(s *parser.TryStatement) {
+	in.inferBlock(s.Body)
+	if s.Catch != nil {
+		in.env = newEnvironment(in.env)
+		in.env.vars[s.Name] = &scheme{t: in.newVar()}
+		in.inferBlock(s.Catch)
+		in.env = in.env.outer
+	}
+	if s.Finally != nil {
+		in.inferBlock(s.Finally)
+	}
+}
+
+func (in *inferrer) inferVarStatement(s *parser.VarStatementNode) {
+	if s.Pattern != nil {
+		in.inferPattern(s.Pattern, in.located(in.infer(s.Value), s.Value.Position()))
+		return
+	}
+	if s.Value == nil {
+		var t Type = Null
+		if s.Type != nil {
+			t = in.fromAnnotation(s.Type)
+		}
+		in.env.vars[s.Name] = &scheme{t: t}
+		return
+	}
+
+	// monomorphic while inferring the value, so recursive calls are allowed
+	t := in.fromAnnotation(s.Type)
+	in.env.vars[s.Name] = &scheme{t: t}
+
+	valueType := in.infer(s.Value)
+	in.unify(t, s.Pos, valueType, s.Value.Position())
+
+	// own monomorphic binding must not prevent generalization
+	delete(in.env.vars, s.Name)
+	in.env.vars[s.Name] = in.generalize(t)
+}
+
+func (in *inferrer) inferReturnStatement(s *parser.ReturnStatementNode) Type {
+	var t Type = Null
+	if s.Value != nil {
+		t = in.infer(s.Value)
+	}
+
+	if len(in.returnTypes) > 0 {
+		ret := in.returnTypes[len(in.returnTypes)-1]
+		in.unify(ret.t, ret.pos, t, s.Pos)
+	}
+	// return never produces a value for the enclosing block
+	return in.newVar()
+}
+
+func (in *inferrer) inferBlock(b *parser.BlockStatement) Type {
+	var out Type = Null
+	for _, st := range b.Statements {
+		out = in.inferStatement(st)
+	}
+	return out
+}
+
+func (in *inferrer) infer(exp parser.ExpressionNode) Type {
+	switch e := exp.(type) {
+	case *parser.IntegerLiteralExpression:
+		return Int
+	case *parser.FloatLiteralExpression:
+		return Float
+	case *parser.BooleanExpression:
+		return Bool
+	case *parser.StringLiteralExpression:
+		return String
+	case *parser.InterpolatedString:
+		for _, v := range e.Values {
+			in.infer(v)
+		}
+		return String
+	case *parser.IdentifierExpression:
+		s := in.env.lookup(e.Name)
+		if s == nil {
+			in.addError("undefined identifier %v (%v)", e.Name, e.Pos)
+			return in.newVar()
+		}
+		return in.instantiate(s)
+	case *parser.PrefixExpression:
+		return in.inferPrefix(e)
+	case *parser.InfixExpression:
+		return in.inferInfix(e)
+	case *parser.IfExpression:
+		return in.inferIf(e)
+	case *parser.ConditionalExpression:
+		return in.inferConditional(e)
+	case *parser.FunctionLiteralExpression:
+		return in.inferFunctionLiteral(e)
+	case *parser.MacroLiteral:
+		// the body builds code, calls of the macro are replaced before evaluation
+		return in.newVar()
+	case *parser.CallExpression:
+		return in.inferCall(e)
+	case *parser.MemberExpression:
+		// members of modules are loaded at runtime and fields aren't typed, each use may have a different type
+		in.infer(e.Object)
+		return in.newVar()
+	case *parser.ArrayLiteralExpression:
+		// elements aren't tracked, arrays may mix types
+		for _, el := range e.Elements {
+			in.infer(el)
+		}
+		return Array
+	case *parser.IndexExpression:
+		return in.inferIndex(e)
+	case *parser.HashLiteralExpression:
+		// like arrays, hashes may mix types of values
+		for i := range e.Keys {
+			in.infer(e.Keys[i])
+			in.infer(e.Values[i])
+		}
+		return Hash
+	case *parser.SpawnExpression:
+		// the result is received from a channel
+		in.inferCall(e.Call)
+		return Channel
+	case *parser.SelectExpression:
+		in.inferSelect(e)
+		return in.newVar()
+	case *parser.MatchExpression:
+		return in.inferMatch(e)
+	}
+	return in.newVar()
+}
+
+// inferMatch unifies literal patterns with the subject, guards with bool and all arm bodies together
+func (in *inferrer) inferMatch(e *parser.MatchExpression) Type {
+	subject := in.infer(e.Subject)
+	var out Type
+	var outPos lexer.Position
+	for _, arm := range e.Arms {
+		in.env = newEnvironment(in.env)
+		in.inferPattern(arm.Pattern, subject)
+		if arm.Guard != nil {
+			guard := in.infer(arm.Guard)
+			in.unify(guard, arm.Guard.Position(), Bool, arm.Position())
+		}
+		body := in.infer(arm.Body)
+		in.env = in.env.outer
+
+		if out == nil {
+			out, outPos = body, arm.Body.Position()
+		} else {
+			in.unify(out, outPos, body, arm.Body.Position())
+		}
+	}
+	if out == nil {
+		return in.newVar()
+	}
+	return out
+}
+
+// inferIndex checks that only arrays and hashes are indexed, elements get a fresh type variable
+func (in *inferrer) inferIndex(e *parser.IndexExpression) Type {
+	left := in.infer(e.Left)
+	// arrays are indexed by integers, hashes also by strings
+	index := in.infer(e.Index)
+	if resolve(index) != String {
+		in.unify(index, e.Index.Position(), Int, e.Pos)
+	}
+
+	l := resolve(left)
+	_, unknown := l.(*TypeVariable)
+	switch {
+	case e.Optional && l == Null:
+		// nothing is indexed, the result is null
+	case resolve(index) == String:
+		in.unify(left, e.Left.Position(), Hash, e.Pos)
+	case !unknown && l != Array && l != Hash:
+		// a not yet known left side indexed by an integer may be either
+		in.unify(left, e.Left.Position(), Array, e.Pos)
+	}
+	return in.newVar()
+}
+
+// inferPattern binds names of the pattern, parts of arrays and hashes get fresh type variables
+func (in *inferrer) inferPattern(pattern parser.Pattern, t Type) {
+	switch p := pattern.(type) {
+	case *parser.BindingPattern:
+		in.env.vars[p.Name] = &scheme{t: t}
+	case *parser.LiteralPattern:
+		in.unify(t, p.Position(), in.infer(p.Value), p.Value.Position())
+	case *parser.ArrayPattern:
+		in.unify(t, p.Position(), Array, p.Position())
+		for _, el := range p.Elements {
+			in.inferPattern(el, in.newVar())
+		}
+		if p.Rest != nil {
+			in.inferPattern(p.Rest, Array)
+		}
+	case *parser.HashPattern:
+		in.unify(t, p.Position(), Hash, p.Position())
+		for _, v := range p.Values {
+			in.inferPattern(v, in.newVar())
+		}
+	}
+}
+
+// inferSelect infers the cases, each received value gets a fresh type variable
+func (in *inferrer) inferSelect(e *parser.SelectExpression) {
+	for _, c := range e.Cases {
+		in.infer(c.Comm)
+		in.env = newEnvironment(in.env)
+		if c.Name != "" {
+			in.env.vars[c.Name] = &scheme{t: in.newVar()}
+		}
+		in.inferBlock(c.Body)
+		in.env = in.env.outer
+	}
+	if e.Default != nil {
+		in.inferBlock(e.Default)
+	}
+}
+
+func (in *inferrer) inferPrefix(e *parser.PrefixExpression) Type {
+	right := in.infer(e.Right)
+	switch e.Operator {
+	case "!":
+		return Bool
+	case "<-":
+		// channels aren't typed, the value may be received as anything
+		in.unify(right, e.Right.Position(), Channel, e.Pos)
+		return in.newVar()
+	case "-":
+		if resolve(right) == Float {
+			return Float
+		}
+		in.unify(right, e.Right.Position(), Int, e.Pos)
+		return Int
+	}
+	return in.newVar()
+}
+
+func (in *inferrer) inferInfix(e *parser.InfixExpression) Type {
+	left := in.infer(e.Left)
+	right := in.infer(e.Right)
+
+	switch e.Operator {
+	case "+", "-", "*", "/":
+		if e.Operator == "+" && (resolve(left) == String || resolve(right) == String) {
+			in.unify(left, e.Left.Position(), String, e.Pos)
+			in.unify(right, e.Right.Position(), String, e.Pos)
+			return String
+		}
+		return in.inferNumeric(e, left, right)
+	case "<", "<=", ">", ">=":
+		in.inferNumeric(e, left, right)
+		return Bool
+	case "==", "!=":
+		in.unify(left, e.Left.Position(), right, e.Right.Position())
+		return Bool
+	case "<-":
+		in.unify(left, e.Left.Position(), Channel, e.Pos)
+		return Null
+	case "??":
+		// the right operand replaces a null, otherwise both have the same type
+		if resolve(left) == Null {
+			return right
+		}
+		in.unify(left, e.Left.Position(), right, e.Right.Position())
+		return left
+	}
+	return in.newVar()
+}
+
+// inferNumeric unifies operands of arithmetic with int, unless one of them is a float.
+// Ints and floats can be mixed, the result is a float then
+func (in *inferrer) inferNumeric(e *parser.InfixExpression, left, right Type) Type {
+	if resolve(left) == Float || resolve(right) == Float {
+		for _, operand := range []struct {
+			t   Type
+			pos lexer.Position
+		}{{left, e.Left.Position()}, {right, e.Right.Position()}} {
+			if resolved := resolve(operand.t); resolved != Int && resolved != Float {
+				in.unify(operand.t, operand.pos, Float, e.Pos)
+			}
+		}
+		return Float
+	}
+	in.unify(left, e.Left.Position(), Int, e.Pos)
+	in.unify(right, e.Right.Position(), Int, e.Pos)
+	return Int
+}
+
+func (in *inferrer) inferIf(e *parser.IfExpression) Type {
+	condition := in.infer(e.Condition)
+	in.unify(condition, e.Condition.Position(), Bool, e.Pos)
+
+	consequence := in.inferBlock(e.Consequence)
+	if e.Alternative == nil {
+		return Null
+	}
+	alternative := in.inferBlock(e.Alternative)
+	in.unify(consequence, blockPosition(e.Consequence), alternative, blockPosition(e.Alternative))
+	return consequence
+}
+
+func (in *inferrer) inferConditional(e *parser.ConditionalExpression) Type {
+	condition := in.infer(e.Condition)
+	in.unify(condition, e.Condition.Position(), Bool, e.Pos)
+
+	consequence := in.infer(e.Consequence)
+	alternative := in.infer(e.Alternative)
+	in.unify(consequence, e.Consequence.Position(), alternative, e.Alternative.Position())
+	return consequence
+}
+
+// blockPosition is the location of the value of the block
+func blockPosition(b *parser.BlockStatement) lexer.Position {
+	if len(b.Statements) == 0 {
+		return b.Pos
+	}
+	return b.Statements[len(b.Statements)-1].Position()
+}
+
+func (in *inferrer) inferFunctionLiteral(e *parser.FunctionLiteralExpression) Type {
+	out := &FunctionType{Parameters: []Type{}}
+
+	in.env = newEnvironment(in.env)
+	defer func() { in.env = in.env.outer }()
+
+	for _, p := range e.Parameters {
+		t := in.fromAnnotation(p.Type)
+		out.Parameters = append(out.Parameters, t)
+		in.env.vars[p.Name] = &scheme{t: t}
+	}
+
+	ret := returnType{t: in.fromAnnotation(e.ReturnType), pos: e.Pos}
+	if e.ReturnType != nil {
+		ret.pos = e.ReturnType.Pos
+	}
+	out.Return = ret.t
+
+	in.returnTypes = append(in.returnTypes, ret)
+	body := in.inferBlock(e.Body)
+	in.returnTypes = in.returnTypes[:len(in.returnTypes)-1]
+
+	in.unify(ret.t, ret.pos, body, blockPosition(e.Body))
+	return out
+}
+
+func (in *inferrer) inferCall(e *parser.CallExpression) Type {
+	callee := in.infer(e.Function)
+
+	expected := &FunctionType{Parameters: []Type{}, Return: in.newVar()}
+	for _, a := range e.Arguments {
+		expected.Parameters = append(expected.Parameters, in.located(in.infer(a), a.Position()))
+	}
+
+	calleeFn, ok := resolve(callee).(*FunctionType)
+	if ok && len(calleeFn.Parameters) != len(expected.Parameters) {
+		in.addError("call error - %v (%v) expects %d arguments, got %d (%v)",
+			e.Function, e.Function.Position(), len(calleeFn.Parameters), len(expected.Parameters), e.Pos)
+		return calleeFn.Return
+	}
+
+	in.unify(callee, e.Function.Position(), expected, e.Pos)
+	return expected.Return
+}
+
+// describePair prints conflicting types along with their locations
+func describePair(a Type, aPos lexer.Position, b Type, bPos lexer.Position) string {
+	names := map[*TypeVariable]string{}
+	return fmt.Sprintf("%v (%v) with %v (%v)", describe(a, names), aPos, describe(b, names), bPos)
+}
+
+// describe prints type, naming unbound type variables a, b, c... in order of appearance
+func describe(t Type, names map[*TypeVariable]string) string {
+	t, _ = prune(t, lexer.Position{})
+	switch tt := t.(type) {
+	case *TypeVariable:
+		if _, ok := names[tt]; !ok {
+			names[tt] = variableName(len(names))
+		}
+		return names[tt]
+	case *FunctionType:
+		params := []string{}
+		for _, p := range tt.Parameters {
+			params = append(params, describe(p, names))
+		}
+		return "fn(" + strings.Join(params, ", ") + "): " + describe(tt.Return, names)
+	}
+	return t.String()
+}
+
+func variableName(idx int) string {
+	name := string(rune('a' + idx%26))
+	if idx >= 26 {
+		name += fmt.Sprint(idx / 26)
+	}
+	return name
+}
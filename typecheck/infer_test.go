@@ -0,0 +1,233 @@
+package typecheck
+
+import (
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func infer(t *testing.T, input string) ([]Binding, []error) {
+	program := parser.ParseWithPositions(lexer.TokenizeWithPositions(input))
+	require.Len(t, program.Errors, 0, "parser errors: %v", program.Errors)
+	return Infer(program)
+}
+
+func TestInferTopLevelTypes(t *testing.T) {
+	tdt := []struct {
+		desc     string
+		input    string
+		expected []string
+	}{
+		{"literals", `var x = 5; var y = true;`, []string{"x: int", "y: bool"}},
+		{"operators", `var x = 1 + 2 * 3; var y = 1 < 2 == !true;`, []string{"x: int", "y: bool"}},
+		{"identity", `var id = fn(x) { x };`, []string{"id: fn(a): a"}},
+		{"constant function", `var k = fn(x, y) { x };`, []string{"k: fn(a, b): a"}},
+		{"inferred parameter", `var inc = fn(x) { x + 1 };`, []string{"inc: fn(int): int"}},
+		{"comparison", `var eq = fn(a, b) { a == b };`, []string{"eq: fn(a, a): bool"}},
+		{"return statement", `var f = fn(x) { return x < 1; };`, []string{"f: fn(int): bool"}},
+		{"early return", `var f = fn(x) { if (x) { return 1; } 2 };`, []string{"f: fn(bool): int"}},
+		{
+			"templates",
+			"var show = fn(x) { `value: ${x}` }; var inc = fn(x) { `${x + 1}` };",
+			[]string{"show: fn(a): string", "inc: fn(int): string"},
+		},
+		{
+			"macros",
+			`var m = macro(a) { quote(unquote(a)) }; var x = m(1) + 1; var y = m(true);`,
+			[]string{"m: a", "x: int", "y: a"},
+		},
+		{
+			"structs",
+			`struct P { x } var p = P(1); var f = fn(q: P) { q.x = 2; q };`,
+			[]string{"p: P", "f: fn(P): P"},
+		},
+		{
+			"polymorphic let",
+			`var id = fn(x) { x };
+			var a = id(1);
+			var b = id(true);`,
+			[]string{"id: fn(a): a", "a: int", "b: bool"},
+		},
+		{
+			"higher order",
+			`var compose = fn(f, g) { fn(x) { f(g(x)) } };`,
+			[]string{"compose: fn(fn(a): b, fn(c): a): fn(c): b"},
+		},
+		{
+			"recursion",
+			`var fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };`,
+			[]string{"fact: fn(int): int"},
+		},
+		{
+			"polymorphic function used inside other function",
+			`var twice = fn(f, x) { f(f(x)) };
+			var add2 = fn(x) { twice(fn(y) { y + 1 }, x) };
+			var notnot = fn(x) { twice(fn(y) { !y }, x) };`,
+			[]string{"twice: fn(fn(a): a, a): a", "add2: fn(int): int", "notnot: fn(bool): bool"},
+		},
+		{"annotations are respected", `var f = fn(x: bool, y) { y };`, []string{"f: fn(bool, a): a"}},
+		{"var without value", `var x; var y: int;`, []string{"x: null", "y: int"}},
+		{"strings", `var s = "a" + "b"; var greet = fn(name) { "hi " + name };`, []string{"s: string", "greet: fn(string): string"}},
+		{
+			"modules",
+			`import "lib.mk" as lib;
+			export var x = lib.f(1) + 1;
+			var y = lib.g(true);`,
+			[]string{"x: int", "y: a"},
+		},
+		{"floats", `var x = 1.5 * 2; var half = fn(n) { n / 2.0 }; var y = -x;`, []string{"x: float", "half: fn(float): float", "y: float"}},
+		{"arrays", `var first = fn(xs, i) { xs[i] };`, []string{"first: fn(a, int): b"}},
+		{"channels", `var c = spawn fn(x) { x + 1 }(1); var v = <-c;`, []string{"c: channel", "v: a"}},
+		{"channel parameter", `var drain = fn(c) { <-c; c <- 1 };`, []string{"drain: fn(channel): null"}},
+		{"exceptions", `var check = fn(x) { if (x < 0) { throw "negative"; } else { x } }; var safe = fn(x) { try { return check(x); } catch (e) { return 0; } };`, []string{"check: fn(int): int", "safe: fn(int): int"}},
+		{"hashes", `var h = {"a": 1}; var get = fn(key: string) { h[key] };`, []string{"h: hash", "get: fn(string): a"}},
+		{"match", `var name = fn(n) { match (n) { 1 => "one", x if x > 9 => "many", _ => "some" } };`, []string{"name: fn(int): string"}},
+		{"destructuring", `var [a, {"k": b}] = [1, {"k": 2}]; var c = a + 1;`, []string{"a: int", "b: a", "c: int"}},
+		{"hash parameter", `var get = fn(h) { h["k"] }; var x = get({"k": 1});`, []string{"get: fn(hash): a", "x: a"}},
+		{
+			"arrays",
+			`var arr = [1, 2]; var pair = fn(x, y) { [x, y] }; var first = fn(xs) { xs[0] }; var [h, ...t] = arr;`,
+			[]string{"arr: array", "pair: fn(a, b): array", "first: fn(a): b", "h: a", "t: array"},
+		},
+		{"conditional operators", `var max = fn(a, b) { a > b ? a : b }; var n; var x = n ?? 1.5;`, []string{"max: fn(int, int): int", "n: null", "x: float"}},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			bindings, errors := infer(t, tc.input)
+			assert.Len(t, errors, 0)
+
+			got := []string{}
+			for _, b := range bindings {
+				got = append(got, b.String())
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestInferErrors(t *testing.T) {
+	tdt := []struct {
+		desc          string
+		input         string
+		expectedError string
+	}{
+		{
+			"mismatched operands",
+			`var x = 1 + true;`,
+			"typecheck error - cannot unify bool (1:13) with int (1:11)",
+		},
+		{
+			"conflicting usages of parameter",
+			`var f = fn(x) {
+				x + 1
+			};
+			f(true);`,
+			"typecheck error - cannot unify int (2:7) with bool (4:6)",
+		},
+		{
+			"monomorphic parameter",
+			`var f = fn(g) { g(1) == g(true) };`,
+			"typecheck error - cannot unify int (1:19) with bool (1:27)",
+		},
+		{
+			"if branches",
+			`var x = if (true) { 1 } else { false };`,
+			"typecheck error - cannot unify int (1:21) with bool (1:32)",
+		},
+		{
+			"conditional branches",
+			`var x = true ? 1 : "a";`,
+			"typecheck error - cannot unify int (1:16) with string (1:20)",
+		},
+		{
+			"coalesced operands",
+			`var f = fn(x) { x + 1 ?? "a" };`,
+			"typecheck error - cannot unify int (1:19) with string (1:26)",
+		},
+		{
+			"array arithmetic",
+			`var x = [1, 2] + 1;`,
+			"typecheck error - cannot unify array (1:9) with int (1:16)",
+		},
+		{
+			"indexing a number",
+			`var x = 1; x[0];`,
+			"typecheck error - cannot unify int (1:9) with array (1:13)",
+		},
+		{
+			"destructuring a number",
+			`var [a] = 1;`,
+			"typecheck error - cannot unify int (1:11) with array (1:5)",
+		},
+		{
+			"hash arithmetic",
+			`var x = {"a": 1} * 2;`,
+			"typecheck error - cannot unify hash (1:9) with int (1:18)",
+		},
+		{
+			"string key of an array",
+			`var x = [1]["a"];`,
+			"typecheck error - cannot unify array (1:9) with hash (1:12)",
+		},
+		{
+			"channel arithmetic",
+			`var c = spawn fn() { 1 }(); c + 1;`,
+			"typecheck error - cannot unify channel (1:9) with int (1:31)",
+		},
+		{
+			"receive from a number",
+			`var x = <-1;`,
+			"typecheck error - cannot unify int (1:11) with channel (1:9)",
+		},
+		{
+			"if condition",
+			`var x = if (1) { 1 } else { 2 };`,
+			"typecheck error - cannot unify int (1:13) with bool (1:9)",
+		},
+		{
+			"arity",
+			`var f = fn(x) { x }; f(1, 2);`,
+			"typecheck error - call error - f (1:22) expects 1 arguments, got 2 (1:23)",
+		},
+		{
+			"recursive type",
+			`var f = fn(x) { x(x) };`,
+			"typecheck error - recursive type a (1:17) with fn(a): b (1:18)",
+		},
+		{
+			"undefined identifier",
+			`var x = y + 1;`,
+			"typecheck error - undefined identifier y (1:9)",
+		},
+		{
+			"annotation",
+			`var x: bool = 5;`,
+			"typecheck error - cannot unify bool (1:1) with int (1:15)",
+		},
+		{
+			"struct",
+			`struct P { x } var p: P = 1;`,
+			"typecheck error - cannot unify P (1:16) with int (1:27)",
+		},
+		{
+			"match arms",
+			`var x = match (1) { 1 => true, _ => 2 };`,
+			"typecheck error - cannot unify bool (1:26) with int (1:37)",
+		},
+		{
+			"match literal",
+			`var f = fn(s: string) { match (s) { 1 => 1, _ => 2 } };`,
+			"typecheck error - cannot unify string (1:37) with int (1:37)",
+		},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, errors := infer(t, tc.input)
+			require.Len(t, errors, 1, "%v", errors)
+			assert.EqualError(t, errors[0], tc.expectedError)
+		})
+	}
+}
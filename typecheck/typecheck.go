@@ -0,0 +1,551 @@
+package typecheck
+
+import (
+	"fmt"
+	"programming-lang/lexer"
+	"programming-lang/parser"
+)
+
+// Check verifies type annotations and operand types of the program.
+// Bindings without annotation get their type inferred from the assigned value
+func Check(program *parser.Program) []error {
+	c := &checker{scope: newScope(nil), methods: declaredMethods(program)}
+	for _, st := range program.Statements {
+		c.checkStatement(st)
+	}
+	return c.errors
+}
+
+type scope struct {
+	vars    map[string]Type
+	structs map[string]*StructType
+	outer   *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: map[string]Type{}, structs: map[string]*StructType{}, outer: outer}
+}
+
+func (s *scope) lookupStruct(name string) *StructType {
+	for sc := s; sc != nil; sc = sc.outer {
+		if st, ok := sc.structs[name]; ok {
+			return st
+		}
+	}
+	return nil
+}
+
+func (s *scope) lookup(name string) Type {
+	for sc := s; sc != nil; sc = sc.outer {
+		if t, ok := sc.vars[name]; ok {
+			return t
+		}
+	}
+	return Unknown
+}
+
+// function being checked, used for return statements
+type function struct {
+	declared Type // nil when return type is not annotated
+	returned []Type
+}
+
+type checker struct {
+	scope     *scope
+	functions []*function
+	errors    []error
+
+	// names of methods by receiver type, methods may be used in functions declared before them
+	methods map[string]map[string]bool
+}
+
+func declaredMethods(program *parser.Program) map[string]map[string]bool {
+	out := map[string]map[string]bool{}
+	parser.Inspect(program, func(n parser.Node) bool {
+		if m, ok := n.(*parser.MethodStatement); ok && m.Receiver.Type != nil {
+			if out[m.Receiver.Type.Name] == nil {
+				out[m.Receiver.Type.Name] = map[string]bool{}
+			}
+			out[m.Receiver.Type.Name][m.Name] = true
+		}
+		return true
+	})
+	return out
+}
+
+// addError reports the problem at the position of the node it was found in
+func (c *checker) addError(pos lexer.Position, format string, args ...any) {
+	c.errors = append(c.errors, &parser.SyntaxError{Message: fmt.Sprintf("typecheck error - "+format, args...), Pos: pos})
+}
+
+func (c *checker) currentFunction() *function {
+	if len(c.functions) == 0 {
+		return nil
+	}
+	return c.functions[len(c.functions)-1]
+}
+
+func (c *checker) checkStatement(st parser.StatementNode) Type {
+	switch s := st.(type) {
+	case *parser.VarStatementNode:
+		c.checkVarStatement(s)
+		return Null
+	case *parser.ReturnStatementNode:
+		return c.checkReturnStatement(s)
+	case *parser.ExpressionStatementNode:
+		return c.checkExpression(s.Value)
+	case *parser.BlockStatement:
+		return c.checkBlock(s)
+	case *parser.ImportStatement:
+		// exports of the module aren't known without loading it
+		c.scope.vars[s.Alias] = Unknown
+		return Null
+	case *parser.ExportStatement:
+		c.checkVarStatement(s.Statement)
+		return Null
+	case *parser.ThrowStatement:
+		// any value can be thrown, the statement never completes
+		c.checkExpression(s.Value)
+		return Unknown
+	case *parser.TryStatement:
+		c.checkTry(s)
+		return Unknown
+	case *parser.StructStatement:
+		c.checkStruct(s)
+		return Null
+	case *parser.MethodStatement:
+		c.checkMethod(s)
+		return Null
+	case *parser.AssignStatement:
+		c.checkAssign(s)
+		return Null
+	}
+	return Unknown
+}
+
+// checkStruct declares the type and its constructor, which takes values of all fields
+func (c *checker) checkStruct(s *parser.StructStatement) {
+	st := &StructType{Name: s.Name, Fields: s.Fields, Methods: map[string]*FunctionType{}}
+	constructor := &FunctionType{Parameters: []Type{}, Return: st}
+	for range s.Fields {
+		constructor.Parameters = append(constructor.Parameters, Unknown)
+	}
+	c.scope.structs[s.Name] = st
+	c.scope.vars[s.Name] = constructor
+}
+
+// checkMethod checks the method as a function taking the receiver first,
+// its type is known before the body is checked, so the method can call itself
+func (c *checker) checkMethod(s *parser.MethodStatement) {
+	fn := &parser.FunctionLiteralExpression{
+		Parameters: append([]*parser.FunctionParameter{s.Receiver}, s.Function.Parameters...),
+		ReturnType: s.Function.ReturnType,
+		Body:       s.Function.Body,
+		Pos:        s.Function.Pos,
+	}
+	signature := c.signature(fn)
+	st, ok := signature.Parameters[0].(*StructType)
+	if !ok {
+		if signature.Parameters[0] != Unknown {
+			c.addError(s.Pos, "cannot declare method %v on %v", s.Name, signature.Parameters[0])
+		}
+		c.checkFunctionLiteral(fn)
+		return
+	}
+	if st.hasField(s.Name) {
+		c.addError(s.Pos, "cannot declare method %v: %v already has field %v", s.Name, st, s.Name)
+	}
+
+	st.Methods[s.Name] = &FunctionType{Parameters: signature.Parameters[1:], Return: signature.Return}
+	checked := c.checkFunctionLiteral(fn).(*FunctionType)
+	st.Methods[s.Name] = &FunctionType{Parameters: checked.Parameters[1:], Return: checked.Return}
+}
+
+func (c *checker) checkAssign(s *parser.AssignStatement) {
+	object := c.checkExpression(s.Target.Object)
+	if st, ok := object.(*StructType); ok && !st.hasField(s.Target.Member) {
+		c.addError(s.Target.Pos, "unknown field %v of %v", s.Target.Member, st)
+	} else if !ok && object != Unknown {
+		c.addError(s.Target.Pos, "cannot assign field %v of %v", s.Target.Member, object)
+	}
+	c.checkExpression(s.Value)
+}
+
+// checkMember returns type of the method, fields and module members are unknown
+func (c *checker) checkMember(e *parser.MemberExpression) Type {
+	st, ok := c.checkExpression(e.Object).(*StructType)
+	if !ok || st.hasField(e.Member) {
+		return Unknown
+	}
+	if method, ok := st.Methods[e.Member]; ok {
+		return method
+	}
+	if !c.methods[st.Name][e.Member] {
+		c.addError(e.Pos, "unknown field %v of %v", e.Member, st)
+	}
+	return Unknown
+}
+
+// checkTry checks all blocks, the caught error is unknown as any value can be thrown
+func (c *checker) checkTry(s *parser.TryStatement) {
+	c.checkBlock(s.Body)
+	if s.Catch != nil {
+		c.scope = newScope(c.scope)
+		c.scope.vars[s.Name] = Unknown
+		c.checkBlock(s.Catch)
+		c.scope = c.scope.outer
+	}
+	if s.Finally != nil {
+		c.checkBlock(s.Finally)
+	}
+}
+
+func (c *checker) checkVarStatement(s *parser.VarStatementNode) {
+	if s.Pattern != nil {
+		// parts of arrays and hashes aren't typed
+		c.checkPattern(s.Pattern, c.checkExpression(s.Value))
+		return
+	}
+
+	declared := c.fromAnnotation(s.Type)
+	if s.Value == nil {
+		c.scope.vars[s.Name] = declared
+		return
+	}
+
+	// make the signature visible in the body, so recursive calls are checked
+	if fn, ok := s.Value.(*parser.FunctionLiteralExpression); ok && s.Type == nil {
+		c.scope.vars[s.Name] = c.signature(fn)
+	} else {
+		c.scope.vars[s.Name] = declared
+	}
+
+	valueType := c.checkExpression(s.Value)
+	if s.Type == nil {
+		c.scope.vars[s.Name] = valueType
+		return
+	}
+	if !assignable(valueType, declared) {
+		c.addError(s.Value.Position(), "cannot assign %v to var %v of type %v", valueType, s.Name, declared)
+	}
+}
+
+func (c *checker) checkReturnStatement(s *parser.ReturnStatementNode) Type {
+	var t Type = Null
+	if s.Value != nil {
+		t = c.checkExpression(s.Value)
+	}
+
+	fn := c.currentFunction()
+	if fn == nil {
+		return t
+	}
+	fn.returned = append(fn.returned, t)
+	if fn.declared != nil && !assignable(t, fn.declared) {
+		c.addError(s.Pos, "return type mismatch - expected %v, got %v", fn.declared, t)
+	}
+	return t
+}
+
+// checkBlock returns type of the last statement, which is the value of the block
+func (c *checker) checkBlock(b *parser.BlockStatement) Type {
+	var out Type = Null
+	for _, st := range b.Statements {
+		out = c.checkStatement(st)
+	}
+	return out
+}
+
+func (c *checker) checkExpression(exp parser.ExpressionNode) Type {
+	switch e := exp.(type) {
+	case *parser.IntegerLiteralExpression:
+		return Int
+	case *parser.FloatLiteralExpression:
+		return Float
+	case *parser.BooleanExpression:
+		return Bool
+	case *parser.StringLiteralExpression:
+		return String
+	case *parser.InterpolatedString:
+		// values of any type are converted to text
+		for _, v := range e.Values {
+			c.checkExpression(v)
+		}
+		return String
+	case *parser.IdentifierExpression:
+		return c.scope.lookup(e.Name)
+	case *parser.PrefixExpression:
+		return c.checkPrefix(e)
+	case *parser.InfixExpression:
+		return c.checkInfix(e)
+	case *parser.IfExpression:
+		return c.checkIf(e)
+	case *parser.ConditionalExpression:
+		return c.checkConditional(e)
+	case *parser.FunctionLiteralExpression:
+		return c.checkFunctionLiteral(e)
+	case *parser.MacroLiteral:
+		// the body builds code, calls of the macro are replaced before evaluation
+		return Unknown
+	case *parser.CallExpression:
+		return c.checkCall(e)
+	case *parser.MemberExpression:
+		return c.checkMember(e)
+	case *parser.ArrayLiteralExpression:
+		for _, el := range e.Elements {
+			c.checkExpression(el)
+		}
+		return Unknown
+	case *parser.IndexExpression:
+		c.checkExpression(e.Left)
+		if index := c.checkExpression(e.Index); !assignable(index, Int) && !assignable(index, String) {
+			c.addError(e.Index.Position(), "index must be int or string, got %v", index)
+		}
+		return Unknown
+	case *parser.HashLiteralExpression:
+		for i := range e.Keys {
+			if key := c.checkExpression(e.Keys[i]); !assignable(key, Int) && !assignable(key, String) {
+				c.addError(e.Keys[i].Position(), "hash key must be int or string, got %v", key)
+			}
+			c.checkExpression(e.Values[i])
+		}
+		return Unknown
+	case *parser.SpawnExpression:
+		// the result is received from a channel
+		c.checkCall(e.Call)
+		return Unknown
+	case *parser.SelectExpression:
+		c.checkSelect(e)
+		return Unknown
+	case *parser.MatchExpression:
+		return c.checkMatch(e)
+	}
+	return Unknown
+}
+
+// checkMatch checks the arms, the result is the common type of the arm bodies
+func (c *checker) checkMatch(e *parser.MatchExpression) Type {
+	subject := c.checkExpression(e.Subject)
+	var out Type
+	for _, arm := range e.Arms {
+		c.scope = newScope(c.scope)
+		c.checkPattern(arm.Pattern, subject)
+		if arm.Guard != nil {
+			c.checkExpression(arm.Guard)
+		}
+		body := c.checkExpression(arm.Body)
+		c.scope = c.scope.outer
+
+		if out == nil {
+			out = body
+			continue
+		}
+		if !assignable(out, body) || !assignable(body, out) {
+			c.addError(arm.Body.Position(), "match arms have incompatible types %v and %v", out, body)
+			return Unknown
+		}
+		out = join(out, body)
+	}
+	if out == nil {
+		return Unknown
+	}
+	return out
+}
+
+// checkPattern declares names bound by the pattern, only a pattern matching the whole value gets its type
+func (c *checker) checkPattern(pattern parser.Pattern, value Type) {
+	switch p := pattern.(type) {
+	case *parser.BindingPattern:
+		c.scope.vars[p.Name] = value
+	case *parser.LiteralPattern:
+		if literal := c.checkExpression(p.Value); !assignable(value, literal) {
+			c.addError(p.Value.Position(), "cannot match %v against %v", value, literal)
+		}
+	case *parser.ArrayPattern, *parser.HashPattern:
+		if value != Unknown {
+			c.addError(pattern.Position(), "cannot destructure %v", value)
+		}
+		for _, b := range parser.Bindings(p) {
+			c.scope.vars[b.Name] = Unknown
+		}
+	}
+}
+
+// checkSelect checks the cases, values received from channels are unknown
+func (c *checker) checkSelect(e *parser.SelectExpression) {
+	for _, sc := range e.Cases {
+		c.checkExpression(sc.Comm)
+		c.scope = newScope(c.scope)
+		if sc.Name != "" {
+			c.scope.vars[sc.Name] = Unknown
+		}
+		c.checkBlock(sc.Body)
+		c.scope = c.scope.outer
+	}
+	if e.Default != nil {
+		c.checkBlock(e.Default)
+	}
+}
+
+func (c *checker) checkPrefix(e *parser.PrefixExpression) Type {
+	right := c.checkExpression(e.Right)
+	switch e.Operator {
+	case "!":
+		return Bool
+	case "-":
+		t := numeric(right, Int)
+		if t == nil {
+			c.addError(e.Pos, "operator %v not defined for %v", e.Operator, right)
+			return Int
+		}
+		return t
+	}
+	return Unknown
+}
+
+func (c *checker) checkInfix(e *parser.InfixExpression) Type {
+	left := c.checkExpression(e.Left)
+	right := c.checkExpression(e.Right)
+
+	switch e.Operator {
+	case "+", "-", "*", "/":
+		if e.Operator == "+" && (left == String || right == String) {
+			if !assignable(left, String) || !assignable(right, String) {
+				c.addError(e.Pos, "operator %v not defined for %v and %v", e.Operator, left, right)
+			}
+			return String
+		}
+		t := numeric(left, right)
+		if t == nil {
+			c.addError(e.Pos, "operator %v not defined for %v and %v", e.Operator, left, right)
+			return Int
+		}
+		return t
+	case "<", "<=", ">", ">=":
+		if numeric(left, right) == nil {
+			c.addError(e.Pos, "operator %v not defined for %v and %v", e.Operator, left, right)
+		}
+		return Bool
+	case "==", "!=":
+		if !assignable(left, right) {
+			c.addError(e.Pos, "cannot compare %v and %v", left, right)
+		}
+		return Bool
+	case "??":
+		// the right operand is only used when the left one is null
+		if left == Null {
+			return right
+		}
+		return join(left, right)
+	}
+	return Unknown
+}
+
+func (c *checker) checkIf(e *parser.IfExpression) Type {
+	c.checkExpression(e.Condition)
+	consequence := c.checkBlock(e.Consequence)
+	if e.Alternative == nil {
+		// null when condition is not met
+		return Unknown
+	}
+
+	alternative := c.checkBlock(e.Alternative)
+	if !assignable(consequence, alternative) || !assignable(alternative, consequence) {
+		c.addError(blockPosition(e.Alternative), "if branches have incompatible types %v and %v", consequence, alternative)
+		return Unknown
+	}
+	return join(consequence, alternative)
+}
+
+func (c *checker) checkConditional(e *parser.ConditionalExpression) Type {
+	c.checkExpression(e.Condition)
+	consequence := c.checkExpression(e.Consequence)
+	alternative := c.checkExpression(e.Alternative)
+	if !assignable(consequence, alternative) || !assignable(alternative, consequence) {
+		c.addError(e.Alternative.Position(), "conditional branches have incompatible types %v and %v", consequence, alternative)
+		return Unknown
+	}
+	return join(consequence, alternative)
+}
+
+// signature is the type of function literal built only from annotations
+func (c *checker) signature(e *parser.FunctionLiteralExpression) *FunctionType {
+	out := &FunctionType{Parameters: []Type{}, Return: c.fromAnnotation(e.ReturnType)}
+	for _, p := range e.Parameters {
+		out.Parameters = append(out.Parameters, c.fromAnnotation(p.Type))
+	}
+	return out
+}
+
+func (c *checker) checkFunctionLiteral(e *parser.FunctionLiteralExpression) Type {
+	out := c.signature(e)
+
+	fn := &function{}
+	if e.ReturnType != nil {
+		fn.declared = out.Return
+	}
+
+	c.scope = newScope(c.scope)
+	c.functions = append(c.functions, fn)
+	defer func() {
+		c.scope = c.scope.outer
+		c.functions = c.functions[:len(c.functions)-1]
+	}()
+
+	for i, p := range e.Parameters {
+		c.scope.vars[p.Name] = out.Parameters[i]
+	}
+
+	bodyType := c.checkBlock(e.Body)
+	// trailing return statement was already checked
+	if !endsWithReturn(e.Body) {
+		fn.returned = append(fn.returned, bodyType)
+		if fn.declared != nil && !assignable(bodyType, fn.declared) {
+			c.addError(blockPosition(e.Body), "return type mismatch - expected %v, got %v", fn.declared, bodyType)
+		}
+	}
+
+	if fn.declared == nil {
+		out.Return = fn.returned[0]
+		for _, r := range fn.returned[1:] {
+			out.Return = join(out.Return, r)
+		}
+	}
+	return out
+}
+
+func endsWithReturn(b *parser.BlockStatement) bool {
+	if len(b.Statements) == 0 {
+		return false
+	}
+	_, ok := b.Statements[len(b.Statements)-1].(*parser.ReturnStatementNode)
+	return ok
+}
+
+func (c *checker) checkCall(e *parser.CallExpression) Type {
+	callee := c.checkExpression(e.Function)
+	args := []Type{}
+	for _, a := range e.Arguments {
+		args = append(args, c.checkExpression(a))
+	}
+
+	if callee == Unknown {
+		return Unknown
+	}
+	fn, ok := callee.(*FunctionType)
+	if !ok {
+		c.addError(e.Pos, "cannot call %v of type %v", e.Function, callee)
+		return Unknown
+	}
+
+	if len(args) != len(fn.Parameters) {
+		c.addError(e.Pos, "call error - %v expects %d arguments, got %d", e.Function, len(fn.Parameters), len(args))
+		return fn.Return
+	}
+	for i := range args {
+		if !assignable(args[i], fn.Parameters[i]) {
+			c.addError(e.Arguments[i].Position(), "call error - argument %d of %v expects %v, got %v", i+1, e.Function, fn.Parameters[i], args[i])
+		}
+	}
+	return fn.Return
+}
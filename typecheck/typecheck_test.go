@@ -0,0 +1,152 @@
+package typecheck
+
+import (
+	"programming-lang/lexer"
+	"programming-lang/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func check(t *testing.T, input string) []error {
+	program := parser.Parse(lexer.Tokenize(input))
+	require.Len(t, program.Errors, 0, "parser errors: %v", program.Errors)
+	return Check(program)
+}
+
+func TestValidPrograms(t *testing.T) {
+	tdt := []struct {
+		desc  string
+		input string
+	}{
+		{"annotated var", `var x: int = 5;`},
+		{"inferred var", `var x = 5; var y: int = x * 2;`},
+		{"boolean operators", `var b: bool = !(1 < 2) == true;`},
+		{"not annotated var", `var x;`},
+		{"unknown identifiers are not checked", `var x: int = foo + bar;`},
+		{"if with matching branches", `var x: int = if (true) { 1 } else { 2 };`},
+		{"if without else", `if (1 < 2) { 1 }`},
+		{"annotated function", `var add = fn(a: int, b: int): int { a + b }; var x: int = add(1, 2);`},
+		{"return statement", `var f = fn(a: int): bool { return a > 2; };`},
+		{"inferred return type", `var f = fn(a: int) { a > 2 }; var b: bool = f(1);`},
+		{"not annotated parameters", `var f = fn(a, b) { a + b }; f(true, 1);`},
+		{"recursion", `var fact = fn(n: int): int { if (n < 2) { 1 } else { n * fact(n - 1) } };`},
+		{"higher order function", `var apply = fn(f: fn(int): int, x: int): int { f(x) }; apply(fn(a: int): int { a * 2 }, 3);`},
+		{"function typed var", `var f: fn(int): bool = fn(x: int): bool { x > 1 };`},
+		{"floats", `var x: float = 1.5 * 2; var y: float = -x / 3; var b: bool = x < 2;`},
+		{"strings", `var s: string = "a" + "b"; var b: bool = s == "ab";`},
+		{"hashes", `var h = {"a": 1, 2: true}; var x: int = h["a"] + h[2];`},
+		{"concurrency", `var c = spawn fn(a: int) { a }(1); select { case <-c as v { v + 1 } case c <- 2 {} default {} }`},
+		{"exceptions", `var f = fn(x: int): int { if (x < 0) { throw "negative"; } else { x } }; try { f(1); } catch (e) { e.message } finally { f(2); }`},
+		{"module members are not checked", `import "lib.mk" as lib; export var x: int = lib.f("a") + lib.y;`},
+		{"match", `var x: int = 5; var y: string = match (x) { 1 => "one", n if n > 10 => "many", _ => "some" };`},
+		{"match binding gets subject type", `var b: bool = match (1.5) { x => x > 1 };`},
+		{"destructuring", `var [a, {"k": b}, ...rest] = [1, {"k": 2}]; var x: int = a + b;`},
+		{"templates", "var s: string = `${1 + 2} ${true}`;"},
+		{"macros", `var unless = macro(c, a) { quote(if (!unquote(c)) { unquote(a) }) }; var x: int = unless(false, 1);`},
+		{"structs", `struct P { x, y } var p: P = P(1, 2); p.x = p.y + 1;`},
+		{"methods", `struct P { x } fn (p: P) add(d: int): int { p.x + d } var n: int = P(1).add(2);`},
+		{"method used before declaration", `struct P { x } var f = fn(p: P) { p.twice() }; fn (p: P) twice() { p.x * 2 }`},
+		{"conditional operators", `var x: int = 1 < 2 ? 1 : 2; var n; var y: int = n ?? 3; var z: string = {"a": 1}?.a ?? "b";`},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Len(t, check(t, tc.input), 0)
+		})
+	}
+}
+
+func TestInvalidPrograms(t *testing.T) {
+	tdt := []struct {
+		desc          string
+		input         string
+		expectedError string
+	}{
+		{"annotated var", `var x: int = true;`, "typecheck error - cannot assign bool to var x of type int"},
+		{"inferred var", `var x = true; var y: int = x;`, "typecheck error - cannot assign bool to var y of type int"},
+		{"unknown type", `var x: foo = 1;`, "typecheck error - unknown type foo"},
+		{"prefix operator", `-true;`, "typecheck error - operator - not defined for bool"},
+		{"infix operator", `1 + true;`, "typecheck error - operator + not defined for int and bool"},
+		{"comparison", `false < 2;`, "typecheck error - operator < not defined for bool and int"},
+		{"equality", `1 == true;`, "typecheck error - cannot compare int and bool"},
+		{"if branches", `if (true) { 1 } else { false }`, "typecheck error - if branches have incompatible types int and bool"},
+		{"return statement", `fn(): int { return true; }`, "typecheck error - return type mismatch - expected int, got bool"},
+		{"function body", `fn(a: int): bool { a + 1 }`, "typecheck error - return type mismatch - expected bool, got int"},
+		{"parameter usage", `fn(a: bool) { a * 2 }`, "typecheck error - operator * not defined for bool and int"},
+		{"arity", `var f = fn(a: int) { a }; f(1, 2);`, "typecheck error - call error - f expects 1 arguments, got 2"},
+		{"argument type", `var f = fn(a: int, b: bool) { a }; f(1, 2);`, "typecheck error - call error - argument 2 of f expects bool, got int"},
+		{"calling not a function", `var x = 1; x(2);`, "typecheck error - cannot call x of type int"},
+		{"inferred return type", `var f = fn() { true }; var x: int = f();`, "typecheck error - cannot assign bool to var x of type int"},
+		{"string concatenation", `"a" + 1;`, "typecheck error - operator + not defined for string and int"},
+		{"float to int", `var x: int = 1 + 0.5;`, "typecheck error - cannot assign float to var x of type int"},
+		{"float operand", `1.5 * true;`, "typecheck error - operator * not defined for float and bool"},
+		{"array index", `var xs = [1, 2]; xs[true];`, "typecheck error - index must be int or string, got bool"},
+		{"hash key", `var h = {1: "a", fn() { 1 }: 2};`, "typecheck error - hash key must be int or string, got fn(): int"},
+		{"spawned call", `var f = fn(a: int) { a }; spawn f(true);`, "typecheck error - call error - argument 1 of f expects int, got bool"},
+		{"select case body", `select { case <-c as v { 1 + true } }`, "typecheck error - operator + not defined for int and bool"},
+		{"thrown value", `throw 1 + true;`, "typecheck error - operator + not defined for int and bool"},
+		{"catch block", `try {} catch (e) { var x: int = "a"; }`, "typecheck error - cannot assign string to var x of type int"},
+		{"exported var", `export var x: bool = "a";`, "typecheck error - cannot assign string to var x of type bool"},
+		{"match arms", `match (1) { 1 => 2, _ => "a" }`, "typecheck error - match arms have incompatible types int and string"},
+		{"conditional branches", `1 < 2 ? 1 : "a";`, "typecheck error - conditional branches have incompatible types int and string"},
+		{"match literal", `match (true) { 1 => 2, _ => 3 }`, "typecheck error - cannot match bool against int"},
+		{"match binding", `match ("a") { s => s * 2 }`, "typecheck error - operator * not defined for string and int"},
+		{"match guard", `match (1) { x if x + true => x }`, "typecheck error - operator + not defined for int and bool"},
+		{"destructuring a number", `var [a, b] = 1;`, "typecheck error - cannot destructure int"},
+		{"template value", "`${1 + true}`;", "typecheck error - operator + not defined for int and bool"},
+		{"template type", "var x: int = `${1}`;", "typecheck error - cannot assign string to var x of type int"},
+		{"struct annotation", `struct P { x } var p: P = 1;`, "typecheck error - cannot assign int to var p of type P"},
+		{"distinct structs", `struct P { x } struct Q { x } var p: P = Q(1);`, "typecheck error - cannot assign Q to var p of type P"},
+		{"constructor arity", `struct P { x, y } P(1);`, "typecheck error - call error - P expects 2 arguments, got 1"},
+		{"unknown field", `struct P { x } P(1).y;`, "typecheck error - unknown field y of P"},
+		{"assigning unknown field", `struct P { x } var p = P(1); p.y = 2;`, "typecheck error - unknown field y of P"},
+		{"assigning field of a number", `var n = 1; n.x = 2;`, "typecheck error - cannot assign field x of int"},
+		{"method argument", `struct P { x } fn (p: P) add(d: int) { d } P(1).add(true);`, "typecheck error - call error - argument 1 of P(1).add expects int, got bool"},
+		{"method on a number", `fn (n: int) twice() { n * 2 }`, "typecheck error - cannot declare method twice on int"},
+		{"method named like a field", `struct P { x } fn (p: P) x() { 1 }`, "typecheck error - cannot declare method x: P already has field x"},
+		{"function type", `var f: fn(int): int = fn(x: bool): int { 1 };`, "typecheck error - cannot assign fn(bool): int to var f of type fn(int): int"},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			errors := check(t, tc.input)
+			require.Len(t, errors, 1)
+			assert.EqualError(t, errors[0], tc.expectedError)
+		})
+	}
+}
+
+func TestMultipleErrorsAreReported(t *testing.T) {
+	errors := check(t, `var x: int = true;
+	var y: bool = 1;
+	1 + false;`)
+	assert.Len(t, errors, 3)
+}
+
+func TestErrorPositions(t *testing.T) {
+	tdt := []struct {
+		desc     string
+		input    string
+		expected lexer.Position
+	}{
+		{"assigned value", `var x: int = true;`, lexer.Position{Line: 1, Column: 14}},
+		{"operator", `var x = 1; x + true;`, lexer.Position{Line: 1, Column: 14}},
+		{"argument", `var f = fn(a: int) { a }; f(true);`, lexer.Position{Line: 1, Column: 29}},
+		{"arity", `var f = fn(a: int) { a }; f(1, 2);`, lexer.Position{Line: 1, Column: 28}},
+		{"return statement", `var f = fn(): int { return true; };`, lexer.Position{Line: 1, Column: 21}},
+		{"function body", `var f = fn(): int { 1; true };`, lexer.Position{Line: 1, Column: 24}},
+		{"if branches", "if (true) { 1 } else {\n\tfalse\n}", lexer.Position{Line: 2, Column: 2}},
+		{"unknown type", `var x: Foo = 1;`, lexer.Position{Line: 1, Column: 8}},
+		{"unknown field", `struct P { x } P(1).y;`, lexer.Position{Line: 1, Column: 20}},
+	}
+	for _, tc := range tdt {
+		t.Run(tc.desc, func(t *testing.T) {
+			program := parser.ParseWithPositions(lexer.TokenizeWithPositions(tc.input))
+			require.Empty(t, program.Errors)
+			errors := Check(program)
+			require.NotEmpty(t, errors)
+			require.IsType(t, &parser.SyntaxError{}, errors[0])
+			assert.Equal(t, tc.expected, errors[0].(*parser.SyntaxError).Pos)
+		})
+	}
+}
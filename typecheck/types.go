@@ -0,0 +1,145 @@
+package typecheck
+
+import (
+	"programming-lang/parser"
+	"strings"
+)
+
+type Type interface {
+	String() string
+}
+
+type BasicType string
+
+func (b BasicType) String() string {
+	return string(b)
+}
+
+const (
+	Int    BasicType = "int"
+	Float  BasicType = "float"
+	Bool   BasicType = "bool"
+	String BasicType = "string"
+	Null   BasicType = "null"
+
+	// Array, Hash and Channel are opaque, types of elements aren't tracked
+	Array   BasicType = "array"
+	Hash    BasicType = "hash"
+	Channel BasicType = "channel"
+
+	// Unknown is the type of bindings that are neither annotated nor inferable.
+	// It's compatible with every other type
+	Unknown BasicType = "unknown"
+)
+
+type FunctionType struct {
+	Parameters []Type
+	Return     Type
+}
+
+func (f *FunctionType) String() string {
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + "): " + f.Return.String()
+}
+
+// StructType is a declared struct, values of fields aren't typed. Each declaration is a distinct type
+type StructType struct {
+	Name    string
+	Fields  []string
+	Methods map[string]*FunctionType // without the receiver
+}
+
+func (s *StructType) String() string {
+	return s.Name
+}
+
+func (s *StructType) hasField(name string) bool {
+	for _, f := range s.Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// assignable reports whether value of type from can be used where type to is expected
+func assignable(from, to Type) bool {
+	if from == Unknown || to == Unknown {
+		return true
+	}
+
+	fromFn, fromIsFn := from.(*FunctionType)
+	toFn, toIsFn := to.(*FunctionType)
+	if fromIsFn != toIsFn {
+		return false
+	} else if !fromIsFn {
+		return from == to
+	}
+
+	if len(fromFn.Parameters) != len(toFn.Parameters) {
+		return false
+	}
+	for i := range fromFn.Parameters {
+		if !assignable(toFn.Parameters[i], fromFn.Parameters[i]) {
+			return false
+		}
+	}
+	return assignable(fromFn.Return, toFn.Return)
+}
+
+// numeric returns the type of arithmetic on the operands, ints are converted to floats when mixed.
+// Returns nil when the operands aren't numbers
+func numeric(left, right Type) Type {
+	isNumber := func(t Type) bool {
+		return assignable(t, Int) || assignable(t, Float)
+	}
+	if !isNumber(left) || !isNumber(right) {
+		return nil
+	}
+	if left == Float || right == Float {
+		return Float
+	}
+	return Int
+}
+
+// join returns the common type of two branches, Unknown when they differ
+func join(a, b Type) Type {
+	if a == Unknown || b == Unknown {
+		return Unknown
+	}
+	if assignable(a, b) && assignable(b, a) {
+		return a
+	}
+	return Unknown
+}
+
+func (c *checker) fromAnnotation(annotation *parser.TypeAnnotation) Type {
+	if annotation == nil {
+		return Unknown
+	}
+
+	switch annotation.Name {
+	case "int":
+		return Int
+	case "float":
+		return Float
+	case "bool":
+		return Bool
+	case "string":
+		return String
+	case "fn":
+		out := &FunctionType{Parameters: []Type{}, Return: c.fromAnnotation(annotation.Return)}
+		for _, p := range annotation.Parameters {
+			out.Parameters = append(out.Parameters, c.fromAnnotation(p))
+		}
+		return out
+	}
+	if st := c.scope.lookupStruct(annotation.Name); st != nil {
+		return st
+	}
+	c.addError(annotation.Pos, "unknown type %v", annotation.Name)
+	return Unknown
+}